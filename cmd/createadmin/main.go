@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/GitNinja36/wello-backend/config"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/service"
+)
+
+// createadmin seeds the first ADMIN account. Every later admin must be
+// created through POST /admin/register by an authenticated admin.
+//
+//	go run ./cmd/createadmin -email admin@wello.com -password '...'
+//
+// Flags fall back to ADMIN_NAME, ADMIN_EMAIL, ADMIN_PHONE and ADMIN_PASSWORD.
+func main() {
+	name := flag.String("name", os.Getenv("ADMIN_NAME"), "admin display name")
+	email := flag.String("email", os.Getenv("ADMIN_EMAIL"), "admin email")
+	phone := flag.String("phone", os.Getenv("ADMIN_PHONE"), "admin phone number")
+	password := flag.String("password", os.Getenv("ADMIN_PASSWORD"), "admin password")
+	position := flag.String("position", "Super Admin", "admin position")
+	department := flag.String("department", "", "admin department")
+	flag.Parse()
+
+	db := config.ConnectDB()
+
+	admin, err := service.NewAdminService(repository.NewGormRepos(db)).BootstrapAdmin(service.AdminInput{
+		Name:       *name,
+		Email:      *email,
+		Phone:      *phone,
+		Password:   *password,
+		Position:   *position,
+		Department: *department,
+	})
+	if errors.Is(err, service.ErrAdminExists) {
+		log.Fatal(" An admin already exists. Use POST /admin/register as that admin instead.")
+	}
+	if errors.Is(err, service.ErrBootstrapRunning) {
+		log.Fatal(" Another createadmin run is in progress.")
+	}
+	if err != nil {
+		log.Fatalf(" Failed to create admin: %v", err)
+	}
+
+	fmt.Printf("Admin account created: %s (%s)\n", admin.Email, admin.ID)
+}
@@ -5,9 +5,17 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/GitNinja36/wello-backend/config"
+	"github.com/GitNinja36/wello-backend/internal/app"
+	"github.com/GitNinja36/wello-backend/internal/controllers"
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/otp"
 	"github.com/GitNinja36/wello-backend/internal/routes"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/GitNinja36/wello-backend/internal/utils"
 	"github.com/joho/godotenv"
 )
 
@@ -17,9 +25,56 @@ func main() {
 		log.Fatalf(" Error loading .env file: %v", err)
 	}
 
-	config.ConnectDB()
+	keys, err := utils.LoadKeysFromEnv()
+	if err != nil {
+		log.Fatalf(" Refusing to start, JWT keys invalid: %v", err)
+	}
+
+	pepper, err := otp.PepperFromEnv()
+	if err != nil {
+		log.Fatalf(" Refusing to start, %v", err)
+	}
+
+	db := config.ConnectDB()
+
+	var otpStore otp.Store = otp.NewPostgresStore(db, otp.DefaultPolicy, pepper)
+	if os.Getenv("OTP_STORE") == "memory" {
+		otpStore = otp.NewMemoryStore(otp.DefaultPolicy, pepper)
+	}
+	go purgeOTPs(otpStore, 10*time.Minute)
+
+	expiry, err := domain.ParseExpiryPolicy(envOr("EXPIRE_PENDING_AFTER", "48h"), envOr("EXPIRE_RESCHEDULE_AFTER", "48h"))
+	if err != nil {
+		log.Fatalf(" Refusing to start, %v", err)
+	}
+	cfg := app.DefaultConfig()
+	cfg.ProposalTTL = expiry.RescheduleTTL
+	cfg.Currency = envOr("PAYMENT_CURRENCY", "INR")
+	cfg.CommissionPercent, err = domain.ParseCommissionPercent(envOr("PLATFORM_COMMISSION_PERCENT", "10"))
+	if err != nil {
+		log.Fatalf(" Refusing to start, %v", err)
+	}
+	cfg.NoShow, err = domain.ParseNoShowPolicy(envOr("NO_SHOW_LIMIT", "3"),
+		envOr("NO_SHOW_WINDOW", "2160h"), envOr("NO_SHOW_RESTRICTION", "720h"))
+	if err != nil {
+		log.Fatalf(" Refusing to start, %v", err)
+	}
+
+	application, err := app.New(db, keys, otpStore, cfg)
+	if err != nil {
+		log.Fatalf(" Refusing to start, notification or payment config invalid: %v", err)
+	}
+
+	go notify.NewWorker(notify.NewGormOutbox(db), application.Notifier).Run(15 * time.Second)
+	reminderOffsets, err := domain.ParseReminderOffsets(envOr("REMINDER_OFFSETS", "24h,1h"))
+	if err != nil {
+		log.Fatalf(" Refusing to start, %v", err)
+	}
+	go service.NewReminderScheduler(application.Repos, reminderOffsets).Run(time.Minute)
 
-	router := routes.SetupRoutes()
+	go service.NewRequestExpirer(application.Appointments, expiry).Run(5 * time.Minute)
+
+	router := routes.SetupRoutes(controllers.NewHandler(application))
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -28,3 +83,21 @@ func main() {
 	fmt.Printf("Server running on http://localhost:%s\n", port)
 	log.Fatal(http.ListenAndServe(":"+port, router))
 }
+
+// purgeOTPs periodically drops expired OTP entries from store.
+func purgeOTPs(store otp.Store, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := store.Purge(); err != nil {
+			log.Printf("OTP purge failed: %v", err)
+		}
+	}
+}
+
+func envOr(key, fallback string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	return fallback
+}
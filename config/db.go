@@ -12,9 +12,9 @@ import (
 	"gorm.io/gorm"
 )
 
-var DB *gorm.DB
-
-func ConnectDB() {
+// ConnectDB opens the database from DB_URL and migrates it, exiting on
+// failure.
+func ConnectDB() *gorm.DB {
 	err := godotenv.Load()
 	if err != nil {
 		log.Fatalf(" Error loading .env file: %v", err)
@@ -37,12 +37,28 @@ func ConnectDB() {
 		&models.Appointment{},
 		&models.MedicalCheck{},
 		&models.Order{},
+		&models.Medicine{},
+		&models.Prescription{},
+		&models.Payment{},
+		&models.Transaction{},
+		&models.LedgerEntry{},
 		&models.Review{},
+		&models.DoctorLeave{},
+		&models.OTPCode{},
+		&models.Session{},
+		&models.RecoveryCode{},
+		&models.Notification{},
+		&models.AppointmentReminder{},
+		&models.RescheduleProposal{},
 	)
 	if err != nil {
 		log.Fatalf(" AutoMigration failed: %v", err)
 	}
 
-	DB = db
+	if err := runDataMigrations(db); err != nil {
+		log.Fatalf(" Data migration failed: %v", err)
+	}
+
 	fmt.Println("Connected to DB & AutoMigrated successfully.")
+	return db
 }
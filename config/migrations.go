@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"gorm.io/gorm"
+)
+
+// runDataMigrations performs one-off data conversions that AutoMigrate
+// cannot express. Each step must be safe to run on every startup.
+func runDataMigrations(db *gorm.DB) error {
+	if err := dropLegacyUserIndexes(db); err != nil {
+		return err
+	}
+	if err := backfillAppointmentDurations(db); err != nil {
+		return err
+	}
+	return migrateLegacyAvailability(db)
+}
+
+// backfillAppointmentDurations stores the doctor's current slot length on
+// appointments booked before the booked duration was recorded.
+func backfillAppointmentDurations(db *gorm.DB) error {
+	return db.Exec(`UPDATE appointments a
+		SET duration_minutes = CASE WHEN d.slot_duration > 0 THEN d.slot_duration ELSE 30 END
+		FROM doctor_profiles d
+		WHERE d.id = a.doctor_profile_id AND a.duration_minutes = 0`).Error
+}
+
+// dropLegacyUserIndexes removes the old unique indexes on users.email and
+// users.phone. They treated "" as a value, so a second phone-only or
+// email-only signup collided; the partial indexes on User replace them.
+func dropLegacyUserIndexes(db *gorm.DB) error {
+	for _, idx := range []string{"idx_users_email", "idx_users_phone"} {
+		if err := db.Exec("DROP INDEX IF EXISTS " + idx).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateLegacyAvailability converts the old free-form availability_slots
+// jsonb string into the typed availability column. The legacy column is
+// left in place so the conversion can be audited or re-run.
+func migrateLegacyAvailability(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.DoctorProfile{}, "availability_slots") {
+		return nil
+	}
+
+	type legacyRow struct {
+		ID                string
+		AvailabilitySlots *string
+		SlotDuration      int
+	}
+	var rows []legacyRow
+	if err := db.Raw(`SELECT id, availability_slots::text AS availability_slots, slot_duration
+		FROM doctor_profiles WHERE availability IS NULL`).Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		raw := ""
+		if row.AvailabilitySlots != nil {
+			raw = *row.AvailabilitySlots
+		}
+		duration := time.Duration(row.SlotDuration) * time.Minute
+		if duration <= 0 {
+			duration = 30 * time.Minute
+		}
+
+		availability, err := domain.FromLegacySlots(raw, duration)
+		if err != nil {
+			fmt.Printf("Skipping availability migration for doctor profile %s: %v\n", row.ID, err)
+			availability = models.Availability{}
+		}
+		if err := db.Model(&models.DoctorProfile{}).Where("id = ?", row.ID).
+			Update("availability", availability).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
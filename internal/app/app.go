@@ -0,0 +1,138 @@
+// Package app wires repositories and services into one value that the HTTP
+// layer is built from, replacing package-level globals.
+package app
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/otp"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+type App struct {
+	Repos repository.Repos
+
+	// Keys signs and verifies every JWT the API issues.
+	Keys *utils.KeyManager
+	// OTP holds the one-time codes sent for login, signup and password
+	// reset.
+	OTP otp.Store
+
+	// Notifier sends immediately (OTP codes); Outbox queues everything else
+	// for the notify worker.
+	Notifier notify.Notifier
+	Outbox   notify.Queue
+
+	// Gateway is the payment provider that fees and orders are charged
+	// through.
+	Gateway payments.Gateway
+
+	Users    *service.UserService
+	Doctors  *service.DoctorService
+	Patients *service.PatientService
+	Stats    *service.StatsService
+	Orders   *service.OrderService
+	Catalog  *service.CatalogService
+
+	Sessions      *service.SessionService
+	Auth          *service.AuthService
+	TwoFactor     *service.TwoFactorService
+	Admin         *service.AdminService
+	Appointments  *service.AppointmentService
+	Prescriptions *service.PrescriptionService
+	Ledger        *service.LedgerService
+	Payments      *service.PaymentService
+}
+
+// Config holds the business settings read at startup.
+type Config struct {
+	// ProposalTTL caps how long a reschedule proposal stays open; it matches
+	// the deadline that expires unanswered reschedule requests.
+	ProposalTTL time.Duration
+	// NoShow restricts booking for patients who repeatedly miss appointments.
+	NoShow domain.NoShowPolicy
+	// Currency is charged for every payment and used for payouts.
+	Currency string
+	// CommissionPercent is the platform's cut of every appointment fee.
+	CommissionPercent float64
+}
+
+// DefaultConfig is the configuration used when the environment sets nothing.
+func DefaultConfig() Config {
+	return Config{
+		ProposalTTL: 48 * time.Hour,
+		NoShow: domain.NoShowPolicy{
+			Limit:       3,
+			Window:      90 * 24 * time.Hour,
+			Restriction: 30 * 24 * time.Hour,
+		},
+		Currency:          "INR",
+		CommissionPercent: 10,
+	}
+}
+
+// New builds the application on Postgres, with notification providers and
+// the payment gateway taken from the environment and the outbox in the
+// notifications table.
+func New(db *gorm.DB, keys *utils.KeyManager, otpStore otp.Store, cfg Config) (*App, error) {
+	notifier, err := notify.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	gateway, err := payments.FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return build(repository.NewGormRepos(db), keys, otpStore, notifier, gateway, cfg), nil
+}
+
+// NewWithRepos builds the application on the given repositories, e.g.
+// repository.NewMemoryRepos() in tests. Tokens are signed with a random
+// HMAC key, OTP codes and notifications are kept in memory, payments go
+// through the mock gateway and the configuration is DefaultConfig.
+func NewWithRepos(repos repository.Repos) *App {
+	secret := make([]byte, 32)
+	rand.Read(secret)
+	keys := utils.NewKeyManager(&utils.SigningKey{ID: "local", Method: jwt.SigningMethodHS256, Secret: secret})
+	pepper := make([]byte, 32)
+	rand.Read(pepper)
+	return build(repos, keys, otp.NewMemoryStore(otp.DefaultPolicy, pepper), notify.NewMemoryNotifier(),
+		payments.NewMockGateway("local-test-webhook-secret"), DefaultConfig())
+}
+
+func build(repos repository.Repos, keys *utils.KeyManager, otpStore otp.Store, n notify.Notifier, gw payments.Gateway, cfg Config) *App {
+	ledger := service.NewLedgerService(repos, cfg.Currency, cfg.CommissionPercent)
+	paymentService := service.NewPaymentService(repos, gw, ledger)
+	orders := service.NewOrderService(repos, paymentService)
+	return &App{
+		Repos:    repos,
+		Keys:     keys,
+		OTP:      otpStore,
+		Notifier: n,
+		Outbox:   repos.Outbox,
+		Gateway:  gw,
+		Users:    service.NewUserService(repos),
+		Doctors:  service.NewDoctorService(repos, repos.Outbox),
+		Patients: service.NewPatientService(repos),
+		Stats:    service.NewStatsService(repos),
+		Orders:   orders,
+		Catalog:  service.NewCatalogService(repos),
+
+		Sessions:      service.NewSessionService(repos, keys),
+		Auth:          service.NewAuthService(repos),
+		TwoFactor:     service.NewTwoFactorService(repos),
+		Admin:         service.NewAdminService(repos),
+		Appointments:  service.NewAppointmentService(repos, paymentService, cfg.ProposalTTL, cfg.NoShow),
+		Prescriptions: service.NewPrescriptionService(repos, orders),
+		Ledger:        ledger,
+		Payments:      paymentService,
+	}
+}
@@ -0,0 +1,38 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/service"
+)
+
+// The services built by NewWithRepos share the given repositories, so what
+// one writes the others read.
+func TestNewWithReposSharesRepositories(t *testing.T) {
+	a := NewWithRepos(repository.NewMemoryRepos())
+
+	user, err := a.Users.OnboardDoctor(service.DoctorRegistration{
+		Name:           "Dr Rao",
+		Phone:          "+919811111111",
+		Specialization: "Cardiology",
+	})
+	if err != nil {
+		t.Fatalf("onboard: %v", err)
+	}
+
+	profile, err := a.Doctors.Profile(user.ID)
+	if err != nil {
+		t.Fatalf("profile: %v", err)
+	}
+	if profile.Specialization != "Cardiology" || !profile.IsPending {
+		t.Fatalf("profile %+v", profile)
+	}
+
+	if _, err := a.Repos.Users.FindByPhone("+919811111111"); err != nil {
+		t.Fatalf("user not in repos: %v", err)
+	}
+	if a.Gateway.Name() != "mock" {
+		t.Fatalf("gateway %q, want the mock", a.Gateway.Name())
+	}
+}
@@ -2,16 +2,21 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"time"
 
-	"github.com/GitNinja36/wello-backend/config"
+	"github.com/GitNinja36/wello-backend/internal/domain"
 	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+	"github.com/GitNinja36/wello-backend/internal/service"
 	"github.com/GitNinja36/wello-backend/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
-func CreateAdminAccount(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateAdminAccount(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		Name       string `json:"name"`
 		Email      string `json:"email"`
@@ -32,43 +37,162 @@ func CreateAdminAccount(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	hashedPassword, err := utils.HashPassword(req.Password)
+	adminUser, err := h.Admin.CreateAdmin(service.AdminInput{
+		Name:       req.Name,
+		Email:      req.Email,
+		Phone:      req.Phone,
+		Password:   req.Password,
+		Position:   req.Position,
+		Department: req.Department,
+	})
+	if errors.Is(err, utils.ErrWeakPassword) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, "Error hashing password", http.StatusInternalServerError)
+		http.Error(w, "Failed to create admin account", http.StatusInternalServerError)
 		return
 	}
 
-	adminUser := models.User{
-		Name:       req.Name,
-		Email:      req.Email,
-		Phone:      req.Phone,
-		Password:   hashedPassword,
-		Role:       models.ADMIN,
-		Verified:   true,
-		IsApproved: true,
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Admin account created successfully",
+		"userId":  adminUser.ID,
+	})
+}
+
+// Change a user's role or approval; the user's sessions are revoked so that
+// stale role/approval claims cannot be used any more
+func (h *Handler) UpdateUserAccess(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	var req struct {
+		Role       *models.Role `json:"role"`
+		IsApproved *bool        `json:"isApproved"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if req.Role == nil && req.IsApproved == nil {
+		http.Error(w, "Nothing to update", http.StatusBadRequest)
+		return
 	}
 
-	if err := config.DB.Create(&adminUser).Error; err != nil {
-		http.Error(w, "Failed to create admin user", http.StatusInternalServerError)
+	err := h.Admin.UpdateUserAccess(userID, service.AccessInput{Role: req.Role, IsApproved: req.IsApproved})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRole):
+			http.Error(w, "Invalid role", http.StatusBadRequest)
+		case errors.Is(err, service.ErrUserNotFound):
+			http.Error(w, "User not found", http.StatusNotFound)
+		case errors.Is(err, service.ErrNoDoctorProfile):
+			http.Error(w, "User has no doctor profile", http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, "Failed to update user", http.StatusInternalServerError)
+		}
 		return
 	}
 
-	adminProfile := models.AdminProfile{
-		UserID:     adminUser.ID,
-		Position:   req.Position,
-		Department: req.Department,
-		CreatedAt:  time.Now(),
-		UpdatedAt:  time.Now(),
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "User access updated; existing sessions were signed out",
+	})
+}
+
+// Count expired appointment requests per doctor, optionally since a date
+func (h *Handler) GetExpiredRequestCounts(w http.ResponseWriter, r *http.Request) {
+	var since *time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "since must be a date like 2006-01-02", http.StatusBadRequest)
+			return
+		}
+		since = &t
 	}
 
-	if err := config.DB.Create(&adminProfile).Error; err != nil {
-		http.Error(w, "Failed to create admin profile", http.StatusInternalServerError)
+	counts, err := h.Stats.ExpiredRequestsByDoctor(since)
+	if err != nil {
+		http.Error(w, "Failed to count expired requests", http.StatusInternalServerError)
+		return
+	}
+
+	var total int64
+	for _, c := range counts {
+		total += c.Count
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"total":   total,
+		"doctors": counts,
+	})
+}
+
+// Let a patient blocked for repeated no-shows book again
+func (h *Handler) LiftBookingRestriction(w http.ResponseWriter, r *http.Request) {
+	if err := h.Appointments.LiftBookingRestriction(chi.URLParam(r, "id")); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to lift restriction", http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Admin account created successfully",
-		"userId":  adminUser.ID,
+		"message": "Booking restriction lifted",
+	})
+}
+
+// Show a doctor's ledger and what they are currently owed
+func (h *Handler) GetDoctorLedger(w http.ResponseWriter, r *http.Request) {
+	doctorProfileID := chi.URLParam(r, "id")
+
+	txns, err := h.Repos.Ledger.ForDoctor(doctorProfileID)
+	if err != nil {
+		http.Error(w, "Failed to fetch ledger", http.StatusInternalServerError)
+		return
+	}
+	balance, err := h.Ledger.DoctorBalance(doctorProfileID)
+	if err != nil {
+		http.Error(w, "Failed to fetch ledger", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"transactions": txns,
+		"balance":      payments.FromMinor(balance),
+	})
+}
+
+// Record money paid out to a doctor: {"amount": 1500, "reference": "NEFT 123"}
+func (h *Handler) CreateDoctorPayout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Amount    float64 `json:"amount"`
+		Reference string  `json:"reference"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	txn, err := h.Ledger.RecordPayout(chi.URLParam(r, "id"), req.Amount, strings.TrimSpace(req.Reference))
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			http.Error(w, "Doctor not found", http.StatusNotFound)
+		case errors.Is(err, domain.ErrInvalidPayout):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case errors.Is(err, domain.ErrPayoutExceedsBalance):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		default:
+			http.Error(w, "Failed to record payout", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Payout recorded",
+		"transaction": txn,
 	})
 }
@@ -2,12 +2,17 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/GitNinja36/wello-backend/config"
+	"github.com/GitNinja36/wello-backend/internal/domain"
 	"github.com/GitNinja36/wello-backend/internal/middleware"
 	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"gorm.io/gorm"
 )
 
 // Book Appointment
@@ -16,13 +21,12 @@ type BookAppointmentRequest struct {
 	ScheduledAt   string `json:"scheduledAt"`
 	Mode          string `json:"mode"`
 	Location      string `json:"location"`
-	FeePaid       bool   `json:"feePaid"`
 	PatientName   string `json:"patientName"`
 	ContactNumber string `json:"contactNumber"`
 	Age           int    `json:"age"`
 }
 
-func BookAppointment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) BookAppointment(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -41,23 +45,84 @@ func BookAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	appt := models.Appointment{
+	if req.Mode == "" {
+		req.Mode = string(models.APPT_MODE_ONLINE)
+	}
+
+	appt, err := h.Appointments.Book(service.BookingInput{
 		PatientID:       userID,
 		DoctorProfileID: req.DoctorID,
 		ScheduledAt:     scheduledTime,
 		Mode:            models.AppointmentMode(req.Mode),
-		Location:        &req.Location,
-		FeePaid:         req.FeePaid,
-		Status:          models.PENDING,
+		Location:        req.Location,
+	})
+	if err != nil {
+		writeBookingError(w, err)
+		return
 	}
 
-	if err := config.DB.Create(&appt).Error; err != nil {
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Appointment booked successfully",
+		"id":      appt.ID,
+	})
+}
+
+// writeBookingError maps booking failures to 404/409/422 responses
+func writeBookingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "Doctor not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrSlotTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrBookingRestricted):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, domain.ErrOutsideAvailability),
+		errors.Is(err, domain.ErrDoctorNotApproved),
+		errors.Is(err, domain.ErrSlotInPast),
+		errors.Is(err, domain.ErrDoctorOnLeave):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
 		http.Error(w, "Failed to book appointment", http.StatusInternalServerError)
+	}
+}
+
+// writeTransitionError maps state machine errors to HTTP responses
+func writeTransitionError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrRoleNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, domain.ErrInvalidTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrUnknownEvent), errors.Is(err, domain.ErrReasonRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrBeforeStart):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// applyWithReason fires event on appointment with the reason from the request
+// body ({"reason": "..."}) and writes message on success
+func (h *Handler) applyWithReason(w http.ResponseWriter, r *http.Request, appointment *models.Appointment, event domain.AppointmentEvent, message string) {
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	var changes map[string]interface{}
+	if reason := strings.TrimSpace(req.Reason); reason != "" {
+		changes = map[string]interface{}{domain.ReasonKey: reason}
+	}
+	if err := h.Appointments.Transition(appointment, event, middleware.GetRoleFromContext(r), changes); err != nil {
+		writeTransitionError(w, err, "Failed to update appointment")
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Appointment booked successfully",
-		"id":      appt.ID,
+		"message": message,
 	})
 }
@@ -2,21 +2,33 @@ package controllers
 
 import (
 	"encoding/json"
-	"fmt"
-	"math/rand"
+	"errors"
 	"net/http"
-	"time"
+	"strings"
 
-	"github.com/GitNinja36/wello-backend/config"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
 	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/otp"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/service"
 	"github.com/GitNinja36/wello-backend/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
 )
 
-func generateOTP() string {
-	return fmt.Sprintf("%06d", rand.Intn(1000000))
+// writeOTPError maps OTP store errors to HTTP responses
+func writeOTPError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, otp.ErrCooldown), errors.Is(err, otp.ErrQuotaExceeded), errors.Is(err, otp.ErrTooManyAttempts):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, otp.ErrInvalidCode), errors.Is(err, otp.ErrExpired):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	default:
+		http.Error(w, "Failed to process OTP", http.StatusInternalServerError)
+	}
 }
 
-func SendOTPPhone(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) SendOTPPhone(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		Phone string `json:"phone"`
 	}
@@ -26,10 +38,18 @@ func SendOTPPhone(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	otp := generateOTP()
-	utils.SaveOTP(req.Phone, otp, 5*time.Minute)
+	if strings.TrimSpace(req.Phone) == "" {
+		http.Error(w, "Phone is required", http.StatusBadRequest)
+		return
+	}
+
+	code, err := otp.Issue(h.OTP, req.Phone)
+	if err != nil {
+		writeOTPError(w, err)
+		return
+	}
 
-	if err := utils.SendSMS(req.Phone, fmt.Sprintf("Your Wello OTP is: %s", otp)); err != nil {
+	if err := h.sendOTP("", req.Phone, code); err != nil {
 		http.Error(w, "Failed to send OTP via SMS", http.StatusInternalServerError)
 		return
 	}
@@ -39,47 +59,19 @@ func SendOTPPhone(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func VerifyOTPPhone(w http.ResponseWriter, r *http.Request) {
-	type Request struct {
+func (h *Handler) VerifyOTPPhone(w http.ResponseWriter, r *http.Request) {
+	var req struct {
 		Phone string `json:"phone"`
 		OTP   string `json:"otp"`
 	}
-	var req Request
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
-
-	if !utils.VerifyOTP(req.Phone, req.OTP) {
-		http.Error(w, "Invalid OTP", http.StatusUnauthorized)
-		return
-	}
-
-	var user models.User
-	result := config.DB.Where("phone = ?", req.Phone).First(&user)
-	if result.Error == nil {
-		token := utils.GenerateJWT(user.ID, string(user.Role), user.IsApproved, false)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"token":        token,
-			"role":         user.Role,
-			"isApproved":   user.IsApproved,
-			"newUser":      false,
-			"needsProfile": false,
-		})
-		return
-	}
-
-	token := utils.GenerateJWT(req.Phone, "PATIENT", true, true)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"token":        token,
-		"role":         "PATIENT",
-		"isApproved":   true,
-		"newUser":      true,
-		"needsProfile": true,
-	})
+	h.verifyOTPLogin(w, r, req.Phone, req.OTP, utils.IdentifierPhone, h.Repos.Users.FindByPhone)
 }
 
-func SendOTPEmail(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) SendOTPEmail(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		Email string `json:"email"`
 	}
@@ -89,10 +81,18 @@ func SendOTPEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	otp := generateOTP()
-	utils.SaveOTP(req.Email, otp, 5*time.Minute)
+	if strings.TrimSpace(req.Email) == "" {
+		http.Error(w, "Email is required", http.StatusBadRequest)
+		return
+	}
+
+	code, err := otp.Issue(h.OTP, req.Email)
+	if err != nil {
+		writeOTPError(w, err)
+		return
+	}
 
-	if err := utils.SendEmailOTP(req.Email, otp); err != nil {
+	if err := h.sendOTP(req.Email, "", code); err != nil {
 		http.Error(w, "Failed to send OTP via email", http.StatusInternalServerError)
 		return
 	}
@@ -102,42 +102,153 @@ func SendOTPEmail(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func VerifyOTPEmail(w http.ResponseWriter, r *http.Request) {
-	type Request struct {
+func (h *Handler) VerifyOTPEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
 		Email string `json:"email"`
 		OTP   string `json:"otp"`
 	}
-	var req Request
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
+	h.verifyOTPLogin(w, r, req.Email, req.OTP, utils.IdentifierEmail, h.Repos.Users.FindByEmail)
+}
 
-	if !utils.VerifyOTP(req.Email, req.OTP) {
-		http.Error(w, "Invalid OTP", http.StatusUnauthorized)
+// verifyOTPLogin checks code for identifier and signs in the account find
+// returns for it. An unknown identifier gets an onboarding token instead; it
+// has no role until signup picks one.
+func (h *Handler) verifyOTPLogin(w http.ResponseWriter, r *http.Request, identifier, code, identifierType string, find func(string) (*models.User, error)) {
+	if err := h.OTP.Verify(identifier, code); err != nil {
+		writeOTPError(w, err)
 		return
 	}
 
-	var user models.User
-	result := config.DB.Where("email = ?", req.Email).First(&user)
-	if result.Error == nil {
-		token := utils.GenerateJWT(user.ID, string(user.Role), user.IsApproved, false)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"token":        token,
-			"role":         user.Role,
-			"isApproved":   user.IsApproved,
-			"newUser":      false,
-			"needsProfile": false,
-		})
+	user, err := find(identifier)
+	if err == nil {
+		if user.Role == models.ADMIN {
+			http.Error(w, "Admins must log in with password and OTP at /auth/login", http.StatusForbidden)
+			return
+		}
+		h.startLogin(w, r, user)
+		return
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Failed to look up account", http.StatusInternalServerError)
 		return
 	}
 
-	token := utils.GenerateJWT(req.Email, "PATIENT", true, true)
+	token, err := h.Keys.GenerateOnboardingJWT(identifier, identifierType)
+	if err != nil {
+		http.Error(w, "Failed to issue token", http.StatusInternalServerError)
+		return
+	}
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"token":        token,
-		"role":         "PATIENT",
-		"isApproved":   true,
+		"role":         "",
+		"isApproved":   false,
 		"newUser":      true,
 		"needsProfile": true,
 	})
 }
+
+// Exchange a refresh token for a new token pair
+func (h *Handler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	tokens, err := h.Sessions.Refresh(req.RefreshToken, r.RemoteAddr)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		http.Error(w, "Failed to refresh session", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(tokens)
+}
+
+// Logout revokes the session of the current access token
+func (h *Handler) Logout(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaimsFromContext(r)
+	if claims == nil || claims.SessionID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.Sessions.Revoke(claims.UserID, claims.SessionID); err != nil &&
+		!errors.Is(err, gorm.ErrRecordNotFound) {
+		http.Error(w, "Failed to log out", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Logged out successfully",
+	})
+}
+
+// List active sessions of the current user
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaimsFromContext(r)
+	if claims == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessions, err := h.Sessions.List(claims.UserID)
+	if err != nil {
+		http.Error(w, "Failed to fetch sessions", http.StatusInternalServerError)
+		return
+	}
+
+	type sessionView struct {
+		models.Session
+		Current bool `json:"current"`
+	}
+	views := make([]sessionView, 0, len(sessions))
+	for _, s := range sessions {
+		views = append(views, sessionView{Session: s, Current: s.ID == claims.SessionID})
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sessions": views,
+	})
+}
+
+// Revoke one session (device) of the current user
+func (h *Handler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	sessionID := chi.URLParam(r, "id")
+	if err := h.Sessions.Revoke(userID, sessionID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to revoke session", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Session revoked successfully",
+	})
+}
+
+// Publish public JWT verification keys for other services
+func (h *Handler) GetJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"keys": h.Keys.JWKS(),
+	})
+}
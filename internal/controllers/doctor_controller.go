@@ -1,22 +1,41 @@
 package controllers
 
 import (
+	"bytes"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
-	"strings"
+	"strconv"
 	"time"
 
-	"github.com/GitNinja36/wello-backend/config"
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/mailer"
 	"github.com/GitNinja36/wello-backend/internal/middleware"
 	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/service"
 	"github.com/GitNinja36/wello-backend/internal/utils"
 	"github.com/go-chi/chi/v5"
 	"github.com/jung-kurt/gofpdf"
+	"gorm.io/gorm"
 )
 
+// writeLookupError maps service not-found errors to 404s and anything else to
+// a 500 with fallback as the message
+func writeLookupError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrDoctorProfileNotFound):
+		http.Error(w, "Doctor profile not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrAppointmentNotFound):
+		http.Error(w, "Appointment not found", http.StatusNotFound)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
 // update Doctor profile
-func UpdateDoctorProfile(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateDoctorProfile(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		Name           string  `json:"name"`
 		Age            *int    `json:"age"`
@@ -43,50 +62,21 @@ func UpdateDoctorProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userUpdates := map[string]interface{}{
-		"name": req.Name,
-	}
-	if req.Age != nil {
-		userUpdates["age"] = *req.Age
-	} else {
-		userUpdates["age"] = nil
-	}
-	if req.Gender != nil {
-		userUpdates["gender"] = *req.Gender
-	} else {
-		userUpdates["gender"] = nil
-	}
-	if req.Address != nil {
-		userUpdates["address"] = *req.Address
-	} else {
-		userUpdates["address"] = nil
-	}
-
-	if err := config.DB.Model(&models.User{}).Where("id = ?", userID).Updates(userUpdates).Error; err != nil {
-		http.Error(w, "Failed to update user details", http.StatusInternalServerError)
-		return
-	}
-
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	profile.Specialization = req.Specialization
-	profile.LicenseNumber = req.LicenseNumber
-	profile.ClinicName = req.ClinicName
-	profile.Experience = req.Experience
-	profile.Bio = req.Bio
-	profile.Certifications = req.Certifications
-	if req.PhotoURL != nil {
-		profile.PhotoURL = req.PhotoURL
-	} else {
-		profile.PhotoURL = nil
-	}
-
-	if err := config.DB.Save(&profile).Error; err != nil {
-		http.Error(w, "Failed to update doctor profile", http.StatusInternalServerError)
+	err := h.Doctors.UpdateProfile(userID, service.DoctorProfileInput{
+		Name:           req.Name,
+		Age:            req.Age,
+		Gender:         req.Gender,
+		Address:        req.Address,
+		Specialization: req.Specialization,
+		LicenseNumber:  req.LicenseNumber,
+		ClinicName:     req.ClinicName,
+		Experience:     req.Experience,
+		Bio:            req.Bio,
+		Certifications: req.Certifications,
+		PhotoURL:       req.PhotoURL,
+	})
+	if err != nil {
+		writeLookupError(w, err, "Failed to update doctor profile")
 		return
 	}
 
@@ -95,14 +85,8 @@ func UpdateDoctorProfile(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// Availability slot input type
-type Slot struct {
-	Day   string   `json:"day"`
-	Slots []string `json:"slots"`
-}
-
 // update doctor slot
-func UpdateDoctorAvailability(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateDoctorAvailability(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -110,7 +94,10 @@ func UpdateDoctorAvailability(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
-		AvailabilitySlots []Slot `json:"availabilitySlots"`
+		Availability  models.Availability `json:"availability"`
+		Timezone      *string             `json:"timezone"`
+		SlotDuration  *int                `json:"slotDuration"`
+		BufferMinutes *int                `json:"bufferMinutes"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -118,21 +105,24 @@ func UpdateDoctorAvailability(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	availabilityJSON, err := json.Marshal(req.AvailabilitySlots)
-	if err != nil {
-		http.Error(w, "Failed to marshal availability", http.StatusInternalServerError)
-		return
-	}
-
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
+	err := h.Doctors.UpdateAvailability(userID, service.AvailabilityInput{
+		Availability:  req.Availability,
+		Timezone:      req.Timezone,
+		SlotDuration:  req.SlotDuration,
+		BufferMinutes: req.BufferMinutes,
+	})
+	var fieldErrors domain.FieldErrors
+	if errors.As(err, &fieldErrors) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Invalid availability",
+			"errors":  fieldErrors,
+		})
 		return
 	}
-
-	profile.AvailabilitySlots = string(availabilityJSON)
-	if err := config.DB.Save(&profile).Error; err != nil {
-		http.Error(w, "Failed to update availability", http.StatusInternalServerError)
+	if err != nil {
+		writeLookupError(w, err, "Failed to update availability")
 		return
 	}
 
@@ -142,7 +132,7 @@ func UpdateDoctorAvailability(w http.ResponseWriter, r *http.Request) {
 }
 
 // update doctor fee
-func UpdateDoctorFee(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateDoctorFee(w http.ResponseWriter, r *http.Request) {
 	userId := middleware.GetUserIDFromContext(r)
 	if userId == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -157,15 +147,8 @@ func UpdateDoctorFee(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userId).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	profile.ConsultationFees = req.ConsultationFees
-	if err := config.DB.Save(&profile).Error; err != nil {
-		http.Error(w, "Failed to update consultation fee", http.StatusInternalServerError)
+	if err := h.Doctors.UpdateFee(userId, req.ConsultationFees); err != nil {
+		writeLookupError(w, err, "Failed to update consultation fee")
 		return
 	}
 
@@ -175,26 +158,16 @@ func UpdateDoctorFee(w http.ResponseWriter, r *http.Request) {
 }
 
 // get All upcoming appointments
-func GetDoctorAppointments(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetDoctorAppointments(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	var appointments []models.Appointment
-	if err := config.DB.Preload("Patient").
-		Preload("DoctorProfile").
-		Where("doctor_profile_id = ?", profile.ID).
-		Order("scheduled_at ASC").
-		Find(&appointments).Error; err != nil {
-		http.Error(w, "Failed to fetch appointments", http.StatusInternalServerError)
+	appointments, err := h.Doctors.AllAppointments(userID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointments")
 		return
 	}
 
@@ -202,99 +175,36 @@ func GetDoctorAppointments(w http.ResponseWriter, r *http.Request) {
 }
 
 // get Doctor Earnings
-func GetDoctorEarnings(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetDoctorEarnings(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	var totalEarnings float64
-	err := config.DB.Model(&models.Appointment{}).
-		Select("COALESCE(SUM(doctor_profiles.consultation_fees), 0)").
-		Joins("JOIN doctor_profiles ON appointments.doctor_profile_id = doctor_profiles.id").
-		Where("appointments.doctor_profile_id = ? AND appointments.status = ?", profile.ID, models.COMPLETED).
-		Scan(&totalEarnings).Error
+	report, err := h.Stats.DoctorEarnings(userID, r.URL.Query().Get("period"))
 	if err != nil {
-		http.Error(w, "Failed to calculate earnings", http.StatusInternalServerError)
-		return
-	}
-
-	var totalAppointments int64
-	err = config.DB.Model(&models.Appointment{}).
-		Where("doctor_profile_id = ? AND status = ?", profile.ID, models.COMPLETED).
-		Count(&totalAppointments).Error
-	if err != nil {
-		http.Error(w, "Failed to count appointments", http.StatusInternalServerError)
-		return
-	}
-
-	period := r.URL.Query().Get("period")
-	if period == "" {
-		period = "all"
-	}
-
-	type GroupedResult struct {
-		Label string  `json:"label"`
-		Total float64 `json:"total"`
-		Count int64   `json:"count"`
-	}
-
-	var groupedData []GroupedResult
-
-	timeFormat := ""
-	switch period {
-	case "daily":
-		timeFormat = "YYYY-MM-DD"
-	case "weekly":
-		timeFormat = "IYYY-IW"
-	case "monthly":
-		timeFormat = "YYYY-MM"
-	case "yearly":
-		timeFormat = "YYYY"
-	}
-
-	if period != "all" {
-		err = config.DB.
-			Model(&models.Appointment{}).
-			Select(`to_char(appointments.scheduled_at, ?) as label,
-				    SUM(doctor_profiles.consultation_fees) as total,
-				    COUNT(*) as count`, timeFormat).
-			Joins("JOIN doctor_profiles ON appointments.doctor_profile_id = doctor_profiles.id").
-			Where("appointments.doctor_profile_id = ? AND appointments.status = ?", profile.ID, models.COMPLETED).
-			Group("label").
-			Order("label ASC").
-			Scan(&groupedData).Error
-		if err != nil {
-			http.Error(w, "Failed to get grouped earnings", http.StatusInternalServerError)
+		if errors.Is(err, service.ErrInvalidPeriod) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		writeLookupError(w, err, "Failed to calculate earnings")
+		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"totalEarnings":     totalEarnings,
-		"totalAppointments": totalAppointments,
-		"groupedData":       groupedData,
-		"period":            period,
-	})
+	json.NewEncoder(w).Encode(report)
 }
 
 // get doctor reviews
-func GetDoctorReviews(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetDoctorReviews(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var reviews []models.Review
-	if err := config.DB.Where("doctor_id = ?", userID).Find(&reviews).Error; err != nil {
+	reviews, err := h.Doctors.ReviewsOf(userID)
+	if err != nil {
 		http.Error(w, "Failed to fetch reviews", http.StatusInternalServerError)
 		return
 	}
@@ -303,7 +213,7 @@ func GetDoctorReviews(w http.ResponseWriter, r *http.Request) {
 }
 
 // accept or reject appointment
-func RespondToAppointment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) RespondToAppointment(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -324,26 +234,25 @@ func RespondToAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Status != "ACCEPTED" && req.Status != "REJECTED" {
+	var event domain.AppointmentEvent
+	switch models.AppointmentStatus(req.Status) {
+	case models.ACCEPTED:
+		event = domain.EventAccept
+	case models.REJECTED:
+		event = domain.EventReject
+	default:
 		http.Error(w, "Invalid status value", http.StatusBadRequest)
 		return
 	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	var appointment models.Appointment
-	if err := config.DB.Where("id = ? AND doctor_profile_id = ?", appointmentID, profile.ID).First(&appointment).Error; err != nil {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
+	appointment, err := h.Doctors.Appointment(userID, appointmentID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
 		return
 	}
 
-	appointment.Status = models.AppointmentStatus(req.Status)
-	if err := config.DB.Save(&appointment).Error; err != nil {
-		http.Error(w, "Failed to update appointment status", http.StatusInternalServerError)
+	if err := h.Appointments.Transition(appointment, event, middleware.GetRoleFromContext(r), nil); err != nil {
+		writeTransitionError(w, err, "Failed to update appointment status")
 		return
 	}
 
@@ -353,28 +262,16 @@ func RespondToAppointment(w http.ResponseWriter, r *http.Request) {
 }
 
 // Seed Dummy Appointment --> Test route
-func SeedDummyAppointment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) SeedDummyAppointment(w http.ResponseWriter, r *http.Request) {
 	doctorUserID := middleware.GetUserIDFromContext(r)
 	if doctorUserID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var doctorProfile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", doctorUserID).First(&doctorProfile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	dummyAppointment := models.Appointment{
-		DoctorProfileID: doctorProfile.ID,
-		PatientID:       "1f3171ff-3a9a-420e-9d0d-d5d097fdb118",
-		Status:          models.PENDING,
-		ScheduledAt:     time.Now().Add(48 * time.Hour),
-	}
-
-	if err := config.DB.Create(&dummyAppointment).Error; err != nil {
-		http.Error(w, "Failed to create dummy appointment", http.StatusInternalServerError)
+	dummyAppointment, err := h.Doctors.SeedAppointment(doctorUserID, "1f3171ff-3a9a-420e-9d0d-d5d097fdb118")
+	if err != nil {
+		writeLookupError(w, err, "Failed to create dummy appointment")
 		return
 	}
 
@@ -385,7 +282,7 @@ func SeedDummyAppointment(w http.ResponseWriter, r *http.Request) {
 }
 
 // to Reschedule Appointment
-func RescheduleAppointment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) RescheduleAppointment(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -400,69 +297,86 @@ func RescheduleAppointment(w http.ResponseWriter, r *http.Request) {
 
 	var req struct {
 		NewDate time.Time `json:"newDate"`
+		Reason  string    `json:"reason"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
+	appointment, err := h.Doctors.Appointment(userID, appointmentID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
 		return
 	}
 
-	var appointment models.Appointment
-	if err := config.DB.Preload("Patient").Where("id = ? AND doctor_profile_id = ?", appointmentID, profile.ID).First(&appointment).Error; err != nil {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
+	// the time only changes once the patient accepts
+	proposal, err := h.Appointments.ProposeReschedule(appointment, service.ProposalInput{
+		Role:   middleware.GetRoleFromContext(r),
+		UserID: userID,
+		Time:   req.NewDate,
+		Reason: req.Reason,
+	})
+	if err != nil {
+		writeProposalError(w, err, "Failed to update appointment")
 		return
 	}
 
-	appointment.ScheduledAt = req.NewDate
-	appointment.Status = models.RESCHEDULE_REQUESTED
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Appointment reschedule request sent to patient",
+		"proposalId": proposal.ID,
+	})
+}
 
-	if err := config.DB.Save(&appointment).Error; err != nil {
-		http.Error(w, "Failed to update appointment", http.StatusInternalServerError)
+// Cancel an appointment from the doctor side; a reason is required
+func (h *Handler) CancelAppointmentByDoctor(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	go utils.SendEmail(
-		appointment.Patient.Email,
-		"Reschedule Request from Doctor",
-		fmt.Sprintf("Your appointment has been rescheduled to: %s", req.NewDate.Format("Jan 2, 2006 3:04PM")),
-	)
-
-	go utils.SendSMS(
-		appointment.Patient.Phone,
-		fmt.Sprintf("Your appointment has been rescheduled to: %s", req.NewDate.Format("Jan 2, 2006 3:04PM")),
-	)
+	appointment, err := h.Doctors.Appointment(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
+		return
+	}
+	if appointment.ScheduledAt.Before(utils.CurrentTime()) {
+		http.Error(w, "Cannot cancel past appointments", http.StatusBadRequest)
+		return
+	}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Appointment reschedule request sent to patient",
-	})
+	h.applyWithReason(w, r, appointment, domain.EventCancelByDoctor, "Appointment cancelled successfully")
 }
 
-// get appointments where reschedule is requested
-func GetDoctorRescheduleRequests(w http.ResponseWriter, r *http.Request) {
+// Record that the patient did not attend; a reason is required
+func (h *Handler) MarkPatientNoShow(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var doctorProfile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&doctorProfile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
+	appointment, err := h.Doctors.Appointment(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
 		return
 	}
 
-	var appointments []models.Appointment
-	if err := config.DB.
-		Preload("Patient").
-		Where("doctor_profile_id = ? AND status = ?", doctorProfile.ID, models.RESCHEDULE_REQUESTED).
-		Order("scheduled_at ASC").
-		Find(&appointments).Error; err != nil {
-		http.Error(w, "Failed to fetch reschedule requests", http.StatusInternalServerError)
+	h.applyWithReason(w, r, appointment, domain.EventNoShowPatient, "Patient no-show recorded")
+}
+
+// get appointments where reschedule is requested
+func (h *Handler) GetDoctorRescheduleRequests(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	appointments, err := h.Doctors.RescheduleRequests(userID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch reschedule requests")
 		return
 	}
 
@@ -472,29 +386,16 @@ func GetDoctorRescheduleRequests(w http.ResponseWriter, r *http.Request) {
 }
 
 // returns all upcoming appointments for a doctor
-func GetUpcomingAppointmentsForDoctor(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetUpcomingAppointmentsForDoctor(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	var appointments []models.Appointment
-	if err := config.DB.
-		Preload("Patient").
-		Preload("DoctorProfile").
-		Where("doctor_profile_id = ? AND scheduled_at > NOW() AND status IN ?",
-			profile.ID,
-			[]models.AppointmentStatus{models.ACCEPTED, models.RESCHEDULED_CONFIRMED}).
-		Order("scheduled_at ASC").
-		Find(&appointments).Error; err != nil {
-		http.Error(w, "Failed to fetch appointments", http.StatusInternalServerError)
+	appointments, err := h.Doctors.UpcomingAppointments(userID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointments")
 		return
 	}
 
@@ -504,7 +405,7 @@ func GetUpcomingAppointmentsForDoctor(w http.ResponseWriter, r *http.Request) {
 }
 
 // mark appointment as completed
-func CompleteAppointment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CompleteAppointment(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -517,28 +418,14 @@ func CompleteAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	var appointment models.Appointment
-	if err := config.DB.
-		Where("id = ? AND doctor_profile_id = ?", appointmentID, profile.ID).
-		First(&appointment).Error; err != nil {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
-		return
-	}
-
-	if appointment.Status != models.ACCEPTED && appointment.Status != models.RESCHEDULED_CONFIRMED {
-		http.Error(w, "Only accepted/rescheduled appointments can be marked as completed", http.StatusBadRequest)
+	appointment, err := h.Doctors.Appointment(userID, appointmentID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
 		return
 	}
 
-	appointment.Status = models.COMPLETED
-	if err := config.DB.Save(&appointment).Error; err != nil {
-		http.Error(w, "Failed to mark appointment as completed", http.StatusInternalServerError)
+	if err := h.Appointments.Transition(appointment, domain.EventComplete, middleware.GetRoleFromContext(r), nil); err != nil {
+		writeTransitionError(w, err, "Failed to mark appointment as completed")
 		return
 	}
 
@@ -548,7 +435,7 @@ func CompleteAppointment(w http.ResponseWriter, r *http.Request) {
 }
 
 // Add Appointment Summary
-func AddAppointmentSummary(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) AddAppointmentSummary(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -569,26 +456,12 @@ func AddAppointmentSummary(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	var appointment models.Appointment
-	if err := config.DB.Where("id = ? AND doctor_profile_id = ?", appointmentID, profile.ID).First(&appointment).Error; err != nil {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
-		return
-	}
-
-	if appointment.Status != models.COMPLETED {
-		http.Error(w, "Summary can only be added to completed appointments", http.StatusBadRequest)
-		return
-	}
-
-	appointment.Summary = &req.Summary
-	if err := config.DB.Save(&appointment).Error; err != nil {
-		http.Error(w, "Failed to save summary", http.StatusInternalServerError)
+	if err := h.Doctors.AddSummary(userID, appointmentID, req.Summary); err != nil {
+		if errors.Is(err, service.ErrSummaryNotCompleted) {
+			http.Error(w, "Summary can only be added to completed appointments", http.StatusBadRequest)
+			return
+		}
+		writeLookupError(w, err, "Failed to save summary")
 		return
 	}
 
@@ -598,27 +471,16 @@ func AddAppointmentSummary(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get All Unique Patients of a Doctor
-func GetAllPatientsForDoctor(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetAllPatientsForDoctor(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var doctorProfile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&doctorProfile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	var patients []models.User
-	if err := config.DB.
-		Model(&models.Appointment{}).
-		Select("DISTINCT users.*").
-		Joins("JOIN users ON users.id = appointments.patient_id").
-		Where("appointments.doctor_profile_id = ? AND appointments.status = ?", doctorProfile.ID, models.COMPLETED).
-		Scan(&patients).Error; err != nil {
-		http.Error(w, "Failed to fetch patients", http.StatusInternalServerError)
+	patients, err := h.Doctors.Patients(userID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch patients")
 		return
 	}
 
@@ -628,7 +490,7 @@ func GetAllPatientsForDoctor(w http.ResponseWriter, r *http.Request) {
 }
 
 // Download/Print Summary as PDF
-func GenerateSummaryPDF(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GenerateSummaryPDF(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -641,27 +503,80 @@ func GenerateSummaryPDF(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var doctorProfile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&doctorProfile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
+	appointment, err := h.Doctors.SummaryAppointment(userID, appointmentID)
+	if err != nil {
+		if errors.Is(err, service.ErrNoSummary) {
+			http.Error(w, "No summary found for this appointment", http.StatusNotFound)
+			return
+		}
+		writeLookupError(w, err, "Failed to fetch appointment")
 		return
 	}
 
-	var appointment models.Appointment
-	if err := config.DB.
-		Preload("Patient").
-		Preload("DoctorProfile").
-		Where("id = ? AND doctor_profile_id = ?", appointmentID, doctorProfile.ID).
-		First(&appointment).Error; err != nil {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
+	data, err := summaryPDF(appointment)
+	if err != nil {
+		http.Error(w, "Failed to generate PDF", http.StatusInternalServerError)
 		return
 	}
 
-	if *appointment.Summary == "" {
-		http.Error(w, "No summary found for this appointment", http.StatusNotFound)
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", "attachment; filename=appointment_summary.pdf")
+	w.Write(data)
+}
+
+// Email the Summary PDF to the patient
+func (h *Handler) EmailSummaryPDF(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
+	appointment, err := h.Doctors.SummaryAppointment(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		if errors.Is(err, service.ErrNoSummary) {
+			http.Error(w, "No summary found for this appointment", http.StatusNotFound)
+			return
+		}
+		writeLookupError(w, err, "Failed to fetch appointment")
+		return
+	}
+	if appointment.Patient.Email == "" {
+		http.Error(w, "Patient has no email address", http.StatusUnprocessableEntity)
+		return
+	}
+
+	data, err := summaryPDF(appointment)
+	if err != nil {
+		http.Error(w, "Failed to generate PDF", http.StatusInternalServerError)
+		return
+	}
+
+	msgs, err := notify.Compose(notify.TemplateAppointmentSummary, notify.AppointmentData(appointment),
+		notify.Recipient{Email: appointment.Patient.Email})
+	if err != nil {
+		http.Error(w, "Failed to prepare email", http.StatusInternalServerError)
+		return
+	}
+	for i := range msgs {
+		msgs[i].Attachments = []mailer.Attachment{{
+			Filename:    "appointment_summary.pdf",
+			ContentType: "application/pdf",
+			Data:        data,
+		}}
+	}
+	if err := notify.SendAll(h.Notifier, msgs); err != nil {
+		http.Error(w, "Failed to send email", http.StatusBadGateway)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Summary sent to patient",
+	})
+}
+
+// summaryPDF renders the appointment summary as a one-page PDF
+func summaryPDF(appointment *models.Appointment) ([]byte, error) {
 	pdf := gofpdf.New("P", "mm", "A4", "")
 	pdf.AddPage()
 	pdf.SetFont("Arial", "B", 16)
@@ -677,16 +592,15 @@ func GenerateSummaryPDF(w http.ResponseWriter, r *http.Request) {
 	pdf.Ln(8)
 	pdf.MultiCell(0, 10, "Summary:\n"+*appointment.Summary, "", "", false)
 
-	w.Header().Set("Content-Type", "application/pdf")
-	w.Header().Set("Content-Disposition", "attachment; filename=appointment_summary.pdf")
-	err := pdf.Output(w)
-	if err != nil {
-		http.Error(w, "Failed to generate PDF", http.StatusInternalServerError)
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, err
 	}
+	return buf.Bytes(), nil
 }
 
 // Add Test from Doctor Side
-func CreateMedicalCheck(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CreateMedicalCheck(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		AppointmentID string          `json:"appointmentId"`
 		Type          models.TestType `json:"type"`
@@ -705,25 +619,17 @@ func CreateMedicalCheck(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	var appointment models.Appointment
-	if err := config.DB.Where("id = ? AND doctor_profile_id = ?", req.AppointmentID, profile.ID).First(&appointment).Error; err != nil {
-		http.Error(w, "Appointment not found or not authorized", http.StatusNotFound)
-		return
-	}
-
-	check := models.MedicalCheck{
+	check, err := h.Doctors.CreateMedicalCheck(userID, service.MedicalCheckInput{
 		AppointmentID: req.AppointmentID,
 		Type:          req.Type,
 		Location:      req.Location,
-	}
-	if err := config.DB.Create(&check).Error; err != nil {
-		http.Error(w, "Failed to create test request", http.StatusInternalServerError)
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrAppointmentNotFound) {
+			http.Error(w, "Appointment not found or not authorized", http.StatusNotFound)
+			return
+		}
+		writeLookupError(w, err, "Failed to create test request")
 		return
 	}
 
@@ -734,7 +640,7 @@ func CreateMedicalCheck(w http.ResponseWriter, r *http.Request) {
 }
 
 // Uploading Test Report
-func UploadTestReport(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UploadTestReport(w http.ResponseWriter, r *http.Request) {
 	testID := chi.URLParam(r, "id")
 	if testID == "" {
 		http.Error(w, "Missing test ID", http.StatusBadRequest)
@@ -754,21 +660,11 @@ func UploadTestReport(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var test models.MedicalCheck
-	if err := config.DB.
-		Joins("JOIN appointments ON appointments.id = medical_checks.appointment_id").
-		Joins("JOIN doctor_profiles ON appointments.doctor_profile_id = doctor_profiles.id").
-		Where("medical_checks.id = ? AND doctor_profiles.user_id = ?", testID, userID).
-		First(&test).Error; err != nil {
-		http.Error(w, "Test not found or unauthorized", http.StatusNotFound)
-		return
-	}
-
-	test.ReportUrl = &req.ReportURL
-	test.ReportUploaded = true
-	test.Status = models.REPORTED
-
-	if err := config.DB.Save(&test).Error; err != nil {
+	if err := h.Doctors.UploadReport(userID, testID, req.ReportURL); err != nil {
+		if errors.Is(err, service.ErrMedicalCheckNotFound) {
+			http.Error(w, "Test not found or unauthorized", http.StatusNotFound)
+			return
+		}
 		http.Error(w, "Failed to update test record", http.StatusInternalServerError)
 		return
 	}
@@ -780,27 +676,22 @@ func UploadTestReport(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get All Doctors
-func GetAllDoctors(w http.ResponseWriter, r *http.Request) {
-	specialization := r.URL.Query().Get("specialization")
-	search := r.URL.Query().Get("search")
-	minRating := r.URL.Query().Get("minRating")
-
-	var doctors []models.DoctorProfile
-
-	query := config.DB.Preload("User").Preload("Reviews").Where("is_pending = ?", false)
-
-	if specialization != "" {
-		query = query.Where("LOWER(specialization) LIKE ?", "%"+strings.ToLower(specialization)+"%")
+func (h *Handler) GetAllDoctors(w http.ResponseWriter, r *http.Request) {
+	filter := repository.DoctorFilter{
+		Specialization: r.URL.Query().Get("specialization"),
+		Search:         r.URL.Query().Get("search"),
 	}
-	if search != "" {
-		query = query.Joins("JOIN users ON users.id = doctor_profiles.user_id").
-			Where("LOWER(users.name) LIKE ?", "%"+strings.ToLower(search)+"%")
-	}
-	if minRating != "" {
-		query = query.Where("rating >= ?", minRating)
+	if v := r.URL.Query().Get("minRating"); v != "" {
+		minRating, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			http.Error(w, "Invalid minRating", http.StatusBadRequest)
+			return
+		}
+		filter.MinRating = minRating
 	}
 
-	if err := query.Find(&doctors).Error; err != nil {
+	doctors, err := h.Doctors.List(filter)
+	if err != nil {
 		http.Error(w, "Failed to fetch doctors", http.StatusInternalServerError)
 		return
 	}
@@ -809,15 +700,10 @@ func GetAllDoctors(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get Single Doctor by ID
-func GetDoctorByID(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetDoctorByID(w http.ResponseWriter, r *http.Request) {
 
 	id := chi.URLParam(r, "id")
-	var doctor models.DoctorProfile
-	err := config.DB.
-		Preload("User").
-		Preload("Reviews").
-		First(&doctor, "id = ?", id).Error
-
+	doctor, err := h.Doctors.Get(id)
 	if err != nil {
 		http.Error(w, "Doctor not found", http.StatusNotFound)
 		return
@@ -826,3 +712,58 @@ func GetDoctorByID(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(doctor)
 }
+
+// Get free slots of a doctor over a date range
+func (h *Handler) GetDoctorAvailability(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	const dateLayout = "2006-01-02"
+	from := time.Now()
+	if v := r.URL.Query().Get("from"); v != "" {
+		parsed, err := time.Parse(dateLayout, v)
+		if err != nil {
+			http.Error(w, "Invalid from date. Expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		from = parsed
+	}
+	to := from.AddDate(0, 0, 6)
+	if v := r.URL.Query().Get("to"); v != "" {
+		parsed, err := time.Parse(dateLayout, v)
+		if err != nil {
+			http.Error(w, "Invalid to date. Expected YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		to = parsed
+	}
+	if to.Before(from) {
+		http.Error(w, "to must not be before from", http.StatusBadRequest)
+		return
+	}
+	if to.Sub(from) > 31*24*time.Hour {
+		http.Error(w, "Date range cannot exceed 31 days", http.StatusBadRequest)
+		return
+	}
+
+	profile, slots, err := h.Appointments.FreeSlots(id, from, to)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Doctor not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to compute availability", http.StatusInternalServerError)
+		return
+	}
+
+	cfg := domain.ScheduleConfig(profile)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"doctorId":      profile.ID,
+		"timezone":      cfg.Location.String(),
+		"slotDuration":  int(cfg.Duration / time.Minute),
+		"bufferMinutes": int(cfg.Buffer / time.Minute),
+		"from":          from.Format(dateLayout),
+		"to":            to.Format(dateLayout),
+		"slots":         slots,
+	})
+}
@@ -0,0 +1,15 @@
+package controllers
+
+import (
+	"github.com/GitNinja36/wello-backend/internal/app"
+)
+
+// Handler turns HTTP requests into service calls. Every controller is a
+// method on it, so handlers only see the dependencies the App provides.
+type Handler struct {
+	*app.App
+}
+
+func NewHandler(a *app.App) *Handler {
+	return &Handler{App: a}
+}
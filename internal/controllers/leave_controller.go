@@ -0,0 +1,121 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// Create a leave / blackout window
+func (h *Handler) CreateDoctorLeave(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		StartsAt time.Time `json:"startsAt"`
+		EndsAt   time.Time `json:"endsAt"`
+		Reason   string    `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.Doctors.Profile(userID)
+	if err != nil {
+		http.Error(w, "Doctor profile not found", http.StatusNotFound)
+		return
+	}
+
+	leave, affected, err := h.Appointments.CreateLeave(profile.ID, req.StartsAt, req.EndsAt, req.Reason)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidLeave) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		http.Error(w, "Failed to create leave", http.StatusInternalServerError)
+		return
+	}
+
+	cancelled := make([]string, 0, len(affected))
+	for _, a := range affected {
+		cancelled = append(cancelled, a.ID)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":               "Leave created successfully",
+		"leave":                 leave,
+		"cancelledAppointments": cancelled,
+	})
+}
+
+// Get upcoming leaves of a doctor
+func (h *Handler) GetDoctorLeaves(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	profile, err := h.Doctors.Profile(userID)
+	if err != nil {
+		http.Error(w, "Doctor profile not found", http.StatusNotFound)
+		return
+	}
+
+	now := utils.CurrentTime()
+	leaves, err := h.Appointments.ActiveLeaves(profile.ID, now, now.AddDate(10, 0, 0))
+	if err != nil {
+		http.Error(w, "Failed to fetch leaves", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"leaves": leaves,
+	})
+}
+
+// Cancel a leave
+func (h *Handler) CancelDoctorLeave(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	leaveID := chi.URLParam(r, "id")
+	if leaveID == "" {
+		http.Error(w, "Missing leave ID", http.StatusBadRequest)
+		return
+	}
+
+	profile, err := h.Doctors.Profile(userID)
+	if err != nil {
+		http.Error(w, "Doctor profile not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.Appointments.CancelLeave(profile.ID, leaveID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			http.Error(w, "Leave not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to cancel leave", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Leave cancelled successfully",
+	})
+}
@@ -0,0 +1,172 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxImportBytes caps the CSV body of a catalog import.
+const maxImportBytes = 5 << 20
+
+// writeMedicineError maps catalog failures to 400/404/409 responses
+func writeMedicineError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrMedicineNotFound):
+		http.Error(w, "Medicine not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidMedicine), errors.Is(err, domain.ErrInvalidCSV):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, service.ErrDuplicateSKU), errors.Is(err, domain.ErrOutOfStock):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// Browse the catalog; ?search=, ?form=, ?inStock=true. Admins may add
+// ?includeInactive=true to see discontinued medicines
+func (h *Handler) GetMedicines(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.MedicineFilter{
+		Search:      strings.TrimSpace(q.Get("search")),
+		Form:        models.MedicineForm(strings.ToUpper(q.Get("form"))),
+		InStockOnly: q.Get("inStock") == "true",
+	}
+	if middleware.GetRoleFromContext(r) == models.ADMIN {
+		filter.IncludeInactive = q.Get("includeInactive") == "true"
+	}
+
+	medicines, err := h.Catalog.List(filter)
+	if err != nil {
+		http.Error(w, "Failed to fetch medicines", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"medicines": medicines,
+	})
+}
+
+// View one medicine
+func (h *Handler) GetMedicine(w http.ResponseWriter, r *http.Request) {
+	isAdmin := middleware.GetRoleFromContext(r) == models.ADMIN
+	medicine, err := h.Catalog.Medicine(chi.URLParam(r, "id"), isAdmin)
+	if err != nil {
+		writeMedicineError(w, err, "Failed to fetch medicine")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"medicine": medicine,
+	})
+}
+
+// Add a medicine to the catalog
+func (h *Handler) CreateMedicine(w http.ResponseWriter, r *http.Request) {
+	var medicine models.Medicine
+	if err := json.NewDecoder(r.Body).Decode(&medicine); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Catalog.Create(&medicine); err != nil {
+		writeMedicineError(w, err, "Failed to create medicine")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Medicine created successfully",
+		"medicine": medicine,
+	})
+}
+
+// Edit catalog fields of a medicine; stock is adjusted separately
+func (h *Handler) UpdateMedicine(w http.ResponseWriter, r *http.Request) {
+	var patch service.MedicinePatch
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	medicine, err := h.Catalog.Update(chi.URLParam(r, "id"), patch)
+	if err != nil {
+		writeMedicineError(w, err, "Failed to update medicine")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Medicine updated successfully",
+		"medicine": medicine,
+	})
+}
+
+// Discontinue a medicine; orders that already include it are unaffected
+func (h *Handler) DeleteMedicine(w http.ResponseWriter, r *http.Request) {
+	if err := h.Catalog.Discontinue(chi.URLParam(r, "id")); err != nil {
+		writeMedicineError(w, err, "Failed to discontinue medicine")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Medicine discontinued",
+	})
+}
+
+// Restock or write off: {"delta": 50} or {"delta": -3}
+func (h *Handler) AdjustMedicineStock(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Delta int `json:"delta"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Delta == 0 {
+		http.Error(w, "delta must be a non-zero integer", http.StatusBadRequest)
+		return
+	}
+
+	medicine, err := h.Catalog.AdjustStock(chi.URLParam(r, "id"), req.Delta)
+	if err != nil {
+		writeMedicineError(w, err, "Failed to adjust stock")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Stock updated",
+		"stockQuantity": medicine.StockQuantity,
+	})
+}
+
+// Bulk import the catalog from a CSV body; rows are matched by SKU and the
+// whole file is rejected if any row is invalid
+func (h *Handler) ImportMedicines(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	medicines, err := domain.ParseMedicineCSV(r.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "CSV must be at most "+strconv.Itoa(maxImportBytes>>20)+" MB", http.StatusRequestEntityTooLarge)
+			return
+		}
+		writeMedicineError(w, err, "Failed to read CSV")
+		return
+	}
+
+	if err := h.Catalog.Import(medicines); err != nil {
+		http.Error(w, "Failed to import medicines", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Medicines imported successfully",
+		"imported": len(medicines),
+	})
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type PlaceOrderRequest struct {
+	Items          models.OrderItems `json:"items"`
+	PaymentMethod  string            `json:"paymentMethod"`
+	PrescriptionID string            `json:"prescriptionId"`
+}
+
+// writeOrderError maps order failures to 400/403/404/409/422 responses
+func writeOrderError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrOrderNotFound):
+		http.Error(w, "Order not found", http.StatusNotFound)
+	case errors.Is(err, service.ErrPrescriptionNotFound):
+		http.Error(w, "Prescription not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrPrescriptionRequired),
+		errors.Is(err, domain.ErrPrescriptionNotValid),
+		errors.Is(err, domain.ErrPrescriptionNotCovered):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, domain.ErrEmptyOrder),
+		errors.Is(err, domain.ErrInvalidOrderItem),
+		errors.Is(err, domain.ErrInvalidPaymentMethod):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrOutOfStock):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrMedicineUnavailable):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, domain.ErrOrderRoleNotAllowed):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, domain.ErrInvalidOrderTransition),
+		errors.Is(err, domain.ErrOrderNotPaid):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// Place an order from the cart; prices and amount come from the catalog
+func (h *Handler) PlaceOrder(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req PlaceOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.Orders.Place(service.OrderInput{
+		UserID:         userID,
+		Items:          req.Items,
+		PaymentMethod:  req.PaymentMethod,
+		PrescriptionID: req.PrescriptionID,
+	})
+	if err != nil {
+		writeOrderError(w, err, "Failed to place order")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Order placed successfully",
+		"order":   order,
+	})
+}
+
+// List the patient's orders
+func (h *Handler) GetMyOrders(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	orders, err := h.Orders.ForUser(userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"orders": orders,
+	})
+}
+
+// View one of the patient's orders
+func (h *Handler) GetMyOrder(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	order, err := h.Orders.Order(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeOrderError(w, err, "Failed to fetch order")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"order": order,
+	})
+}
+
+// Cancel an order that has not been picked up yet
+func (h *Handler) CancelOrder(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	order, err := h.Orders.Order(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeOrderError(w, err, "Failed to fetch order")
+		return
+	}
+
+	if err := h.Orders.Transition(order, models.ORDER_CANCELLED, models.PATIENT); err != nil {
+		writeOrderError(w, err, "Failed to cancel order")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Order cancelled successfully",
+	})
+}
+
+// List orders for fulfilment, optionally filtered by ?status=
+func (h *Handler) GetOrderQueue(w http.ResponseWriter, r *http.Request) {
+	status := models.OrderStatus(strings.ToUpper(r.URL.Query().Get("status")))
+
+	orders, err := h.Orders.Queue(status)
+	if err != nil {
+		http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"orders": orders,
+	})
+}
+
+// Move an order to PROCESSING, SHIPPED, DELIVERED or CANCELLED
+func (h *Handler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Status models.OrderStatus `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.Orders.Any(chi.URLParam(r, "id"))
+	if err != nil {
+		writeOrderError(w, err, "Failed to fetch order")
+		return
+	}
+
+	if err := h.Orders.Transition(order, req.Status, middleware.GetRoleFromContext(r)); err != nil {
+		writeOrderError(w, err, "Failed to update order")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Order status updated",
+		"order":   order,
+	})
+}
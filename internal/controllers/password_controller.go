@@ -0,0 +1,250 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/otp"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+// sendOTP delivers code right away, by email when one is given, otherwise by SMS
+func (h *Handler) sendOTP(email, phone, code string) error {
+	to := notify.Recipient{Email: email}
+	if email == "" {
+		to.Phone = phone
+	}
+	msgs, err := notify.Compose(notify.TemplateOTP, map[string]interface{}{"Code": code}, to)
+	if err != nil {
+		return err
+	}
+	return notify.SendAll(h.Notifier, msgs)
+}
+
+// writeSession starts a session for user and writes the token response
+func (h *Handler) writeSession(w http.ResponseWriter, r *http.Request, user *models.User) {
+	tokens, err := h.Sessions.Start(user, r.UserAgent(), r.RemoteAddr)
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	writeTokens(w, tokens, user)
+}
+
+// writeTokens is the one token response of every login path, so clients see
+// the same shape however the user signed in
+func writeTokens(w http.ResponseWriter, tokens *service.TokenPair, user *models.User) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+		"role":         user.Role,
+		"isApproved":   user.IsApproved,
+		"newUser":      false,
+		"needsProfile": false,
+	})
+}
+
+// Password login. Admins get an OTP challenge instead of a session.
+func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email    string `json:"email"`
+		Phone    string `json:"phone"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Password == "" {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Auth.Login(req.Email, req.Phone, req.Password)
+	if err != nil {
+		switch {
+		// a locked account looks like a wrong password, so the lockout does
+		// not confirm that the account exists
+		case errors.Is(err, service.ErrAccountLocked),
+			errors.Is(err, service.ErrInvalidCredentials):
+			http.Error(w, service.ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+		default:
+			http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// TOTP replaces the emailed code for admins who enrolled
+	if user.Role != models.ADMIN || user.TOTPEnabled {
+		h.startLogin(w, r, user)
+		return
+	}
+
+	code, err := otp.Issue(h.OTP, "login:"+user.ID)
+	if err != nil {
+		writeOTPError(w, err)
+		return
+	}
+	if err := h.sendOTP(user.Email, user.Phone, code); err != nil {
+		http.Error(w, "Failed to send login OTP", http.StatusInternalServerError)
+		return
+	}
+
+	challenge, err := h.Keys.GenerateChallengeJWT(user.ID, utils.ScopeLoginOTP)
+	if err != nil {
+		http.Error(w, "Failed to issue challenge", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"otpRequired":    true,
+		"challengeToken": challenge,
+		"message":        "OTP sent, complete login at /auth/login/otp",
+	})
+}
+
+// Second login step for accounts that need password plus OTP
+func (h *Handler) VerifyLoginOTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChallengeToken string `json:"challengeToken"`
+		OTP            string `json:"otp"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := h.Keys.ParseJWT(req.ChallengeToken)
+	if err != nil || claims.Scope != utils.ScopeLoginOTP || claims.UserID == "" {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.OTP.Verify("login:"+claims.UserID, req.OTP); err != nil {
+		writeOTPError(w, err)
+		return
+	}
+
+	user, err := h.Users.Get(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+	h.writeSession(w, r, user)
+}
+
+// Start password reset; always answers the same way to avoid leaking accounts
+func (h *Handler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string `json:"email"`
+		Phone string `json:"phone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || (req.Email == "" && req.Phone == "") {
+		http.Error(w, "Email or phone is required", http.StatusBadRequest)
+		return
+	}
+
+	// the code is issued and sent in the background, so the response takes
+	// the same time whether or not the account exists. Failures (cooldown,
+	// quota, delivery) only happen for real accounts and are logged.
+	if user, err := h.Auth.FindByIdentifier(req.Email, req.Phone); err == nil {
+		go func() {
+			code, err := otp.Issue(h.OTP, "reset:"+user.ID)
+			if err == nil {
+				err = h.sendOTP(req.Email, req.Phone, code)
+			}
+			if err != nil {
+				log.Printf("password reset OTP for user %s not sent: %v", user.ID, err)
+			}
+		}()
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "If the account exists, a reset code has been sent",
+	})
+}
+
+// Finish password reset with the OTP
+func (h *Handler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email       string `json:"email"`
+		Phone       string `json:"phone"`
+		OTP         string `json:"otp"`
+		NewPassword string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := utils.ValidatePassword(req.NewPassword); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Auth.FindByIdentifier(req.Email, req.Phone)
+	if err != nil {
+		writeOTPError(w, otp.ErrInvalidCode)
+		return
+	}
+	if err := h.OTP.Verify("reset:"+user.ID, req.OTP); err != nil {
+		writeOTPError(w, err)
+		return
+	}
+
+	if err := h.Auth.SetPassword(user.ID, req.NewPassword); err != nil {
+		http.Error(w, "Failed to reset password", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password reset successfully, please log in again",
+	})
+}
+
+// Set or change the password of the current user
+func (h *Handler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.Users.Get(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	// users who signed up with OTP only may set a first password directly
+	if user.Password != "" {
+		if err := h.Auth.Authenticate(user, req.CurrentPassword); err != nil {
+			http.Error(w, "Current password is incorrect", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	if err := h.Auth.SetPassword(user.ID, req.NewPassword); err != nil {
+		if errors.Is(err, utils.ErrWeakPassword) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		http.Error(w, "Failed to update password", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Password updated, other sessions were signed out",
+	})
+}
@@ -2,18 +2,18 @@ package controllers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
 
-	"github.com/GitNinja36/wello-backend/config"
+	"github.com/GitNinja36/wello-backend/internal/domain"
 	"github.com/GitNinja36/wello-backend/internal/middleware"
-	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/service"
 	"github.com/GitNinja36/wello-backend/internal/utils"
 	"github.com/go-chi/chi/v5"
 )
 
 // Patient response to reschedule request
-func PatientRespondReschedule(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) PatientRespondReschedule(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -34,21 +34,9 @@ func PatientRespondReschedule(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var appointment models.Appointment
-	if err := config.DB.Where("id = ? AND patient_id = ?", appointmentID, userID).
-		First(&appointment).Error; err != nil {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
-		return
-	}
-
-	if req.Accept {
-		appointment.Status = models.RESCHEDULED_CONFIRMED
-	} else {
-		appointment.Status = models.RESCHEDULE_REJECTED
-	}
-
-	if err := config.DB.Save(&appointment).Error; err != nil {
-		http.Error(w, "Failed to update appointment", http.StatusInternalServerError)
+	appointment, err := h.Patients.Appointment(userID, appointmentID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
 		return
 	}
 
@@ -57,19 +45,9 @@ func PatientRespondReschedule(w http.ResponseWriter, r *http.Request) {
 		message = "Reschedule accepted"
 	}
 
-	var doctorProfile models.DoctorProfile
-	if err := config.DB.Preload("User").Where("id = ?", appointment.DoctorProfileID).First(&doctorProfile).Error; err == nil {
-		go utils.SendEmail(
-			doctorProfile.User.Email,
-			"Patient Response to Reschedule",
-			fmt.Sprintf("The patient has %s your reschedule request.",
-				map[bool]string{true: "accepted", false: "rejected"}[req.Accept]),
-		)
-		go utils.SendSMS(
-			doctorProfile.User.Phone,
-			fmt.Sprintf("Patient %s your reschedule request.",
-				map[bool]string{true: "accepted", false: "rejected"}[req.Accept]),
-		)
+	if _, err := h.Appointments.RespondToProposal(appointment, "", middleware.GetRoleFromContext(r), req.Accept); err != nil {
+		writeProposalError(w, err, "Failed to update appointment")
+		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]string{
@@ -78,21 +56,15 @@ func PatientRespondReschedule(w http.ResponseWriter, r *http.Request) {
 }
 
 // returns all future confirmed appointments
-func GetUpcomingAppointmentsForPatient(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetUpcomingAppointmentsForPatient(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var appointments []models.Appointment
-	if err := config.DB.
-		Preload("DoctorProfile.User").
-		Preload("Patient").
-		Where("patient_id = ? AND scheduled_at > NOW() AND status IN ?", userID,
-			[]models.AppointmentStatus{models.PENDING, models.ACCEPTED, models.RESCHEDULED_CONFIRMED}).
-		Order("scheduled_at ASC").
-		Find(&appointments).Error; err != nil {
+	appointments, err := h.Patients.Upcoming(userID)
+	if err != nil {
 		http.Error(w, "Failed to fetch upcoming appointments", http.StatusInternalServerError)
 		return
 	}
@@ -103,7 +75,7 @@ func GetUpcomingAppointmentsForPatient(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get Patient History for a Doctor
-func GetPatientHistoryForDoctor(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetPatientHistoryForDoctor(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -116,21 +88,9 @@ func GetPatientHistoryForDoctor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var doctorProfile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", userID).First(&doctorProfile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
-		return
-	}
-
-	var appointments []models.Appointment
-	if err := config.DB.
-		Preload("Patient").
-		Preload("DoctorProfile").
-		Where("doctor_profile_id = ? AND patient_id = ? AND status = ?",
-			doctorProfile.ID, patientID, models.COMPLETED).
-		Order("scheduled_at DESC").
-		Find(&appointments).Error; err != nil {
-		http.Error(w, "Failed to fetch patient history", http.StatusInternalServerError)
+	appointments, err := h.Doctors.PatientHistory(userID, patientID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch patient history")
 		return
 	}
 
@@ -140,20 +100,15 @@ func GetPatientHistoryForDoctor(w http.ResponseWriter, r *http.Request) {
 }
 
 // View Past Appointment History
-func GetPatientAppointmentHistory(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetPatientAppointmentHistory(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var appointments []models.Appointment
-	if err := config.DB.
-		Preload("DoctorProfile.User").
-		Preload("Patient").
-		Where("patient_id = ? AND status = ?", userID, models.COMPLETED).
-		Order("scheduled_at DESC").
-		Find(&appointments).Error; err != nil {
+	appointments, err := h.Patients.History(userID)
+	if err != nil {
 		http.Error(w, "Failed to fetch history", http.StatusInternalServerError)
 		return
 	}
@@ -164,7 +119,7 @@ func GetPatientAppointmentHistory(w http.ResponseWriter, r *http.Request) {
 }
 
 // Cancel upcoming appointment
-func CancelAppointmentByPatient(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) CancelAppointmentByPatient(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -177,19 +132,9 @@ func CancelAppointmentByPatient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var appointment models.Appointment
-	if err := config.DB.
-		Preload("DoctorProfile.User").
-		Where("id = ? AND patient_id = ?", appointmentID, userID).
-		First(&appointment).Error; err != nil {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
-		return
-	}
-
-	if appointment.Status != models.PENDING &&
-		appointment.Status != models.ACCEPTED &&
-		appointment.Status != models.RESCHEDULED_CONFIRMED {
-		http.Error(w, "Only upcoming appointments can be cancelled", http.StatusBadRequest)
+	appointment, err := h.Patients.Appointment(userID, appointmentID)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
 		return
 	}
 
@@ -198,33 +143,29 @@ func CancelAppointmentByPatient(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	appointment.Status = models.CANCELLED_BY_PATIENT
-	if err := config.DB.Save(&appointment).Error; err != nil {
-		http.Error(w, "Failed to cancel appointment", http.StatusInternalServerError)
+	// reason is optional for patients
+	h.applyWithReason(w, r, appointment, domain.EventCancelByPatient, "Appointment cancelled successfully")
+}
+
+// Report that the doctor did not attend
+func (h *Handler) ReportDoctorNoShow(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Notify doctor
-	go utils.SendEmail(
-		appointment.DoctorProfile.User.Email,
-		"Appointment Cancelled",
-		fmt.Sprintf("Your appointment with the patient on %s has been cancelled by the patient.",
-			appointment.ScheduledAt.Format("Jan 2, 2006 at 3:04 PM")),
-	)
-
-	go utils.SendSMS(
-		appointment.DoctorProfile.User.Phone,
-		fmt.Sprintf("Patient cancelled the appointment scheduled for %s.",
-			appointment.ScheduledAt.Format("Jan 2, 2006 3:04 PM")),
-	)
+	appointment, err := h.Patients.Appointment(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
+		return
+	}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Appointment cancelled successfully",
-	})
+	h.applyWithReason(w, r, appointment, domain.EventNoShowDoctor, "Doctor no-show recorded")
 }
 
 // Submit rating and review for a completed appointment
-func SubmitReviewForAppointment(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) SubmitReviewForAppointment(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -247,30 +188,15 @@ func SubmitReviewForAppointment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Rating < 1 || req.Rating > 5 {
-		http.Error(w, "Rating must be between 1 and 5", http.StatusBadRequest)
-		return
-	}
-
-	var appointment models.Appointment
-	if err := config.DB.
-		Preload("DoctorProfile").
-		Where("id = ? AND patient_id = ?", appointmentID, userID).
-		First(&appointment).Error; err != nil {
-		http.Error(w, "Appointment not found", http.StatusNotFound)
-		return
-	}
-
-	if appointment.Status != models.COMPLETED {
-		http.Error(w, "Only completed appointments can be reviewed", http.StatusBadRequest)
-		return
-	}
-
-	appointment.Rating = &req.Rating
-	appointment.Review = &req.Review
-
-	if err := config.DB.Save(&appointment).Error; err != nil {
-		http.Error(w, "Failed to submit review", http.StatusInternalServerError)
+	if err := h.Patients.SubmitReview(userID, appointmentID, req.Rating, req.Review); err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidRating):
+			http.Error(w, "Rating must be between 1 and 5", http.StatusBadRequest)
+		case errors.Is(err, service.ErrReviewNotCompleted):
+			http.Error(w, "Only completed appointments can be reviewed", http.StatusBadRequest)
+		default:
+			writeLookupError(w, err, "Failed to submit review")
+		}
 		return
 	}
 
@@ -280,20 +206,15 @@ func SubmitReviewForAppointment(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get All Appointments of a Patient
-func GetAllAppointmentsForPatient(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetAllAppointmentsForPatient(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var appointments []models.Appointment
-	if err := config.DB.
-		Preload("DoctorProfile.User").
-		Preload("Patient").
-		Where("patient_id = ?", userID).
-		Order("scheduled_at DESC").
-		Find(&appointments).Error; err != nil {
+	appointments, err := h.Patients.All(userID)
+	if err != nil {
 		http.Error(w, "Failed to fetch appointments", http.StatusInternalServerError)
 		return
 	}
@@ -304,15 +225,15 @@ func GetAllAppointmentsForPatient(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get Patient Profile
-func GetPatientProfile(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetPatientProfile(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var patient models.User
-	if err := config.DB.Where("id = ? AND role = ?", userID, models.PATIENT).First(&patient).Error; err != nil {
+	patient, err := h.Patients.Profile(userID)
+	if err != nil {
 		http.Error(w, "Patient not found", http.StatusNotFound)
 		return
 	}
@@ -320,17 +241,15 @@ func GetPatientProfile(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get Patient Test History
-func GetPatientTestHistory(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetPatientTestHistory(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserIDFromContext(r)
 	if userID == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var tests []models.MedicalCheck
-	if err := config.DB.Where("appointment_id IN (SELECT id FROM appointments WHERE patient_id = ?)", userID).
-		Order("created_at DESC").
-		Find(&tests).Error; err != nil {
+	tests, err := h.Patients.Tests(userID)
+	if err != nil {
 		http.Error(w, "Failed to fetch test history", http.StatusInternalServerError)
 		return
 	}
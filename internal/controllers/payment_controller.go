@@ -0,0 +1,192 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// maxWebhookBytes caps a gateway callback body.
+const maxWebhookBytes = 1 << 20
+
+// writePaymentError maps payment failures to 404/409/422/502 responses
+func writePaymentError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrPaymentNotFound):
+		http.Error(w, "Payment not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrAlreadyPaid), errors.Is(err, domain.ErrNotRefundable):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrNotPayable):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	case errors.Is(err, service.ErrRefundNotSent):
+		http.Error(w, err.Error(), http.StatusBadGateway)
+	case errors.Is(err, payments.ErrInvalidAmount),
+		errors.Is(err, payments.ErrNotRefundable),
+		errors.Is(err, payments.ErrUnknownIntent):
+		http.Error(w, "Payment gateway error: "+err.Error(), http.StatusBadGateway)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// writeIntent returns what the client needs to complete checkout
+func writeIntent(w http.ResponseWriter, payment *models.Payment) {
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"payment":      payment,
+		"provider":     payment.Provider,
+		"intentId":     payment.IntentID,
+		"clientSecret": payment.ClientSecret,
+	})
+}
+
+// Start paying the fee of one of the patient's appointments
+func (h *Handler) CreateAppointmentPayment(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	appointment, err := h.Patients.Appointment(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
+		return
+	}
+
+	payment, err := h.Payments.StartAppointmentPayment(appointment)
+	if err != nil {
+		writePaymentError(w, err, "Failed to start payment")
+		return
+	}
+	writeIntent(w, payment)
+}
+
+// Start paying one of the patient's online orders
+func (h *Handler) CreateOrderPayment(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	order, err := h.Orders.Order(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeOrderError(w, err, "Failed to fetch order")
+		return
+	}
+
+	payment, err := h.Payments.StartOrderPayment(order)
+	if err != nil {
+		writePaymentError(w, err, "Failed to start payment")
+		return
+	}
+	writeIntent(w, payment)
+}
+
+// List the user's payments
+func (h *Handler) GetMyPayments(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	list, err := h.Payments.ForUser(userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch payments", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"payments": list,
+	})
+}
+
+// Gateway callback; the only way a fee or order becomes paid
+func (h *Handler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBytes))
+	if err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	h.applyPaymentEvent(w, payload, r.Header.Get(payments.SignatureHeader))
+}
+
+// applyPaymentEvent verifies a signed gateway event and applies it
+func (h *Handler) applyPaymentEvent(w http.ResponseWriter, payload []byte, signature string) {
+	event, err := h.Gateway.VerifyWebhook(payload, signature)
+	if err != nil {
+		http.Error(w, "Invalid signature", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Payments.HandleEvent(event); err != nil {
+		// unknown intents will never match; acknowledge so the gateway stops retrying
+		if errors.Is(err, service.ErrPaymentNotFound) {
+			log.Printf("payment event %s for unknown intent %s ignored", event.ID, event.IntentID)
+			json.NewEncoder(w).Encode(map[string]string{"status": "ignored"})
+			return
+		}
+		log.Printf("payment event %s failed: %v", event.ID, err)
+		http.Error(w, "Failed to process event", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+}
+
+// Refund a succeeded payment in full; also retries a refund the gateway has
+// not confirmed
+func (h *Handler) RefundPayment(w http.ResponseWriter, r *http.Request) {
+	payment, err := h.Payments.Refund(chi.URLParam(r, "id"))
+	if err != nil {
+		writePaymentError(w, err, "Failed to refund payment")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Payment refunded",
+		"payment": payment,
+	})
+}
+
+// Complete a mock checkout: {"succeed": false} simulates a declined card.
+// Only routed when the mock gateway is configured
+func (h *Handler) MockCheckout(w http.ResponseWriter, r *http.Request) {
+	mock, ok := h.Gateway.(*payments.MockGateway)
+	if !ok {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	req := struct {
+		Succeed bool `json:"succeed"`
+	}{Succeed: true}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	payload, signature, err := mock.Pay(chi.URLParam(r, "intentId"), req.Succeed)
+	if err != nil {
+		if errors.Is(err, payments.ErrUnknownIntent) {
+			http.Error(w, "Intent not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	// deliver the signed event through the same path as a real callback
+	h.applyPaymentEvent(w, payload, signature)
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+type IssuePrescriptionRequest struct {
+	Items models.PrescriptionItems `json:"items"`
+	Notes string                   `json:"notes"`
+	// ValidDays defaults to 30
+	ValidDays int `json:"validDays"`
+}
+
+// writePrescriptionError maps prescription failures to 400/404/422 responses
+func writePrescriptionError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, service.ErrPrescriptionNotFound):
+		http.Error(w, "Prescription not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrInvalidPrescription):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrNotPrescribable), errors.Is(err, domain.ErrMedicineUnavailable):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		http.Error(w, fallback, http.StatusInternalServerError)
+	}
+}
+
+// Issue a prescription for one of the doctor's appointments
+func (h *Handler) IssuePrescription(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req IssuePrescriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	// only the treating doctor finds the appointment
+	appointment, err := h.Doctors.Appointment(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
+		return
+	}
+
+	prescription, err := h.Prescriptions.Issue(appointment, service.PrescriptionInput{
+		Items:    req.Items,
+		Notes:    req.Notes,
+		ValidFor: time.Duration(req.ValidDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		writePrescriptionError(w, err, "Failed to issue prescription")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":      "Prescription issued successfully",
+		"prescription": prescription,
+	})
+}
+
+// List prescriptions issued for one of the doctor's appointments
+func (h *Handler) GetAppointmentPrescriptions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	appointment, err := h.Doctors.Appointment(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
+		return
+	}
+
+	prescriptions, err := h.Prescriptions.ForAppointment(appointment.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch prescriptions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"prescriptions": prescriptions,
+	})
+}
+
+// List the patient's prescriptions
+func (h *Handler) GetMyPrescriptions(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	prescriptions, err := h.Prescriptions.ForPatient(userID)
+	if err != nil {
+		http.Error(w, "Failed to fetch prescriptions", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"prescriptions": prescriptions,
+	})
+}
+
+// View one of the patient's prescriptions
+func (h *Handler) GetMyPrescription(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	prescription, err := h.Prescriptions.Prescription(userID, chi.URLParam(r, "id"))
+	if err != nil {
+		writePrescriptionError(w, err, "Failed to fetch prescription")
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"prescription": prescription,
+	})
+}
+
+// Order every catalog medicine on a prescription in one call
+func (h *Handler) OrderPrescription(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		PaymentMethod string `json:"paymentMethod"`
+	}
+	// the body is optional; payment defaults to ONLINE
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		http.Error(w, "Invalid request format", http.StatusBadRequest)
+		return
+	}
+
+	order, err := h.Prescriptions.Order(userID, chi.URLParam(r, "id"), req.PaymentMethod)
+	if err != nil {
+		writeOrderError(w, err, "Failed to place order")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Order placed successfully",
+		"order":   order,
+	})
+}
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/go-chi/chi/v5"
+)
+
+// writeProposalError maps reschedule proposal failures to HTTP responses
+func writeProposalError(w http.ResponseWriter, err error, fallback string) {
+	switch {
+	case errors.Is(err, domain.ErrOwnProposal):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, domain.ErrNoOpenProposal),
+		errors.Is(err, domain.ErrProposalExpired),
+		errors.Is(err, domain.ErrSlotTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, domain.ErrSameTime),
+		errors.Is(err, domain.ErrSlotInPast),
+		errors.Is(err, domain.ErrOutsideAvailability),
+		errors.Is(err, domain.ErrDoctorOnLeave),
+		errors.Is(err, domain.ErrDoctorNotApproved):
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+	default:
+		writeTransitionError(w, err, fallback)
+	}
+}
+
+// appointmentForUser loads the {id} appointment if it belongs to the caller,
+// as its patient or its doctor
+func (h *Handler) appointmentForUser(r *http.Request) (*models.Appointment, error) {
+	userID := middleware.GetUserIDFromContext(r)
+	appointmentID := chi.URLParam(r, "id")
+	if middleware.GetRoleFromContext(r) == models.DOCTOR {
+		return h.Doctors.Appointment(userID, appointmentID)
+	}
+	return h.Patients.Appointment(userID, appointmentID)
+}
+
+// Propose a new time (or counter the open proposal)
+func (h *Handler) ProposeReschedule(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+	if userID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	var req struct {
+		ProposedTime string `json:"proposedTime"`
+		Reason       string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	proposedTime, err := time.Parse(time.RFC3339, req.ProposedTime)
+	if err != nil {
+		http.Error(w, "Invalid date format. Expected RFC3339", http.StatusBadRequest)
+		return
+	}
+
+	appointment, err := h.appointmentForUser(r)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
+		return
+	}
+
+	proposal, err := h.Appointments.ProposeReschedule(appointment, service.ProposalInput{
+		Role:   middleware.GetRoleFromContext(r),
+		UserID: userID,
+		Time:   proposedTime,
+		Reason: req.Reason,
+	})
+	if err != nil {
+		writeProposalError(w, err, "Failed to propose new time")
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Reschedule proposal sent",
+		"proposal": proposal,
+	})
+}
+
+// List the reschedule proposals of an appointment
+func (h *Handler) GetRescheduleProposals(w http.ResponseWriter, r *http.Request) {
+	appointment, err := h.appointmentForUser(r)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
+		return
+	}
+
+	proposals, err := h.Appointments.ListProposals(appointment.ID)
+	if err != nil {
+		http.Error(w, "Failed to fetch proposals", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"scheduledAt": appointment.ScheduledAt,
+		"status":      appointment.Status,
+		"proposals":   proposals,
+	})
+}
+
+// Accept the open proposal; the appointment moves to the proposed time
+func (h *Handler) AcceptRescheduleProposal(w http.ResponseWriter, r *http.Request) {
+	h.respondToProposal(w, r, true)
+}
+
+// Reject the open proposal; the appointment keeps its time
+func (h *Handler) RejectRescheduleProposal(w http.ResponseWriter, r *http.Request) {
+	h.respondToProposal(w, r, false)
+}
+
+func (h *Handler) respondToProposal(w http.ResponseWriter, r *http.Request, accept bool) {
+	appointment, err := h.appointmentForUser(r)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch appointment")
+		return
+	}
+
+	proposal, err := h.Appointments.RespondToProposal(appointment, chi.URLParam(r, "proposalId"),
+		middleware.GetRoleFromContext(r), accept)
+	if err != nil {
+		writeProposalError(w, err, "Failed to answer proposal")
+		return
+	}
+
+	message := "Reschedule rejected"
+	if accept {
+		message = "Reschedule accepted"
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     message,
+		"scheduledAt": appointment.ScheduledAt,
+		"proposal":    proposal,
+	})
+}
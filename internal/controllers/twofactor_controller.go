@@ -0,0 +1,249 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+	"github.com/go-chi/chi/v5"
+	"gorm.io/gorm"
+)
+
+// secondFactorRequest carries either a TOTP code or a recovery code
+type secondFactorRequest struct {
+	Code         string `json:"code"`
+	RecoveryCode string `json:"recoveryCode"`
+}
+
+// writeTwoFactorError maps 2FA service errors to HTTP responses
+func writeTwoFactorError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidSecondFactor):
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	case errors.Is(err, service.ErrSecondFactorLocked):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, service.ErrTOTPAlreadyEnabled), errors.Is(err, service.ErrTOTPNotEnrolled),
+		errors.Is(err, service.ErrTOTPNotStarted):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, service.ErrTwoFactorEnforced), errors.Is(err, service.ErrTwoFactorRole):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		http.Error(w, "User not found", http.StatusNotFound)
+	case errors.Is(err, utils.ErrEncryptionKeyMissing):
+		http.Error(w, "Two-factor authentication is not configured", http.StatusServiceUnavailable)
+	default:
+		http.Error(w, "Failed to process two-factor request", http.StatusInternalServerError)
+	}
+}
+
+// startLogin finishes a first login factor: accounts with TOTP enabled get a
+// challenge token for /auth/login/totp, everyone else a session.
+func (h *Handler) startLogin(w http.ResponseWriter, r *http.Request, user *models.User) {
+	if !user.TOTPEnabled {
+		h.writeSession(w, r, user)
+		return
+	}
+
+	challenge, err := h.Keys.GenerateChallengeJWT(user.ID, utils.ScopeLoginTOTP)
+	if err != nil {
+		http.Error(w, "Failed to issue challenge", http.StatusInternalServerError)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"totpRequired":   true,
+		"challengeToken": challenge,
+		"message":        "Enter the code from your authenticator app at /auth/login/totp",
+	})
+}
+
+// Second login step for accounts with TOTP enabled
+func (h *Handler) VerifyLoginTOTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ChallengeToken string `json:"challengeToken"`
+		secondFactorRequest
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	claims, err := h.Keys.ParseJWT(req.ChallengeToken)
+	if err != nil || claims.Scope != utils.ScopeLoginTOTP || claims.UserID == "" {
+		http.Error(w, "Invalid or expired challenge", http.StatusUnauthorized)
+		return
+	}
+
+	if err := h.TwoFactor.VerifySecondFactor(claims.UserID, req.Code, req.RecoveryCode); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	user, err := h.Users.Get(claims.UserID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := h.Sessions.Start(user, r.UserAgent(), r.RemoteAddr)
+	if err != nil {
+		http.Error(w, "Failed to start session", http.StatusInternalServerError)
+		return
+	}
+	// the login itself just passed the second factor
+	h.TwoFactor.MarkStepUp(tokens.SessionID)
+
+	writeTokens(w, tokens, user)
+}
+
+// Start TOTP enrollment; returns the secret and a provisioning URI for a QR code
+func (h *Handler) EnrollTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+
+	user, err := h.Users.Get(userID)
+	if err != nil {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+
+	secret, uri, err := h.TwoFactor.BeginTOTPEnrollment(user)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"secret":          secret,
+		"provisioningUri": uri,
+		"message":         "Scan the QR code, then confirm with a code at /auth/2fa/totp/confirm",
+	})
+}
+
+// Confirm TOTP enrollment with a first code; returns recovery codes once
+func (h *Handler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaimsFromContext(r)
+
+	var req secondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.TwoFactor.ConfirmTOTPEnrollment(claims.UserID, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	h.TwoFactor.MarkStepUp(claims.SessionID)
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Two-factor authentication enabled. Store the recovery codes safely, they are shown only once",
+		"recoveryCodes": codes,
+	})
+}
+
+// Turn TOTP off for the current user
+func (h *Handler) DisableTOTP(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+
+	var req secondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TwoFactor.DisableTOTP(userID, req.Code, req.RecoveryCode); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+// Replace the recovery codes of the current user
+func (h *Handler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserIDFromContext(r)
+
+	var req secondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Code == "" {
+		http.Error(w, "Code is required", http.StatusBadRequest)
+		return
+	}
+
+	codes, err := h.TwoFactor.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"recoveryCodes": codes,
+	})
+}
+
+// Step-up: re-verify the second factor to unlock sensitive endpoints
+func (h *Handler) StepUp(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaimsFromContext(r)
+
+	var req secondFactorRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TwoFactor.VerifySecondFactor(claims.UserID, req.Code, req.RecoveryCode); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+	if err := h.TwoFactor.MarkStepUp(claims.SessionID); err != nil {
+		http.Error(w, "Failed to record verification", http.StatusInternalServerError)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Verified",
+		"expiresIn": int(service.StepUpTTL.Seconds()),
+	})
+}
+
+// Admin: enforce or relax two-factor for a doctor or admin
+func (h *Handler) SetUserTwoFactorRequired(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	var req struct {
+		Required *bool `json:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Required == nil {
+		http.Error(w, "required is mandatory", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.TwoFactor.SetTwoFactorRequired(userID, *req.Required); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Two-factor requirement updated; existing sessions were signed out",
+		"required": *req.Required,
+	})
+}
+
+// Admin: remove a user's TOTP device, e.g. after they lost it
+func (h *Handler) ResetUserTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	if err := h.TwoFactor.ResetTwoFactor(userID); err != nil {
+		writeTwoFactorError(w, err)
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Two-factor authentication reset; the user must enroll again",
+	})
+}
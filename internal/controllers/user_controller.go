@@ -2,162 +2,176 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
-	"github.com/GitNinja36/wello-backend/config"
 	"github.com/GitNinja36/wello-backend/internal/middleware"
 	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/service"
+	"github.com/GitNinja36/wello-backend/internal/utils"
 	"github.com/go-chi/chi/v5"
-	"github.com/google/uuid"
 )
 
-// Register Patient
-func RegisterPatient(w http.ResponseWriter, r *http.Request) {
-	type Request struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
-		Phone string `json:"phone"`
-	}
-
-	var req Request
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid Request", http.StatusBadRequest)
+// Admin Approve Doctor
+func (h *Handler) ApproveDoctor(w http.ResponseWriter, r *http.Request) {
+	adminID := middleware.GetUserIDFromContext(r)
+	if adminID == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	user := models.User{
-		ID:       uuid.NewString(),
-		Name:     req.Name,
-		Email:    req.Email,
-		Phone:    req.Phone,
-		Role:     models.PATIENT,
-		Verified: true,
-	}
+	doctorId := chi.URLParam(r, "id")
 
-	if err := config.DB.Create(&user).Error; err != nil {
-		http.Error(w, "User creation failed", http.StatusInternalServerError)
+	profile, err := h.Doctors.Profile(doctorId)
+	if err != nil {
+		writeLookupError(w, err, "Failed to fetch doctor profile")
+		return
+	}
+	if err := h.Admin.ApproveDoctor(profile, adminID); err != nil {
+		if errors.Is(err, service.ErrAlreadyApproved) {
+			http.Error(w, "Doctor is already approved", http.StatusConflict)
+			return
+		}
+		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Patient registered successfully",
-		"userId":  user.ID,
+		"message":    "Doctor approved successfully",
+		"approvedBy": adminID,
 	})
 }
 
-// Register Doctor
-func RegisterDoctor(w http.ResponseWriter, r *http.Request) {
-	type Request struct {
-		Name             string  `json:"name"`
-		Email            string  `json:"email"`
-		Phone            string  `json:"phone"`
-		Specialization   string  `json:"specialization"`
-		LicenseNumber    string  `json:"licenseNumber"`
-		ConsultationFees float64 `json:"consultationFees"`
-	}
-
-	var req Request
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
-		return
-	}
-
-	user := models.User{
-		ID:                uuid.NewString(),
-		Name:              req.Name,
-		Email:             req.Email,
-		Phone:             req.Phone,
-		Role:              models.DOCTOR,
-		RequestedAsDoctor: true,
-		IsApproved:        false,
-		Verified:          true,
+// onboardingIdentifier returns the phone or email proven by the onboarding
+// token. It is the only identifier a new account is created with: anything
+// else would let the caller claim an address they never verified.
+func onboardingIdentifier(w http.ResponseWriter, r *http.Request) (phone, email string, ok bool) {
+	claims := middleware.GetClaimsFromContext(r)
+	if claims == nil || claims.Identifier == "" {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return "", "", false
 	}
-
-	if err := config.DB.Create(&user).Error; err != nil {
-		http.Error(w, "Doctor user creation failed", http.StatusInternalServerError)
-		return
+	switch claims.IdentifierType {
+	case utils.IdentifierPhone:
+		return claims.Identifier, "", true
+	case utils.IdentifierEmail:
+		return "", claims.Identifier, true
 	}
+	http.Error(w, "Invalid onboarding token", http.StatusUnauthorized)
+	return "", "", false
+}
 
-	profile := models.DoctorProfile{
-		ID:                uuid.NewString(),
-		UserID:            user.ID,
-		Specialization:    req.Specialization,
-		LicenseNumber:     req.LicenseNumber,
-		ConsultationFees:  req.ConsultationFees,
-		AvailabilitySlots: "[]",
-		IsPending:         true,
+// writeOnboardingError maps account creation errors to HTTP responses
+func writeOnboardingError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrNameRequired):
+		http.Error(w, "Name is required", http.StatusBadRequest)
+	case errors.Is(err, service.ErrAccountExists):
+		http.Error(w, "An account with this phone or email already exists", http.StatusConflict)
+	default:
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
 	}
+}
 
-	if err := config.DB.Create(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile creation failed", http.StatusInternalServerError)
+// writeOnboarded starts the first session of a freshly created account
+func (h *Handler) writeOnboarded(w http.ResponseWriter, r *http.Request, user *models.User, message string) {
+	tokens, err := h.Sessions.Start(user, r.UserAgent(), r.RemoteAddr)
+	if err != nil {
+		http.Error(w, "Account created but failed to start session", http.StatusInternalServerError)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Doctor registration requested. Awaiting admin approval.",
-		"userId":  user.ID,
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":      message,
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+		"userId":       user.ID,
+		"role":         user.Role,
+		"isApproved":   user.IsApproved,
+		"needsProfile": false,
 	})
 }
 
-// Admin Approve Doctor
-func ApproveDoctor(w http.ResponseWriter, r *http.Request) {
-	doctorId := chi.URLParam(r, "id")
+// patient-onboarding: turns a verified OTP identifier into a PATIENT account
+func (h *Handler) CompletePatientOnboarding(w http.ResponseWriter, r *http.Request) {
+	type Request struct {
+		Name    string `json:"name"`
+		Age     int    `json:"age"`
+		Gender  string `json:"gender"`
+		Address string `json:"address"`
+	}
 
-	var profile models.DoctorProfile
-	if err := config.DB.Where("user_id = ?", doctorId).First(&profile).Error; err != nil {
-		http.Error(w, "Doctor profile not found", http.StatusNotFound)
+	phone, email, ok := onboardingIdentifier(w, r)
+	if !ok {
 		return
 	}
-	profile.IsPending = false
-	adminID := middleware.GetUserIDFromContext(r)
-	profile.ApprovedBy = &adminID
-	if err := config.DB.Save(&profile).Error; err != nil {
-		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid input", http.StatusBadRequest)
 		return
 	}
 
-	config.DB.Model(&models.User{}).Where("id = ?", profile.UserID).
-		Update("is_approved", true)
-
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Doctor approved successfully",
+	user, err := h.Users.OnboardPatient(service.PatientInput{
+		Name:    req.Name,
+		Email:   email,
+		Phone:   phone,
+		Age:     req.Age,
+		Gender:  req.Gender,
+		Address: req.Address,
 	})
+	if err != nil {
+		writeOnboardingError(w, err)
+		return
+	}
+
+	h.writeOnboarded(w, r, user, "Patient profile completed")
 }
 
-// patient-onboarding
-func CompletePatientOnboarding(w http.ResponseWriter, r *http.Request) {
+// doctor-onboarding: turns a verified OTP identifier into a DOCTOR account
+// with a profile awaiting admin approval
+func (h *Handler) CompleteDoctorOnboarding(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
+		Name             string  `json:"name"`
+		Specialization   string  `json:"specialization"`
+		LicenseNumber    string  `json:"licenseNumber"`
+		ConsultationFees float64 `json:"consultationFees"`
 	}
 
-	userID := r.Context().Value("userID").(string)
+	phone, email, ok := onboardingIdentifier(w, r)
+	if !ok {
+		return
+	}
 
 	var req Request
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid input", http.StatusBadRequest)
+		http.Error(w, "Invalid request", http.StatusBadRequest)
 		return
 	}
 
-	// Update user
-	if err := config.DB.Model(&models.User{}).Where("id = ?", userID).
-		Updates(map[string]interface{}{
-			"name":  req.Name,
-			"email": req.Email,
-		}).Error; err != nil {
-		http.Error(w, "Failed to update profile", http.StatusInternalServerError)
+	user, err := h.Users.OnboardDoctor(service.DoctorRegistration{
+		Name:             req.Name,
+		Email:            email,
+		Phone:            phone,
+		Specialization:   req.Specialization,
+		LicenseNumber:    req.LicenseNumber,
+		ConsultationFees: req.ConsultationFees,
+	})
+	if err != nil {
+		writeOnboardingError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]string{
-		"message": "Patient profile completed",
-	})
+	h.writeOnboarded(w, r, user, "Doctor registration requested. Awaiting admin approval.")
 }
 
 // Get all Users By Role
-func GetUsersByRole(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetUsersByRole(w http.ResponseWriter, r *http.Request) {
 	role := strings.ToUpper(r.URL.Query().Get("role"))
 
 	if role != "PATIENT" && role != "DOCTOR" {
@@ -176,28 +190,9 @@ func GetUsersByRole(w http.ResponseWriter, r *http.Request) {
 	if err != nil || limit < 1 {
 		limit = 10
 	}
-	offset := (page - 1) * limit
-
-	var users []models.User
-	var total int64
-
-	config.DB.Model(&models.User{}).
-		Where("role = ?", role).
-		Count(&total)
-
-	db := config.DB.Preload("Appointments").Preload("Orders")
-
-	if role == "DOCTOR" {
-		db = db.Preload("DoctorProfile")
-	} else {
-		db = db.Preload("AdminProfile")
-	}
 
-	if err := db.
-		Where("role = ?", role).
-		Limit(limit).
-		Offset(offset).
-		Find(&users).Error; err != nil {
+	users, total, err := h.Users.ListByRole(models.Role(role), page, limit)
+	if err != nil {
 		http.Error(w, "Failed to fetch users", http.StatusInternalServerError)
 		return
 	}
@@ -212,10 +207,9 @@ func GetUsersByRole(w http.ResponseWriter, r *http.Request) {
 }
 
 // Update the Patient Profile
-func UpdatePatientProfile(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdatePatientProfile(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		Name    string `json:"name"`
-		Email   string `json:"email"`
 		Age     int    `json:"age"`
 		Gender  string `json:"gender"`
 		Bio     string `json:"bio"`
@@ -234,20 +228,18 @@ func UpdatePatientProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var user models.User
-	if err := config.DB.First(&user, "id = ?", userID).Error; err != nil {
-		http.Error(w, "User not found", http.StatusInternalServerError)
-		return
-	}
-
-	user.Name = req.Name
-	user.Email = req.Email
-	user.Age = req.Age
-	user.Gender = req.Gender
-	user.Bio = req.Bio
-	user.Address = req.Address
-
-	if err := config.DB.Save(&user).Error; err != nil {
+	err := h.Users.UpdatePatientProfile(userID, service.PatientProfileInput{
+		Name:    req.Name,
+		Age:     req.Age,
+		Gender:  req.Gender,
+		Bio:     req.Bio,
+		Address: req.Address,
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			http.Error(w, "User not found", http.StatusInternalServerError)
+			return
+		}
 		http.Error(w, "Failed to update user", http.StatusInternalServerError)
 		return
 	}
@@ -258,15 +250,15 @@ func UpdatePatientProfile(w http.ResponseWriter, r *http.Request) {
 }
 
 // Get user info
-func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	UserId := middleware.GetUserIDFromContext(r)
 	if UserId == "" {
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	var user models.User
-	if err := config.DB.First(&user, "id = ?", UserId).Error; err != nil {
+	user, err := h.Users.Get(UserId)
+	if err != nil {
 		http.Error(w, "User not found", http.StatusInternalServerError)
 		return
 	}
@@ -287,7 +279,7 @@ func GetCurrentUser(w http.ResponseWriter, r *http.Request) {
 }
 
 // update profile photo
-func UpdateProfilePhoto(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) UpdateProfilePhoto(w http.ResponseWriter, r *http.Request) {
 	type Request struct {
 		PhotoURL string `json:"photoUrl"`
 	}
@@ -304,8 +296,7 @@ func UpdateProfilePhoto(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := config.DB.Model(&models.User{}).Where("id = ?", userID).
-		Update("photo_url", req.PhotoURL).Error; err != nil {
+	if err := h.Users.UpdatePhoto(userID, req.PhotoURL); err != nil {
 		http.Error(w, "Failed to update photo", http.StatusInternalServerError)
 		return
 	}
@@ -0,0 +1,233 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+var (
+	ErrReasonRequired    = errors.New("a reason is required for this status change")
+	ErrBeforeStart       = errors.New("this can only be recorded after the appointment has started")
+	ErrInvalidTransition = errors.New("appointment status transition not allowed")
+	ErrRoleNotAllowed    = errors.New("role may not trigger this appointment transition")
+	ErrUnknownEvent      = errors.New("unknown appointment event")
+)
+
+// AppointmentEvent is an action that moves an appointment between statuses.
+type AppointmentEvent string
+
+const (
+	EventAccept            AppointmentEvent = "ACCEPT"
+	EventReject            AppointmentEvent = "REJECT"
+	EventRequestReschedule AppointmentEvent = "REQUEST_RESCHEDULE"
+	EventAcceptReschedule  AppointmentEvent = "ACCEPT_RESCHEDULE"
+	EventRejectReschedule  AppointmentEvent = "REJECT_RESCHEDULE"
+	EventComplete          AppointmentEvent = "COMPLETE"
+	EventCancelByPatient   AppointmentEvent = "CANCEL_BY_PATIENT"
+	EventCancelByDoctor    AppointmentEvent = "CANCEL_BY_DOCTOR"
+	EventExpire            AppointmentEvent = "EXPIRE"
+	EventNoShowPatient     AppointmentEvent = "NO_SHOW_PATIENT"
+	EventNoShowDoctor      AppointmentEvent = "NO_SHOW_DOCTOR"
+)
+
+// SystemRole fires transitions on behalf of background jobs; no user has it.
+const SystemRole models.Role = "SYSTEM"
+
+// Party is who gets notified once a transition is applied.
+type Party string
+
+const (
+	NotifyNone    Party = ""
+	NotifyPatient Party = "PATIENT"
+	NotifyDoctor  Party = "DOCTOR"
+	NotifyBoth    Party = "BOTH"
+	// NotifyOther is the party that did not fire the event.
+	NotifyOther Party = "OTHER"
+)
+
+// Transition is one row of the appointment state machine.
+type Transition struct {
+	From   []models.AppointmentStatus
+	To     models.AppointmentStatus
+	Roles  []models.Role
+	Notify Party
+	// Template names the notify template sent to the Notify party.
+	Template string
+	// RequiresReason makes a status_reason change mandatory.
+	RequiresReason bool
+	// AfterStart only allows the event once the appointment time has passed.
+	AfterStart bool
+}
+
+// ReasonKey is the change column carrying the reason for a status change.
+const ReasonKey = "status_reason"
+
+// attendedFrom are the statuses of an appointment expected to take place.
+var attendedFrom = []models.AppointmentStatus{
+	models.ACCEPTED,
+	models.RESCHEDULED_CONFIRMED,
+	models.RESCHEDULE_REJECTED,
+}
+
+// Transitions is the single source of truth for appointment status changes.
+var Transitions = map[AppointmentEvent]Transition{
+	EventAccept: {
+		From:     []models.AppointmentStatus{models.PENDING},
+		To:       models.ACCEPTED,
+		Roles:    []models.Role{models.DOCTOR},
+		Notify:   NotifyPatient,
+		Template: "appointment.accepted",
+	},
+	EventReject: {
+		From:     []models.AppointmentStatus{models.PENDING},
+		To:       models.REJECTED,
+		Roles:    []models.Role{models.DOCTOR},
+		Notify:   NotifyPatient,
+		Template: "appointment.rejected",
+	},
+	// only a confirmed time can be rescheduled: a rejected proposal falls back
+	// to RESCHEDULE_REJECTED, which counts as confirmed. A PENDING request is
+	// accepted or rejected instead.
+	EventRequestReschedule: {
+		From: []models.AppointmentStatus{
+			models.ACCEPTED,
+			models.RESCHEDULED_CONFIRMED,
+			models.RESCHEDULE_REJECTED,
+			// a counter-proposal
+			models.RESCHEDULE_REQUESTED,
+		},
+		To:       models.RESCHEDULE_REQUESTED,
+		Roles:    []models.Role{models.DOCTOR, models.PATIENT},
+		Notify:   NotifyOther,
+		Template: "appointment.reschedule_requested",
+	},
+	EventAcceptReschedule: {
+		From:     []models.AppointmentStatus{models.RESCHEDULE_REQUESTED},
+		To:       models.RESCHEDULED_CONFIRMED,
+		Roles:    []models.Role{models.DOCTOR, models.PATIENT},
+		Notify:   NotifyOther,
+		Template: "appointment.reschedule_accepted",
+	},
+	EventRejectReschedule: {
+		From:     []models.AppointmentStatus{models.RESCHEDULE_REQUESTED},
+		To:       models.RESCHEDULE_REJECTED,
+		Roles:    []models.Role{models.DOCTOR, models.PATIENT},
+		Notify:   NotifyOther,
+		Template: "appointment.reschedule_rejected",
+	},
+	EventComplete: {
+		From:   []models.AppointmentStatus{models.ACCEPTED, models.RESCHEDULED_CONFIRMED, models.RESCHEDULE_REJECTED},
+		To:     models.COMPLETED,
+		Roles:  []models.Role{models.DOCTOR},
+		Notify: NotifyNone,
+	},
+	EventCancelByPatient: {
+		From: []models.AppointmentStatus{
+			models.PENDING,
+			models.ACCEPTED,
+			models.RESCHEDULE_REQUESTED,
+			models.RESCHEDULED_CONFIRMED,
+			models.RESCHEDULE_REJECTED,
+		},
+		To:       models.CANCELLED_BY_PATIENT,
+		Roles:    []models.Role{models.PATIENT},
+		Notify:   NotifyDoctor,
+		Template: "appointment.cancelled_by_patient",
+	},
+	EventCancelByDoctor: {
+		From: []models.AppointmentStatus{
+			models.PENDING,
+			models.ACCEPTED,
+			models.RESCHEDULE_REQUESTED,
+			models.RESCHEDULED_CONFIRMED,
+			models.RESCHEDULE_REJECTED,
+		},
+		To:             models.CANCELLED_BY_DOCTOR,
+		Roles:          []models.Role{models.DOCTOR},
+		Notify:         NotifyPatient,
+		Template:       "appointment.cancelled_by_doctor",
+		RequiresReason: true,
+	},
+	EventNoShowPatient: {
+		From:           attendedFrom,
+		To:             models.NO_SHOW_PATIENT,
+		Roles:          []models.Role{models.DOCTOR},
+		Notify:         NotifyPatient,
+		Template:       "appointment.no_show_patient",
+		RequiresReason: true,
+		AfterStart:     true,
+	},
+	EventNoShowDoctor: {
+		From:           attendedFrom,
+		To:             models.NO_SHOW_DOCTOR,
+		Roles:          []models.Role{models.PATIENT},
+		Notify:         NotifyDoctor,
+		Template:       "appointment.no_show_doctor",
+		RequiresReason: true,
+		AfterStart:     true,
+	},
+	EventExpire: {
+		From:     []models.AppointmentStatus{models.PENDING, models.RESCHEDULE_REQUESTED},
+		To:       models.EXPIRED,
+		Roles:    []models.Role{SystemRole},
+		Notify:   NotifyBoth,
+		Template: "appointment.expired",
+	},
+}
+
+// NextStatus validates that role may fire event while the appointment is in
+// current, and returns the transition to apply.
+func NextStatus(current models.AppointmentStatus, event AppointmentEvent, role models.Role) (Transition, error) {
+	t, ok := Transitions[event]
+	if !ok {
+		return Transition{}, ErrUnknownEvent
+	}
+	if !containsRole(t.Roles, role) {
+		return Transition{}, ErrRoleNotAllowed
+	}
+	if !containsStatus(t.From, current) {
+		return Transition{}, fmt.Errorf("%w: %s cannot %s", ErrInvalidTransition, current, event)
+	}
+	return t, nil
+}
+
+// CheckPreconditions enforces the transition's RequiresReason and AfterStart
+// flags for an appointment scheduled at scheduledAt, given the reason the
+// caller supplied and the current time.
+func (t Transition) CheckPreconditions(reason string, scheduledAt, now time.Time) error {
+	if t.RequiresReason && strings.TrimSpace(reason) == "" {
+		return ErrReasonRequired
+	}
+	if t.AfterStart && now.Before(scheduledAt) {
+		return ErrBeforeStart
+	}
+	return nil
+}
+
+// CanTransition reports whether event is allowed for role from current.
+func CanTransition(current models.AppointmentStatus, event AppointmentEvent, role models.Role) bool {
+	_, err := NextStatus(current, event, role)
+	return err == nil
+}
+
+func containsRole(roles []models.Role, role models.Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func containsStatus(statuses []models.AppointmentStatus, status models.AppointmentStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+var allStatuses = []models.AppointmentStatus{
+	models.PENDING,
+	models.ACCEPTED,
+	models.REJECTED,
+	models.COMPLETED,
+	models.RESCHEDULE_REQUESTED,
+	models.RESCHEDULED,
+	models.RESCHEDULE_REJECTED,
+	models.RESCHEDULED_CONFIRMED,
+	models.CANCELLED_BY_PATIENT,
+	models.CANCELLED_BY_DOCTOR,
+	models.EXPIRED,
+	models.NO_SHOW_PATIENT,
+	models.NO_SHOW_DOCTOR,
+}
+
+var allRoles = []models.Role{
+	models.PATIENT,
+	models.DOCTOR,
+	models.ADMIN,
+	models.PHARMACIST,
+	SystemRole,
+}
+
+// allowed lists every permitted transition, written out independently of the
+// Transitions table so a change to the table has to be mirrored here.
+var allowed = []struct {
+	event AppointmentEvent
+	roles []models.Role
+	from  []models.AppointmentStatus
+	to    models.AppointmentStatus
+}{
+	{EventAccept, []models.Role{models.DOCTOR},
+		[]models.AppointmentStatus{models.PENDING}, models.ACCEPTED},
+	{EventReject, []models.Role{models.DOCTOR},
+		[]models.AppointmentStatus{models.PENDING}, models.REJECTED},
+	{EventRequestReschedule, []models.Role{models.DOCTOR, models.PATIENT},
+		[]models.AppointmentStatus{models.ACCEPTED, models.RESCHEDULED_CONFIRMED,
+			models.RESCHEDULE_REJECTED, models.RESCHEDULE_REQUESTED}, models.RESCHEDULE_REQUESTED},
+	{EventAcceptReschedule, []models.Role{models.DOCTOR, models.PATIENT},
+		[]models.AppointmentStatus{models.RESCHEDULE_REQUESTED}, models.RESCHEDULED_CONFIRMED},
+	{EventRejectReschedule, []models.Role{models.DOCTOR, models.PATIENT},
+		[]models.AppointmentStatus{models.RESCHEDULE_REQUESTED}, models.RESCHEDULE_REJECTED},
+	{EventComplete, []models.Role{models.DOCTOR},
+		[]models.AppointmentStatus{models.ACCEPTED, models.RESCHEDULED_CONFIRMED, models.RESCHEDULE_REJECTED},
+		models.COMPLETED},
+	{EventCancelByPatient, []models.Role{models.PATIENT},
+		[]models.AppointmentStatus{models.PENDING, models.ACCEPTED, models.RESCHEDULE_REQUESTED,
+			models.RESCHEDULED_CONFIRMED, models.RESCHEDULE_REJECTED}, models.CANCELLED_BY_PATIENT},
+	{EventCancelByDoctor, []models.Role{models.DOCTOR},
+		[]models.AppointmentStatus{models.PENDING, models.ACCEPTED, models.RESCHEDULE_REQUESTED,
+			models.RESCHEDULED_CONFIRMED, models.RESCHEDULE_REJECTED}, models.CANCELLED_BY_DOCTOR},
+	{EventNoShowPatient, []models.Role{models.DOCTOR},
+		[]models.AppointmentStatus{models.ACCEPTED, models.RESCHEDULED_CONFIRMED, models.RESCHEDULE_REJECTED},
+		models.NO_SHOW_PATIENT},
+	{EventNoShowDoctor, []models.Role{models.PATIENT},
+		[]models.AppointmentStatus{models.ACCEPTED, models.RESCHEDULED_CONFIRMED, models.RESCHEDULE_REJECTED},
+		models.NO_SHOW_DOCTOR},
+	{EventExpire, []models.Role{SystemRole},
+		[]models.AppointmentStatus{models.PENDING, models.RESCHEDULE_REQUESTED}, models.EXPIRED},
+}
+
+func TestNextStatus(t *testing.T) {
+	if len(allowed) != len(Transitions) {
+		t.Fatalf("test covers %d events, table has %d", len(allowed), len(Transitions))
+	}
+
+	for _, tc := range allowed {
+		for _, role := range allRoles {
+			for _, from := range allStatuses {
+				roleOK := containsRole(tc.roles, role)
+				fromOK := containsStatus(tc.from, from)
+
+				got, err := NextStatus(from, tc.event, role)
+				switch {
+				case roleOK && fromOK:
+					if err != nil {
+						t.Errorf("%s by %s from %s: unexpected error %v", tc.event, role, from, err)
+					} else if got.To != tc.to {
+						t.Errorf("%s by %s from %s: got %s, want %s", tc.event, role, from, got.To, tc.to)
+					}
+				case !roleOK:
+					if !errors.Is(err, ErrRoleNotAllowed) {
+						t.Errorf("%s by %s from %s: got %v, want ErrRoleNotAllowed", tc.event, role, from, err)
+					}
+				default:
+					if !errors.Is(err, ErrInvalidTransition) {
+						t.Errorf("%s by %s from %s: got %v, want ErrInvalidTransition", tc.event, role, from, err)
+					}
+				}
+				if CanTransition(from, tc.event, role) != (err == nil) {
+					t.Errorf("%s by %s from %s: CanTransition disagrees with NextStatus", tc.event, role, from)
+				}
+			}
+		}
+	}
+}
+
+func TestNextStatusUnknownEvent(t *testing.T) {
+	if _, err := NextStatus(models.PENDING, "TELEPORT", models.DOCTOR); !errors.Is(err, ErrUnknownEvent) {
+		t.Fatalf("got %v, want ErrUnknownEvent", err)
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	start := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		event  AppointmentEvent
+		reason string
+		now    time.Time
+		want   error
+	}{
+		{"accept needs nothing", EventAccept, "", start.Add(-time.Hour), nil},
+		{"patient cancel needs no reason", EventCancelByPatient, "", start.Add(-time.Hour), nil},
+		{"doctor cancel without reason", EventCancelByDoctor, "", start.Add(-time.Hour), ErrReasonRequired},
+		{"doctor cancel with blank reason", EventCancelByDoctor, "  \t", start.Add(-time.Hour), ErrReasonRequired},
+		{"doctor cancel with reason", EventCancelByDoctor, "emergency", start.Add(-time.Hour), nil},
+		{"doctor cancel before start is fine", EventCancelByDoctor, "emergency", start.Add(-48 * time.Hour), nil},
+		{"patient no-show before start", EventNoShowPatient, "did not join", start.Add(-time.Minute), ErrBeforeStart},
+		{"patient no-show at start", EventNoShowPatient, "did not join", start, nil},
+		{"patient no-show after start", EventNoShowPatient, "did not join", start.Add(15 * time.Minute), nil},
+		{"patient no-show without reason", EventNoShowPatient, "", start.Add(time.Hour), ErrReasonRequired},
+		{"doctor no-show before start", EventNoShowDoctor, "never came", start.Add(-time.Second), ErrBeforeStart},
+		{"doctor no-show after start", EventNoShowDoctor, "never came", start.Add(time.Hour), nil},
+		{"doctor no-show reason checked first", EventNoShowDoctor, "", start.Add(-time.Hour), ErrReasonRequired},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Transitions[tc.event].CheckPreconditions(tc.reason, start, tc.now)
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestReasonAndStartFlags(t *testing.T) {
+	for event, tr := range Transitions {
+		wantReason := event == EventCancelByDoctor || event == EventNoShowPatient || event == EventNoShowDoctor
+		wantStart := event == EventNoShowPatient || event == EventNoShowDoctor
+		if tr.RequiresReason != wantReason {
+			t.Errorf("%s: RequiresReason = %v, want %v", event, tr.RequiresReason, wantReason)
+		}
+		if tr.AfterStart != wantStart {
+			t.Errorf("%s: AfterStart = %v, want %v", event, tr.AfterStart, wantStart)
+		}
+	}
+}
+
+// A rejected proposal lands in RESCHEDULE_REJECTED, which counts as a
+// confirmed booking, so a proposal may only start from a confirmed status.
+func TestRescheduleRejectKeepsConfirmedTime(t *testing.T) {
+	for _, from := range allStatuses {
+		if !CanTransition(from, EventRequestReschedule, models.DOCTOR) || from == models.RESCHEDULE_REQUESTED {
+			continue
+		}
+		if !containsStatus(attendedFrom, from) {
+			t.Errorf("reschedule allowed from unconfirmed %s", from)
+		}
+	}
+	if CanTransition(models.PENDING, EventRequestReschedule, models.PATIENT) {
+		t.Error("a PENDING request can be rescheduled")
+	}
+}
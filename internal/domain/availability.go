@@ -0,0 +1,402 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+const (
+	defaultSlotDuration = 30 * time.Minute
+	// MaxSlotDuration is the longest slot a doctor may configure.
+	MaxSlotDuration = 240 * time.Minute
+	DateLayout      = "2006-01-02"
+	clockLayout     = "15:04"
+)
+
+var (
+	ErrOutsideAvailability = errors.New("requested time is outside the doctor's availability")
+	ErrSlotTaken           = errors.New("requested slot is already booked")
+	ErrDoctorNotApproved   = errors.New("doctor is not accepting appointments")
+	ErrSlotInPast          = errors.New("requested time is in the past")
+	ErrDoctorOnLeave       = errors.New("doctor is on leave at the requested time")
+	ErrInvalidLeave        = errors.New("leave must end after it starts and end in the future")
+)
+
+// BlockingStatuses are the appointment statuses that hold a doctor's slot.
+// A rejected reschedule keeps its original time, so it still blocks.
+var BlockingStatuses = []models.AppointmentStatus{
+	models.PENDING,
+	models.ACCEPTED,
+	models.RESCHEDULE_REQUESTED,
+	models.RESCHEDULED_CONFIRMED,
+	models.RESCHEDULE_REJECTED,
+}
+
+var weekdays = map[models.Weekday]time.Weekday{
+	models.SUNDAY:    time.Sunday,
+	models.MONDAY:    time.Monday,
+	models.TUESDAY:   time.Tuesday,
+	models.WEDNESDAY: time.Wednesday,
+	models.THURSDAY:  time.Thursday,
+	models.FRIDAY:    time.Friday,
+	models.SATURDAY:  time.Saturday,
+}
+
+// SlotConfig holds the per-doctor settings used to expand availability.
+type SlotConfig struct {
+	Location *time.Location
+	Duration time.Duration
+	Buffer   time.Duration
+}
+
+// ScheduleConfig reads the doctor's timezone, default slot duration and
+// buffer, falling back to UTC and 30 minutes.
+func ScheduleConfig(p *models.DoctorProfile) SlotConfig {
+	cfg := SlotConfig{
+		Location: time.UTC,
+		Duration: defaultSlotDuration,
+		Buffer:   time.Duration(p.BufferMinutes) * time.Minute,
+	}
+	if p.Timezone != "" {
+		if loc, err := time.LoadLocation(p.Timezone); err == nil {
+			cfg.Location = loc
+		}
+	}
+	if p.SlotDuration > 0 {
+		cfg.Duration = time.Duration(p.SlotDuration) * time.Minute
+	}
+	if cfg.Buffer < 0 {
+		cfg.Buffer = 0
+	}
+	return cfg
+}
+
+// SlotWindow is one concrete bookable slot.
+type SlotWindow struct {
+	Start    time.Time
+	Duration time.Duration
+}
+
+func (s SlotWindow) End() time.Time {
+	return s.Start.Add(s.Duration)
+}
+
+// Clashes reports whether the slot and b overlap once each is followed by
+// buffer, i.e. whether [start, end+buffer) of the two intersect.
+func (s SlotWindow) Clashes(b SlotWindow, buffer time.Duration) bool {
+	return s.Start.Before(b.End().Add(buffer)) && b.Start.Before(s.End().Add(buffer))
+}
+
+// BookedWindow is the slot appt occupies. Appointments booked before the
+// duration was stored count as default length.
+func BookedWindow(appt *models.Appointment) SlotWindow {
+	duration := time.Duration(appt.DurationMinutes) * time.Minute
+	if duration <= 0 {
+		duration = defaultSlotDuration
+	}
+	return SlotWindow{Start: appt.ScheduledAt, Duration: duration}
+}
+
+// LeaveCovers reports whether leave overlaps any part of slot.
+func LeaveCovers(leave *models.DoctorLeave, slot SlotWindow) bool {
+	return slot.Start.Before(leave.EndsAt) && slot.End().After(leave.StartsAt)
+}
+
+// DaySlots returns the slots for the calendar day of date in the doctor's
+// timezone. A date override replaces the weekly rules for that day.
+func DaySlots(a models.Availability, date time.Time, cfg SlotConfig) []SlotWindow {
+	local := date.In(cfg.Location)
+	day := local.Format(DateLayout)
+
+	var ranges []models.TimeRange
+	overridden := false
+	for _, o := range a.Overrides {
+		if o.Date == day {
+			ranges = append(ranges, o.Ranges...)
+			overridden = true
+		}
+	}
+	if !overridden {
+		for _, rule := range a.Weekly {
+			if wd, ok := weekdays[rule.Day]; !ok || wd != local.Weekday() {
+				continue
+			}
+			if !ruleEffectiveOn(rule, day) {
+				continue
+			}
+			ranges = append(ranges, rule.Ranges...)
+		}
+	}
+
+	var out []SlotWindow
+	for _, tr := range ranges {
+		from, errFrom := ParseClock(tr.Start)
+		to, errTo := ParseClock(tr.End)
+		if errFrom != nil || errTo != nil {
+			continue
+		}
+		duration := cfg.Duration
+		if tr.SlotDuration > 0 {
+			duration = time.Duration(tr.SlotDuration) * time.Minute
+		}
+		for t := from; t+duration <= to; t += duration + cfg.Buffer {
+			start := time.Date(local.Year(), local.Month(), local.Day(),
+				int(t/time.Hour), int(t%time.Hour/time.Minute), 0, 0, cfg.Location)
+			out = append(out, SlotWindow{Start: start, Duration: duration})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Start.Before(out[j].Start) })
+	return out
+}
+
+// ExpandSlots returns every concrete slot starting in [from, to).
+func ExpandSlots(a models.Availability, from, to time.Time, cfg SlotConfig) []SlotWindow {
+	var out []SlotWindow
+	from, to = from.In(cfg.Location), to.In(cfg.Location)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 12, 0, 0, 0, cfg.Location)
+	for ; day.Before(to.Add(24 * time.Hour)); day = day.AddDate(0, 0, 1) {
+		for _, s := range DaySlots(a, day, cfg) {
+			if s.Start.Before(from) || !s.Start.Before(to) {
+				continue
+			}
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// MatchSlot finds the slot that starts exactly at at.
+func MatchSlot(a models.Availability, at time.Time, cfg SlotConfig) (SlotWindow, bool) {
+	for _, s := range DaySlots(a, at, cfg) {
+		if s.Start.Equal(at) {
+			return s, true
+		}
+	}
+	return SlotWindow{}, false
+}
+
+// ParseClock reads "HH:MM" into an offset from midnight.
+func ParseClock(s string) (time.Duration, error) {
+	t, err := time.Parse(clockLayout, strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func ruleEffectiveOn(rule models.WeeklyRule, day string) bool {
+	if rule.EffectiveFrom != nil && day < *rule.EffectiveFrom {
+		return false
+	}
+	if rule.EffectiveTo != nil && day > *rule.EffectiveTo {
+		return false
+	}
+	return true
+}
+
+// FieldErrors maps a JSON path such as "weekly[0].ranges[1].end" to a message.
+type FieldErrors map[string]string
+
+func (f FieldErrors) Error() string {
+	keys := make([]string, 0, len(f))
+	for k := range f {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+": "+f[k])
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ValidateAvailability checks weekday names, HH:MM ranges, slot durations and
+// effective dates, and rejects ranges that overlap on the same day.
+func ValidateAvailability(a models.Availability) FieldErrors {
+	errs := FieldErrors{}
+
+	type dayRanges struct {
+		rule  int
+		spans [][2]time.Duration
+	}
+	perDay := map[models.Weekday][]dayRanges{}
+
+	for i, rule := range a.Weekly {
+		path := fmt.Sprintf("weekly[%d]", i)
+		if _, ok := weekdays[rule.Day]; !ok {
+			errs[path+".day"] = "must be one of MONDAY..SUNDAY"
+		}
+		if rule.EffectiveFrom != nil {
+			if _, err := time.Parse(DateLayout, *rule.EffectiveFrom); err != nil {
+				errs[path+".effectiveFrom"] = "must be a YYYY-MM-DD date"
+			}
+		}
+		if rule.EffectiveTo != nil {
+			if _, err := time.Parse(DateLayout, *rule.EffectiveTo); err != nil {
+				errs[path+".effectiveTo"] = "must be a YYYY-MM-DD date"
+			}
+		}
+		if rule.EffectiveFrom != nil && rule.EffectiveTo != nil && *rule.EffectiveTo < *rule.EffectiveFrom {
+			errs[path+".effectiveTo"] = "must not be before effectiveFrom"
+		}
+
+		spans := validateRanges(rule.Ranges, path, errs)
+		perDay[rule.Day] = append(perDay[rule.Day], dayRanges{rule: i, spans: spans})
+	}
+
+	// ranges of different rules on the same weekday may not overlap while
+	// both rules are in effect
+	for _, rules := range perDay {
+		for x := 0; x < len(rules); x++ {
+			for y := x + 1; y < len(rules); y++ {
+				if !effectivePeriodsIntersect(a.Weekly[rules[x].rule], a.Weekly[rules[y].rule]) {
+					continue
+				}
+				for _, sx := range rules[x].spans {
+					for _, sy := range rules[y].spans {
+						if sx[0] < sy[1] && sy[0] < sx[1] {
+							errs[fmt.Sprintf("weekly[%d].ranges", rules[y].rule)] =
+								fmt.Sprintf("overlaps weekly[%d] on the same day", rules[x].rule)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	seen := map[string]int{}
+	for i, o := range a.Overrides {
+		path := fmt.Sprintf("overrides[%d]", i)
+		if _, err := time.Parse(DateLayout, o.Date); err != nil {
+			errs[path+".date"] = "must be a YYYY-MM-DD date"
+		} else if prev, dup := seen[o.Date]; dup {
+			errs[path+".date"] = fmt.Sprintf("duplicates overrides[%d]", prev)
+		} else {
+			seen[o.Date] = i
+		}
+		validateRanges(o.Ranges, path, errs)
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// validateRanges checks one list of ranges and returns the parsed spans.
+func validateRanges(ranges []models.TimeRange, path string, errs FieldErrors) [][2]time.Duration {
+	var spans [][2]time.Duration
+	for j, tr := range ranges {
+		rpath := fmt.Sprintf("%s.ranges[%d]", path, j)
+		from, err := ParseClock(tr.Start)
+		if err != nil {
+			errs[rpath+".start"] = "must be HH:MM"
+			continue
+		}
+		to, err := ParseClock(tr.End)
+		if err != nil {
+			errs[rpath+".end"] = "must be HH:MM"
+			continue
+		}
+		if to <= from {
+			errs[rpath+".end"] = "must be after start"
+			continue
+		}
+		if tr.SlotDuration < 0 || time.Duration(tr.SlotDuration)*time.Minute > MaxSlotDuration {
+			errs[rpath+".slotDuration"] = "must be between 0 and 240 minutes"
+			continue
+		}
+		if tr.SlotDuration > 0 && time.Duration(tr.SlotDuration)*time.Minute > to-from {
+			errs[rpath+".slotDuration"] = "is longer than the range"
+			continue
+		}
+		for k, prev := range spans {
+			if from < prev[1] && prev[0] < to {
+				errs[rpath] = fmt.Sprintf("overlaps %s.ranges[%d]", path, k)
+			}
+		}
+		spans = append(spans, [2]time.Duration{from, to})
+	}
+	return spans
+}
+
+func effectivePeriodsIntersect(a, b models.WeeklyRule) bool {
+	if a.EffectiveTo != nil && b.EffectiveFrom != nil && *a.EffectiveTo < *b.EffectiveFrom {
+		return false
+	}
+	if b.EffectiveTo != nil && a.EffectiveFrom != nil && *b.EffectiveTo < *a.EffectiveFrom {
+		return false
+	}
+	return true
+}
+
+// LegacySlot is the pre-typed availability format: free-text day names and
+// either "HH:MM" starts or "HH:MM-HH:MM" ranges.
+type LegacySlot struct {
+	Day   string   `json:"day"`
+	Slots []string `json:"slots"`
+}
+
+// FromLegacySlots converts the old jsonb string into the typed model,
+// dropping entries that cannot be understood.
+func FromLegacySlots(raw string, duration time.Duration) (models.Availability, error) {
+	var legacy []LegacySlot
+	if strings.TrimSpace(raw) != "" {
+		if err := json.Unmarshal([]byte(raw), &legacy); err != nil {
+			return models.Availability{}, err
+		}
+	}
+
+	out := models.Availability{Weekly: []models.WeeklyRule{}, Overrides: []models.AvailabilityOverride{}}
+	for _, l := range legacy {
+		day, ok := parseLegacyDay(l.Day)
+		if !ok {
+			continue
+		}
+		rule := models.WeeklyRule{Day: day}
+		for _, entry := range l.Slots {
+			parts := strings.Split(entry, "-")
+			from, err := ParseClock(parts[0])
+			if err != nil || len(parts) > 2 {
+				continue
+			}
+			to := from + duration
+			if len(parts) == 2 {
+				if to, err = ParseClock(parts[1]); err != nil || to <= from {
+					continue
+				}
+			}
+			if to > 24*time.Hour {
+				continue
+			}
+			rule.Ranges = append(rule.Ranges, models.TimeRange{
+				Start: formatClock(from),
+				End:   formatClock(to),
+			})
+		}
+		if len(rule.Ranges) > 0 {
+			out.Weekly = append(out.Weekly, rule)
+		}
+	}
+	return out, nil
+}
+
+func parseLegacyDay(day string) (models.Weekday, bool) {
+	day = strings.ToUpper(strings.TrimSpace(day))
+	for wd := range weekdays {
+		if day == string(wd) || (len(day) == 3 && strings.HasPrefix(string(wd), day)) {
+			return wd, true
+		}
+	}
+	return "", false
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d/time.Hour), int(d%time.Hour/time.Minute))
+}
@@ -0,0 +1,193 @@
+package domain
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+// monday is 2025-01-06, a Monday, at hh:mm in loc.
+func monday(hh, mm int, loc *time.Location) time.Time {
+	return time.Date(2025, 1, 6, hh, mm, 0, 0, loc)
+}
+
+func mornings(days ...models.Weekday) models.Availability {
+	var a models.Availability
+	for _, d := range days {
+		a.Weekly = append(a.Weekly, models.WeeklyRule{Day: d, Ranges: []models.TimeRange{{Start: "09:00", End: "11:00"}}})
+	}
+	return a
+}
+
+func starts(slots []SlotWindow, loc *time.Location) []string {
+	out := make([]string, len(slots))
+	for i, s := range slots {
+		out[i] = s.Start.In(loc).Format("Mon 15:04")
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDaySlots(t *testing.T) {
+	halfHour := SlotConfig{Location: time.UTC, Duration: 30 * time.Minute}
+	buffered := halfHour
+	buffered.Buffer = 10 * time.Minute
+	after := "2025-02-01"
+
+	tests := []struct {
+		name string
+		a    models.Availability
+		cfg  SlotConfig
+		want []string
+	}{
+		{"weekly rule", mornings(models.MONDAY), halfHour,
+			[]string{"Mon 09:00", "Mon 09:30", "Mon 10:00", "Mon 10:30"}},
+		{"other weekday", mornings(models.TUESDAY), halfHour, []string{}},
+		{"buffer between slots", mornings(models.MONDAY), buffered,
+			[]string{"Mon 09:00", "Mon 09:40", "Mon 10:20"}},
+		{"range slot length", models.Availability{Weekly: []models.WeeklyRule{{Day: models.MONDAY,
+			Ranges: []models.TimeRange{{Start: "09:00", End: "11:30", SlotDuration: 60}}}}}, halfHour,
+			[]string{"Mon 09:00", "Mon 10:00"}},
+		{"override replaces the week", models.Availability{
+			Weekly:    mornings(models.MONDAY).Weekly,
+			Overrides: []models.AvailabilityOverride{{Date: "2025-01-06", Ranges: []models.TimeRange{{Start: "14:00", End: "15:00"}}}},
+		}, halfHour, []string{"Mon 14:00", "Mon 14:30"}},
+		{"empty override closes the day", models.Availability{
+			Weekly:    mornings(models.MONDAY).Weekly,
+			Overrides: []models.AvailabilityOverride{{Date: "2025-01-06"}},
+		}, halfHour, []string{}},
+		{"rule not yet effective", models.Availability{Weekly: []models.WeeklyRule{{Day: models.MONDAY, EffectiveFrom: &after,
+			Ranges: []models.TimeRange{{Start: "09:00", End: "11:00"}}}}}, halfHour, []string{}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := starts(DaySlots(tc.a, monday(12, 0, time.UTC), tc.cfg), time.UTC)
+			if !equalStrings(got, tc.want) {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDaySlotsInDoctorTimezone(t *testing.T) {
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	if err != nil {
+		t.Skip("no tzdata")
+	}
+	cfg := SlotConfig{Location: kolkata, Duration: 30 * time.Minute}
+
+	// 20:00 UTC on Sunday is already Monday 01:30 in Kolkata
+	slots := DaySlots(mornings(models.MONDAY), time.Date(2025, 1, 5, 20, 0, 0, 0, time.UTC), cfg)
+	if len(slots) != 4 {
+		t.Fatalf("%d slots, want 4", len(slots))
+	}
+	if want := time.Date(2025, 1, 6, 3, 30, 0, 0, time.UTC); !slots[0].Start.Equal(want) {
+		t.Fatalf("first slot %v, want %v", slots[0].Start.UTC(), want)
+	}
+}
+
+func TestMatchSlot(t *testing.T) {
+	a := models.Availability{Weekly: []models.WeeklyRule{{Day: models.MONDAY, Ranges: []models.TimeRange{
+		{Start: "09:00", End: "10:00"},
+		{Start: "14:00", End: "16:00", SlotDuration: 60},
+	}}}}
+	cfg := SlotConfig{Location: time.UTC, Duration: 30 * time.Minute}
+
+	tests := []struct {
+		name     string
+		at       time.Time
+		ok       bool
+		duration time.Duration
+	}{
+		{"start of a slot", monday(9, 30, time.UTC), true, 30 * time.Minute},
+		{"range slot length", monday(15, 0, time.UTC), true, time.Hour},
+		{"off the grid", monday(9, 15, time.UTC), false, 0},
+		{"between slots of a long range", monday(14, 30, time.UTC), false, 0},
+		{"end of the range", monday(10, 0, time.UTC), false, 0},
+		{"other day", monday(9, 0, time.UTC).AddDate(0, 0, 1), false, 0},
+		{"same instant in another zone", monday(9, 30, time.UTC).In(time.FixedZone("X", 5*3600)), true, 30 * time.Minute},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			slot, ok := MatchSlot(a, tc.at, cfg)
+			if ok != tc.ok {
+				t.Fatalf("matched %v, want %v", ok, tc.ok)
+			}
+			if ok && slot.Duration != tc.duration {
+				t.Fatalf("duration %v, want %v", slot.Duration, tc.duration)
+			}
+		})
+	}
+}
+
+func TestExpandSlots(t *testing.T) {
+	a := mornings(models.MONDAY, models.TUESDAY)
+	cfg := SlotConfig{Location: time.UTC, Duration: 30 * time.Minute}
+
+	// [from, to): starts at from are in, starts at to are out
+	got := starts(ExpandSlots(a, monday(10, 0, time.UTC), monday(9, 30, time.UTC).AddDate(0, 0, 1), cfg), time.UTC)
+	want := []string{"Mon 10:00", "Mon 10:30", "Tue 09:00"}
+	if !equalStrings(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+
+	if got := ExpandSlots(a, monday(11, 0, time.UTC), monday(11, 0, time.UTC), cfg); len(got) != 0 {
+		t.Fatalf("empty range expanded to %v", starts(got, time.UTC))
+	}
+}
+
+func TestSlotClashes(t *testing.T) {
+	slot := SlotWindow{Start: monday(10, 0, time.UTC), Duration: 30 * time.Minute}
+	window := func(hh, mm int, minutes int) SlotWindow {
+		return SlotWindow{Start: monday(hh, mm, time.UTC), Duration: time.Duration(minutes) * time.Minute}
+	}
+
+	tests := []struct {
+		name   string
+		b      SlotWindow
+		buffer time.Duration
+		want   bool
+	}{
+		{"same slot", window(10, 0, 30), 0, true},
+		{"ends where it starts", window(9, 30, 30), 0, false},
+		{"starts where it ends", window(10, 30, 30), 0, false},
+		{"long booking covers it", window(9, 0, 120), 0, true},
+		{"long booking ends before", window(8, 0, 120), 0, false},
+		{"inside it", window(10, 10, 10), 0, true},
+		{"buffer after the booking", window(9, 30, 30), 10 * time.Minute, true},
+		{"buffer after the slot", window(10, 30, 30), 10 * time.Minute, true},
+		{"clear of the buffer", window(10, 40, 30), 10 * time.Minute, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := slot.Clashes(tc.b, tc.buffer); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+			if got := tc.b.Clashes(slot, tc.buffer); got != tc.want {
+				t.Fatalf("not symmetric: reversed got %v", got)
+			}
+		})
+	}
+}
+
+func TestBookedWindow(t *testing.T) {
+	at := monday(10, 0, time.UTC)
+	if got := BookedWindow(&models.Appointment{ScheduledAt: at, DurationMinutes: 45}); got.Duration != 45*time.Minute {
+		t.Fatalf("stored duration: got %v", got.Duration)
+	}
+	if got := BookedWindow(&models.Appointment{ScheduledAt: at}); got.Duration != defaultSlotDuration {
+		t.Fatalf("legacy row: got %v, want %v", got.Duration, defaultSlotDuration)
+	}
+}
@@ -0,0 +1,59 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+var ErrInvalidExpiryPolicy = errors.New("invalid request expiry deadline")
+
+// ExpiryPolicy is how long an unanswered request may wait. A PENDING
+// appointment waits on the doctor, a RESCHEDULE_REQUESTED one on the patient.
+// The wait is measured from when the request was made: the appointment's
+// creation for PENDING, the latest proposal's creation for
+// RESCHEDULE_REQUESTED. Unrelated edits such as a fee payment do not reset it.
+type ExpiryPolicy struct {
+	PendingTTL    time.Duration
+	RescheduleTTL time.Duration
+}
+
+// ParseExpiryPolicy parses both deadlines as Go durations, e.g. "48h".
+func ParseExpiryPolicy(pending, reschedule string) (ExpiryPolicy, error) {
+	var p ExpiryPolicy
+	var err error
+	if p.PendingTTL, err = time.ParseDuration(pending); err != nil || p.PendingTTL <= 0 {
+		return p, fmt.Errorf("%w: pending %q", ErrInvalidExpiryPolicy, pending)
+	}
+	if p.RescheduleTTL, err = time.ParseDuration(reschedule); err != nil || p.RescheduleTTL <= 0 {
+		return p, fmt.Errorf("%w: reschedule %q", ErrInvalidExpiryPolicy, reschedule)
+	}
+	return p, nil
+}
+
+// MadeBy is the cutoff for requests in status at now: one made at or before
+// it has waited out its deadline. ok is false for statuses that never expire.
+// The expiry query selects by these cutoffs, so it agrees with Expired.
+func (p ExpiryPolicy) MadeBy(status models.AppointmentStatus, now time.Time) (cutoff time.Time, ok bool) {
+	switch status {
+	case models.PENDING:
+		return now.Add(-p.PendingTTL), true
+	case models.RESCHEDULE_REQUESTED:
+		return now.Add(-p.RescheduleTTL), true
+	}
+	return time.Time{}, false
+}
+
+// Expired reports whether a request in status, made at madeAt for a slot at
+// scheduledAt, is stale at now: its slot has begun or it has waited out its
+// deadline. madeAt is the appointment's creation for PENDING and the latest
+// proposal's for RESCHEDULE_REQUESTED.
+func (p ExpiryPolicy) Expired(status models.AppointmentStatus, scheduledAt, madeAt, now time.Time) bool {
+	cutoff, ok := p.MadeBy(status, now)
+	if !ok {
+		return false
+	}
+	return !scheduledAt.After(now) || !madeAt.After(cutoff)
+}
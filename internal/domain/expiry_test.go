@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+func TestExpired(t *testing.T) {
+	p := ExpiryPolicy{PendingTTL: 48 * time.Hour, RescheduleTTL: 24 * time.Hour}
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	later := now.Add(72 * time.Hour)
+
+	tests := []struct {
+		name        string
+		status      models.AppointmentStatus
+		scheduledAt time.Time
+		madeAt      time.Time
+		want        bool
+	}{
+		{"pending, fresh", models.PENDING, later, now.Add(-47 * time.Hour), false},
+		{"pending, just inside", models.PENDING, later, now.Add(-48*time.Hour + time.Second), false},
+		{"pending, exactly at the deadline", models.PENDING, later, now.Add(-48 * time.Hour), true},
+		{"pending, past the deadline", models.PENDING, later, now.Add(-49 * time.Hour), true},
+		{"pending, slot begun", models.PENDING, now, now.Add(-time.Hour), true},
+		{"pending, slot a second away", models.PENDING, now.Add(time.Second), now.Add(-time.Hour), false},
+
+		// the shorter reschedule deadline applies, counted from the proposal
+		{"reschedule, fresh", models.RESCHEDULE_REQUESTED, later, now.Add(-23 * time.Hour), false},
+		{"reschedule, at the deadline", models.RESCHEDULE_REQUESTED, later, now.Add(-24 * time.Hour), true},
+		{"reschedule, slot passed", models.RESCHEDULE_REQUESTED, now.Add(-time.Minute), now, true},
+
+		{"accepted never expires", models.ACCEPTED, now.Add(-time.Hour), now.Add(-100 * time.Hour), false},
+		{"confirmed reschedule never expires", models.RESCHEDULED_CONFIRMED, now.Add(-time.Hour), now.Add(-100 * time.Hour), false},
+		{"already expired", models.EXPIRED, now.Add(-time.Hour), now.Add(-100 * time.Hour), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := p.Expired(tc.status, tc.scheduledAt, tc.madeAt, now); got != tc.want {
+				t.Fatalf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// The expiry query selects by MadeBy; a request is past the cutoff exactly
+// when Expired says its deadline has run out.
+func TestMadeByMatchesExpired(t *testing.T) {
+	p := ExpiryPolicy{PendingTTL: 48 * time.Hour, RescheduleTTL: 24 * time.Hour}
+	now := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	future := now.Add(240 * time.Hour)
+
+	for _, status := range []models.AppointmentStatus{models.PENDING, models.RESCHEDULE_REQUESTED} {
+		cutoff, ok := p.MadeBy(status, now)
+		if !ok {
+			t.Fatalf("%s has no cutoff", status)
+		}
+		for _, offset := range []time.Duration{-time.Hour, -time.Nanosecond, 0, time.Nanosecond, time.Hour} {
+			madeAt := cutoff.Add(offset)
+			if got, want := p.Expired(status, future, madeAt, now), !madeAt.After(cutoff); got != want {
+				t.Errorf("%s made %v from the cutoff: Expired %v, cutoff says %v", status, offset, got, want)
+			}
+		}
+	}
+	if _, ok := p.MadeBy(models.ACCEPTED, now); ok {
+		t.Error("ACCEPTED has a cutoff")
+	}
+}
+
+func TestParseExpiryPolicy(t *testing.T) {
+	tests := []struct {
+		pending, reschedule string
+		want                error
+	}{
+		{"48h", "24h", nil},
+		{"90m", "1h30m", nil},
+		{"", "24h", ErrInvalidExpiryPolicy},
+		{"48h", "0s", ErrInvalidExpiryPolicy},
+		{"-1h", "24h", ErrInvalidExpiryPolicy},
+		{"two days", "24h", ErrInvalidExpiryPolicy},
+	}
+	for _, tc := range tests {
+		if _, err := ParseExpiryPolicy(tc.pending, tc.reschedule); !errors.Is(err, tc.want) {
+			t.Errorf("(%q, %q): got %v, want %v", tc.pending, tc.reschedule, err, tc.want)
+		}
+	}
+}
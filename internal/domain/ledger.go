@@ -0,0 +1,185 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+var (
+	ErrUnbalanced           = errors.New("ledger transaction does not balance")
+	ErrInvalidCommission    = errors.New("commission must be a percentage between 0 and 100")
+	ErrInvalidPayout        = errors.New("payout amount must be positive")
+	ErrPayoutExceedsBalance = errors.New("payout exceeds the amount owed to the doctor")
+)
+
+// ParseCommissionPercent parses the platform's cut of each appointment fee,
+// e.g. "10" for 10%.
+func ParseCommissionPercent(s string) (float64, error) {
+	p, err := strconv.ParseFloat(s, 64)
+	if err != nil || p < 0 || p > 100 || math.IsNaN(p) {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCommission, s)
+	}
+	return p, nil
+}
+
+// Commission is percent of amount, rounded to the nearest minor unit.
+func Commission(amount int64, percent float64) int64 {
+	return int64(math.Round(float64(amount) * percent / 100))
+}
+
+// CheckBalanced verifies that t has at least two non-zero entries summing
+// to zero.
+func CheckBalanced(t *models.Transaction) error {
+	if len(t.Entries) < 2 {
+		return fmt.Errorf("%w: %s needs two entries", ErrUnbalanced, t.Kind)
+	}
+	var sum int64
+	for _, e := range t.Entries {
+		if e.Amount == 0 {
+			return fmt.Errorf("%w: %s has a zero entry", ErrUnbalanced, t.Kind)
+		}
+		sum += e.Amount
+	}
+	if sum != 0 {
+		return fmt.Errorf("%w: %s is off by %d", ErrUnbalanced, t.Kind, sum)
+	}
+	return nil
+}
+
+// entry is shorthand for a ledger line; amount is signed (debit positive).
+func entry(account models.LedgerAccount, doctorProfileID *string, amount int64) models.LedgerEntry {
+	return models.LedgerEntry{Account: account, DoctorProfileID: doctorProfileID, Amount: amount}
+}
+
+// AppointmentFeeReceived records a paid appointment fee of amount minor
+// units: the cash is owed to the doctor, then the platform takes its
+// commission from it. The commission transaction is left out when it is
+// zero.
+func AppointmentFeeReceived(p *models.Payment, doctorProfileID string, amount, commission int64, at time.Time) []models.Transaction {
+	doctor := &doctorProfileID
+	txns := []models.Transaction{{
+		Kind:            models.TXN_PAYMENT,
+		PaymentID:       &p.ID,
+		AppointmentID:   p.AppointmentID,
+		DoctorProfileID: doctor,
+		Currency:        p.Currency,
+		Description:     "Appointment fee",
+		OccurredAt:      at,
+		Entries: []models.LedgerEntry{
+			entry(models.ACCOUNT_GATEWAY_CASH, nil, amount),
+			entry(models.ACCOUNT_DOCTOR_PAYABLE, doctor, -amount),
+		},
+	}}
+	if commission > 0 {
+		txns = append(txns, models.Transaction{
+			Kind:            models.TXN_COMMISSION,
+			PaymentID:       &p.ID,
+			AppointmentID:   p.AppointmentID,
+			DoctorProfileID: doctor,
+			Currency:        p.Currency,
+			Description:     "Platform commission",
+			OccurredAt:      at,
+			Entries: []models.LedgerEntry{
+				entry(models.ACCOUNT_DOCTOR_PAYABLE, doctor, commission),
+				entry(models.ACCOUNT_PLATFORM_REVENUE, nil, -commission),
+			},
+		})
+	}
+	return txns
+}
+
+// AppointmentFeeRefunded reverses AppointmentFeeReceived: the doctor's share
+// and the commission taken are both returned to the patient.
+func AppointmentFeeRefunded(p *models.Payment, doctorProfileID string, amount, commission int64, at time.Time) models.Transaction {
+	doctor := &doctorProfileID
+	entries := []models.LedgerEntry{entry(models.ACCOUNT_GATEWAY_CASH, nil, -amount)}
+	if share := amount - commission; share != 0 {
+		entries = append(entries, entry(models.ACCOUNT_DOCTOR_PAYABLE, doctor, share))
+	}
+	if commission > 0 {
+		entries = append(entries, entry(models.ACCOUNT_PLATFORM_REVENUE, nil, commission))
+	}
+	return models.Transaction{
+		Kind:            models.TXN_REFUND,
+		PaymentID:       &p.ID,
+		AppointmentID:   p.AppointmentID,
+		DoctorProfileID: doctor,
+		Currency:        p.Currency,
+		Description:     "Appointment fee refund",
+		OccurredAt:      at,
+		Entries:         entries,
+	}
+}
+
+// OrderPaymentReceived records a paid pharmacy order.
+func OrderPaymentReceived(p *models.Payment, amount int64, at time.Time) models.Transaction {
+	return models.Transaction{
+		Kind:        models.TXN_PAYMENT,
+		PaymentID:   &p.ID,
+		OrderID:     p.OrderID,
+		Currency:    p.Currency,
+		Description: "Pharmacy order",
+		OccurredAt:  at,
+		Entries: []models.LedgerEntry{
+			entry(models.ACCOUNT_GATEWAY_CASH, nil, amount),
+			entry(models.ACCOUNT_PHARMACY_REVENUE, nil, -amount),
+		},
+	}
+}
+
+// OrderPaymentRefunded reverses OrderPaymentReceived.
+func OrderPaymentRefunded(p *models.Payment, amount int64, at time.Time) models.Transaction {
+	return models.Transaction{
+		Kind:        models.TXN_REFUND,
+		PaymentID:   &p.ID,
+		OrderID:     p.OrderID,
+		Currency:    p.Currency,
+		Description: "Pharmacy order refund",
+		OccurredAt:  at,
+		Entries: []models.LedgerEntry{
+			entry(models.ACCOUNT_PHARMACY_REVENUE, nil, amount),
+			entry(models.ACCOUNT_GATEWAY_CASH, nil, -amount),
+		},
+	}
+}
+
+// DoctorPayout records amount minor units paid out to the doctor, who must
+// be owed at least that much (balance).
+func DoctorPayout(doctorProfileID string, amount, balance int64, currency, reference string, at time.Time) (models.Transaction, error) {
+	if amount <= 0 {
+		return models.Transaction{}, ErrInvalidPayout
+	}
+	if amount > balance {
+		return models.Transaction{}, fmt.Errorf("%w: owed %d", ErrPayoutExceedsBalance, balance)
+	}
+	doctor := &doctorProfileID
+	return models.Transaction{
+		Kind:            models.TXN_PAYOUT,
+		DoctorProfileID: doctor,
+		Currency:        currency,
+		Description:     "Doctor payout",
+		Reference:       reference,
+		OccurredAt:      at,
+		Entries: []models.LedgerEntry{
+			entry(models.ACCOUNT_DOCTOR_PAYABLE, doctor, amount),
+			entry(models.ACCOUNT_GATEWAY_CASH, nil, -amount),
+		},
+	}, nil
+}
+
+// DoctorOwed is what the DOCTOR_PAYABLE entries say the platform owes.
+// Credits are negative, so the balance is the negated sum.
+func DoctorOwed(entries []models.LedgerEntry) int64 {
+	var sum int64
+	for _, e := range entries {
+		if e.Account == models.ACCOUNT_DOCTOR_PAYABLE {
+			sum += e.Amount
+		}
+	}
+	return -sum
+}
@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+func TestCheckBalanced(t *testing.T) {
+	line := func(amount int64) models.LedgerEntry {
+		return models.LedgerEntry{Account: models.ACCOUNT_GATEWAY_CASH, Amount: amount}
+	}
+	tests := []struct {
+		name    string
+		entries []models.LedgerEntry
+		wantErr bool
+	}{
+		{"two balanced lines", []models.LedgerEntry{line(500), line(-500)}, false},
+		{"three balanced lines", []models.LedgerEntry{line(-500), line(450), line(50)}, false},
+		{"no lines", nil, true},
+		{"one line", []models.LedgerEntry{line(0)}, true},
+		{"zero line", []models.LedgerEntry{line(500), line(-500), line(0)}, true},
+		{"off by one", []models.LedgerEntry{line(500), line(-499)}, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckBalanced(&models.Transaction{Kind: models.TXN_PAYMENT, Entries: tc.entries})
+			if tc.wantErr != errors.Is(err, ErrUnbalanced) || (!tc.wantErr && err != nil) {
+				t.Fatalf("got %v, want unbalanced %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommission(t *testing.T) {
+	tests := []struct {
+		amount  int64
+		percent float64
+		want    int64
+	}{
+		{50000, 10, 5000},
+		{999, 10, 100},
+		{994, 10, 99},
+		{50000, 0, 0},
+		{50000, 100, 50000},
+		{333, 12.5, 42},
+	}
+	for _, tc := range tests {
+		if got := Commission(tc.amount, tc.percent); got != tc.want {
+			t.Errorf("Commission(%d, %v) = %d, want %d", tc.amount, tc.percent, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"-1", "100.5", "NaN", "ten", ""} {
+		if _, err := ParseCommissionPercent(bad); !errors.Is(err, ErrInvalidCommission) {
+			t.Errorf("ParseCommissionPercent(%q) = %v, want ErrInvalidCommission", bad, err)
+		}
+	}
+	if p, err := ParseCommissionPercent("12.5"); err != nil || p != 12.5 {
+		t.Errorf("ParseCommissionPercent(12.5) = %v, %v", p, err)
+	}
+}
+
+// accountTotals sums the entries of txns per account.
+func accountTotals(txns []models.Transaction) map[models.LedgerAccount]int64 {
+	totals := map[models.LedgerAccount]int64{}
+	for _, tx := range txns {
+		for _, e := range tx.Entries {
+			totals[e.Account] += e.Amount
+		}
+	}
+	return totals
+}
+
+func TestAppointmentFeeRefunded(t *testing.T) {
+	at := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	apptID := "appt-1"
+	p := &models.Payment{ID: "pay-1", AppointmentID: &apptID, Currency: "INR"}
+
+	tests := []struct {
+		name       string
+		amount     int64
+		commission int64
+		wantOwed   int64
+		wantLines  int
+	}{
+		{"share and commission", 50000, 5000, 45000, 3},
+		{"no commission", 50000, 0, 50000, 2},
+		{"commission takes everything", 50000, 50000, 0, 2},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			received := AppointmentFeeReceived(p, "doc-1", tc.amount, tc.commission, at)
+			var entries []models.LedgerEntry
+			for _, txn := range received {
+				if err := CheckBalanced(&txn); err != nil {
+					t.Fatalf("payment: %v", err)
+				}
+				entries = append(entries, txn.Entries...)
+			}
+			if got := DoctorOwed(entries); got != tc.wantOwed {
+				t.Fatalf("owed after payment %d, want %d", got, tc.wantOwed)
+			}
+
+			refund := AppointmentFeeRefunded(p, "doc-1", tc.amount, tc.commission, at)
+			if err := CheckBalanced(&refund); err != nil {
+				t.Fatalf("refund: %v", err)
+			}
+			if len(refund.Entries) != tc.wantLines {
+				t.Errorf("refund has %d lines, want %d", len(refund.Entries), tc.wantLines)
+			}
+			if refund.Kind != models.TXN_REFUND || *refund.PaymentID != "pay-1" || *refund.AppointmentID != apptID ||
+				*refund.DoctorProfileID != "doc-1" || refund.Currency != "INR" {
+				t.Errorf("refund header %+v", refund)
+			}
+
+			// the refund undoes the payment and the commission on every account
+			for account, total := range accountTotals(append(received, refund)) {
+				if total != 0 {
+					t.Errorf("%s left at %d after the refund", account, total)
+				}
+			}
+			if got := DoctorOwed(refund.Entries); got != -tc.wantOwed {
+				t.Errorf("refund takes %d from the doctor, want %d", -got, tc.wantOwed)
+			}
+		})
+	}
+}
+
+func TestDoctorPayout(t *testing.T) {
+	at := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		amount  int64
+		balance int64
+		wantErr error
+	}{
+		{"part of the balance", 20000, 45000, nil},
+		{"the whole balance", 45000, 45000, nil},
+		{"more than owed", 45001, 45000, ErrPayoutExceedsBalance},
+		{"nothing owed", 100, 0, ErrPayoutExceedsBalance},
+		{"owed money back", 100, -500, ErrPayoutExceedsBalance},
+		{"zero", 0, 45000, ErrInvalidPayout},
+		{"negative", -100, 45000, ErrInvalidPayout},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			txn, err := DoctorPayout("doc-1", tc.amount, tc.balance, "INR", "NEFT-42", at)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("got %v, want %v", err, tc.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if err := CheckBalanced(&txn); err != nil {
+				t.Fatal(err)
+			}
+			if txn.Kind != models.TXN_PAYOUT || txn.Reference != "NEFT-42" || txn.Currency != "INR" {
+				t.Errorf("payout header %+v", txn)
+			}
+			if got := DoctorOwed(txn.Entries); got != -tc.amount {
+				t.Errorf("payout reduces what is owed by %d, want %d", -got, tc.amount)
+			}
+		})
+	}
+}
+
+func TestOrderPaymentRefunded(t *testing.T) {
+	at := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	orderID := "order-1"
+	p := &models.Payment{ID: "pay-2", OrderID: &orderID, Currency: "INR"}
+
+	received := OrderPaymentReceived(p, 12000, at)
+	refund := OrderPaymentRefunded(p, 12000, at)
+	for _, txn := range []models.Transaction{received, refund} {
+		if err := CheckBalanced(&txn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for account, total := range accountTotals([]models.Transaction{received, refund}) {
+		if total != 0 {
+			t.Errorf("%s left at %d after the refund", account, total)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+package domain
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+var (
+	ErrInvalidMedicine = errors.New("invalid medicine")
+	ErrInvalidCSV      = errors.New("invalid medicine CSV")
+)
+
+// MaxImportRows bounds a single CSV import.
+const MaxImportRows = 5000
+
+var medicineForms = []models.MedicineForm{
+	models.FORM_TABLET,
+	models.FORM_CAPSULE,
+	models.FORM_SYRUP,
+	models.FORM_INJECTION,
+	models.FORM_OINTMENT,
+	models.FORM_DROPS,
+	models.FORM_INHALER,
+	models.FORM_OTHER,
+}
+
+// ParseMedicineForm accepts any case and defaults an empty form to OTHER.
+func ParseMedicineForm(s string) (models.MedicineForm, error) {
+	f := models.MedicineForm(strings.ToUpper(strings.TrimSpace(s)))
+	if f == "" {
+		return models.FORM_OTHER, nil
+	}
+	for _, known := range medicineForms {
+		if f == known {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unknown form %q", ErrInvalidMedicine, s)
+}
+
+// ValidateMedicine trims m, upper-cases its SKU and checks the required
+// fields, price and stock.
+func ValidateMedicine(m *models.Medicine) error {
+	m.SKU = strings.ToUpper(strings.TrimSpace(m.SKU))
+	m.Name = strings.TrimSpace(m.Name)
+	m.Strength = strings.TrimSpace(m.Strength)
+
+	form, err := ParseMedicineForm(string(m.Form))
+	if err != nil {
+		return err
+	}
+	m.Form = form
+
+	switch {
+	case m.SKU == "":
+		return fmt.Errorf("%w: sku is required", ErrInvalidMedicine)
+	case m.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidMedicine)
+	case m.Price < 0 || math.IsNaN(m.Price) || math.IsInf(m.Price, 0):
+		return fmt.Errorf("%w: price must be zero or more", ErrInvalidMedicine)
+	case m.StockQuantity < 0:
+		return fmt.Errorf("%w: stock cannot be negative", ErrInvalidMedicine)
+	}
+	m.Price = roundCents(m.Price)
+	return nil
+}
+
+// ParseMedicineCSV reads a catalog import. The first row is a header naming
+// the columns, in any order:
+//
+//	sku,name,strength,form,price,prescription_required,stock_quantity
+//
+// sku, name and price are required. Every row is validated and the first
+// error is returned with its line number, so an import is all or nothing.
+func ParseMedicineCSV(r io.Reader) ([]models.Medicine, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidCSV)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"sku", "name", "price"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("%w: header has no %s column", ErrInvalidCSV, required)
+		}
+	}
+
+	var meds []models.Medicine
+	seen := map[string]int{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+		line, _ := cr.FieldPos(0)
+		if len(meds) == MaxImportRows {
+			return nil, fmt.Errorf("%w: more than %d rows", ErrInvalidCSV, MaxImportRows)
+		}
+
+		field := func(name string) string {
+			if i, ok := cols[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		m := models.Medicine{
+			SKU:      field("sku"),
+			Name:     field("name"),
+			Strength: field("strength"),
+			Form:     models.MedicineForm(field("form")),
+			Active:   true,
+		}
+		if m.Price, err = strconv.ParseFloat(field("price"), 64); err != nil {
+			return nil, fmt.Errorf("%w: line %d: price %q", ErrInvalidCSV, line, field("price"))
+		}
+		if v := field("prescription_required"); v != "" {
+			if m.RequiresPrescription, err = strconv.ParseBool(v); err != nil {
+				return nil, fmt.Errorf("%w: line %d: prescription_required %q", ErrInvalidCSV, line, v)
+			}
+		}
+		if v := field("stock_quantity"); v != "" {
+			if m.StockQuantity, err = strconv.Atoi(v); err != nil {
+				return nil, fmt.Errorf("%w: line %d: stock_quantity %q", ErrInvalidCSV, line, v)
+			}
+		}
+		if err := ValidateMedicine(&m); err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidCSV, line, err)
+		}
+		if first, dup := seen[m.SKU]; dup {
+			return nil, fmt.Errorf("%w: line %d: sku %s repeats line %d", ErrInvalidCSV, line, m.SKU, first)
+		}
+		seen[m.SKU] = line
+		meds = append(meds, m)
+	}
+	if len(meds) == 0 {
+		return nil, fmt.Errorf("%w: no rows", ErrInvalidCSV)
+	}
+	return meds, nil
+}
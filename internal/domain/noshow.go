@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrBookingRestricted = errors.New("booking is restricted after repeated no-shows")
+	ErrInvalidNoShow     = errors.New("invalid no-show policy")
+)
+
+// NoShowPolicy blocks a patient from booking for Restriction once they have
+// Limit no-shows within Window. A Limit of 0 disables the restriction.
+type NoShowPolicy struct {
+	Limit       int
+	Window      time.Duration
+	Restriction time.Duration
+}
+
+// ParseNoShowPolicy parses the limit as an integer and the two periods as Go
+// durations, e.g. "3", "2160h", "720h".
+func ParseNoShowPolicy(limit, window, restriction string) (NoShowPolicy, error) {
+	var p NoShowPolicy
+	var err error
+	if p.Limit, err = strconv.Atoi(limit); err != nil || p.Limit < 0 {
+		return p, fmt.Errorf("%w: limit %q", ErrInvalidNoShow, limit)
+	}
+	if p.Window, err = time.ParseDuration(window); err != nil || p.Window <= 0 {
+		return p, fmt.Errorf("%w: window %q", ErrInvalidNoShow, window)
+	}
+	if p.Restriction, err = time.ParseDuration(restriction); err != nil || p.Restriction <= 0 {
+		return p, fmt.Errorf("%w: restriction %q", ErrInvalidNoShow, restriction)
+	}
+	return p, nil
+}
+
+// RestrictUntil returns when bookings reopen for a patient with recent
+// no-shows inside the window, or false when no restriction applies.
+func (p NoShowPolicy) RestrictUntil(recent int, now time.Time) (time.Time, bool) {
+	if p.Limit <= 0 || recent < p.Limit {
+		return time.Time{}, false
+	}
+	return now.Add(p.Restriction), true
+}
@@ -0,0 +1,147 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrEmptyOrder             = errors.New("order has no items")
+	ErrInvalidOrderItem       = errors.New("invalid order item")
+	ErrMedicineUnavailable    = errors.New("medicine is not available")
+	ErrOutOfStock             = errors.New("not enough stock")
+	ErrInvalidPaymentMethod   = errors.New("payment method must be ONLINE or COD")
+	ErrInvalidOrderTransition = errors.New("order status transition not allowed")
+	ErrOrderRoleNotAllowed    = errors.New("role may not move the order to this status")
+	ErrOrderNotPaid           = errors.New("online order has not been paid")
+)
+
+// MaxOrderQuantity caps a single line so a typo cannot order thousands.
+const MaxOrderQuantity = 100
+
+// OrderStaff are the roles that fulfil orders.
+var OrderStaff = []models.Role{models.ADMIN, models.PHARMACIST}
+
+// OrderTransition is one row of the order state machine, keyed by target.
+type OrderTransition struct {
+	From  []models.OrderStatus
+	Roles []models.Role
+	// RequiresPayment holds ONLINE orders back until their payment is
+	// captured. COD orders are paid on delivery.
+	RequiresPayment bool
+}
+
+// OrderTransitions is the single source of truth for order status changes.
+// Patients may only cancel while the order is still PENDING.
+var OrderTransitions = map[models.OrderStatus]OrderTransition{
+	models.PROCESSING: {
+		From:            []models.OrderStatus{models.ORDER_PENDING},
+		Roles:           OrderStaff,
+		RequiresPayment: true,
+	},
+	models.SHIPPED: {
+		From:  []models.OrderStatus{models.PROCESSING},
+		Roles: OrderStaff,
+	},
+	models.DELIVERED: {
+		From:  []models.OrderStatus{models.SHIPPED},
+		Roles: OrderStaff,
+	},
+	models.ORDER_CANCELLED: {
+		From:  []models.OrderStatus{models.ORDER_PENDING, models.PROCESSING},
+		Roles: append([]models.Role{models.PATIENT}, OrderStaff...),
+	},
+}
+
+// NextOrderStatus validates that role may move order to to.
+func NextOrderStatus(order *models.Order, to models.OrderStatus, role models.Role) error {
+	current := order.Status
+	t, ok := OrderTransitions[to]
+	if !ok {
+		return fmt.Errorf("%w: unknown status %s", ErrInvalidOrderTransition, to)
+	}
+	if !containsRole(t.Roles, role) {
+		return ErrOrderRoleNotAllowed
+	}
+	// a patient can only withdraw an order nobody has started on
+	if role == models.PATIENT && current != models.ORDER_PENDING {
+		return fmt.Errorf("%w: %s orders can no longer be cancelled", ErrInvalidOrderTransition, current)
+	}
+	allowed := false
+	for _, s := range t.From {
+		if s == current {
+			allowed = true
+		}
+	}
+	if !allowed {
+		return fmt.Errorf("%w: %s to %s", ErrInvalidOrderTransition, current, to)
+	}
+	if t.RequiresPayment && order.PaymentMethod == models.PAYMENT_ONLINE && order.PaymentID == nil {
+		return ErrOrderNotPaid
+	}
+	return nil
+}
+
+// ParsePaymentMethod defaults an empty method to ONLINE.
+func ParsePaymentMethod(s string) (models.PaymentMethod, error) {
+	switch m := models.PaymentMethod(strings.ToUpper(strings.TrimSpace(s))); m {
+	case "":
+		return models.PAYMENT_ONLINE, nil
+	case models.PAYMENT_ONLINE, models.PAYMENT_COD:
+		return m, nil
+	default:
+		return "", ErrInvalidPaymentMethod
+	}
+}
+
+// NormalizeCart validates the client's cart and merges repeated lines for
+// the same medicine. Lines come back sorted by MedicineID, which is the order
+// stock rows are locked in. Only MedicineID and Quantity are kept.
+func NormalizeCart(items models.OrderItems) (models.OrderItems, error) {
+	if len(items) == 0 {
+		return nil, ErrEmptyOrder
+	}
+	quantities := map[string]int{}
+	for i, item := range items {
+		id := strings.TrimSpace(item.MedicineID)
+		if _, err := uuid.Parse(id); err != nil {
+			return nil, fmt.Errorf("%w: item %d has no valid medicineId", ErrInvalidOrderItem, i+1)
+		}
+		if item.Quantity < 1 {
+			return nil, fmt.Errorf("%w: quantity for %s must be at least 1", ErrInvalidOrderItem, id)
+		}
+		quantities[id] += item.Quantity
+		if quantities[id] > MaxOrderQuantity {
+			return nil, fmt.Errorf("%w: at most %d of %s per order", ErrInvalidOrderItem, MaxOrderQuantity, id)
+		}
+	}
+
+	cart := make(models.OrderItems, 0, len(quantities))
+	for id, qty := range quantities {
+		cart = append(cart, models.OrderItem{MedicineID: id, Quantity: qty})
+	}
+	sort.Slice(cart, func(i, j int) bool { return cart[i].MedicineID < cart[j].MedicineID })
+	return cart, nil
+}
+
+// PriceItems fills in every LineTotal from UnitPrice and returns the order
+// amount. Amounts are rounded to cents.
+func PriceItems(items models.OrderItems) float64 {
+	var total float64
+	for i := range items {
+		items[i].UnitPrice = roundCents(items[i].UnitPrice)
+		items[i].LineTotal = roundCents(items[i].UnitPrice * float64(items[i].Quantity))
+		total += items[i].LineTotal
+	}
+	return roundCents(total)
+}
+
+func roundCents(v float64) float64 {
+	return math.Round(v*100) / 100
+}
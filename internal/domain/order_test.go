@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+func TestNextOrderStatus(t *testing.T) {
+	paid := "pi_1"
+	order := func(status models.OrderStatus, method models.PaymentMethod, paymentID *string) *models.Order {
+		return &models.Order{Status: status, PaymentMethod: method, PaymentID: paymentID}
+	}
+
+	tests := []struct {
+		name  string
+		order *models.Order
+		to    models.OrderStatus
+		role  models.Role
+		want  error
+	}{
+		{"process paid online order", order(models.ORDER_PENDING, models.PAYMENT_ONLINE, &paid), models.PROCESSING, models.PHARMACIST, nil},
+		{"process unpaid online order", order(models.ORDER_PENDING, models.PAYMENT_ONLINE, nil), models.PROCESSING, models.ADMIN, ErrOrderNotPaid},
+		{"process cash on delivery", order(models.ORDER_PENDING, models.PAYMENT_COD, nil), models.PROCESSING, models.PHARMACIST, nil},
+		{"ship processing", order(models.PROCESSING, models.PAYMENT_COD, nil), models.SHIPPED, models.PHARMACIST, nil},
+		{"ship pending", order(models.ORDER_PENDING, models.PAYMENT_ONLINE, &paid), models.SHIPPED, models.PHARMACIST, ErrInvalidOrderTransition},
+		{"patient may not process", order(models.ORDER_PENDING, models.PAYMENT_COD, nil), models.PROCESSING, models.PATIENT, ErrOrderRoleNotAllowed},
+		{"patient cancels pending", order(models.ORDER_PENDING, models.PAYMENT_ONLINE, nil), models.ORDER_CANCELLED, models.PATIENT, nil},
+		{"patient cancels processing", order(models.PROCESSING, models.PAYMENT_ONLINE, &paid), models.ORDER_CANCELLED, models.PATIENT, ErrInvalidOrderTransition},
+		{"staff cancels processing", order(models.PROCESSING, models.PAYMENT_ONLINE, &paid), models.ORDER_CANCELLED, models.ADMIN, nil},
+		{"cancel unpaid online order", order(models.ORDER_PENDING, models.PAYMENT_ONLINE, nil), models.ORDER_CANCELLED, models.ADMIN, nil},
+		{"unknown status", order(models.ORDER_PENDING, models.PAYMENT_COD, nil), "LOST", models.ADMIN, ErrInvalidOrderTransition},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := NextOrderStatus(tc.order, tc.to, tc.role); !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
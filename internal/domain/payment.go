@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+)
+
+var (
+	ErrAlreadyPaid     = errors.New("already paid")
+	ErrNotPayable      = errors.New("nothing to pay in this state")
+	ErrPaymentMismatch = errors.New("gateway amount or currency does not match the payment")
+	ErrNotRefundable   = errors.New("only succeeded payments can be refunded")
+)
+
+// payableAppointment are the appointment statuses a fee may be paid in.
+var payableAppointment = []models.AppointmentStatus{
+	models.PENDING,
+	models.ACCEPTED,
+	models.RESCHEDULE_REQUESTED,
+	models.RESCHEDULED_CONFIRMED,
+	models.RESCHEDULE_REJECTED,
+	models.COMPLETED,
+}
+
+// CheckAppointmentPayable reports why appt's fee cannot be paid, if so.
+func CheckAppointmentPayable(appt *models.Appointment) error {
+	if appt.FeePaid {
+		return ErrAlreadyPaid
+	}
+	if !containsStatus(payableAppointment, appt.Status) || appt.DoctorProfile.ConsultationFees <= 0 {
+		return ErrNotPayable
+	}
+	return nil
+}
+
+// refundedAppointment are the statuses that end an appointment without the
+// consultation its fee paid for.
+var refundedAppointment = []models.AppointmentStatus{
+	models.REJECTED,
+	models.CANCELLED_BY_PATIENT,
+	models.CANCELLED_BY_DOCTOR,
+	models.EXPIRED,
+	models.NO_SHOW_DOCTOR,
+}
+
+// RefundsFee reports whether an appointment moving to status gets its fee
+// refunded and its open payment intents voided.
+func RefundsFee(status models.AppointmentStatus) bool {
+	return containsStatus(refundedAppointment, status)
+}
+
+// CheckOrderPayable reports why order cannot be paid online, if so.
+func CheckOrderPayable(order *models.Order) error {
+	if order.PaymentID != nil {
+		return ErrAlreadyPaid
+	}
+	if order.PaymentMethod != models.PAYMENT_ONLINE || order.Status != models.ORDER_PENDING || order.Amount <= 0 {
+		return ErrNotPayable
+	}
+	return nil
+}
+
+// PaymentAction is what a verified gateway event does to a payment.
+type PaymentAction string
+
+const (
+	// PaymentIgnore leaves the payment alone: it already left PENDING, so
+	// the event is a replay, or the event type changes nothing.
+	PaymentIgnore  PaymentAction = "IGNORE"
+	PaymentSucceed PaymentAction = "SUCCEED"
+	PaymentFail    PaymentAction = "FAIL"
+	// PaymentRefund sends captured money back because what it paid for is
+	// no longer payable, e.g. it was cancelled or paid through another
+	// intent meanwhile.
+	PaymentRefund PaymentAction = "REFUND"
+)
+
+// PaymentEventAction decides what ev does to p. payable is the result of
+// CheckAppointmentPayable or CheckOrderPayable for what p pays for, re-run
+// with that row locked; it only matters for a success. A success whose
+// amount or currency differs from p fails with ErrPaymentMismatch.
+func PaymentEventAction(p *models.Payment, ev payments.Event, payable error) (PaymentAction, error) {
+	if p.Status != models.PAYMENT_PENDING {
+		return PaymentIgnore, nil
+	}
+	switch ev.Type {
+	case payments.EventPaymentSucceeded:
+		if ev.Amount != payments.ToMinor(p.Amount) || !strings.EqualFold(ev.Currency, p.Currency) {
+			return PaymentIgnore, ErrPaymentMismatch
+		}
+		if payable != nil {
+			return PaymentRefund, nil
+		}
+		return PaymentSucceed, nil
+	case payments.EventPaymentFailed:
+		return PaymentFail, nil
+	}
+	return PaymentIgnore, nil
+}
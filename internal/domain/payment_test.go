@@ -0,0 +1,130 @@
+package domain
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+)
+
+func TestPaymentEventAction(t *testing.T) {
+	payment := func(status models.PaymentStatus) *models.Payment {
+		return &models.Payment{Status: status, Amount: 1500, Currency: "INR"}
+	}
+	event := func(typ payments.EventType, amount int64, currency string) payments.Event {
+		return payments.Event{Type: typ, IntentID: "pi_1", Amount: amount, Currency: currency}
+	}
+	succeeded := event(payments.EventPaymentSucceeded, 150000, "INR")
+	failed := event(payments.EventPaymentFailed, 150000, "INR")
+
+	tests := []struct {
+		name    string
+		p       *models.Payment
+		ev      payments.Event
+		payable error
+		want    PaymentAction
+		wantErr error
+	}{
+		{"success", payment(models.PAYMENT_PENDING), succeeded, nil, PaymentSucceed, nil},
+		{"currency case ignored", payment(models.PAYMENT_PENDING),
+			event(payments.EventPaymentSucceeded, 150000, "inr"), nil, PaymentSucceed, nil},
+		{"failure", payment(models.PAYMENT_PENDING), failed, nil, PaymentFail, nil},
+		{"unknown event type", payment(models.PAYMENT_PENDING),
+			event("payment.disputed", 150000, "INR"), nil, PaymentIgnore, nil},
+
+		{"amount mismatch", payment(models.PAYMENT_PENDING),
+			event(payments.EventPaymentSucceeded, 149999, "INR"), nil, PaymentIgnore, ErrPaymentMismatch},
+		{"currency mismatch", payment(models.PAYMENT_PENDING),
+			event(payments.EventPaymentSucceeded, 150000, "USD"), nil, PaymentIgnore, ErrPaymentMismatch},
+		{"mismatch wins over unpayable", payment(models.PAYMENT_PENDING),
+			event(payments.EventPaymentSucceeded, 1, "INR"), ErrNotPayable, PaymentIgnore, ErrPaymentMismatch},
+
+		{"cancelled meanwhile", payment(models.PAYMENT_PENDING), succeeded, ErrNotPayable, PaymentRefund, nil},
+		{"paid by another intent", payment(models.PAYMENT_PENDING), succeeded, ErrAlreadyPaid, PaymentRefund, nil},
+		{"failure of unpayable is still a failure", payment(models.PAYMENT_PENDING), failed, ErrNotPayable, PaymentFail, nil},
+
+		{"replayed success", payment(models.PAYMENT_SUCCEEDED), succeeded, nil, PaymentIgnore, nil},
+		{"success after failure", payment(models.PAYMENT_FAILED), succeeded, nil, PaymentIgnore, nil},
+		{"failure after success", payment(models.PAYMENT_SUCCEEDED), failed, nil, PaymentIgnore, nil},
+		{"replay while refunding", payment(models.PAYMENT_REFUNDING), succeeded, nil, PaymentIgnore, nil},
+		{"replay after refund", payment(models.PAYMENT_REFUNDED), succeeded, nil, PaymentIgnore, nil},
+		{"success after void", payment(models.PAYMENT_CANCELLED), succeeded, nil, PaymentIgnore, nil},
+		{"mismatched replay is not an error", payment(models.PAYMENT_SUCCEEDED),
+			event(payments.EventPaymentSucceeded, 1, "USD"), nil, PaymentIgnore, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := PaymentEventAction(tc.p, tc.ev, tc.payable)
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("error %v, want %v", err, tc.wantErr)
+			}
+			if got != tc.want {
+				t.Fatalf("action %s, want %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckPayable(t *testing.T) {
+	fee := models.DoctorProfile{ConsultationFees: 500}
+	appointments := []struct {
+		name string
+		appt models.Appointment
+		want error
+	}{
+		{"pending", models.Appointment{Status: models.PENDING, DoctorProfile: fee}, nil},
+		{"already paid", models.Appointment{Status: models.ACCEPTED, FeePaid: true, DoctorProfile: fee}, ErrAlreadyPaid},
+		{"cancelled", models.Appointment{Status: models.CANCELLED_BY_PATIENT, DoctorProfile: fee}, ErrNotPayable},
+		{"free", models.Appointment{Status: models.ACCEPTED}, ErrNotPayable},
+	}
+	for _, tc := range appointments {
+		if err := CheckAppointmentPayable(&tc.appt); !errors.Is(err, tc.want) {
+			t.Errorf("appointment %s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	paid := "pi_1"
+	orders := []struct {
+		name  string
+		order models.Order
+		want  error
+	}{
+		{"pending online", models.Order{Status: models.ORDER_PENDING, PaymentMethod: models.PAYMENT_ONLINE, Amount: 10}, nil},
+		{"already paid", models.Order{Status: models.ORDER_PENDING, PaymentMethod: models.PAYMENT_ONLINE, Amount: 10, PaymentID: &paid}, ErrAlreadyPaid},
+		{"cancelled", models.Order{Status: models.ORDER_CANCELLED, PaymentMethod: models.PAYMENT_ONLINE, Amount: 10}, ErrNotPayable},
+		{"cash on delivery", models.Order{Status: models.ORDER_PENDING, PaymentMethod: models.PAYMENT_COD, Amount: 10}, ErrNotPayable},
+	}
+	for _, tc := range orders {
+		if err := CheckOrderPayable(&tc.order); !errors.Is(err, tc.want) {
+			t.Errorf("order %s: got %v, want %v", tc.name, err, tc.want)
+		}
+	}
+}
+
+func TestRefundsFee(t *testing.T) {
+	tests := []struct {
+		status models.AppointmentStatus
+		want   bool
+	}{
+		{models.PENDING, false},
+		{models.ACCEPTED, false},
+		{models.RESCHEDULE_REQUESTED, false},
+		{models.COMPLETED, false},
+		{models.NO_SHOW_PATIENT, false},
+		{models.REJECTED, true},
+		{models.CANCELLED_BY_PATIENT, true},
+		{models.CANCELLED_BY_DOCTOR, true},
+		{models.EXPIRED, true},
+		{models.NO_SHOW_DOCTOR, true},
+	}
+	for _, tc := range tests {
+		if got := RefundsFee(tc.status); got != tc.want {
+			t.Errorf("RefundsFee(%s) = %v, want %v", tc.status, got, tc.want)
+		}
+		// a status that refunds the fee must not take a new one
+		if tc.want && containsStatus(payableAppointment, tc.status) {
+			t.Errorf("%s both refunds and accepts the fee", tc.status)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidPrescription    = errors.New("invalid prescription")
+	ErrNotPrescribable        = errors.New("prescriptions can only be issued for accepted or completed appointments")
+	ErrPrescriptionRequired   = errors.New("order contains prescription-only medicines")
+	ErrPrescriptionNotValid   = errors.New("prescription is expired or already used")
+	ErrPrescriptionNotCovered = errors.New("prescription does not cover this medicine")
+)
+
+// DefaultPrescriptionValidity is how long a prescription backs an order when
+// the doctor does not say otherwise; MaxPrescriptionValidity caps it.
+const (
+	DefaultPrescriptionValidity = 30 * 24 * time.Hour
+	MaxPrescriptionValidity     = 365 * 24 * time.Hour
+)
+
+// PrescribableStatuses are the appointment statuses a prescription may be
+// issued in.
+var PrescribableStatuses = []models.AppointmentStatus{models.ACCEPTED, models.COMPLETED}
+
+// CanPrescribe reports whether a prescription may be issued for status.
+func CanPrescribe(status models.AppointmentStatus) bool {
+	return containsStatus(PrescribableStatuses, status)
+}
+
+// ValidatePrescriptionItems trims every line and checks that drug, dose,
+// frequency and duration are given. A line linked to the catalog must also
+// say how many units to dispense.
+func ValidatePrescriptionItems(items models.PrescriptionItems) error {
+	if len(items) == 0 {
+		return fmt.Errorf("%w: no items", ErrInvalidPrescription)
+	}
+	for i := range items {
+		item := &items[i]
+		item.MedicineID = strings.TrimSpace(item.MedicineID)
+		item.Drug = strings.TrimSpace(item.Drug)
+		item.Dose = strings.TrimSpace(item.Dose)
+		item.Frequency = strings.TrimSpace(item.Frequency)
+		item.Duration = strings.TrimSpace(item.Duration)
+		item.Instructions = strings.TrimSpace(item.Instructions)
+
+		switch {
+		case item.Drug == "", item.Dose == "", item.Frequency == "", item.Duration == "":
+			return fmt.Errorf("%w: item %d needs drug, dose, frequency and duration", ErrInvalidPrescription, i+1)
+		case item.MedicineID == "":
+			item.Quantity = 0
+		case uuid.Validate(item.MedicineID) != nil:
+			return fmt.Errorf("%w: item %d has an invalid medicineId", ErrInvalidPrescription, i+1)
+		case item.Quantity < 1 || item.Quantity > MaxOrderQuantity:
+			return fmt.Errorf("%w: item %d quantity must be between 1 and %d", ErrInvalidPrescription, i+1, MaxOrderQuantity)
+		}
+	}
+	return nil
+}
+
+// PrescriptionCart turns the catalog-linked lines of items into an order
+// cart. Lines without a medicine are left out.
+func PrescriptionCart(items models.PrescriptionItems) models.OrderItems {
+	var cart models.OrderItems
+	for _, item := range items {
+		if item.MedicineID != "" {
+			cart = append(cart, models.OrderItem{MedicineID: item.MedicineID, Quantity: item.Quantity})
+		}
+	}
+	return cart
+}
+
+// CoveredQuantity is how many units of medicineID rx allows.
+func CoveredQuantity(rx *models.Prescription, medicineID string) int {
+	var n int
+	for _, item := range rx.Items {
+		if item.MedicineID == medicineID {
+			n += item.Quantity
+		}
+	}
+	return n
+}
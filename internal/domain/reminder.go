@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+var ErrInvalidReminderOffsets = errors.New("invalid reminder offsets")
+
+// ReminderStatuses are the appointment statuses that get reminders.
+var ReminderStatuses = []models.AppointmentStatus{
+	models.ACCEPTED,
+	models.RESCHEDULED_CONFIRMED,
+	models.RESCHEDULE_REJECTED,
+}
+
+// ParseReminderOffsets parses a comma separated list of Go durations, such as
+// "24h,1h". An empty string or "off" yields no offsets, disabling reminders.
+func ParseReminderOffsets(s string) ([]time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "off") {
+		return nil, nil
+	}
+	var offsets []time.Duration
+	seen := map[time.Duration]bool{}
+	for _, f := range strings.Split(s, ",") {
+		d, err := time.ParseDuration(strings.TrimSpace(f))
+		if err != nil || d < time.Minute {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidReminderOffsets, f)
+		}
+		if !seen[d] {
+			seen[d] = true
+			offsets = append(offsets, d)
+		}
+	}
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] > offsets[j] })
+	return offsets, nil
+}
+
+// DueReminder decides which reminders apply to an appointment that starts in
+// `until`. Every offset at least as long as `until` that is not yet in sent is
+// due and returned in mark. Only the closest one is actually delivered
+// (send), so an appointment booked an hour ahead gets one reminder, not two.
+// ok is false when nothing should be delivered.
+func DueReminder(offsets []time.Duration, until time.Duration, sent map[time.Duration]bool) (send time.Duration, mark []time.Duration, ok bool) {
+	if until <= 0 {
+		return 0, nil, false
+	}
+	closestSent := time.Duration(-1)
+	for _, o := range offsets {
+		if until > o {
+			continue
+		}
+		if sent[o] {
+			if closestSent < 0 || o < closestSent {
+				closestSent = o
+			}
+			continue
+		}
+		mark = append(mark, o)
+		if !ok || o < send {
+			send, ok = o, true
+		}
+	}
+	// a closer reminder already went out; just record the stale ones
+	if ok && closestSent >= 0 && closestSent < send {
+		ok = false
+	}
+	return send, mark, ok
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+func TestDueReminder(t *testing.T) {
+	offsets := []time.Duration{24 * time.Hour, time.Hour}
+	sent := func(os ...time.Duration) map[time.Duration]bool {
+		m := map[time.Duration]bool{}
+		for _, o := range os {
+			m[o] = true
+		}
+		return m
+	}
+
+	tests := []struct {
+		name     string
+		offsets  []time.Duration
+		until    time.Duration
+		sent     map[time.Duration]bool
+		wantSend time.Duration
+		wantMark []time.Duration
+		wantOK   bool
+	}{
+		{"before the first window", offsets, 24*time.Hour + time.Second, nil, 0, nil, false},
+		{"first window opens", offsets, 24 * time.Hour, nil, 24 * time.Hour, []time.Duration{24 * time.Hour}, true},
+		{"inside the first window", offsets, 2 * time.Hour, nil, 24 * time.Hour, []time.Duration{24 * time.Hour}, true},
+		{"just before the second window", offsets, time.Hour + time.Second, sent(24 * time.Hour), 0, nil, false},
+		{"second window opens", offsets, time.Hour, sent(24 * time.Hour), time.Hour, []time.Duration{time.Hour}, true},
+		{"booked late sends only the closest", offsets, 30 * time.Minute, nil, time.Hour, []time.Duration{24 * time.Hour, time.Hour}, true},
+		{"first already sent", offsets, 10 * time.Hour, sent(24 * time.Hour), 0, nil, false},
+		{"all already sent", offsets, 30 * time.Minute, sent(24*time.Hour, time.Hour), 0, nil, false},
+		{"closer one sent records the stale one", offsets, 30 * time.Minute, sent(time.Hour), time.Hour, []time.Duration{24 * time.Hour}, false},
+		{"starting now", offsets, 0, nil, 0, nil, false},
+		{"already started", offsets, -time.Minute, nil, 0, nil, false},
+		{"no offsets", nil, 30 * time.Minute, nil, 0, nil, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			send, mark, ok := DueReminder(tc.offsets, tc.until, tc.sent)
+			if ok != tc.wantOK || !reflect.DeepEqual(mark, tc.wantMark) {
+				t.Fatalf("got (%v, %v, %v), want (%v, %v, %v)", send, mark, ok, tc.wantSend, tc.wantMark, tc.wantOK)
+			}
+			if ok && send != tc.wantSend {
+				t.Fatalf("send %v, want %v", send, tc.wantSend)
+			}
+		})
+	}
+}
+
+func TestReminderStatuses(t *testing.T) {
+	tests := []struct {
+		status models.AppointmentStatus
+		want   bool
+	}{
+		{models.ACCEPTED, true},
+		{models.RESCHEDULED_CONFIRMED, true},
+		{models.RESCHEDULE_REJECTED, true},
+		{models.PENDING, false},
+		{models.RESCHEDULE_REQUESTED, false},
+		{models.REJECTED, false},
+		{models.COMPLETED, false},
+		{models.CANCELLED_BY_PATIENT, false},
+		{models.CANCELLED_BY_DOCTOR, false},
+		{models.EXPIRED, false},
+		{models.NO_SHOW_PATIENT, false},
+		{models.NO_SHOW_DOCTOR, false},
+	}
+	for _, tc := range tests {
+		if got := containsStatus(ReminderStatuses, tc.status); got != tc.want {
+			t.Errorf("%s: reminded %v, want %v", tc.status, got, tc.want)
+		}
+	}
+}
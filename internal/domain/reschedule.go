@@ -0,0 +1,37 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+var (
+	ErrNoOpenProposal  = errors.New("no open reschedule proposal for this appointment")
+	ErrOwnProposal     = errors.New("a proposal must be answered by the other party")
+	ErrProposalExpired = errors.New("reschedule proposal has expired")
+	ErrSameTime        = errors.New("proposed time equals the current appointment time")
+)
+
+// ProposalExpiry is when a proposal made at now lapses: after ttl, but never
+// later than the proposed time or the appointment's current time.
+func ProposalExpiry(now, proposed, current time.Time, ttl time.Duration) time.Time {
+	expires := now.Add(ttl)
+	if proposed.Before(expires) {
+		expires = proposed
+	}
+	if current.Before(expires) {
+		expires = current
+	}
+	return expires
+}
+
+// ClosedProposalStatus is what an open proposal becomes when its appointment
+// leaves RESCHEDULE_REQUESTED other than by an answer to the proposal.
+func ClosedProposalStatus(to models.AppointmentStatus) models.ProposalStatus {
+	if to == models.EXPIRED {
+		return models.PROPOSAL_EXPIRED
+	}
+	return models.PROPOSAL_CANCELLED
+}
@@ -0,0 +1,119 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+var (
+	ErrStartTLSUnsupported = errors.New("SMTP server does not support STARTTLS")
+	ErrAuthUnsupported     = errors.New("SMTP server does not support AUTH")
+)
+
+// Client sends messages with one SMTP connection per message.
+type Client struct {
+	Config Config
+	// TLSConfig overrides the default TLS settings, e.g. to trust a test CA.
+	TLSConfig *tls.Config
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{Config: cfg}
+}
+
+// Send delivers m from the configured sender.
+func (c *Client) Send(m Message) error {
+	rcpts, err := m.Recipients()
+	if err != nil {
+		return err
+	}
+	raw, err := Build(c.Config.From, m, time.Now())
+	if err != nil {
+		return err
+	}
+
+	conn, err := c.dial()
+	if err != nil {
+		return err
+	}
+	if c.Config.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(c.Config.Timeout))
+	}
+
+	sc, err := smtp.NewClient(conn, c.Config.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer sc.Close()
+
+	if c.Config.TLS == TLSStartTLS {
+		if ok, _ := sc.Extension("STARTTLS"); !ok {
+			return ErrStartTLSUnsupported
+		}
+		if err := sc.StartTLS(c.tlsConfig()); err != nil {
+			return err
+		}
+	}
+
+	if auth := c.auth(); auth != nil {
+		if ok, _ := sc.Extension("AUTH"); !ok {
+			return ErrAuthUnsupported
+		}
+		if err := sc.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := sc.Mail(c.Config.From.Address); err != nil {
+		return err
+	}
+	for _, r := range rcpts {
+		if err := sc.Rcpt(r); err != nil {
+			return fmt.Errorf("recipient %s: %w", r, err)
+		}
+	}
+	wc, err := sc.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := wc.Write(raw); err != nil {
+		return err
+	}
+	if err := wc.Close(); err != nil {
+		return err
+	}
+	return sc.Quit()
+}
+
+func (c *Client) dial() (net.Conn, error) {
+	d := &net.Dialer{Timeout: c.Config.Timeout}
+	if c.Config.TLS == TLSImplicit {
+		return tls.DialWithDialer(d, "tcp", c.Config.Addr(), c.tlsConfig())
+	}
+	return d.Dial("tcp", c.Config.Addr())
+}
+
+func (c *Client) tlsConfig() *tls.Config {
+	if c.TLSConfig != nil {
+		return c.TLSConfig
+	}
+	return &tls.Config{ServerName: c.Config.Host, MinVersion: tls.VersionTLS12}
+}
+
+func (c *Client) auth() smtp.Auth {
+	if c.Config.Username == "" {
+		return nil
+	}
+	switch c.Config.Auth {
+	case AuthPlain:
+		return smtp.PlainAuth("", c.Config.Username, c.Config.Password, c.Config.Host)
+	case AuthCRAMMD5:
+		return smtp.CRAMMD5Auth(c.Config.Username, c.Config.Password)
+	}
+	return nil
+}
@@ -0,0 +1,438 @@
+package mailer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/big"
+	"mime"
+	"net"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServer is an in-process SMTP server speaking just enough of the
+// protocol for net/smtp: EHLO, STARTTLS, AUTH PLAIN and CRAM-MD5, MAIL,
+// RCPT, DATA and QUIT. Each connection is recorded as a session.
+type fakeServer struct {
+	ln       net.Listener
+	cert     tls.Certificate
+	roots    *x509.CertPool
+	implicit bool     // TLS from the first byte
+	startTLS bool     // advertise STARTTLS
+	auth     []string // advertised AUTH mechanisms
+	silent   bool     // accept but never greet
+	reject   string   // recipient answered with 550
+	user     string
+	pass     string
+	sessions chan *session
+}
+
+type session struct {
+	tls    bool
+	authed bool
+	from   string
+	rcpts  []string
+	data   []byte
+}
+
+// newFakeServer starts a server; configure adjusts it before it accepts.
+func newFakeServer(t *testing.T, configure ...func(*fakeServer)) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	cert, roots := selfSigned(t)
+	s := &fakeServer{
+		ln:       ln,
+		cert:     cert,
+		roots:    roots,
+		startTLS: true,
+		auth:     []string{"PLAIN", "CRAM-MD5"},
+		user:     "mailer",
+		pass:     "s3cret",
+		sessions: make(chan *session, 4),
+	}
+	for _, f := range configure {
+		if f != nil {
+			f(s)
+		}
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+// client returns a Client for s that trusts its certificate.
+func (s *fakeServer) client(mode TLSMode, auth AuthMode) *Client {
+	c := NewClient(Config{
+		Host:     "127.0.0.1",
+		Port:     s.ln.Addr().(*net.TCPAddr).Port,
+		TLS:      mode,
+		Auth:     auth,
+		Username: s.user,
+		Password: s.pass,
+		From:     mail.Address{Name: "Wello", Address: "noreply@wello.test"},
+		Timeout:  2 * time.Second,
+	})
+	c.TLSConfig = &tls.Config{RootCAs: s.roots, ServerName: "127.0.0.1", MinVersion: tls.VersionTLS12}
+	return c
+}
+
+// session waits for the next connection to end.
+func (s *fakeServer) session(t *testing.T) *session {
+	t.Helper()
+	select {
+	case sess := <-s.sessions:
+		return sess
+	case <-time.After(5 * time.Second):
+		t.Fatal("no SMTP session recorded")
+		return nil
+	}
+}
+
+func (s *fakeServer) serve(conn net.Conn) {
+	sess := &session{}
+	defer func() {
+		conn.Close()
+		s.sessions <- sess
+	}()
+
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{s.cert}}
+	if s.implicit {
+		tc := tls.Server(conn, tlsConfig)
+		if err := tc.Handshake(); err != nil {
+			return
+		}
+		conn, sess.tls = tc, true
+	}
+	if s.silent {
+		io.Copy(io.Discard, conn)
+		return
+	}
+
+	tp := textproto.NewConn(conn)
+	tp.PrintfLine("220 fake.test ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			ext := []string{"fake.test"}
+			if s.startTLS && !sess.tls {
+				ext = append(ext, "STARTTLS")
+			}
+			if len(s.auth) > 0 {
+				ext = append(ext, "AUTH "+strings.Join(s.auth, " "))
+			}
+			for i, e := range ext {
+				sep := "-"
+				if i == len(ext)-1 {
+					sep = " "
+				}
+				tp.PrintfLine("250%s%s", sep, e)
+			}
+		case "STARTTLS":
+			tp.PrintfLine("220 ready to start TLS")
+			tc := tls.Server(conn, tlsConfig)
+			if err := tc.Handshake(); err != nil {
+				return
+			}
+			conn, sess.tls = tc, true
+			tp = textproto.NewConn(conn)
+		case "AUTH":
+			if !s.checkAuth(tp, arg) {
+				tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			sess.authed = true
+			tp.PrintfLine("235 authenticated")
+		case "MAIL":
+			sess.from = envelopeAddr(arg)
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			rcpt := envelopeAddr(arg)
+			if rcpt == s.reject {
+				tp.PrintfLine("550 no such user")
+				continue
+			}
+			sess.rcpts = append(sess.rcpts, rcpt)
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 end with <CRLF>.<CRLF>")
+			if sess.data, err = tp.ReadDotBytes(); err != nil {
+				return
+			}
+			tp.PrintfLine("250 queued")
+		case "QUIT":
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *fakeServer) checkAuth(tp *textproto.Conn, arg string) bool {
+	mech, initial, _ := strings.Cut(arg, " ")
+	switch mech {
+	case "PLAIN":
+		b, err := base64.StdEncoding.DecodeString(initial)
+		return err == nil && string(b) == "\x00"+s.user+"\x00"+s.pass
+	case "CRAM-MD5":
+		challenge := "<1896.697170952@fake.test>"
+		tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(challenge)))
+		line, err := tp.ReadLine()
+		if err != nil {
+			return false
+		}
+		b, err := base64.StdEncoding.DecodeString(line)
+		if err != nil {
+			return false
+		}
+		mac := hmac.New(md5.New, []byte(s.pass))
+		mac.Write([]byte(challenge))
+		return string(b) == s.user+" "+hex.EncodeToString(mac.Sum(nil))
+	}
+	return false
+}
+
+// envelopeAddr extracts the address from "FROM:<a@b>" or "TO:<a@b>".
+func envelopeAddr(arg string) string {
+	start, end := strings.Index(arg, "<"), strings.Index(arg, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return arg[start+1 : end]
+}
+
+// selfSigned makes a certificate for 127.0.0.1 and a pool trusting it.
+func selfSigned(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "fake.test"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("certificate: %v", err)
+	}
+	parsed, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(parsed)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, roots
+}
+
+func testMessage() Message {
+	return Message{
+		To:      []string{"Asha Rao <asha@example.com>", "ravi@example.com"},
+		Subject: "Your code\r\nBcc: attacker@example.com",
+		Text:    "Your login code is 123456",
+	}
+}
+
+func TestSendDeliversEnvelopeAndHeaders(t *testing.T) {
+	s := newFakeServer(t)
+	if err := s.client(TLSStartTLS, AuthPlain).Send(testMessage()); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	sess := s.session(t)
+
+	if !sess.tls || !sess.authed {
+		t.Fatalf("tls=%v authed=%v, want both", sess.tls, sess.authed)
+	}
+	if sess.from != "noreply@wello.test" {
+		t.Errorf("MAIL FROM %q", sess.from)
+	}
+	if strings.Join(sess.rcpts, ",") != "asha@example.com,ravi@example.com" {
+		t.Errorf("RCPT TO %v, want bare addresses", sess.rcpts)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(sess.data)))
+	if err != nil {
+		t.Fatalf("parse delivered message: %v", err)
+	}
+	if got := msg.Header.Get("From"); got != `"Wello" <noreply@wello.test>` {
+		t.Errorf("From %q", got)
+	}
+	to, err := msg.Header.AddressList("To")
+	if err != nil || len(to) != 2 || to[0].Name != "Asha Rao" || to[1].Address != "ravi@example.com" {
+		t.Errorf("To %v (%v)", to, err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Your code  Bcc: attacker@example.com" {
+		t.Errorf("Subject %q (%v)", subject, err)
+	}
+	if msg.Header.Get("Bcc") != "" {
+		t.Error("subject newline injected a Bcc header")
+	}
+	for _, h := range []string{"Date", "Message-ID"} {
+		if msg.Header.Get(h) == "" {
+			t.Errorf("missing %s header", h)
+		}
+	}
+	if msg.Header.Get("MIME-Version") != "1.0" || !strings.HasPrefix(msg.Header.Get("Content-Type"), "text/plain") {
+		t.Errorf("MIME headers %v", msg.Header)
+	}
+	body, _ := io.ReadAll(msg.Body)
+	if !strings.Contains(string(body), "Your login code is 123456") {
+		t.Errorf("body %q", body)
+	}
+}
+
+func TestSendTransports(t *testing.T) {
+	tests := []struct {
+		name       string
+		implicit   bool
+		mode       TLSMode
+		auth       AuthMode
+		wantTLS    bool
+		wantAuthed bool
+	}{
+		{"starttls with cram-md5", false, TLSStartTLS, AuthCRAMMD5, true, true},
+		{"implicit tls with plain", true, TLSImplicit, AuthPlain, true, true},
+		{"plain relay without auth", false, TLSNone, AuthNone, false, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newFakeServer(t, func(s *fakeServer) { s.implicit = tc.implicit })
+			if err := s.client(tc.mode, tc.auth).Send(testMessage()); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			sess := s.session(t)
+			if sess.tls != tc.wantTLS || sess.authed != tc.wantAuthed {
+				t.Fatalf("tls=%v authed=%v, want %v %v", sess.tls, sess.authed, tc.wantTLS, tc.wantAuthed)
+			}
+			if len(sess.data) == 0 {
+				t.Fatal("no message delivered")
+			}
+		})
+	}
+}
+
+func TestSendFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		server func(s *fakeServer)
+		client func(c *Client)
+		check  func(t *testing.T, err error)
+	}{
+		{"starttls not offered",
+			func(s *fakeServer) { s.startTLS = false }, nil,
+			func(t *testing.T, err error) {
+				if !errors.Is(err, ErrStartTLSUnsupported) {
+					t.Fatalf("got %v, want ErrStartTLSUnsupported", err)
+				}
+			}},
+		{"untrusted certificate",
+			nil, func(c *Client) { c.TLSConfig = nil },
+			func(t *testing.T, err error) {
+				var verr *tls.CertificateVerificationError
+				if !errors.As(err, &verr) {
+					t.Fatalf("got %v, want a certificate verification error", err)
+				}
+			}},
+		{"auth not offered",
+			func(s *fakeServer) { s.auth = nil }, nil,
+			func(t *testing.T, err error) {
+				if !errors.Is(err, ErrAuthUnsupported) {
+					t.Fatalf("got %v, want ErrAuthUnsupported", err)
+				}
+			}},
+		{"wrong password",
+			nil, func(c *Client) { c.Config.Password = "wrong" },
+			func(t *testing.T, err error) {
+				var perr *textproto.Error
+				if !errors.As(err, &perr) || perr.Code != 535 {
+					t.Fatalf("got %v, want 535", err)
+				}
+			}},
+		{"rejected recipient",
+			func(s *fakeServer) { s.reject = "ravi@example.com" }, nil,
+			func(t *testing.T, err error) {
+				if err == nil || !strings.Contains(err.Error(), "recipient ravi@example.com") {
+					t.Fatalf("got %v, want the rejected recipient named", err)
+				}
+			}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := newFakeServer(t, tc.server)
+			c := s.client(TLSStartTLS, AuthPlain)
+			if tc.client != nil {
+				tc.client(c)
+			}
+			tc.check(t, c.Send(testMessage()))
+			if sess := s.session(t); len(sess.data) != 0 {
+				t.Fatal("message delivered despite the failure")
+			}
+		})
+	}
+}
+
+func TestSendTimesOutOnSilentServer(t *testing.T) {
+	s := newFakeServer(t, func(s *fakeServer) { s.silent = true })
+	c := s.client(TLSStartTLS, AuthPlain)
+	c.Config.Timeout = 200 * time.Millisecond
+
+	err := c.Send(testMessage())
+	var nerr net.Error
+	if !errors.As(err, &nerr) || !nerr.Timeout() {
+		t.Fatalf("got %v, want a timeout", err)
+	}
+	s.session(t)
+}
+
+func TestSendRejectsBadMessageBeforeDialing(t *testing.T) {
+	s := newFakeServer(t)
+	c := s.client(TLSStartTLS, AuthPlain)
+	if err := c.Send(Message{Subject: "hi", Text: "x"}); !errors.Is(err, ErrNoRecipients) {
+		t.Fatalf("got %v, want ErrNoRecipients", err)
+	}
+	if err := c.Send(Message{To: []string{"a@example.com"}, Subject: "hi"}); !errors.Is(err, ErrEmptyBody) {
+		t.Fatalf("got %v, want ErrEmptyBody", err)
+	}
+	select {
+	case <-s.sessions:
+		t.Fatal("connected for an invalid message")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,113 @@
+// Package mailer sends MIME email over SMTP. The transport (host, port, TLS
+// mode, auth) comes from the environment, so the same code talks to Gmail in
+// production and to a local fake server in tests.
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLSMode selects how the connection is secured.
+type TLSMode string
+
+const (
+	TLSStartTLS TLSMode = "starttls" // plain connect, then STARTTLS (port 587)
+	TLSImplicit TLSMode = "tls"      // TLS from the first byte (port 465)
+	TLSNone     TLSMode = "none"     // no TLS; local relays and fake servers only
+)
+
+// AuthMode selects the SMTP AUTH mechanism.
+type AuthMode string
+
+const (
+	AuthPlain   AuthMode = "plain"
+	AuthCRAMMD5 AuthMode = "crammd5"
+	AuthNone    AuthMode = "none"
+)
+
+var ErrInvalidConfig = errors.New("invalid SMTP configuration")
+
+type Config struct {
+	Host     string
+	Port     int
+	TLS      TLSMode
+	Auth     AuthMode
+	Username string
+	Password string
+	From     mail.Address
+	Timeout  time.Duration
+}
+
+// Addr is host:port for dialing.
+func (c Config) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// ConfigFromEnv reads the SMTP_* variables. The defaults keep the previous
+// behaviour: Gmail on 587 with STARTTLS, logging in as SMTP_EMAIL.
+//
+//	SMTP_HOST       default smtp.gmail.com
+//	SMTP_PORT       default 587, 465 for tls, 25 for none
+//	SMTP_TLS        starttls | tls | none
+//	SMTP_AUTH       plain | crammd5 | none
+//	SMTP_USERNAME   default SMTP_EMAIL
+//	SMTP_PASSWORD
+//	SMTP_EMAIL      sender address
+//	EMAIL_FROM_NAME sender display name
+//	SMTP_TIMEOUT    seconds, default 15
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Host:     envOr("SMTP_HOST", "smtp.gmail.com"),
+		TLS:      TLSMode(strings.ToLower(envOr("SMTP_TLS", string(TLSStartTLS)))),
+		Auth:     AuthMode(strings.ToLower(envOr("SMTP_AUTH", string(AuthPlain)))),
+		Username: envOr("SMTP_USERNAME", os.Getenv("SMTP_EMAIL")),
+		Password: os.Getenv("SMTP_PASSWORD"),
+		From:     mail.Address{Name: os.Getenv("EMAIL_FROM_NAME"), Address: os.Getenv("SMTP_EMAIL")},
+		Timeout:  15 * time.Second,
+	}
+
+	switch cfg.TLS {
+	case TLSStartTLS:
+		cfg.Port = 587
+	case TLSImplicit:
+		cfg.Port = 465
+	case TLSNone:
+		cfg.Port = 25
+	default:
+		return cfg, fmt.Errorf("%w: SMTP_TLS %q", ErrInvalidConfig, cfg.TLS)
+	}
+	switch cfg.Auth {
+	case AuthPlain, AuthCRAMMD5, AuthNone:
+	default:
+		return cfg, fmt.Errorf("%w: SMTP_AUTH %q", ErrInvalidConfig, cfg.Auth)
+	}
+
+	if v := os.Getenv("SMTP_PORT"); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil || port <= 0 || port > 65535 {
+			return cfg, fmt.Errorf("%w: SMTP_PORT %q", ErrInvalidConfig, v)
+		}
+		cfg.Port = port
+	}
+	if v := os.Getenv("SMTP_TIMEOUT"); v != "" {
+		secs, err := strconv.Atoi(v)
+		if err != nil || secs <= 0 {
+			return cfg, fmt.Errorf("%w: SMTP_TIMEOUT %q", ErrInvalidConfig, v)
+		}
+		cfg.Timeout = time.Duration(secs) * time.Second
+	}
+	return cfg, nil
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
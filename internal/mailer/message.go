@@ -0,0 +1,252 @@
+package mailer
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoRecipients = errors.New("email has no recipients")
+	ErrEmptyBody    = errors.New("email has neither text nor HTML body")
+	ErrBadFilename  = errors.New("invalid attachment filename")
+)
+
+// Message is one email. Text and HTML are sent as alternatives when both are
+// set.
+type Message struct {
+	To          []string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+type Attachment struct {
+	Filename string
+	// ContentType defaults to a guess from the filename extension.
+	ContentType string
+	Data        []byte
+}
+
+// part is a MIME entity: its own headers plus encoded body.
+type part struct {
+	header textproto.MIMEHeader
+	body   []byte
+}
+
+// Build renders m as an RFC 5322 message from from. Addresses are parsed and
+// re-serialised and the subject is RFC 2047 encoded, so no caller-supplied
+// value can add header lines.
+func Build(from mail.Address, m Message, date time.Time) ([]byte, error) {
+	to, err := parseRecipients(m.To)
+	if err != nil {
+		return nil, err
+	}
+	if m.Text == "" && m.HTML == "" {
+		return nil, ErrEmptyBody
+	}
+
+	body, err := bodyPart(m)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]string, len(to))
+	for i, a := range to {
+		list[i] = a.String()
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from.String())
+	writeHeader(&buf, "To", strings.Join(list, ", "))
+	subject := strings.NewReplacer("\r", " ", "\n", " ").Replace(m.Subject)
+	writeHeader(&buf, "Subject", mime.QEncoding.Encode("utf-8", subject))
+	writeHeader(&buf, "Date", date.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageID(from.Address))
+	writeHeader(&buf, "MIME-Version", "1.0")
+	for _, k := range []string{"Content-Type", "Content-Transfer-Encoding"} {
+		if v := body.header.Get(k); v != "" {
+			writeHeader(&buf, k, v)
+		}
+	}
+	buf.WriteString("\r\n")
+	buf.Write(body.body)
+	return buf.Bytes(), nil
+}
+
+// Recipients returns the bare addresses of m.To for the SMTP envelope.
+func (m Message) Recipients() ([]string, error) {
+	to, err := parseRecipients(m.To)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, len(to))
+	for i, a := range to {
+		out[i] = a.Address
+	}
+	return out, nil
+}
+
+func parseRecipients(raw []string) ([]*mail.Address, error) {
+	if len(raw) == 0 {
+		return nil, ErrNoRecipients
+	}
+	out := make([]*mail.Address, 0, len(raw))
+	for _, r := range raw {
+		a, err := mail.ParseAddress(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid recipient %q: %w", r, err)
+		}
+		out = append(out, a)
+	}
+	return out, nil
+}
+
+func bodyPart(m Message) (part, error) {
+	var content part
+	switch {
+	case m.Text != "" && m.HTML != "":
+		text, err := textPart("text/plain", m.Text)
+		if err != nil {
+			return part{}, err
+		}
+		html, err := textPart("text/html", m.HTML)
+		if err != nil {
+			return part{}, err
+		}
+		if content, err = multipartOf("alternative", text, html); err != nil {
+			return part{}, err
+		}
+	case m.HTML != "":
+		p, err := textPart("text/html", m.HTML)
+		if err != nil {
+			return part{}, err
+		}
+		content = p
+	default:
+		p, err := textPart("text/plain", m.Text)
+		if err != nil {
+			return part{}, err
+		}
+		content = p
+	}
+
+	if len(m.Attachments) == 0 {
+		return content, nil
+	}
+	parts := []part{content}
+	for _, a := range m.Attachments {
+		p, err := attachmentPart(a)
+		if err != nil {
+			return part{}, err
+		}
+		parts = append(parts, p)
+	}
+	return multipartOf("mixed", parts...)
+}
+
+func textPart(contentType, s string) (part, error) {
+	var buf bytes.Buffer
+	qp := quotedprintable.NewWriter(&buf)
+	if _, err := qp.Write([]byte(s)); err != nil {
+		return part{}, err
+	}
+	if err := qp.Close(); err != nil {
+		return part{}, err
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType+"; charset=utf-8")
+	h.Set("Content-Transfer-Encoding", "quoted-printable")
+	return part{header: h, body: buf.Bytes()}, nil
+}
+
+func attachmentPart(a Attachment) (part, error) {
+	name := filepath.Base(a.Filename)
+	if name == "" || name == "." || name == "/" || strings.ContainsAny(name, "\r\n") {
+		return part{}, fmt.Errorf("%w %q", ErrBadFilename, a.Filename)
+	}
+	disposition := mime.FormatMediaType("attachment", map[string]string{"filename": name})
+	if disposition == "" {
+		return part{}, fmt.Errorf("%w %q", ErrBadFilename, a.Filename)
+	}
+
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(name))
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if _, _, err := mime.ParseMediaType(contentType); err != nil {
+		return part{}, fmt.Errorf("invalid attachment content type %q: %w", contentType, err)
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", contentType)
+	h.Set("Content-Transfer-Encoding", "base64")
+	h.Set("Content-Disposition", disposition)
+	return part{header: h, body: base64Lines(a.Data)}, nil
+}
+
+func multipartOf(subtype string, parts ...part) (part, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	for _, p := range parts {
+		pw, err := w.CreatePart(p.header)
+		if err != nil {
+			return part{}, err
+		}
+		if _, err := pw.Write(p.body); err != nil {
+			return part{}, err
+		}
+	}
+	if err := w.Close(); err != nil {
+		return part{}, err
+	}
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Type", fmt.Sprintf("multipart/%s; boundary=%q", subtype, w.Boundary()))
+	return part{header: h, body: buf.Bytes()}, nil
+}
+
+// base64Lines encodes data in 76-character lines as RFC 2045 requires.
+func base64Lines(data []byte) []byte {
+	enc := base64.StdEncoding.EncodeToString(data)
+	var buf bytes.Buffer
+	for len(enc) > 76 {
+		buf.WriteString(enc[:76])
+		buf.WriteString("\r\n")
+		enc = enc[76:]
+	}
+	buf.WriteString(enc)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+func writeHeader(buf *bytes.Buffer, key, value string) {
+	buf.WriteString(key)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
@@ -5,38 +5,109 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/GitNinja36/wello-backend/internal/models"
 	"github.com/GitNinja36/wello-backend/internal/utils"
 )
 
 type contextKey string
 
-const UserIDKey = contextKey("userId")
+const (
+	UserIDKey = contextKey("userId")
+	ClaimsKey = contextKey("claims")
+)
+
+// TokenParser verifies an access token and returns its claims.
+type TokenParser interface {
+	ParseJWT(token string) (*utils.Claims, error)
+}
+
+// SessionChecker reports whether the session behind an access token is still
+// active.
+type SessionChecker interface {
+	Active(sessionID string) bool
+}
+
+// JWTAuthMiddleware accepts session tokens only, and only while sessions
+// reports their session active; scoped tokens (onboarding, login challenges)
+// are rejected.
+func JWTAuthMiddleware(tokens TokenParser, sessions SessionChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := bearerClaims(tokens, w, r)
+			if !ok {
+				return
+			}
+			if claims.Scope != "" || claims.UserID == "" {
+				http.Error(w, "Scoped token cannot be used here", http.StatusUnauthorized)
+				return
+			}
+			if claims.SessionID == "" || !sessions.Active(claims.SessionID) {
+				http.Error(w, "Session revoked or expired", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, ClaimsKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OnboardingAuthMiddleware accepts only the short-lived token issued after a
+// new identifier passed OTP verification.
+func OnboardingAuthMiddleware(tokens TokenParser) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := bearerClaims(tokens, w, r)
+			if !ok {
+				return
+			}
+			if claims.Scope != utils.ScopeOnboarding || claims.Identifier == "" {
+				http.Error(w, "Onboarding token required", http.StatusUnauthorized)
+				return
+			}
 
-func JWTAuthMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header missing", http.StatusUnauthorized)
-			return
-		}
+			ctx := context.WithValue(r.Context(), ClaimsKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Invalid Authorization format", http.StatusUnauthorized)
-			return
-		}
-		claims, err := utils.ParseJWT(parts[1])
-		if err != nil {
-			http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
-			return
-		}
+// bearerClaims parses the Authorization header, writing a 401 on failure.
+func bearerClaims(tokens TokenParser, w http.ResponseWriter, r *http.Request) (*utils.Claims, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		http.Error(w, "Authorization header missing", http.StatusUnauthorized)
+		return nil, false
+	}
 
-		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		http.Error(w, "Invalid Authorization format", http.StatusUnauthorized)
+		return nil, false
+	}
+	claims, err := tokens.ParseJWT(parts[1])
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return nil, false
+	}
+	return claims, true
 }
 
 func GetUserIDFromContext(r *http.Request) string {
 	userID, _ := r.Context().Value(UserIDKey).(string)
 	return userID
 }
+
+func GetClaimsFromContext(r *http.Request) *utils.Claims {
+	claims, _ := r.Context().Value(ClaimsKey).(*utils.Claims)
+	return claims
+}
+
+func GetRoleFromContext(r *http.Request) models.Role {
+	claims := GetClaimsFromContext(r)
+	if claims == nil {
+		return ""
+	}
+	return models.Role(claims.Role)
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+// RequireRole allows the request through only when the JWT role is one of roles.
+// It must run after JWTAuthMiddleware.
+func RequireRole(roles ...models.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaimsFromContext(r)
+			if claims == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			for _, role := range roles {
+				if models.Role(claims.Role) == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+			http.Error(w, "Forbidden: insufficient role", http.StatusForbidden)
+		})
+	}
+}
+
+// RequireApproved blocks accounts that are still waiting for admin approval.
+func RequireApproved(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := GetClaimsFromContext(r)
+		if claims == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !claims.IsApproved {
+			http.Error(w, "Forbidden: account pending approval", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireProfile blocks tokens issued before the user finished onboarding.
+func RequireProfile(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims := GetClaimsFromContext(r)
+		if claims == nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if claims.NeedsProfile {
+			http.Error(w, "Forbidden: profile incomplete", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// StepUpChecker reports why a session may not use a sensitive endpoint
+// (missing enrollment or no recent second-factor check).
+type StepUpChecker interface {
+	CheckStepUp(userID, sessionID string) error
+}
+
+// RequireStepUp guards endpoints that need a fresh second factor, such as
+// reading patient history or approving doctors. It must run after
+// JWTAuthMiddleware.
+func RequireStepUp(checker StepUpChecker) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaimsFromContext(r)
+			if claims == nil {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+			if err := checker.CheckStepUp(claims.UserID, claims.SessionID); err != nil {
+				http.Error(w, "Forbidden: "+err.Error(), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
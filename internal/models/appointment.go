@@ -13,12 +13,16 @@ type Appointment struct {
 	Mode            AppointmentMode   `gorm:"type:text;default:'ONLINE'" json:"mode"`
 	Status          AppointmentStatus `gorm:"type:text;default:'PENDING'" json:"status"`
 	ScheduledAt     time.Time         `json:"scheduledAt"`
-	MeetingLink     *string           `json:"meetingLink,omitempty"`
-	Location        *string           `json:"location,omitempty"`
-	FeePaid         bool              `gorm:"default:false" json:"feePaid"`
-	Summary         *string           `json:"summary,omitempty"`
-	Rating          *int              `json:"rating"`
-	Review          *string           `json:"review"`
-	CreatedAt       time.Time         `json:"createdAt"`
-	UpdatedAt       time.Time         `json:"updatedAt"`
+	// DurationMinutes is the length of the slot as booked, so later changes
+	// to the doctor's slot length do not move its end.
+	DurationMinutes int       `gorm:"default:0" json:"durationMinutes"`
+	MeetingLink     *string   `json:"meetingLink,omitempty"`
+	Location        *string   `json:"location,omitempty"`
+	FeePaid         bool      `gorm:"default:false" json:"feePaid"`
+	Summary         *string   `json:"summary,omitempty"`
+	StatusReason    *string   `json:"statusReason,omitempty"`
+	Rating          *int      `json:"rating"`
+	Review          *string   `json:"review"`
+	CreatedAt       time.Time `json:"createdAt"`
+	UpdatedAt       time.Time `json:"updatedAt"`
 }
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// AppointmentReminder records that the reminder for one offset has been
+// handled for an appointment at a given time. The unique index makes a
+// reminder fire at most once; a reschedule changes ScheduledAt, so the new
+// time is reminded again.
+type AppointmentReminder struct {
+	ID            string      `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AppointmentID string      `gorm:"uniqueIndex:idx_reminder_once,priority:1" json:"appointmentId"`
+	Appointment   Appointment `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	OffsetMinutes int         `gorm:"uniqueIndex:idx_reminder_once,priority:2" json:"offsetMinutes"`
+	ScheduledAt   time.Time   `gorm:"uniqueIndex:idx_reminder_once,priority:3" json:"scheduledAt"`
+	// Sent is false for reminders skipped because a closer one went out.
+	Sent      bool      `json:"sent"`
+	CreatedAt time.Time `json:"createdAt"`
+}
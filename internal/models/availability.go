@@ -0,0 +1,67 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// TimeRange is a bookable window within a day in "HH:MM" 24h clock.
+// SlotDuration (minutes) overrides the profile default when non-zero.
+type TimeRange struct {
+	Start        string `json:"start"`
+	End          string `json:"end"`
+	SlotDuration int    `json:"slotDuration,omitempty"`
+}
+
+// WeeklyRule repeats every Day between the optional EffectiveFrom and
+// EffectiveTo dates ("YYYY-MM-DD", inclusive).
+type WeeklyRule struct {
+	Day           Weekday     `json:"day"`
+	Ranges        []TimeRange `json:"ranges"`
+	EffectiveFrom *string     `json:"effectiveFrom,omitempty"`
+	EffectiveTo   *string     `json:"effectiveTo,omitempty"`
+}
+
+// AvailabilityOverride replaces the weekly rules for a single date.
+// An override without ranges marks the whole day as unavailable.
+type AvailabilityOverride struct {
+	Date   string      `json:"date"`
+	Reason string      `json:"reason,omitempty"`
+	Ranges []TimeRange `json:"ranges"`
+}
+
+type Availability struct {
+	Weekly    []WeeklyRule           `json:"weekly"`
+	Overrides []AvailabilityOverride `json:"overrides"`
+}
+
+func (a Availability) Value() (driver.Value, error) {
+	if a.Weekly == nil {
+		a.Weekly = []WeeklyRule{}
+	}
+	if a.Overrides == nil {
+		a.Overrides = []AvailabilityOverride{}
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (a *Availability) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*a = Availability{}
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Availability", value)
+	}
+	return json.Unmarshal(raw, a)
+}
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// DoctorLeave is a blackout window during which the doctor takes no bookings.
+type DoctorLeave struct {
+	ID              string        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	DoctorProfileID string        `gorm:"index" json:"doctorProfileId"`
+	DoctorProfile   DoctorProfile `gorm:"foreignKey:DoctorProfileID" json:"-"`
+	StartsAt        time.Time     `gorm:"index" json:"startsAt"`
+	EndsAt          time.Time     `gorm:"index" json:"endsAt"`
+	Reason          string        `json:"reason"`
+	CancelledAt     *time.Time    `json:"cancelledAt,omitempty"`
+	CreatedAt       time.Time     `json:"createdAt"`
+	UpdatedAt       time.Time     `json:"updatedAt"`
+}
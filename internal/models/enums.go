@@ -3,9 +3,10 @@ package models
 type Role string
 
 const (
-	PATIENT Role = "PATIENT"
-	DOCTOR  Role = "DOCTOR"
-	ADMIN   Role = "ADMIN"
+	PATIENT    Role = "PATIENT"
+	DOCTOR     Role = "DOCTOR"
+	ADMIN      Role = "ADMIN"
+	PHARMACIST Role = "PHARMACIST"
 )
 
 type AppointmentMode string
@@ -27,6 +28,10 @@ const (
 	RESCHEDULE_REJECTED   AppointmentStatus = "RESCHEDULE_REJECTED"
 	RESCHEDULED_CONFIRMED AppointmentStatus = "RESCHEDULED_CONFIRMED"
 	CANCELLED_BY_PATIENT  AppointmentStatus = "CANCELLED_BY_PATIENT"
+	CANCELLED_BY_DOCTOR   AppointmentStatus = "CANCELLED_BY_DOCTOR"
+	EXPIRED               AppointmentStatus = "EXPIRED"
+	NO_SHOW_PATIENT       AppointmentStatus = "NO_SHOW_PATIENT"
+	NO_SHOW_DOCTOR        AppointmentStatus = "NO_SHOW_DOCTOR"
 )
 
 type TestType string
@@ -58,9 +63,94 @@ const (
 	ORDER_CANCELLED OrderStatus = "CANCELLED"
 )
 
+type MedicineForm string
+
+const (
+	FORM_TABLET    MedicineForm = "TABLET"
+	FORM_CAPSULE   MedicineForm = "CAPSULE"
+	FORM_SYRUP     MedicineForm = "SYRUP"
+	FORM_INJECTION MedicineForm = "INJECTION"
+	FORM_OINTMENT  MedicineForm = "OINTMENT"
+	FORM_DROPS     MedicineForm = "DROPS"
+	FORM_INHALER   MedicineForm = "INHALER"
+	FORM_OTHER     MedicineForm = "OTHER"
+)
+
 type PaymentMethod string
 
 const (
 	PAYMENT_ONLINE PaymentMethod = "ONLINE"
 	PAYMENT_COD    PaymentMethod = "COD"
 )
+
+type PaymentStatus string
+
+const (
+	PAYMENT_PENDING   PaymentStatus = "PENDING"
+	PAYMENT_SUCCEEDED PaymentStatus = "SUCCEEDED"
+	PAYMENT_FAILED    PaymentStatus = "FAILED"
+	// PAYMENT_REFUNDING is a refund recorded locally whose gateway call has
+	// not been confirmed yet.
+	PAYMENT_REFUNDING PaymentStatus = "REFUNDING"
+	PAYMENT_REFUNDED  PaymentStatus = "REFUNDED"
+	// PAYMENT_CANCELLED is a pending intent voided before it was paid.
+	PAYMENT_CANCELLED PaymentStatus = "CANCELLED"
+)
+
+type PaymentPurpose string
+
+const (
+	PURPOSE_APPOINTMENT_FEE PaymentPurpose = "APPOINTMENT_FEE"
+	PURPOSE_ORDER           PaymentPurpose = "ORDER"
+)
+
+type TransactionKind string
+
+const (
+	TXN_PAYMENT    TransactionKind = "PAYMENT"
+	TXN_REFUND     TransactionKind = "REFUND"
+	TXN_COMMISSION TransactionKind = "COMMISSION"
+	TXN_PAYOUT     TransactionKind = "PAYOUT"
+)
+
+type LedgerAccount string
+
+const (
+	// ACCOUNT_GATEWAY_CASH is money held at the payment gateway.
+	ACCOUNT_GATEWAY_CASH LedgerAccount = "GATEWAY_CASH"
+	// ACCOUNT_DOCTOR_PAYABLE is what the platform owes a doctor.
+	ACCOUNT_DOCTOR_PAYABLE   LedgerAccount = "DOCTOR_PAYABLE"
+	ACCOUNT_PLATFORM_REVENUE LedgerAccount = "PLATFORM_REVENUE"
+	ACCOUNT_PHARMACY_REVENUE LedgerAccount = "PHARMACY_REVENUE"
+)
+
+type Weekday string
+
+const (
+	MONDAY    Weekday = "MONDAY"
+	TUESDAY   Weekday = "TUESDAY"
+	WEDNESDAY Weekday = "WEDNESDAY"
+	THURSDAY  Weekday = "THURSDAY"
+	FRIDAY    Weekday = "FRIDAY"
+	SATURDAY  Weekday = "SATURDAY"
+	SUNDAY    Weekday = "SUNDAY"
+)
+
+type NotificationStatus string
+
+const (
+	NOTIFICATION_PENDING NotificationStatus = "PENDING"
+	NOTIFICATION_SENT    NotificationStatus = "SENT"
+	NOTIFICATION_FAILED  NotificationStatus = "FAILED"
+)
+
+type ProposalStatus string
+
+const (
+	PROPOSAL_OPEN       ProposalStatus = "OPEN"
+	PROPOSAL_ACCEPTED   ProposalStatus = "ACCEPTED"
+	PROPOSAL_REJECTED   ProposalStatus = "REJECTED"
+	PROPOSAL_SUPERSEDED ProposalStatus = "SUPERSEDED"
+	PROPOSAL_EXPIRED    ProposalStatus = "EXPIRED"
+	PROPOSAL_CANCELLED  ProposalStatus = "CANCELLED"
+)
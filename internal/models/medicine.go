@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Medicine is one sellable catalog entry. StockQuantity is what is left to
+// sell; placing an order reserves from it and cancelling returns it.
+type Medicine struct {
+	ID                   string       `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	SKU                  string       `gorm:"uniqueIndex;not null" json:"sku"`
+	Name                 string       `gorm:"index;not null" json:"name"`
+	Strength             string       `json:"strength"`
+	Form                 MedicineForm `gorm:"type:text" json:"form"`
+	Price                float64      `json:"price"`
+	RequiresPrescription bool         `gorm:"default:false" json:"requiresPrescription"`
+	StockQuantity        int          `gorm:"default:0;check:stock_quantity >= 0" json:"stockQuantity"`
+	// Active is false once discontinued; past orders still reference it.
+	Active    bool      `gorm:"default:true" json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
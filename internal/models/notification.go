@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Notification is an outbox row: a rendered message waiting for delivery.
+// Rows are written in the same transaction as the change they announce and
+// delivered by the notify worker, which retries with backoff.
+type Notification struct {
+	ID            string             `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Channel       string             `json:"channel"`
+	Recipient     string             `json:"recipient"`
+	Template      string             `json:"template"`
+	Subject       string             `json:"subject"`
+	Body          string             `gorm:"type:text" json:"body"`
+	HTMLBody      string             `gorm:"type:text" json:"-"`
+	Status        NotificationStatus `gorm:"type:text;default:'PENDING';index:idx_notifications_due,priority:1" json:"status"`
+	Attempts      int                `json:"attempts"`
+	NextAttemptAt time.Time          `gorm:"index:idx_notifications_due,priority:2" json:"nextAttemptAt"`
+	LastError     string             `json:"lastError,omitempty"`
+	SentAt        *time.Time         `json:"sentAt,omitempty"`
+	CreatedAt     time.Time          `json:"createdAt"`
+	UpdatedAt     time.Time          `json:"updatedAt"`
+}
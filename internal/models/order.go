@@ -1,18 +1,64 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 )
 
+// OrderItem is one cart line. The client sends MedicineID and Quantity; the
+// rest is copied from the catalog when the order is placed, so the order
+// keeps the price it was sold at.
+type OrderItem struct {
+	MedicineID string  `json:"medicineId"`
+	SKU        string  `json:"sku"`
+	Name       string  `json:"name"`
+	Quantity   int     `json:"quantity"`
+	UnitPrice  float64 `json:"unitPrice"`
+	LineTotal  float64 `json:"lineTotal"`
+}
+
+type OrderItems []OrderItem
+
+func (items OrderItems) Value() (driver.Value, error) {
+	if items == nil {
+		items = OrderItems{}
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (items *OrderItems) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*items = OrderItems{}
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into OrderItems", value)
+	}
+	return json.Unmarshal(raw, items)
+}
+
 type Order struct {
 	ID            string        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
 	UserID        string        `json:"userId"`
 	User          User          `gorm:"foreignKey:UserID"`
-	Items         string        `gorm:"type:jsonb" json:"items"`
+	Items         OrderItems    `gorm:"type:jsonb" json:"items"`
 	Amount        float64       `json:"amount"`
 	Status        OrderStatus   `gorm:"type:text;default:'PENDING'" json:"status"`
 	PaymentMethod PaymentMethod `gorm:"type:text;default:'ONLINE'" json:"paymentMethod"`
 	PaymentID     *string       `json:"paymentId,omitempty"`
-	CreatedAt     time.Time     `json:"createdAt"`
-	UpdatedAt     time.Time     `json:"updatedAt"`
+	// PrescriptionID backs the prescription-only lines of the order.
+	PrescriptionID *string   `gorm:"type:uuid;index" json:"prescriptionId,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
 }
@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// OTPCode holds the hashed one-time code and abuse counters for a phone
+// number or email address.
+type OTPCode struct {
+	Identifier  string    `gorm:"primaryKey" json:"identifier"`
+	CodeHash    string    `json:"-"`
+	ExpiresAt   time.Time `gorm:"index" json:"expiresAt"`
+	Attempts    int       `gorm:"default:0" json:"attempts"`
+	LastSentAt  time.Time `json:"lastSentAt"`
+	WindowStart time.Time `json:"windowStart"`
+	SendCount   int       `gorm:"default:0" json:"sendCount"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
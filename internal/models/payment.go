@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Payment tracks one gateway intent for an appointment fee or an order. It
+// only becomes SUCCEEDED on a verified gateway callback.
+type Payment struct {
+	ID            string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Provider      string         `json:"provider"`
+	IntentID      string         `gorm:"uniqueIndex;not null" json:"intentId"`
+	ClientSecret  string         `json:"-"`
+	Purpose       PaymentPurpose `gorm:"type:text" json:"purpose"`
+	UserID        string         `gorm:"index" json:"userId"`
+	AppointmentID *string        `gorm:"type:uuid;index" json:"appointmentId,omitempty"`
+	OrderID       *string        `gorm:"type:uuid;index" json:"orderId,omitempty"`
+	Amount        float64        `json:"amount"`
+	Currency      string         `json:"currency"`
+	Status        PaymentStatus  `gorm:"type:text;default:'PENDING'" json:"status"`
+	PaidAt        *time.Time     `json:"paidAt,omitempty"`
+	RefundedAt    *time.Time     `json:"refundedAt,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+}
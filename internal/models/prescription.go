@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// PrescriptionItem is one prescribed drug. MedicineID and Quantity are set
+// when the drug is in the catalog; only those lines can be ordered.
+type PrescriptionItem struct {
+	MedicineID   string `json:"medicineId,omitempty"`
+	Drug         string `json:"drug"`
+	Dose         string `json:"dose"`
+	Frequency    string `json:"frequency"`
+	Duration     string `json:"duration"`
+	Instructions string `json:"instructions,omitempty"`
+	Quantity     int    `json:"quantity,omitempty"`
+}
+
+type PrescriptionItems []PrescriptionItem
+
+func (items PrescriptionItems) Value() (driver.Value, error) {
+	if items == nil {
+		items = PrescriptionItems{}
+	}
+	b, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (items *PrescriptionItems) Scan(value interface{}) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*items = PrescriptionItems{}
+		return nil
+	case []byte:
+		raw = v
+	case string:
+		raw = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into PrescriptionItems", value)
+	}
+	return json.Unmarshal(raw, items)
+}
+
+// Prescription is issued by the treating doctor for one appointment. It can
+// back a single pharmacy order until ValidUntil.
+type Prescription struct {
+	ID              string            `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AppointmentID   string            `gorm:"index;not null" json:"appointmentId"`
+	Appointment     Appointment       `gorm:"foreignKey:AppointmentID" json:"-"`
+	DoctorProfileID string            `gorm:"index;not null" json:"doctorProfileId"`
+	PatientID       string            `gorm:"index;not null" json:"patientId"`
+	Items           PrescriptionItems `gorm:"type:jsonb" json:"items"`
+	Notes           string            `json:"notes,omitempty"`
+	ValidUntil      time.Time         `json:"validUntil"`
+	CreatedAt       time.Time         `json:"createdAt"`
+	UpdatedAt       time.Time         `json:"updatedAt"`
+}
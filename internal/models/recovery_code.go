@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use backup code for a user's TOTP second factor.
+// Only the hash is stored; the plain codes are shown once at generation.
+type RecoveryCode struct {
+	ID        string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID    string     `gorm:"index" json:"userId"`
+	User      User       `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	CodeHash  string     `gorm:"index" json:"-"`
+	UsedAt    *time.Time `json:"usedAt,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// RescheduleProposal is a request by the patient or the doctor to move an
+// appointment. At most one proposal per appointment is OPEN; a counter-proposal
+// supersedes it and points back to it through ParentID. The appointment keeps
+// its time until a proposal is accepted.
+type RescheduleProposal struct {
+	ID            string         `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	AppointmentID string         `gorm:"index" json:"appointmentId"`
+	Appointment   Appointment    `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	ParentID      *string        `json:"parentId,omitempty"`
+	ProposedBy    Role           `gorm:"type:text" json:"proposedBy"`
+	ProposerID    string         `json:"proposerId"`
+	ProposedTime  time.Time      `json:"proposedTime"`
+	OriginalTime  time.Time      `json:"originalTime"`
+	Reason        string         `json:"reason,omitempty"`
+	Status        ProposalStatus `gorm:"type:text;default:'OPEN';index" json:"status"`
+	ExpiresAt     time.Time      `json:"expiresAt"`
+	RespondedAt   *time.Time     `json:"respondedAt,omitempty"`
+	CreatedAt     time.Time      `json:"createdAt"`
+	UpdatedAt     time.Time      `json:"updatedAt"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Session is one signed-in device. The refresh token is stored hashed and
+// rotated on every use.
+type Session struct {
+	ID               string     `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	UserID           string     `gorm:"index" json:"userId"`
+	User             User       `gorm:"constraint:OnDelete:CASCADE" json:"-"`
+	RefreshTokenHash string     `json:"-"`
+	Device           string     `json:"device"`
+	IPAddress        string     `json:"ipAddress"`
+	ExpiresAt        time.Time  `json:"expiresAt"`
+	LastUsedAt       time.Time  `json:"lastUsedAt"`
+	StepUpAt         *time.Time `json:"stepUpAt,omitempty"`
+	RevokedAt        *time.Time `json:"revokedAt,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+}
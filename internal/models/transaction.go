@@ -1,5 +1,34 @@
 package models
 
+import "time"
+
+// Transaction is one balanced journal entry in the ledger: the amounts of
+// its Entries sum to zero. Rows are never updated; a correction is a new
+// transaction.
 type Transaction struct {
-	//
+	ID              string          `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	Kind            TransactionKind `gorm:"type:text;index;not null" json:"kind"`
+	PaymentID       *string         `gorm:"type:uuid;index" json:"paymentId,omitempty"`
+	AppointmentID   *string         `gorm:"type:uuid;index" json:"appointmentId,omitempty"`
+	OrderID         *string         `gorm:"type:uuid;index" json:"orderId,omitempty"`
+	DoctorProfileID *string         `gorm:"type:uuid;index" json:"doctorProfileId,omitempty"`
+	Currency        string          `json:"currency"`
+	Description     string          `json:"description"`
+	// Reference is an external reference, e.g. the bank transfer of a payout.
+	Reference  string        `json:"reference,omitempty"`
+	OccurredAt time.Time     `gorm:"index" json:"occurredAt"`
+	Entries    []LedgerEntry `gorm:"foreignKey:TransactionID" json:"entries"`
+	CreatedAt  time.Time     `json:"createdAt"`
+}
+
+// LedgerEntry moves Amount minor units (e.g. paise) on one account. Debits
+// are positive and credits negative. DoctorProfileID names the doctor for
+// DOCTOR_PAYABLE entries.
+type LedgerEntry struct {
+	ID              string        `gorm:"type:uuid;default:gen_random_uuid();primaryKey" json:"id"`
+	TransactionID   string        `gorm:"type:uuid;index;not null" json:"transactionId"`
+	Account         LedgerAccount `gorm:"type:text;index;not null" json:"account"`
+	DoctorProfileID *string       `gorm:"type:uuid;index" json:"doctorProfileId,omitempty"`
+	Amount          int64         `gorm:"not null" json:"amount"`
+	CreatedAt       time.Time     `json:"createdAt"`
 }
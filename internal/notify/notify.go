@@ -0,0 +1,75 @@
+// Package notify delivers user-facing messages. Callers render a named
+// template into Messages and either send them at once through a Notifier
+// (OTP codes) or queue them in the outbox, which a Worker delivers with
+// retries (everything else).
+package notify
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/GitNinja36/wello-backend/internal/mailer"
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+type Channel string
+
+const (
+	Email Channel = "EMAIL"
+	SMS   Channel = "SMS"
+)
+
+var ErrNoProvider = errors.New("no notification provider for channel")
+
+// Message is one rendered notification for one recipient on one channel.
+// HTML and Attachments are used by email only; attachments cannot be queued
+// and must be sent directly.
+type Message struct {
+	Channel     Channel
+	To          string
+	Template    string
+	Subject     string
+	Body        string
+	HTML        string
+	Attachments []mailer.Attachment
+}
+
+// Notifier delivers a message synchronously.
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// Router sends each message with the provider registered for its channel.
+type Router map[Channel]Notifier
+
+func (r Router) Send(msg Message) error {
+	n, ok := r[msg.Channel]
+	if !ok || n == nil {
+		return fmt.Errorf("%w %s", ErrNoProvider, msg.Channel)
+	}
+	return n.Send(msg)
+}
+
+// Recipient is where a person can be reached; empty fields are skipped.
+type Recipient struct {
+	Email string
+	Phone string
+}
+
+func RecipientOf(u *models.User) Recipient {
+	if u == nil {
+		return Recipient{}
+	}
+	return Recipient{Email: u.Email, Phone: u.Phone}
+}
+
+// SendAll sends msgs and returns the first error, after trying every message.
+func SendAll(n Notifier, msgs []Message) error {
+	var first error
+	for _, m := range msgs {
+		if err := n.Send(m); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
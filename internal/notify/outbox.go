@@ -0,0 +1,345 @@
+package notify
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrAttachmentNotQueued = errors.New("messages with attachments cannot be queued")
+
+// Queue accepts messages for later delivery.
+type Queue interface {
+	Enqueue(msgs ...Message) error
+}
+
+// Outbox is the store the worker delivers from. Queue writes to it.
+type Outbox interface {
+	Queue
+	// Claim returns up to limit PENDING rows due at now, oldest first, and
+	// hides them from other claims until now+lease.
+	Claim(now time.Time, lease time.Duration, limit int) ([]models.Notification, error)
+	// Extend hides the claimed rows ids until until.
+	Extend(ids []string, until time.Time) error
+	// Record stores the outcome of a delivery attempt of n.
+	Record(n *models.Notification) error
+}
+
+type gormQueue struct {
+	db *gorm.DB
+}
+
+// NewGormQueue writes messages to the notifications outbox. Pass a
+// transaction to make the enqueue commit or roll back with it.
+func NewGormQueue(db *gorm.DB) Queue {
+	return gormQueue{db: db}
+}
+
+// NewGormOutbox is the notifications table for the worker. Several workers
+// may share it; rows are claimed with SKIP LOCKED.
+func NewGormOutbox(db *gorm.DB) Outbox {
+	return gormQueue{db: db}
+}
+
+func (q gormQueue) Enqueue(msgs ...Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	rows, err := outboxRows(msgs, utils.CurrentTime())
+	if err != nil {
+		return err
+	}
+	return q.db.Create(&rows).Error
+}
+
+func (q gormQueue) Claim(now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	var rows []models.Notification
+	err := q.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.NOTIFICATION_PENDING, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&rows).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		ids := make([]string, len(rows))
+		for i := range rows {
+			rows[i].NextAttemptAt = now.Add(lease)
+			ids[i] = rows[i].ID
+		}
+		return tx.Model(&models.Notification{}).Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).Error
+	})
+	return rows, err
+}
+
+func (q gormQueue) Extend(ids []string, until time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return q.db.Model(&models.Notification{}).
+		Where("id IN ? AND status = ?", ids, models.NOTIFICATION_PENDING).
+		Update("next_attempt_at", until).Error
+}
+
+func (q gormQueue) Record(n *models.Notification) error {
+	return q.db.Model(&models.Notification{}).Where("id = ?", n.ID).Updates(map[string]interface{}{
+		"status":          n.Status,
+		"attempts":        n.Attempts,
+		"next_attempt_at": n.NextAttemptAt,
+		"last_error":      n.LastError,
+		"sent_at":         n.SentAt,
+	}).Error
+}
+
+// outboxRows turns msgs into PENDING rows due at now.
+func outboxRows(msgs []Message, now time.Time) ([]models.Notification, error) {
+	rows := make([]models.Notification, 0, len(msgs))
+	for _, m := range msgs {
+		if len(m.Attachments) > 0 {
+			return nil, ErrAttachmentNotQueued
+		}
+		rows = append(rows, models.Notification{
+			Channel:       string(m.Channel),
+			Recipient:     m.To,
+			Template:      m.Template,
+			Subject:       m.Subject,
+			Body:          m.Body,
+			HTMLBody:      m.HTML,
+			Status:        models.NOTIFICATION_PENDING,
+			NextAttemptAt: now,
+		})
+	}
+	return rows, nil
+}
+
+// MemoryQueue keeps enqueued messages in memory, for tests. It is also an
+// Outbox, so a Worker can deliver from it.
+type MemoryQueue struct {
+	mu   sync.Mutex
+	msgs []Message
+	rows []models.Notification
+}
+
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{}
+}
+
+func (q *MemoryQueue) Enqueue(msgs ...Message) error {
+	rows, err := outboxRows(msgs, utils.CurrentTime())
+	if err != nil {
+		return err
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for i := range rows {
+		rows[i].ID = uuid.NewString()
+		rows[i].CreatedAt = rows[i].NextAttemptAt
+	}
+	q.msgs = append(q.msgs, msgs...)
+	q.rows = append(q.rows, rows...)
+	return nil
+}
+
+// Messages returns a copy of everything enqueued so far.
+func (q *MemoryQueue) Messages() []Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]Message(nil), q.msgs...)
+}
+
+// Rows returns a copy of the outbox rows.
+func (q *MemoryQueue) Rows() []models.Notification {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]models.Notification(nil), q.rows...)
+}
+
+func (q *MemoryQueue) Claim(now time.Time, lease time.Duration, limit int) ([]models.Notification, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	var due []int
+	for i, n := range q.rows {
+		if n.Status == models.NOTIFICATION_PENDING && !n.NextAttemptAt.After(now) {
+			due = append(due, i)
+		}
+	}
+	sort.SliceStable(due, func(a, b int) bool {
+		return q.rows[due[a]].NextAttemptAt.Before(q.rows[due[b]].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	claimed := make([]models.Notification, 0, len(due))
+	for _, i := range due {
+		q.rows[i].NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, q.rows[i])
+	}
+	return claimed, nil
+}
+
+func (q *MemoryQueue) Extend(ids []string, until time.Time) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, id := range ids {
+		if i := q.find(id); i >= 0 && q.rows[i].Status == models.NOTIFICATION_PENDING {
+			q.rows[i].NextAttemptAt = until
+		}
+	}
+	return nil
+}
+
+func (q *MemoryQueue) Record(n *models.Notification) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	i := q.find(n.ID)
+	if i < 0 {
+		return gorm.ErrRecordNotFound
+	}
+	q.rows[i].Status = n.Status
+	q.rows[i].Attempts = n.Attempts
+	q.rows[i].NextAttemptAt = n.NextAttemptAt
+	q.rows[i].LastError = n.LastError
+	q.rows[i].SentAt = n.SentAt
+	return nil
+}
+
+func (q *MemoryQueue) find(id string) int {
+	for i := range q.rows {
+		if q.rows[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+const (
+	DefaultBatchSize   = 50
+	DefaultMaxAttempts = 6
+	// claimLease is how long a claimed row stays invisible to other workers
+	// while it is being sent.
+	claimLease = 2 * time.Minute
+)
+
+// DefaultBackoff doubles from 30s per failed attempt, capped at an hour.
+func DefaultBackoff(attempt int) time.Duration {
+	d := 30 * time.Second
+	for i := 1; i < attempt && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return d
+}
+
+// Worker delivers pending outbox rows. Several workers may share an
+// Outbox; each claims a batch under a short lease, which it keeps renewing
+// while it works through the batch.
+type Worker struct {
+	Outbox      Outbox
+	Notifier    Notifier
+	BatchSize   int
+	MaxAttempts int
+	Backoff     func(attempt int) time.Duration
+	Now         func() time.Time
+}
+
+func NewWorker(outbox Outbox, n Notifier) *Worker {
+	return &Worker{
+		Outbox:      outbox,
+		Notifier:    n,
+		BatchSize:   DefaultBatchSize,
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+		Now:         utils.CurrentTime,
+	}
+}
+
+// Run calls RunOnce every interval, forever.
+func (w *Worker) Run(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := w.RunOnce(); err != nil {
+			log.Printf("Notification delivery failed: %v", err)
+		}
+	}
+}
+
+// RunOnce claims a batch of due rows, sends them and records the outcome.
+// It returns the number of rows attempted. Once half the lease has passed,
+// the rows not yet sent are leased again, so a slow provider cannot let
+// another worker claim and send them too.
+func (w *Worker) RunOnce() (int, error) {
+	rows, err := w.Outbox.Claim(w.Now(), claimLease, w.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	leasedUntil := rows[0].NextAttemptAt
+
+	for i := range rows {
+		if now := w.Now(); !now.Before(leasedUntil.Add(-claimLease / 2)) {
+			if err := w.Outbox.Extend(notificationIDs(rows[i:]), now.Add(claimLease)); err != nil {
+				return i, err
+			}
+			leasedUntil = now.Add(claimLease)
+		}
+
+		n := &rows[i]
+		sendErr := w.Notifier.Send(Message{
+			Channel:  Channel(n.Channel),
+			To:       n.Recipient,
+			Template: n.Template,
+			Subject:  n.Subject,
+			Body:     n.Body,
+			HTML:     n.HTMLBody,
+		})
+		w.outcome(n, sendErr)
+		if err := w.Outbox.Record(n); err != nil {
+			return i, err
+		}
+	}
+	return len(rows), nil
+}
+
+// outcome records one delivery attempt in n: sent, retried after the
+// backoff, or failed for good after MaxAttempts.
+func (w *Worker) outcome(n *models.Notification, sendErr error) {
+	now := w.Now()
+	n.Attempts++
+	switch {
+	case sendErr == nil:
+		n.Status = models.NOTIFICATION_SENT
+		n.SentAt = &now
+		n.LastError = ""
+	case n.Attempts >= w.MaxAttempts:
+		n.Status = models.NOTIFICATION_FAILED
+		n.LastError = sendErr.Error()
+	default:
+		n.NextAttemptAt = now.Add(w.Backoff(n.Attempts))
+		n.LastError = sendErr.Error()
+	}
+}
+
+func notificationIDs(rows []models.Notification) []string {
+	ids := make([]string, len(rows))
+	for i := range rows {
+		ids[i] = rows[i].ID
+	}
+	return ids
+}
@@ -0,0 +1,235 @@
+package notify
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/mailer"
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+func TestDefaultBackoff(t *testing.T) {
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{50, time.Hour},
+	}
+	for _, tc := range tests {
+		if got := DefaultBackoff(tc.attempt); got != tc.want {
+			t.Errorf("attempt %d: got %s, want %s", tc.attempt, got, tc.want)
+		}
+	}
+}
+
+func TestWorkerOutcome(t *testing.T) {
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	w := &Worker{MaxAttempts: 3, Backoff: DefaultBackoff, Now: func() time.Time { return now }}
+	down := errors.New("smtp: connection refused")
+	due := now.Add(-time.Minute)
+
+	tests := []struct {
+		name      string
+		attempts  int
+		sendErr   error
+		wantState models.NotificationStatus
+		wantNext  time.Time
+	}{
+		{"first try sent", 0, nil, models.NOTIFICATION_SENT, due},
+		{"retry sent", 2, nil, models.NOTIFICATION_SENT, due},
+		{"first failure backs off", 0, down, models.NOTIFICATION_PENDING, now.Add(30 * time.Second)},
+		{"second failure backs off longer", 1, down, models.NOTIFICATION_PENDING, now.Add(time.Minute)},
+		{"last attempt fails for good", 2, down, models.NOTIFICATION_FAILED, due},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			n := &models.Notification{Status: models.NOTIFICATION_PENDING, Attempts: tc.attempts, NextAttemptAt: due}
+			w.outcome(n, tc.sendErr)
+			if n.Attempts != tc.attempts+1 {
+				t.Errorf("attempts %d, want %d", n.Attempts, tc.attempts+1)
+			}
+			if n.Status != tc.wantState {
+				t.Errorf("status %s, want %s", n.Status, tc.wantState)
+			}
+			if !n.NextAttemptAt.Equal(tc.wantNext) {
+				t.Errorf("next attempt %v, want %v", n.NextAttemptAt, tc.wantNext)
+			}
+			wantErr := ""
+			if tc.sendErr != nil {
+				wantErr = tc.sendErr.Error()
+			}
+			if n.LastError != wantErr {
+				t.Errorf("last error %q, want %q", n.LastError, wantErr)
+			}
+			if sent := n.SentAt != nil; sent != (tc.sendErr == nil) {
+				t.Errorf("sent_at set = %v", sent)
+			}
+		})
+	}
+}
+
+// clock is a settable Now for workers.
+type clock struct{ now time.Time }
+
+func (c *clock) Now() time.Time { return c.now }
+
+func newTestWorker(q *MemoryQueue, n Notifier, c *clock) *Worker {
+	w := NewWorker(q, n)
+	w.Now = c.Now
+	return w
+}
+
+func enqueueN(t *testing.T, q Queue, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := q.Enqueue(Message{Channel: Email, To: "a@example.com", Template: "otp", Subject: "Code", Body: "123456"}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+}
+
+func TestWorkerDeliversQueuedMessages(t *testing.T) {
+	q := NewMemoryQueue()
+	enqueueN(t, q, 3)
+	notifier := NewMemoryNotifier()
+	c := &clock{now: time.Now().Add(time.Second)}
+	w := newTestWorker(q, notifier, c)
+	w.BatchSize = 2
+
+	if n, err := w.RunOnce(); err != nil || n != 2 {
+		t.Fatalf("first run = %d, %v; want a batch of 2", n, err)
+	}
+	if n, err := w.RunOnce(); err != nil || n != 1 {
+		t.Fatalf("second run = %d, %v; want the last row", n, err)
+	}
+	if n, err := w.RunOnce(); err != nil || n != 0 {
+		t.Fatalf("third run = %d, %v; want nothing due", n, err)
+	}
+
+	if got := len(notifier.Sent()); got != 3 {
+		t.Fatalf("sent %d messages, want 3", got)
+	}
+	for _, row := range q.Rows() {
+		if row.Status != models.NOTIFICATION_SENT || row.Attempts != 1 || row.SentAt == nil {
+			t.Errorf("row %s: status %s, attempts %d", row.ID, row.Status, row.Attempts)
+		}
+	}
+}
+
+func TestWorkerRetriesThenFails(t *testing.T) {
+	q := NewMemoryQueue()
+	enqueueN(t, q, 1)
+	notifier := NewMemoryNotifier()
+	notifier.Err = errors.New("provider down")
+	c := &clock{now: time.Now().Add(time.Second)}
+	w := newTestWorker(q, notifier, c)
+	w.MaxAttempts = 2
+
+	if n, err := w.RunOnce(); err != nil || n != 1 {
+		t.Fatalf("first run = %d, %v", n, err)
+	}
+	row := q.Rows()[0]
+	if row.Status != models.NOTIFICATION_PENDING || row.Attempts != 1 || !row.NextAttemptAt.Equal(c.now.Add(30*time.Second)) {
+		t.Fatalf("after one failure: status %s, attempts %d, next %v", row.Status, row.Attempts, row.NextAttemptAt)
+	}
+
+	// not due again until the backoff has passed
+	c.now = c.now.Add(29 * time.Second)
+	if n, _ := w.RunOnce(); n != 0 {
+		t.Fatalf("retried %d rows during the backoff", n)
+	}
+
+	c.now = c.now.Add(time.Second)
+	if n, err := w.RunOnce(); err != nil || n != 1 {
+		t.Fatalf("retry = %d, %v", n, err)
+	}
+	row = q.Rows()[0]
+	if row.Status != models.NOTIFICATION_FAILED || row.LastError != "provider down" {
+		t.Fatalf("last attempt: status %s, error %q", row.Status, row.LastError)
+	}
+
+	c.now = c.now.Add(time.Hour)
+	if n, _ := w.RunOnce(); n != 0 {
+		t.Fatal("failed row attempted again")
+	}
+}
+
+func TestClaimHidesRowsUntilTheLeaseEnds(t *testing.T) {
+	q := NewMemoryQueue()
+	enqueueN(t, q, 2)
+	now := time.Now().Add(time.Second)
+
+	rows, err := q.Claim(now, claimLease, 10)
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("claim = %d, %v", len(rows), err)
+	}
+	if again, _ := q.Claim(now.Add(claimLease-time.Second), claimLease, 10); len(again) != 0 {
+		t.Fatalf("claimed %d leased rows", len(again))
+	}
+	// a worker that died holding them loses them when the lease ends
+	if again, _ := q.Claim(now.Add(claimLease), claimLease, 10); len(again) != 2 {
+		t.Fatalf("reclaimed %d rows after the lease, want 2", len(again))
+	}
+}
+
+// slowNotifier takes step of the clock per send and runs during every send.
+type slowNotifier struct {
+	c      *clock
+	step   time.Duration
+	during func()
+	sent   int
+}
+
+func (n *slowNotifier) Send(Message) error {
+	n.c.now = n.c.now.Add(n.step)
+	n.during()
+	n.sent++
+	return nil
+}
+
+// A batch that takes longer than the lease keeps its rows: another worker
+// never claims a row the first one has yet to send.
+func TestWorkerKeepsTheLeaseOnASlowBatch(t *testing.T) {
+	q := NewMemoryQueue()
+	enqueueN(t, q, 5)
+	c := &clock{now: time.Now().Add(time.Second)}
+
+	stolen := 0
+	notifier := &slowNotifier{c: c, step: claimLease / 2, during: func() {
+		rows, err := q.Claim(c.now, claimLease, 10)
+		if err != nil {
+			t.Fatalf("claim: %v", err)
+		}
+		stolen += len(rows)
+	}}
+	w := newTestWorker(q, notifier, c)
+
+	if n, err := w.RunOnce(); err != nil || n != 5 {
+		t.Fatalf("RunOnce = %d, %v", n, err)
+	}
+	if stolen != 0 {
+		t.Fatalf("another worker claimed %d rows mid-batch", stolen)
+	}
+	for _, row := range q.Rows() {
+		if row.Status != models.NOTIFICATION_SENT || row.Attempts != 1 {
+			t.Errorf("row %s: status %s, attempts %d", row.ID, row.Status, row.Attempts)
+		}
+	}
+}
+
+func TestMemoryQueueRejectsAttachments(t *testing.T) {
+	q := NewMemoryQueue()
+	err := q.Enqueue(Message{Channel: Email, To: "a@example.com", Attachments: []mailer.Attachment{{Filename: "x.pdf"}}})
+	if !errors.Is(err, ErrAttachmentNotQueued) {
+		t.Fatalf("got %v, want ErrAttachmentNotQueued", err)
+	}
+	if len(q.Rows()) != 0 {
+		t.Fatal("queued a row")
+	}
+}
@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"log"
+	"os"
+	"sync"
+
+	"github.com/GitNinja36/wello-backend/internal/mailer"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+// SMTPNotifier sends email through an SMTP client, with the HTML body as an
+// alternative to the text when the template has one.
+type SMTPNotifier struct {
+	Client *mailer.Client
+}
+
+func (n SMTPNotifier) Send(msg Message) error {
+	return n.Client.Send(mailer.Message{
+		To:          []string{msg.To},
+		Subject:     msg.Subject,
+		Text:        msg.Body,
+		HTML:        msg.HTML,
+		Attachments: msg.Attachments,
+	})
+}
+
+// TwilioNotifier sends SMS through Twilio.
+type TwilioNotifier struct{}
+
+func (TwilioNotifier) Send(msg Message) error {
+	return utils.SendSMS(msg.To, msg.Body)
+}
+
+// LogNotifier writes messages to a logger instead of delivering them. Useful
+// in development, where OTP codes then show up in the server log.
+type LogNotifier struct {
+	Logger *log.Logger
+}
+
+func (n LogNotifier) Send(msg Message) error {
+	logger := n.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	logger.Printf("[notify] %s to %s (%s, %d attachments): %s\n%s",
+		msg.Channel, msg.To, msg.Template, len(msg.Attachments), msg.Subject, msg.Body)
+	return nil
+}
+
+// MemoryNotifier records messages for tests. Setting Err makes every send fail.
+type MemoryNotifier struct {
+	mu   sync.Mutex
+	sent []Message
+	Err  error
+}
+
+func NewMemoryNotifier() *MemoryNotifier {
+	return &MemoryNotifier{}
+}
+
+func (n *MemoryNotifier) Send(msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.Err != nil {
+		return n.Err
+	}
+	n.sent = append(n.sent, msg)
+	return nil
+}
+
+// Sent returns a copy of the delivered messages.
+func (n *MemoryNotifier) Sent() []Message {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]Message(nil), n.sent...)
+}
+
+// FromEnv builds the production router, with SMTP configured by
+// mailer.ConfigFromEnv. NOTIFY_PROVIDER=log routes every channel to the log
+// instead.
+func FromEnv() (Notifier, error) {
+	if os.Getenv("NOTIFY_PROVIDER") == "log" {
+		return Router{Email: LogNotifier{}, SMS: LogNotifier{}}, nil
+	}
+	cfg, err := mailer.ConfigFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return Router{Email: SMTPNotifier{Client: mailer.NewClient(cfg)}, SMS: TwilioNotifier{}}, nil
+}
@@ -0,0 +1,237 @@
+package notify
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+// Template names. Appointment templates are referenced by name from the
+// domain transition table.
+const (
+	TemplateOTP                 = "otp"
+	TemplateAppointmentBooked   = "appointment.booked"
+	TemplateAppointmentAccepted = "appointment.accepted"
+	TemplateAppointmentRejected = "appointment.rejected"
+	TemplateRescheduleRequested = "appointment.reschedule_requested"
+	TemplateRescheduleAccepted  = "appointment.reschedule_accepted"
+	TemplateRescheduleRejected  = "appointment.reschedule_rejected"
+	TemplateCancelledByPatient  = "appointment.cancelled_by_patient"
+	TemplateCancelledByDoctor   = "appointment.cancelled_by_doctor"
+	TemplateNoShowPatient       = "appointment.no_show_patient"
+	TemplateNoShowDoctor        = "appointment.no_show_doctor"
+	TemplateAppointmentExpired  = "appointment.expired"
+	TemplateReminderPatient     = "appointment.reminder_patient"
+	TemplateReminderDoctor      = "appointment.reminder_doctor"
+	TemplateAppointmentSummary  = "appointment.summary"
+	TemplateTestReported        = "test.reported"
+	TemplatePrescriptionIssued  = "prescription.issued"
+)
+
+var ErrUnknownTemplate = errors.New("unknown notification template")
+
+// Template is a text/template for the email subject and body, plus an
+// optional shorter SMS body (the email body is used when empty) and an
+// optional html/template email body sent as an alternative to the text.
+type Template struct {
+	Subject string
+	Body    string
+	SMS     string
+	HTML    string
+}
+
+type parsedTemplate struct {
+	subject, body, sms *template.Template
+	html               *htmltemplate.Template
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]parsedTemplate{}
+)
+
+// Register adds or replaces a template. It panics on syntax errors, like
+// template.Must, since templates are defined at startup.
+func Register(name string, t Template) {
+	p := parsedTemplate{
+		subject: template.Must(template.New(name + ".subject").Option("missingkey=error").Parse(t.Subject)),
+		body:    template.Must(template.New(name + ".body").Option("missingkey=error").Parse(t.Body)),
+	}
+	if t.SMS != "" {
+		p.sms = template.Must(template.New(name + ".sms").Option("missingkey=error").Parse(t.SMS))
+	}
+	if t.HTML != "" {
+		p.html = htmltemplate.Must(htmltemplate.New(name + ".html").Option("missingkey=error").Parse(t.HTML))
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = p
+}
+
+// Compose renders template name with data into one message per channel the
+// recipient can be reached on.
+func Compose(name string, data map[string]interface{}, to Recipient) ([]Message, error) {
+	registryMu.RLock()
+	p, ok := registry[name]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownTemplate, name)
+	}
+
+	subject, err := execute(p.subject, data)
+	if err != nil {
+		return nil, err
+	}
+	body, err := execute(p.body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []Message
+	if to.Email != "" {
+		html := ""
+		if p.html != nil {
+			if html, err = execute(p.html, data); err != nil {
+				return nil, err
+			}
+		}
+		msgs = append(msgs, Message{Channel: Email, To: to.Email, Template: name, Subject: subject, Body: body, HTML: html})
+	}
+	if to.Phone != "" {
+		text := body
+		if p.sms != nil {
+			if text, err = execute(p.sms, data); err != nil {
+				return nil, err
+			}
+		}
+		msgs = append(msgs, Message{Channel: SMS, To: to.Phone, Template: name, Subject: subject, Body: text})
+	}
+	return msgs, nil
+}
+
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+func execute(t executor, data map[string]interface{}) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// AppointmentData is the template data for appointment notifications. The
+// time is shown in the doctor's timezone.
+func AppointmentData(a *models.Appointment) map[string]interface{} {
+	reason := ""
+	if a.StatusReason != nil {
+		reason = *a.StatusReason
+	}
+	doctorName := ""
+	if a.DoctorProfile.User != nil {
+		doctorName = a.DoctorProfile.User.Name
+	}
+	return map[string]interface{}{
+		"AppointmentID": a.ID,
+		"PatientName":   a.Patient.Name,
+		"DoctorName":    doctorName,
+		"When":          FormatTime(a, a.ScheduledAt),
+		"Mode":          string(a.Mode),
+		"Reason":        reason,
+	}
+}
+
+// FormatTime formats t for messages about a, in the doctor's timezone.
+func FormatTime(a *models.Appointment, t time.Time) string {
+	loc, err := time.LoadLocation(a.DoctorProfile.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	return t.In(loc).Format("Jan 2, 2006 3:04 PM MST")
+}
+
+func init() {
+	Register(TemplateOTP, Template{
+		Subject: "Your Wello OTP",
+		Body:    "Hi,\nYour OTP is: {{.Code}}\n\nThis OTP is valid for 5 minutes.\n\nDo not share it with anyone.\n\nThanks,\nWello",
+		SMS:     "Your Wello OTP is: {{.Code}}",
+		HTML:    `<p>Hi,</p><p>Your OTP is: <strong>{{.Code}}</strong></p><p>This OTP is valid for 5 minutes. Do not share it with anyone.</p><p>Thanks,<br>Wello</p>`,
+	})
+	Register(TemplateAppointmentBooked, Template{
+		Subject: "New Appointment Request",
+		Body:    "{{.PatientName}} requested an appointment on {{.When}}. Please accept or reject it from your dashboard.",
+	})
+	Register(TemplateAppointmentAccepted, Template{
+		Subject: "Appointment Accepted",
+		Body:    "Your appointment on {{.When}} has been accepted by the doctor.",
+	})
+	Register(TemplateAppointmentRejected, Template{
+		Subject: "Appointment Rejected",
+		Body:    "Your appointment request for {{.When}} was rejected by the doctor.",
+	})
+	Register(TemplateRescheduleRequested, Template{
+		Subject: "Reschedule Proposal",
+		Body:    "The {{.ProposerRole}} proposed moving your appointment on {{.When}} to {{.ProposedWhen}}.{{if .Reason}} Reason: {{.Reason}}.{{end}} Please accept, reject or propose another time.",
+	})
+	Register(TemplateRescheduleAccepted, Template{
+		Subject: "Reschedule Accepted",
+		Body:    "Your reschedule proposal was accepted. The appointment is now on {{.When}}.",
+	})
+	Register(TemplateRescheduleRejected, Template{
+		Subject: "Reschedule Rejected",
+		Body:    "Your proposal to move the appointment to {{.ProposedWhen}} was rejected. The appointment stays on {{.When}}.",
+	})
+	Register(TemplateCancelledByPatient, Template{
+		Subject: "Appointment Cancelled",
+		Body:    "Your appointment with the patient on {{.When}} has been cancelled by the patient.",
+	})
+	Register(TemplateCancelledByDoctor, Template{
+		Subject: "Appointment Cancelled by Doctor",
+		Body:    "Your appointment on {{.When}} has been cancelled by the doctor.{{if .Reason}} Reason: {{.Reason}}.{{end}} Please book a new slot.",
+	})
+	Register(TemplateTestReported, Template{
+		Subject: "Your Test Report Is Ready",
+		Body:    "The report for your {{.TestType}} test is ready: {{.ReportURL}}",
+		SMS:     "Your Wello {{.TestType}} test report is ready. Open the app to view it.",
+		HTML:    `<p>The report for your {{.TestType}} test is ready.</p><p><a href="{{.ReportURL}}">View report</a></p>`,
+	})
+	Register(TemplateNoShowPatient, Template{
+		Subject: "Missed Appointment",
+		Body:    "You were marked as not attending your appointment with {{.DoctorName}} on {{.When}}. Reason: {{.Reason}}. Repeated missed appointments may restrict new bookings.",
+	})
+	Register(TemplateNoShowDoctor, Template{
+		Subject: "Missed Appointment Reported",
+		Body:    "{{.PatientName}} reported that you did not attend the appointment on {{.When}}. Reason: {{.Reason}}.",
+	})
+	Register(TemplateAppointmentExpired, Template{
+		Subject: "Appointment Request Expired",
+		Body:    "The appointment request between {{.PatientName}} and {{.DoctorName}} for {{.When}} expired without a response. Please book a new slot if you still need one.",
+	})
+	Register(TemplateReminderPatient, Template{
+		Subject: "Appointment Reminder",
+		Body:    "Reminder: your appointment with {{.DoctorName}} is {{.Lead}}, on {{.When}}.",
+	})
+	Register(TemplateReminderDoctor, Template{
+		Subject: "Appointment Reminder",
+		Body:    "Reminder: your appointment with {{.PatientName}} is {{.Lead}}, on {{.When}}.",
+	})
+	Register(TemplateAppointmentSummary, Template{
+		Subject: "Your Appointment Summary",
+		Body:    "Hi {{.PatientName}},\nThe summary of your appointment on {{.When}} is attached.\n\nThanks,\nWello",
+	})
+	Register(TemplatePrescriptionIssued, Template{
+		Subject: "New Prescription",
+		Body:    "{{.DoctorName}} issued a prescription after your appointment on {{.When}}. It is valid until {{.ValidUntil}} and can be ordered from the app.",
+		SMS:     "{{.DoctorName}} sent you a prescription. Open the Wello app to view or order it.",
+	})
+}
@@ -0,0 +1,109 @@
+package notify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func init() {
+	Register("test.compose", Template{
+		Subject: "Hello {{.Name}}",
+		Body:    "  Dear {{.Name}}, see {{.Link}}  ",
+		HTML:    `<p>Dear <b>{{.Name}}</b>, <a href="{{.Link}}">see here</a></p>`,
+	})
+}
+
+func TestCompose(t *testing.T) {
+	data := map[string]interface{}{"Name": "<Asha>", "Link": "https://example.com/r?a=1&b=2"}
+	both := Recipient{Email: "asha@example.com", Phone: "+911234567890"}
+
+	msgs, err := Compose("test.compose", data, both)
+	if err != nil {
+		t.Fatalf("compose: %v", err)
+	}
+	if len(msgs) != 2 || msgs[0].Channel != Email || msgs[1].Channel != SMS {
+		t.Fatalf("got %+v, want an email then an SMS", msgs)
+	}
+	email, sms := msgs[0], msgs[1]
+	if email.To != both.Email || sms.To != both.Phone {
+		t.Errorf("recipients %q and %q", email.To, sms.To)
+	}
+	if email.Template != "test.compose" || email.Subject != "Hello <Asha>" {
+		t.Errorf("email template %q subject %q", email.Template, email.Subject)
+	}
+	if email.Body != "Dear <Asha>, see https://example.com/r?a=1&b=2" {
+		t.Errorf("text body %q, want trimmed and unescaped", email.Body)
+	}
+	if !strings.Contains(email.HTML, "<b>&lt;Asha&gt;</b>") || !strings.Contains(email.HTML, "a=1&amp;b=2") {
+		t.Errorf("HTML body %q, want escaped values", email.HTML)
+	}
+	if sms.Body != email.Body || sms.HTML != "" {
+		t.Errorf("SMS without its own template: body %q html %q", sms.Body, sms.HTML)
+	}
+}
+
+func TestComposeChannels(t *testing.T) {
+	tests := []struct {
+		name string
+		to   Recipient
+		want []Channel
+	}{
+		{"email only", Recipient{Email: "a@example.com"}, []Channel{Email}},
+		{"phone only", Recipient{Phone: "+911234567890"}, []Channel{SMS}},
+		{"both", Recipient{Email: "a@example.com", Phone: "+911234567890"}, []Channel{Email, SMS}},
+		{"unreachable", Recipient{}, nil},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			msgs, err := Compose(TemplateOTP, map[string]interface{}{"Code": "123456"}, tc.to)
+			if err != nil {
+				t.Fatalf("compose: %v", err)
+			}
+			if len(msgs) != len(tc.want) {
+				t.Fatalf("got %d messages, want %d", len(msgs), len(tc.want))
+			}
+			for i, m := range msgs {
+				if m.Channel != tc.want[i] {
+					t.Errorf("message %d on %s, want %s", i, m.Channel, tc.want[i])
+				}
+				if m.Channel == SMS && m.Body != "Your Wello OTP is: 123456" {
+					t.Errorf("SMS body %q, want the SMS template", m.Body)
+				}
+				if m.Channel == Email && (!strings.Contains(m.Body, "123456") || !strings.Contains(m.HTML, "<strong>123456</strong>")) {
+					t.Errorf("email body %q html %q", m.Body, m.HTML)
+				}
+			}
+		})
+	}
+}
+
+func TestComposeErrors(t *testing.T) {
+	to := Recipient{Email: "a@example.com"}
+	if _, err := Compose("no.such.template", nil, to); !errors.Is(err, ErrUnknownTemplate) {
+		t.Fatalf("got %v, want ErrUnknownTemplate", err)
+	}
+	if _, err := Compose("test.compose", map[string]interface{}{"Name": "Asha"}, to); err == nil {
+		t.Fatal("missing template data rendered without error")
+	}
+}
+
+// Every template the services use must render with the data they pass.
+func TestAppointmentTemplatesRender(t *testing.T) {
+	data := map[string]interface{}{
+		"AppointmentID": "a1", "PatientName": "Asha", "DoctorName": "Dr Rao",
+		"When": "Mar 10, 2025 9:00 AM IST", "Mode": "VIDEO", "Reason": "",
+		"ProposerRole": "doctor", "ProposedWhen": "Mar 11, 2025 9:00 AM IST", "Lead": "in 1 hour",
+	}
+	for _, name := range []string{
+		TemplateAppointmentBooked, TemplateAppointmentAccepted, TemplateAppointmentRejected,
+		TemplateRescheduleRequested, TemplateRescheduleAccepted, TemplateRescheduleRejected,
+		TemplateCancelledByPatient, TemplateCancelledByDoctor, TemplateNoShowPatient,
+		TemplateNoShowDoctor, TemplateAppointmentExpired, TemplateReminderPatient,
+		TemplateReminderDoctor, TemplateAppointmentSummary,
+	} {
+		if _, err := Compose(name, data, Recipient{Email: "a@example.com", Phone: "+911234567890"}); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package otp
+
+import (
+	"sync"
+	"time"
+)
+
+type memoryEntry struct {
+	hash        string
+	expires     time.Time
+	attempts    int
+	lastSent    time.Time
+	windowStart time.Time
+	sends       int
+}
+
+// MemoryStore keeps codes in process memory. It is meant for development and
+// single-replica deployments.
+type MemoryStore struct {
+	policy  Policy
+	pepper  []byte
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+	Now     func() time.Time
+}
+
+func NewMemoryStore(policy Policy, pepper []byte) *MemoryStore {
+	return &MemoryStore{
+		policy:  policy,
+		pepper:  pepper,
+		entries: make(map[string]*memoryEntry),
+		Now:     time.Now,
+	}
+}
+
+func (s *MemoryStore) Save(identifier, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	e, ok := s.entries[identifier]
+	if !ok {
+		e = &memoryEntry{windowStart: now}
+		s.entries[identifier] = e
+	}
+	if now.Sub(e.lastSent) < s.policy.ResendCooldown {
+		return ErrCooldown
+	}
+	if now.Sub(e.windowStart) >= s.policy.SendWindow {
+		e.windowStart = now
+		e.sends = 0
+	}
+	if e.sends >= s.policy.MaxSends {
+		return ErrQuotaExceeded
+	}
+
+	e.hash = hashCode(s.pepper, identifier, code)
+	e.expires = now.Add(s.policy.TTL)
+	e.attempts = 0
+	e.lastSent = now
+	e.sends++
+	return nil
+}
+
+func (s *MemoryStore) Verify(identifier, code string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[identifier]
+	if !ok || e.hash == "" {
+		return ErrInvalidCode
+	}
+	if s.Now().After(e.expires) {
+		e.hash = ""
+		return ErrExpired
+	}
+	if e.attempts >= s.policy.MaxAttempts {
+		e.hash = ""
+		return ErrTooManyAttempts
+	}
+	if !hashesEqual(e.hash, hashCode(s.pepper, identifier, code)) {
+		e.attempts++
+		if e.attempts >= s.policy.MaxAttempts {
+			e.hash = ""
+			return ErrTooManyAttempts
+		}
+		return ErrInvalidCode
+	}
+
+	e.hash = ""
+	return nil
+}
+
+func (s *MemoryStore) Purge() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.Now()
+	for id, e := range s.entries {
+		if now.After(e.expires) && now.Sub(e.windowStart) >= s.policy.SendWindow {
+			delete(s.entries, id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package otp
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+var testPolicy = Policy{
+	TTL:            5 * time.Minute,
+	MaxAttempts:    3,
+	ResendCooldown: time.Minute,
+	MaxSends:       3,
+	SendWindow:     time.Hour,
+}
+
+// step advances the clock by after and then saves code, or verifies it
+// when verify is set.
+type step struct {
+	after  time.Duration
+	verify bool
+	code   string
+	want   error
+}
+
+func save(after time.Duration, code string, want error) step {
+	return step{after: after, code: code, want: want}
+}
+
+func verify(after time.Duration, code string, want error) step {
+	return step{after: after, verify: true, code: code, want: want}
+}
+
+func TestMemoryStore(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{"right code once", []step{
+			save(0, "111111", nil),
+			verify(0, "111111", nil),
+			verify(0, "111111", ErrInvalidCode),
+		}},
+		{"unknown identifier", []step{
+			verify(0, "111111", ErrInvalidCode),
+		}},
+
+		{"resend inside the cooldown", []step{
+			save(0, "111111", nil),
+			save(59*time.Second, "222222", ErrCooldown),
+			// the refused resend leaves the first code usable
+			verify(0, "111111", nil),
+		}},
+		{"resend after the cooldown replaces the code", []step{
+			save(0, "111111", nil),
+			save(time.Minute, "222222", nil),
+			verify(0, "111111", ErrInvalidCode),
+			verify(0, "222222", nil),
+		}},
+
+		{"quota within the window", []step{
+			save(0, "111111", nil),
+			save(time.Minute, "222222", nil),
+			save(time.Minute, "333333", nil),
+			save(time.Minute, "444444", ErrQuotaExceeded),
+			save(56*time.Minute, "555555", ErrQuotaExceeded),
+		}},
+		{"quota resets with the window", []step{
+			save(0, "111111", nil),
+			save(time.Minute, "222222", nil),
+			save(time.Minute, "333333", nil),
+			save(58*time.Minute, "444444", nil),
+			verify(0, "444444", nil),
+		}},
+
+		{"attempts exhausted", []step{
+			save(0, "111111", nil),
+			verify(0, "000000", ErrInvalidCode),
+			verify(0, "000000", ErrInvalidCode),
+			verify(0, "000000", ErrTooManyAttempts),
+			// the code is burnt, even the right one fails now
+			verify(0, "111111", ErrInvalidCode),
+		}},
+		{"a success after failures", []step{
+			save(0, "111111", nil),
+			verify(0, "000000", ErrInvalidCode),
+			verify(0, "000000", ErrInvalidCode),
+			verify(0, "111111", nil),
+		}},
+		{"a new code gets fresh attempts", []step{
+			save(0, "111111", nil),
+			verify(0, "000000", ErrInvalidCode),
+			verify(0, "000000", ErrInvalidCode),
+			save(time.Minute, "222222", nil),
+			verify(0, "000000", ErrInvalidCode),
+			verify(0, "222222", nil),
+		}},
+
+		{"last moment of the TTL", []step{
+			save(0, "111111", nil),
+			verify(5*time.Minute, "111111", nil),
+		}},
+		{"expired", []step{
+			save(0, "111111", nil),
+			verify(5*time.Minute+time.Second, "111111", ErrExpired),
+			verify(0, "111111", ErrInvalidCode),
+		}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+			s := NewMemoryStore(testPolicy, []byte("test-pepper"))
+			s.Now = func() time.Time { return now }
+
+			for i, st := range tc.steps {
+				now = now.Add(st.after)
+				var err error
+				if st.verify {
+					err = s.Verify("user@example.com", st.code)
+				} else {
+					err = s.Save("user@example.com", st.code)
+				}
+				if !errors.Is(err, st.want) {
+					t.Fatalf("step %d: got %v, want %v", i+1, err, st.want)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreKeysCodesByIdentifier(t *testing.T) {
+	s := NewMemoryStore(testPolicy, []byte("test-pepper"))
+	if err := s.Save("a@example.com", "111111"); err != nil {
+		t.Fatal(err)
+	}
+	// one identifier's cooldown does not hold back another
+	if err := s.Save("b@example.com", "222222"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Verify("b@example.com", "111111"); !errors.Is(err, ErrInvalidCode) {
+		t.Fatalf("code of another identifier: got %v, want ErrInvalidCode", err)
+	}
+	if err := s.Verify("a@example.com", "111111"); err != nil {
+		t.Fatalf("own code: %v", err)
+	}
+}
+
+func TestMemoryStorePurge(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryStore(testPolicy, []byte("test-pepper"))
+	s.Now = func() time.Time { return now }
+	for i := 0; i < testPolicy.MaxSends; i++ {
+		if err := s.Save("user@example.com", "111111"); err != nil {
+			t.Fatal(err)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	// expired but still inside the quota window: kept, so the quota holds
+	now = now.Add(10 * time.Minute)
+	s.Purge()
+	if err := s.Save("user@example.com", "222222"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("after an early purge: got %v, want ErrQuotaExceeded", err)
+	}
+
+	now = now.Add(time.Hour)
+	s.Purge()
+	if len(s.entries) != 0 {
+		t.Fatalf("%d entries left after the window", len(s.entries))
+	}
+}
+
+func TestPepperFromEnv(t *testing.T) {
+	t.Setenv("OTP_PEPPER", "")
+	if _, err := PepperFromEnv(); !errors.Is(err, ErrNoPepper) {
+		t.Fatalf("empty pepper: got %v, want ErrNoPepper", err)
+	}
+	t.Setenv("OTP_PEPPER", "s3cret")
+	if pepper, err := PepperFromEnv(); err != nil || string(pepper) != "s3cret" {
+		t.Fatalf("got %q, %v", pepper, err)
+	}
+}
+
+func TestHashCodeDependsOnPepper(t *testing.T) {
+	if hashCode([]byte("a"), "id", "111111") == hashCode([]byte("b"), "id", "111111") {
+		t.Fatal("different peppers give the same hash")
+	}
+}
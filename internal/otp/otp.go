@@ -0,0 +1,93 @@
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidCode     = errors.New("invalid OTP")
+	ErrExpired         = errors.New("OTP expired")
+	ErrTooManyAttempts = errors.New("too many OTP attempts, request a new code")
+	ErrCooldown        = errors.New("please wait before requesting another OTP")
+	ErrQuotaExceeded   = errors.New("OTP send limit reached, try again later")
+	ErrNoPepper        = errors.New("OTP_PEPPER must be set")
+)
+
+// Policy controls code lifetime and abuse limits.
+type Policy struct {
+	TTL            time.Duration
+	MaxAttempts    int
+	ResendCooldown time.Duration
+	MaxSends       int
+	SendWindow     time.Duration
+}
+
+var DefaultPolicy = Policy{
+	TTL:            5 * time.Minute,
+	MaxAttempts:    5,
+	ResendCooldown: time.Minute,
+	MaxSends:       5,
+	SendWindow:     time.Hour,
+}
+
+// Store persists hashed OTP codes per identifier (phone or email).
+type Store interface {
+	// Save stores a freshly generated code, enforcing the resend cooldown
+	// and the per-identifier send quota.
+	Save(identifier, code string) error
+	// Verify consumes the code on success and counts failed attempts.
+	Verify(identifier, code string) error
+	// Purge removes entries that are expired and outside the quota window.
+	Purge() error
+}
+
+// Issue generates a new code for identifier and saves it in store.
+func Issue(store Store, identifier string) (string, error) {
+	code, err := Generate()
+	if err != nil {
+		return "", err
+	}
+	if err := store.Save(identifier, code); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// Generate returns a 6-digit code from crypto/rand.
+func Generate() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// PepperFromEnv reads OTP_PEPPER, the server-side secret codes are hashed
+// with. Without it a leaked table could be brute-forced, so it is required.
+func PepperFromEnv() ([]byte, error) {
+	pepper := os.Getenv("OTP_PEPPER")
+	if pepper == "" {
+		return nil, ErrNoPepper
+	}
+	return []byte(pepper), nil
+}
+
+// hashCode binds the code to its identifier so hashes cannot be swapped
+// between rows, keyed with the server-side pepper.
+func hashCode(pepper []byte, identifier, code string) string {
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(identifier + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func hashesEqual(a, b string) bool {
+	return hmac.Equal([]byte(a), []byte(b))
+}
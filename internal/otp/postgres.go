@@ -0,0 +1,111 @@
+package otp
+
+import (
+	"errors"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostgresStore shares codes and counters between replicas through the
+// otp_codes table. Rows are locked while they are checked and updated.
+type PostgresStore struct {
+	db     *gorm.DB
+	policy Policy
+	pepper []byte
+	Now    func() time.Time
+}
+
+func NewPostgresStore(db *gorm.DB, policy Policy, pepper []byte) *PostgresStore {
+	return &PostgresStore{db: db, policy: policy, pepper: pepper, Now: time.Now}
+}
+
+func (s *PostgresStore) Save(identifier, code string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		now := s.Now()
+		// Insert the row on first issue, or touch the existing one, in one
+		// statement. Either way the row is locked, so concurrent first
+		// issues for the same identifier queue up instead of racing on the
+		// primary key.
+		seed := models.OTPCode{Identifier: identifier, WindowStart: now}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "identifier"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"updated_at": now}),
+		}).Create(&seed).Error; err != nil {
+			return err
+		}
+
+		var row models.OTPCode
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&row, "identifier = ?", identifier).Error; err != nil {
+			return err
+		}
+
+		if now.Sub(row.LastSentAt) < s.policy.ResendCooldown {
+			return ErrCooldown
+		}
+		if now.Sub(row.WindowStart) >= s.policy.SendWindow {
+			row.WindowStart = now
+			row.SendCount = 0
+		}
+		if row.SendCount >= s.policy.MaxSends {
+			return ErrQuotaExceeded
+		}
+
+		row.CodeHash = hashCode(s.pepper, identifier, code)
+		row.ExpiresAt = now.Add(s.policy.TTL)
+		row.Attempts = 0
+		row.LastSentAt = now
+		row.SendCount++
+		return tx.Save(&row).Error
+	})
+}
+
+func (s *PostgresStore) Verify(identifier, code string) error {
+	var result error
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var row models.OTPCode
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&row, "identifier = ?", identifier).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			result = ErrInvalidCode
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case row.CodeHash == "":
+			result = ErrInvalidCode
+		case s.Now().After(row.ExpiresAt):
+			row.CodeHash = ""
+			result = ErrExpired
+		case row.Attempts >= s.policy.MaxAttempts:
+			row.CodeHash = ""
+			result = ErrTooManyAttempts
+		case !hashesEqual(row.CodeHash, hashCode(s.pepper, identifier, code)):
+			row.Attempts++
+			result = ErrInvalidCode
+			if row.Attempts >= s.policy.MaxAttempts {
+				row.CodeHash = ""
+				result = ErrTooManyAttempts
+			}
+		default:
+			row.CodeHash = ""
+		}
+		return tx.Model(&row).Select("code_hash", "attempts").Updates(&row).Error
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+func (s *PostgresStore) Purge() error {
+	now := s.Now()
+	return s.db.Where("expires_at < ? AND window_start < ?", now, now.Add(-s.policy.SendWindow)).
+		Delete(&models.OTPCode{}).Error
+}
@@ -0,0 +1,214 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTolerance is how old a signed webhook may be.
+const DefaultTolerance = 5 * time.Minute
+
+// MockGateway keeps intents in memory and signs its events with HMAC-SHA256
+// like a real provider would. Pay stands in for the customer completing
+// checkout. Meant for development and tests only.
+type MockGateway struct {
+	secret    []byte
+	Tolerance time.Duration
+	Now       func() time.Time
+
+	mu      sync.Mutex
+	intents map[string]*Intent
+	// refunds remembers refunds by idempotency key.
+	refunds map[string]Refund
+}
+
+func NewMockGateway(secret string) *MockGateway {
+	return &MockGateway{
+		secret:    []byte(secret),
+		Tolerance: DefaultTolerance,
+		Now:       time.Now,
+		intents:   map[string]*Intent{},
+		refunds:   map[string]Refund{},
+	}
+}
+
+func (g *MockGateway) Name() string { return "mock" }
+
+func (g *MockGateway) CreateIntent(req IntentRequest) (Intent, error) {
+	if req.Amount <= 0 {
+		return Intent{}, ErrInvalidAmount
+	}
+	id := "mock_pi_" + randomHex(12)
+	intent := &Intent{
+		ID:           id,
+		ClientSecret: id + "_secret_" + randomHex(12),
+		Amount:       req.Amount,
+		Currency:     strings.ToUpper(req.Currency),
+		Reference:    req.Reference,
+		Status:       IntentRequiresPayment,
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.intents[id] = intent
+	return *intent, nil
+}
+
+// Pay simulates the customer's checkout. On success the intent is
+// authorised and captured; either way the signed webhook the provider would
+// send is returned.
+func (g *MockGateway) Pay(intentID string, succeed bool) (payload []byte, signature string, err error) {
+	g.mu.Lock()
+	intent, ok := g.intents[intentID]
+	if !ok {
+		g.mu.Unlock()
+		return nil, "", ErrUnknownIntent
+	}
+	if intent.Status != IntentRequiresPayment {
+		g.mu.Unlock()
+		return nil, "", fmt.Errorf("%w: intent is %s", ErrNotCapturable, intent.Status)
+	}
+
+	ev := Event{
+		ID:        "mock_evt_" + randomHex(12),
+		Type:      EventPaymentFailed,
+		IntentID:  intent.ID,
+		Reference: intent.Reference,
+		Amount:    intent.Amount,
+		Currency:  intent.Currency,
+		CreatedAt: g.Now(),
+	}
+	intent.Status = IntentFailed
+	if succeed {
+		intent.Status = IntentRequiresCapture
+		if err := capture(intent); err != nil {
+			g.mu.Unlock()
+			return nil, "", err
+		}
+		ev.Type = EventPaymentSucceeded
+	}
+	g.mu.Unlock()
+	return g.Sign(ev)
+}
+
+func (g *MockGateway) Capture(intentID string) (Intent, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return Intent{}, ErrUnknownIntent
+	}
+	if err := capture(intent); err != nil {
+		return Intent{}, err
+	}
+	return *intent, nil
+}
+
+func capture(intent *Intent) error {
+	if intent.Status != IntentRequiresCapture {
+		return fmt.Errorf("%w: intent is %s", ErrNotCapturable, intent.Status)
+	}
+	intent.Status = IntentSucceeded
+	return nil
+}
+
+func (g *MockGateway) Cancel(intentID string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return ErrUnknownIntent
+	}
+	if intent.Status != IntentRequiresPayment {
+		return fmt.Errorf("%w: intent is %s", ErrNotCancellable, intent.Status)
+	}
+	intent.Status = IntentCanceled
+	return nil
+}
+
+func (g *MockGateway) Refund(intentID string, amount int64, idempotencyKey string) (Refund, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if r, ok := g.refunds[idempotencyKey]; ok && idempotencyKey != "" {
+		return r, nil
+	}
+	intent, ok := g.intents[intentID]
+	if !ok {
+		return Refund{}, ErrUnknownIntent
+	}
+	if intent.Status != IntentSucceeded {
+		return Refund{}, fmt.Errorf("%w: intent is %s", ErrNotRefundable, intent.Status)
+	}
+	if amount <= 0 || intent.Refunded+amount > intent.Amount {
+		return Refund{}, fmt.Errorf("%w: %d of %d already refunded", ErrNotRefundable, intent.Refunded, intent.Amount)
+	}
+	intent.Refunded += amount
+	r := Refund{ID: "mock_re_" + randomHex(12), IntentID: intentID, Amount: amount}
+	if idempotencyKey != "" {
+		g.refunds[idempotencyKey] = r
+	}
+	return r, nil
+}
+
+// Sign encodes ev and signs it the way VerifyWebhook expects.
+func (g *MockGateway) Sign(ev Event) (payload []byte, signature string, err error) {
+	payload, err = json.Marshal(ev)
+	if err != nil {
+		return nil, "", err
+	}
+	ts := strconv.FormatInt(g.Now().Unix(), 10)
+	return payload, "t=" + ts + ",v1=" + g.mac(ts, payload), nil
+}
+
+func (g *MockGateway) VerifyWebhook(payload []byte, signature string) (Event, error) {
+	var ts, sig string
+	for _, part := range strings.Split(signature, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch k {
+		case "t":
+			ts = v
+		case "v1":
+			sig = v
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return Event{}, ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(g.mac(ts, payload))) {
+		return Event{}, ErrInvalidSignature
+	}
+	if age := g.Now().Sub(time.Unix(unix, 0)); age > g.Tolerance || age < -g.Tolerance {
+		return Event{}, fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	var ev Event
+	if err := json.Unmarshal(payload, &ev); err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return ev, nil
+}
+
+func (g *MockGateway) mac(ts string, payload []byte) string {
+	m := hmac.New(sha256.New, g.secret)
+	m.Write([]byte(ts))
+	m.Write([]byte("."))
+	m.Write(payload)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
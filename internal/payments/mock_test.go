@@ -0,0 +1,183 @@
+package payments
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestGateway(now time.Time) *MockGateway {
+	g := NewMockGateway("test-webhook-secret-0123")
+	g.Now = func() time.Time { return now }
+	return g
+}
+
+func testEvent(at time.Time) Event {
+	return Event{
+		ID:        "evt_1",
+		Type:      EventPaymentSucceeded,
+		IntentID:  "pi_1",
+		Reference: "order:1",
+		Amount:    150000,
+		Currency:  "INR",
+		CreatedAt: at,
+	}
+}
+
+func TestVerifyWebhookAcceptsSignedEvent(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	g := newTestGateway(now)
+	payload, sig, err := g.Sign(testEvent(now))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	ev, err := g.VerifyWebhook(payload, sig)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if ev.ID != "evt_1" || ev.Amount != 150000 || ev.Type != EventPaymentSucceeded {
+		t.Fatalf("decoded %+v", ev)
+	}
+}
+
+func TestVerifyWebhookRejectsBadSignature(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	g := newTestGateway(now)
+	payload, sig, err := g.Sign(testEvent(now))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	other := NewMockGateway("another-secret-entirely")
+	other.Now = g.Now
+	_, foreign, _ := other.Sign(testEvent(now))
+
+	ts := strings.TrimPrefix(strings.Split(sig, ",")[0], "t=")
+	tests := []struct {
+		name      string
+		payload   []byte
+		signature string
+	}{
+		{"tampered amount", []byte(strings.Replace(string(payload), "150000", "1", 1)), sig},
+		{"other secret", payload, foreign},
+		{"missing header", payload, ""},
+		{"missing v1", payload, "t=" + ts},
+		{"missing timestamp", payload, strings.Split(sig, ",")[1]},
+		{"bad timestamp", payload, "t=soon," + strings.Split(sig, ",")[1]},
+		{"timestamp swapped", payload, "t=" + ts + "1," + strings.Split(sig, ",")[1]},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := g.VerifyWebhook(tc.payload, tc.signature); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestVerifyWebhookRejectsStaleTimestamp(t *testing.T) {
+	signedAt := time.Unix(1_700_000_000, 0)
+	g := newTestGateway(signedAt)
+	payload, sig, err := g.Sign(testEvent(signedAt))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		wantErr bool
+	}{
+		{"just inside tolerance", signedAt.Add(DefaultTolerance), false},
+		{"too old", signedAt.Add(DefaultTolerance + time.Second), true},
+		{"from the future", signedAt.Add(-DefaultTolerance - time.Second), true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			g.Now = func() time.Time { return tc.now }
+			_, err := g.VerifyWebhook(payload, sig)
+			if tc.wantErr != (err != nil) {
+				t.Fatalf("got %v, want error %v", err, tc.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("got %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+// A captured callback can be replayed verbatim. Within the tolerance it
+// verifies to the same event, which the payment service ignores once the
+// payment has left PENDING; after it, the signature is refused.
+func TestVerifyWebhookReplay(t *testing.T) {
+	signedAt := time.Unix(1_700_000_000, 0)
+	g := newTestGateway(signedAt)
+	payload, sig, err := g.Sign(testEvent(signedAt))
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+
+	first, err := g.VerifyWebhook(payload, sig)
+	if err != nil {
+		t.Fatalf("first delivery: %v", err)
+	}
+	g.Now = func() time.Time { return signedAt.Add(time.Minute) }
+	again, err := g.VerifyWebhook(payload, sig)
+	if err != nil {
+		t.Fatalf("replay within tolerance: %v", err)
+	}
+	if again.ID != first.ID || again.IntentID != first.IntentID {
+		t.Fatalf("replay decoded to %+v, want %+v", again, first)
+	}
+
+	g.Now = func() time.Time { return signedAt.Add(time.Hour) }
+	if _, err := g.VerifyWebhook(payload, sig); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("late replay: got %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestRefundIdempotencyKey(t *testing.T) {
+	g := NewMockGateway("test-webhook-secret-0123")
+	intent, err := g.CreateIntent(IntentRequest{Amount: 5000, Currency: "inr", Reference: "order:1"})
+	if err != nil {
+		t.Fatalf("intent: %v", err)
+	}
+	if _, _, err := g.Pay(intent.ID, true); err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+
+	first, err := g.Refund(intent.ID, 5000, "refund:p1")
+	if err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	retry, err := g.Refund(intent.ID, 5000, "refund:p1")
+	if err != nil {
+		t.Fatalf("retried refund: %v", err)
+	}
+	if retry.ID != first.ID {
+		t.Fatalf("retry refunded again: %s then %s", first.ID, retry.ID)
+	}
+	if _, err := g.Refund(intent.ID, 5000, "refund:p2"); !errors.Is(err, ErrNotRefundable) {
+		t.Fatalf("second refund under a new key: got %v, want ErrNotRefundable", err)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	g := NewMockGateway("test-webhook-secret-0123")
+	open, _ := g.CreateIntent(IntentRequest{Amount: 5000, Currency: "INR"})
+	if err := g.Cancel(open.ID); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if _, _, err := g.Pay(open.ID, true); !errors.Is(err, ErrNotCapturable) {
+		t.Fatalf("paying a cancelled intent: got %v, want ErrNotCapturable", err)
+	}
+
+	paid, _ := g.CreateIntent(IntentRequest{Amount: 5000, Currency: "INR"})
+	if _, _, err := g.Pay(paid.ID, true); err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	if err := g.Cancel(paid.ID); !errors.Is(err, ErrNotCancellable) {
+		t.Fatalf("cancelling a paid intent: got %v, want ErrNotCancellable", err)
+	}
+}
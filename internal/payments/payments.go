@@ -0,0 +1,122 @@
+// Package payments talks to payment providers behind one Gateway interface.
+// Nothing is marked paid from a client request: only events that pass
+// VerifyWebhook are trusted.
+package payments
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrUnknownIntent    = errors.New("unknown payment intent")
+	ErrInvalidAmount    = errors.New("payment amount must be positive")
+	ErrNotCapturable    = errors.New("payment intent cannot be captured")
+	ErrNotRefundable    = errors.New("payment intent cannot be refunded")
+	ErrNotCancellable   = errors.New("payment intent cannot be cancelled")
+)
+
+// SignatureHeader carries the webhook signature, "t=<unix>,v1=<hex>".
+const SignatureHeader = "X-Payment-Signature"
+
+type IntentStatus string
+
+const (
+	IntentRequiresPayment IntentStatus = "requires_payment"
+	IntentRequiresCapture IntentStatus = "requires_capture"
+	IntentSucceeded       IntentStatus = "succeeded"
+	IntentFailed          IntentStatus = "failed"
+	IntentCanceled        IntentStatus = "canceled"
+)
+
+// IntentRequest asks for Amount minor units (e.g. paise) of Currency.
+// Reference is echoed back in events so callbacks can be matched.
+type IntentRequest struct {
+	Amount    int64
+	Currency  string
+	Reference string
+}
+
+// Intent is the provider's record of one payment attempt. ClientSecret is
+// handed to the client to complete the payment with the provider.
+type Intent struct {
+	ID           string       `json:"id"`
+	ClientSecret string       `json:"clientSecret"`
+	Amount       int64        `json:"amount"`
+	Currency     string       `json:"currency"`
+	Reference    string       `json:"reference"`
+	Status       IntentStatus `json:"status"`
+	Refunded     int64        `json:"refunded"`
+}
+
+type Refund struct {
+	ID       string `json:"id"`
+	IntentID string `json:"intentId"`
+	Amount   int64  `json:"amount"`
+}
+
+type EventType string
+
+const (
+	EventPaymentSucceeded EventType = "payment.succeeded"
+	EventPaymentFailed    EventType = "payment.failed"
+)
+
+// Event is a verified provider callback.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	IntentID  string    `json:"intentId"`
+	Reference string    `json:"reference"`
+	Amount    int64     `json:"amount"`
+	Currency  string    `json:"currency"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Gateway is one payment provider.
+type Gateway interface {
+	Name() string
+	CreateIntent(req IntentRequest) (Intent, error)
+	// Capture settles an authorised intent.
+	Capture(intentID string) (Intent, error)
+	// Cancel voids an intent that has not been paid, so it can no longer be.
+	Cancel(intentID string) error
+	// Refund returns amount minor units of a settled intent to the payer.
+	// Repeating a call with the same idempotencyKey returns the first
+	// refund instead of refunding again.
+	Refund(intentID string, amount int64, idempotencyKey string) (Refund, error)
+	// VerifyWebhook checks signature against the raw payload and decodes
+	// the event. Events must never be acted on without it.
+	VerifyWebhook(payload []byte, signature string) (Event, error)
+}
+
+// ToMinor converts an amount in major units to minor units.
+func ToMinor(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// FromMinor converts minor units back to major units.
+func FromMinor(amount int64) float64 {
+	return float64(amount) / 100
+}
+
+// FromEnv builds the gateway named by PAYMENT_GATEWAY. Only "mock" exists
+// so far; it signs webhooks with PAYMENT_WEBHOOK_SECRET.
+func FromEnv() (Gateway, error) {
+	switch name := os.Getenv("PAYMENT_GATEWAY"); name {
+	case "mock":
+		secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+		if len(secret) < 16 {
+			return nil, errors.New("PAYMENT_WEBHOOK_SECRET must be at least 16 characters")
+		}
+		return NewMockGateway(secret), nil
+	case "":
+		return nil, errors.New("PAYMENT_GATEWAY is not set")
+	default:
+		return nil, fmt.Errorf("unknown PAYMENT_GATEWAY %q", name)
+	}
+}
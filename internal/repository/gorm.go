@@ -0,0 +1,783 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NewGormRepos returns Postgres-backed repositories sharing db. The outbox
+// is the notifications table.
+func NewGormRepos(db *gorm.DB) Repos {
+	return Repos{
+		Tx:            gormTx{db: db},
+		Users:         &gormUserRepo{db: db},
+		Doctors:       &gormDoctorRepo{db: db},
+		Admins:        &gormAdminRepo{db: db},
+		Appointments:  &gormAppointmentRepo{db: db},
+		Proposals:     &gormProposalRepo{db: db},
+		Leaves:        &gormLeaveRepo{db: db},
+		Reminders:     &gormReminderRepo{db: db},
+		MedicalChecks: &gormMedicalCheckRepo{db: db},
+		Prescriptions: &gormPrescriptionRepo{db: db},
+		Orders:        &gormOrderRepo{db: db},
+		Medicines:     &gormMedicineRepo{db: db},
+		Payments:      &gormPaymentRepo{db: db},
+		Ledger:        &gormLedgerRepo{db: db},
+		Reviews:       &gormReviewRepo{db: db},
+		Sessions:      &gormSessionRepo{db: db},
+		RecoveryCodes: &gormRecoveryCodeRepo{db: db},
+		Outbox:        notify.NewGormQueue(db),
+	}
+}
+
+var forUpdate = clause.Locking{Strength: "UPDATE"}
+
+type gormTx struct{ db *gorm.DB }
+
+func (t gormTx) Transaction(fn func(r Repos) error) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewGormRepos(tx))
+	})
+}
+
+func (t gormTx) TryAdvisoryLock(key int64) (bool, error) {
+	var locked bool
+	err := t.db.Raw("SELECT pg_try_advisory_xact_lock(?)", key).Scan(&locked).Error
+	return locked, err
+}
+
+// updateIf runs a guarded update and reports whether a row matched.
+func updateIf(q *gorm.DB, fields map[string]interface{}) (bool, error) {
+	res := q.Updates(fields)
+	return res.RowsAffected > 0, res.Error
+}
+
+type gormUserRepo struct{ db *gorm.DB }
+
+func (r *gormUserRepo) FindByID(id string) (*models.User, error) {
+	var user models.User
+	if err := r.db.First(&user, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepo) FindByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepo) FindByPhone(phone string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Where("phone = ?", phone).First(&user).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepo) ExistsByPhoneOrEmail(phone, email string) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).
+		Where("(phone <> '' AND phone = ?) OR (email <> '' AND email = ?)", phone, email).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *gormUserRepo) ListByRole(role models.Role, limit, offset int) ([]models.User, int64, error) {
+	var total int64
+	if err := r.db.Model(&models.User{}).Where("role = ?", role).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	q := r.db.Preload("Appointments").Preload("Orders")
+	if role == models.DOCTOR {
+		q = q.Preload("DoctorProfile")
+	} else {
+		q = q.Preload("AdminProfile")
+	}
+
+	var users []models.User
+	err := q.Where("role = ?", role).Limit(limit).Offset(offset).Find(&users).Error
+	return users, total, err
+}
+
+func (r *gormUserRepo) Create(user *models.User) error {
+	return r.db.Create(user).Error
+}
+
+func (r *gormUserRepo) Save(user *models.User) error {
+	return r.db.Save(user).Error
+}
+
+func (r *gormUserRepo) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *gormUserRepo) Lock(id string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Clauses(forUpdate).First(&user, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *gormUserRepo) IncrementFailedLogins(id string) (int, error) {
+	user := models.User{ID: id}
+	err := r.db.Model(&user).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "failed_login_attempts"}}}).
+		Update("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error
+	return user.FailedLoginAttempts, err
+}
+
+func (r *gormUserRepo) IncrementNoShows(id string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).
+		Update("no_show_count", gorm.Expr("no_show_count + 1")).Error
+}
+
+func (r *gormUserRepo) ExtendBookingBlock(id string, until time.Time) error {
+	return r.db.Model(&models.User{}).
+		Where("id = ? AND (booking_blocked_until IS NULL OR booking_blocked_until < ?)", id, until).
+		Update("booking_blocked_until", until).Error
+}
+
+type gormDoctorRepo struct{ db *gorm.DB }
+
+func (r *gormDoctorRepo) FindByID(id string) (*models.DoctorProfile, error) {
+	var profile models.DoctorProfile
+	if err := r.db.Preload("User").Preload("Reviews").First(&profile, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *gormDoctorRepo) FindByUserID(userID string) (*models.DoctorProfile, error) {
+	var profile models.DoctorProfile
+	if err := r.db.Where("user_id = ?", userID).First(&profile).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *gormDoctorRepo) List(filter DoctorFilter) ([]models.DoctorProfile, error) {
+	q := r.db.Preload("User").Preload("Reviews").Where("is_pending = ?", false)
+	if filter.Specialization != "" {
+		q = q.Where("LOWER(specialization) LIKE ?", "%"+strings.ToLower(filter.Specialization)+"%")
+	}
+	if filter.Search != "" {
+		q = q.Joins("JOIN users ON users.id = doctor_profiles.user_id").
+			Where("LOWER(users.name) LIKE ?", "%"+strings.ToLower(filter.Search)+"%")
+	}
+	if filter.MinRating > 0 {
+		q = q.Where("rating >= ?", filter.MinRating)
+	}
+
+	var doctors []models.DoctorProfile
+	err := q.Find(&doctors).Error
+	return doctors, err
+}
+
+func (r *gormDoctorRepo) Create(profile *models.DoctorProfile) error {
+	return r.db.Create(profile).Error
+}
+
+func (r *gormDoctorRepo) Save(profile *models.DoctorProfile) error {
+	return r.db.Omit("User", "Reviews").Save(profile).Error
+}
+
+func (r *gormDoctorRepo) Lock(id string) (*models.DoctorProfile, error) {
+	var profile models.DoctorProfile
+	if err := r.db.Clauses(forUpdate).First(&profile, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+func (r *gormDoctorRepo) Approve(id, adminID string) (bool, error) {
+	return updateIf(r.db.Model(&models.DoctorProfile{}).Where("id = ? AND is_pending = ?", id, true),
+		map[string]interface{}{"is_pending": false, "approved_by": adminID})
+}
+
+func (r *gormDoctorRepo) IncrementNoShows(id string) error {
+	return r.db.Model(&models.DoctorProfile{}).Where("id = ?", id).
+		Update("no_show_count", gorm.Expr("no_show_count + 1")).Error
+}
+
+type gormAdminRepo struct{ db *gorm.DB }
+
+func (r *gormAdminRepo) Create(profile *models.AdminProfile) error {
+	return r.db.Create(profile).Error
+}
+
+func (r *gormAdminRepo) Ensure(userID string) error {
+	return r.db.Where(models.AdminProfile{UserID: userID}).FirstOrCreate(&models.AdminProfile{}).Error
+}
+
+type gormAppointmentRepo struct{ db *gorm.DB }
+
+func (r *gormAppointmentRepo) preloaded() *gorm.DB {
+	return r.db.Preload("Patient").Preload("DoctorProfile.User")
+}
+
+func (r *gormAppointmentRepo) FindByID(id string) (*models.Appointment, error) {
+	var appt models.Appointment
+	if err := r.preloaded().First(&appt, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &appt, nil
+}
+
+func (r *gormAppointmentRepo) FindForDoctor(id, doctorProfileID string) (*models.Appointment, error) {
+	var appt models.Appointment
+	if err := r.preloaded().
+		Where("id = ? AND doctor_profile_id = ?", id, doctorProfileID).
+		First(&appt).Error; err != nil {
+		return nil, err
+	}
+	return &appt, nil
+}
+
+func (r *gormAppointmentRepo) FindForPatient(id, patientID string) (*models.Appointment, error) {
+	var appt models.Appointment
+	if err := r.preloaded().
+		Where("id = ? AND patient_id = ?", id, patientID).
+		First(&appt).Error; err != nil {
+		return nil, err
+	}
+	return &appt, nil
+}
+
+func (r *gormAppointmentRepo) List(filter AppointmentFilter) ([]models.Appointment, error) {
+	q := r.preloaded()
+	if filter.DoctorProfileID != "" {
+		q = q.Where("doctor_profile_id = ?", filter.DoctorProfileID)
+	}
+	if filter.PatientID != "" {
+		q = q.Where("patient_id = ?", filter.PatientID)
+	}
+	if len(filter.Statuses) > 0 {
+		q = q.Where("status IN ?", filter.Statuses)
+	}
+	if filter.After != nil {
+		q = q.Where("scheduled_at > ?", *filter.After)
+	}
+	if filter.From != nil {
+		q = q.Where("scheduled_at >= ?", *filter.From)
+	}
+	if filter.Before != nil {
+		q = q.Where("scheduled_at < ?", *filter.Before)
+	}
+	if filter.NewestFirst {
+		q = q.Order("scheduled_at DESC")
+	} else {
+		q = q.Order("scheduled_at ASC")
+	}
+
+	var appts []models.Appointment
+	err := q.Find(&appts).Error
+	return appts, err
+}
+
+func (r *gormAppointmentRepo) PatientsOf(doctorProfileID string, status models.AppointmentStatus) ([]models.User, error) {
+	var patients []models.User
+	err := r.db.Model(&models.Appointment{}).
+		Select("DISTINCT users.*").
+		Joins("JOIN users ON users.id = appointments.patient_id").
+		Where("appointments.doctor_profile_id = ? AND appointments.status = ?", doctorProfileID, status).
+		Scan(&patients).Error
+	return patients, err
+}
+
+func (r *gormAppointmentRepo) CountByDoctor(status models.AppointmentStatus, since *time.Time) ([]DoctorCount, error) {
+	q := r.db.Model(&models.Appointment{}).
+		Select("appointments.doctor_profile_id, users.name AS doctor_name, COUNT(*) AS count").
+		Joins("JOIN doctor_profiles ON doctor_profiles.id = appointments.doctor_profile_id").
+		Joins("JOIN users ON users.id = doctor_profiles.user_id").
+		Where("appointments.status = ?", status)
+	if since != nil {
+		q = q.Where("appointments.updated_at >= ?", *since)
+	}
+
+	var counts []DoctorCount
+	err := q.Group("appointments.doctor_profile_id, users.name").
+		Order("count DESC").
+		Scan(&counts).Error
+	return counts, err
+}
+
+func (r *gormAppointmentRepo) Create(appt *models.Appointment) error {
+	return r.db.Omit("Patient", "DoctorProfile").Create(appt).Error
+}
+
+func (r *gormAppointmentRepo) Save(appt *models.Appointment) error {
+	return r.db.Omit("Patient", "DoctorProfile").Save(appt).Error
+}
+
+func (r *gormAppointmentRepo) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.Appointment{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *gormAppointmentRepo) UpdateIfStatus(id string, status models.AppointmentStatus, fields map[string]interface{}) (bool, error) {
+	return updateIf(r.db.Model(&models.Appointment{}).Where("id = ? AND status = ?", id, status), fields)
+}
+
+func (r *gormAppointmentRepo) Lock(id string) (*models.Appointment, error) {
+	var appt models.Appointment
+	if err := r.db.Clauses(forUpdate).First(&appt, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &appt, nil
+}
+
+func (r *gormAppointmentRepo) LockStaleRequests(f StaleRequestFilter) ([]models.Appointment, error) {
+	var stale []models.Appointment
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("(status = ? AND (scheduled_at <= ? OR created_at <= ?)) OR (status = ? AND (scheduled_at <= ? OR "+
+			"COALESCE((SELECT MAX(p.created_at) FROM reschedule_proposals p WHERE p.appointment_id = appointments.id), appointments.created_at) <= ?))",
+			models.PENDING, f.Now, f.PendingMadeBy,
+			models.RESCHEDULE_REQUESTED, f.Now, f.RescheduleMadeBy).
+		Order("scheduled_at").
+		Limit(f.Limit).
+		Find(&stale).Error
+	return stale, err
+}
+
+type gormProposalRepo struct{ db *gorm.DB }
+
+func (r *gormProposalRepo) Create(p *models.RescheduleProposal) error {
+	return r.db.Omit("Appointment").Create(p).Error
+}
+
+func (r *gormProposalRepo) FindOpen(appointmentID string) (*models.RescheduleProposal, error) {
+	var p models.RescheduleProposal
+	if err := r.db.Where("appointment_id = ? AND status = ?", appointmentID, models.PROPOSAL_OPEN).
+		First(&p).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *gormProposalRepo) Close(id string, status models.ProposalStatus, at time.Time) error {
+	return r.db.Model(&models.RescheduleProposal{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "responded_at": at}).Error
+}
+
+func (r *gormProposalRepo) ExpireDue(now time.Time) error {
+	return r.db.Model(&models.RescheduleProposal{}).
+		Where("status = ? AND expires_at <= ?", models.PROPOSAL_OPEN, now).
+		Updates(map[string]interface{}{"status": models.PROPOSAL_EXPIRED, "responded_at": now}).Error
+}
+
+func (r *gormProposalRepo) ListByAppointment(appointmentID string) ([]models.RescheduleProposal, error) {
+	var proposals []models.RescheduleProposal
+	err := r.db.Where("appointment_id = ?", appointmentID).Order("created_at DESC").Find(&proposals).Error
+	return proposals, err
+}
+
+type gormLeaveRepo struct{ db *gorm.DB }
+
+func (r *gormLeaveRepo) Create(leave *models.DoctorLeave) error {
+	return r.db.Omit("DoctorProfile").Create(leave).Error
+}
+
+func (r *gormLeaveRepo) Cancel(id, doctorProfileID string, at time.Time) (bool, error) {
+	return updateIf(r.db.Model(&models.DoctorLeave{}).
+		Where("id = ? AND doctor_profile_id = ? AND cancelled_at IS NULL", id, doctorProfileID),
+		map[string]interface{}{"cancelled_at": at})
+}
+
+func (r *gormLeaveRepo) Active(doctorProfileID string, from, to time.Time) ([]models.DoctorLeave, error) {
+	var leaves []models.DoctorLeave
+	err := r.db.Where("doctor_profile_id = ? AND cancelled_at IS NULL AND starts_at < ? AND ends_at > ?",
+		doctorProfileID, to, from).
+		Order("starts_at ASC").
+		Find(&leaves).Error
+	return leaves, err
+}
+
+type gormReminderRepo struct{ db *gorm.DB }
+
+func (r *gormReminderRepo) ForAppointments(ids []string) ([]models.AppointmentReminder, error) {
+	var rows []models.AppointmentReminder
+	err := r.db.Where("appointment_id IN ?", ids).Find(&rows).Error
+	return rows, err
+}
+
+func (r *gormReminderRepo) CreateNew(rows []models.AppointmentReminder) (int64, error) {
+	res := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows)
+	return res.RowsAffected, res.Error
+}
+
+type gormMedicalCheckRepo struct{ db *gorm.DB }
+
+func (r *gormMedicalCheckRepo) FindForDoctor(id, doctorUserID string) (*models.MedicalCheck, error) {
+	var check models.MedicalCheck
+	if err := r.db.
+		Joins("JOIN appointments ON appointments.id = medical_checks.appointment_id").
+		Joins("JOIN doctor_profiles ON appointments.doctor_profile_id = doctor_profiles.id").
+		Where("medical_checks.id = ? AND doctor_profiles.user_id = ?", id, doctorUserID).
+		First(&check).Error; err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+func (r *gormMedicalCheckRepo) ListByPatient(patientID string) ([]models.MedicalCheck, error) {
+	var checks []models.MedicalCheck
+	err := r.db.Where("appointment_id IN (SELECT id FROM appointments WHERE patient_id = ?)", patientID).
+		Order("created_at DESC").
+		Find(&checks).Error
+	return checks, err
+}
+
+func (r *gormMedicalCheckRepo) Create(check *models.MedicalCheck) error {
+	return r.db.Omit("Appointment").Create(check).Error
+}
+
+func (r *gormMedicalCheckRepo) Save(check *models.MedicalCheck) error {
+	return r.db.Omit("Appointment").Save(check).Error
+}
+
+type gormOrderRepo struct{ db *gorm.DB }
+
+func (r *gormOrderRepo) FindByID(id string) (*models.Order, error) {
+	var order models.Order
+	if err := r.db.First(&order, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *gormOrderRepo) FindForUser(id, userID string) (*models.Order, error) {
+	var order models.Order
+	if err := r.db.First(&order, "id = ? AND user_id = ?", id, userID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *gormOrderRepo) List(filter OrderFilter) ([]models.Order, error) {
+	q := r.db.Model(&models.Order{})
+	if filter.UserID != "" {
+		q = q.Where("user_id = ?", filter.UserID)
+	}
+	if filter.Status != "" {
+		q = q.Where("status = ?", filter.Status)
+	}
+	if filter.PrescriptionID != "" {
+		q = q.Where("prescription_id = ?", filter.PrescriptionID)
+	}
+	var orders []models.Order
+	err := q.Order("created_at ASC").Find(&orders).Error
+	return orders, err
+}
+
+func (r *gormOrderRepo) ListByUser(userID string) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&orders).Error
+	return orders, err
+}
+
+func (r *gormOrderRepo) Create(order *models.Order) error {
+	return r.db.Omit("User").Create(order).Error
+}
+
+func (r *gormOrderRepo) Save(order *models.Order) error {
+	return r.db.Omit("User").Save(order).Error
+}
+
+func (r *gormOrderRepo) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.Order{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *gormOrderRepo) Lock(id string) (*models.Order, error) {
+	var order models.Order
+	if err := r.db.Clauses(forUpdate).First(&order, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+type gormMedicineRepo struct{ db *gorm.DB }
+
+func (r *gormMedicineRepo) FindByID(id string) (*models.Medicine, error) {
+	var med models.Medicine
+	if err := r.db.First(&med, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &med, nil
+}
+
+func (r *gormMedicineRepo) FindBySKU(sku string) (*models.Medicine, error) {
+	var med models.Medicine
+	if err := r.db.First(&med, "sku = ?", sku).Error; err != nil {
+		return nil, err
+	}
+	return &med, nil
+}
+
+func (r *gormMedicineRepo) List(filter MedicineFilter) ([]models.Medicine, error) {
+	q := r.db.Model(&models.Medicine{})
+	if !filter.IncludeInactive {
+		q = q.Where("active = ?", true)
+	}
+	if filter.Search != "" {
+		like := "%" + strings.ToLower(filter.Search) + "%"
+		q = q.Where("LOWER(name) LIKE ? OR LOWER(sku) LIKE ?", like, like)
+	}
+	if filter.Form != "" {
+		q = q.Where("form = ?", filter.Form)
+	}
+	if filter.InStockOnly {
+		q = q.Where("stock_quantity > 0")
+	}
+	var meds []models.Medicine
+	err := q.Order("name ASC").Find(&meds).Error
+	return meds, err
+}
+
+func (r *gormMedicineRepo) Create(med *models.Medicine) error {
+	return r.db.Create(med).Error
+}
+
+func (r *gormMedicineRepo) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.Medicine{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *gormMedicineRepo) UpsertBySKU(meds []models.Medicine) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "sku"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"name", "strength", "form", "price", "requires_prescription",
+			"stock_quantity", "active", "updated_at",
+		}),
+	}).CreateInBatches(&meds, 500).Error
+}
+
+func (r *gormMedicineRepo) LockMany(ids []string) ([]models.Medicine, error) {
+	var meds []models.Medicine
+	err := r.db.Clauses(forUpdate).Where("id IN ?", ids).Order("id ASC").Find(&meds).Error
+	return meds, err
+}
+
+func (r *gormMedicineRepo) AddStock(id string, delta int) (bool, error) {
+	res := r.db.Model(&models.Medicine{}).
+		Where("id = ? AND stock_quantity + ? >= 0", id, delta).
+		Update("stock_quantity", gorm.Expr("stock_quantity + ?", delta))
+	return res.RowsAffected > 0, res.Error
+}
+
+type gormLedgerRepo struct{ db *gorm.DB }
+
+func (r *gormLedgerRepo) Create(txn *models.Transaction) error {
+	return r.db.Create(txn).Error
+}
+
+func (r *gormLedgerRepo) ForDoctor(doctorProfileID string) ([]models.Transaction, error) {
+	var txns []models.Transaction
+	err := r.db.Preload("Entries").
+		Where("doctor_profile_id = ?", doctorProfileID).
+		Order("occurred_at ASC").
+		Find(&txns).Error
+	return txns, err
+}
+
+func (r *gormLedgerRepo) DoctorBalance(doctorProfileID string) (int64, error) {
+	var sum int64
+	err := r.db.Model(&models.LedgerEntry{}).
+		Where("account = ? AND doctor_profile_id = ?", models.ACCOUNT_DOCTOR_PAYABLE, doctorProfileID).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&sum).Error
+	// credits are negative
+	return -sum, err
+}
+
+func (r *gormLedgerRepo) CommissionTaken(paymentID string) (int64, error) {
+	var taken int64
+	err := r.db.Model(&models.LedgerEntry{}).
+		Joins("JOIN transactions ON transactions.id = ledger_entries.transaction_id").
+		Where("transactions.payment_id = ? AND transactions.kind = ? AND ledger_entries.account = ?",
+			paymentID, models.TXN_COMMISSION, models.ACCOUNT_PLATFORM_REVENUE).
+		Select("COALESCE(-SUM(ledger_entries.amount), 0)").
+		Scan(&taken).Error
+	return taken, err
+}
+
+type gormReviewRepo struct{ db *gorm.DB }
+
+func (r *gormReviewRepo) ListByDoctor(doctorID string) ([]models.Review, error) {
+	var reviews []models.Review
+	err := r.db.Where("doctor_id = ?", doctorID).Find(&reviews).Error
+	return reviews, err
+}
+
+func (r *gormReviewRepo) Create(review *models.Review) error {
+	return r.db.Create(review).Error
+}
+
+type gormPrescriptionRepo struct{ db *gorm.DB }
+
+func (r *gormPrescriptionRepo) Create(rx *models.Prescription) error {
+	return r.db.Omit("Appointment").Create(rx).Error
+}
+
+func (r *gormPrescriptionRepo) FindForPatient(id, patientID string) (*models.Prescription, error) {
+	var rx models.Prescription
+	if err := r.db.First(&rx, "id = ? AND patient_id = ?", id, patientID).Error; err != nil {
+		return nil, err
+	}
+	return &rx, nil
+}
+
+func (r *gormPrescriptionRepo) LockForPatient(id, patientID string) (*models.Prescription, error) {
+	var rx models.Prescription
+	if err := r.db.Clauses(forUpdate).First(&rx, "id = ? AND patient_id = ?", id, patientID).Error; err != nil {
+		return nil, err
+	}
+	return &rx, nil
+}
+
+func (r *gormPrescriptionRepo) ListByPatient(patientID string) ([]models.Prescription, error) {
+	var list []models.Prescription
+	err := r.db.Where("patient_id = ?", patientID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+func (r *gormPrescriptionRepo) ListByAppointment(appointmentID string) ([]models.Prescription, error) {
+	var list []models.Prescription
+	err := r.db.Where("appointment_id = ?", appointmentID).Order("created_at DESC").Find(&list).Error
+	return list, err
+}
+
+type gormPaymentRepo struct{ db *gorm.DB }
+
+func (r *gormPaymentRepo) Create(p *models.Payment) error {
+	return r.db.Create(p).Error
+}
+
+func (r *gormPaymentRepo) Lock(id string) (*models.Payment, error) {
+	var p models.Payment
+	if err := r.db.Clauses(forUpdate).First(&p, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *gormPaymentRepo) LockByIntent(intentID string) (*models.Payment, error) {
+	var p models.Payment
+	if err := r.db.Clauses(forUpdate).First(&p, "intent_id = ?", intentID).Error; err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *gormPaymentRepo) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.Payment{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *gormPaymentRepo) UpdateIfStatus(id string, status models.PaymentStatus, fields map[string]interface{}) (bool, error) {
+	return updateIf(r.db.Model(&models.Payment{}).Where("id = ? AND status = ?", id, status), fields)
+}
+
+func (r *gormPaymentRepo) query(filter PaymentFilter) *gorm.DB {
+	q := r.db.Model(&models.Payment{})
+	if filter.UserID != "" {
+		q = q.Where("user_id = ?", filter.UserID)
+	}
+	if filter.AppointmentID != "" {
+		q = q.Where("appointment_id = ?", filter.AppointmentID)
+	}
+	if filter.OrderID != "" {
+		q = q.Where("order_id = ?", filter.OrderID)
+	}
+	if len(filter.Statuses) > 0 {
+		q = q.Where("status IN ?", filter.Statuses)
+	}
+	return q.Order("created_at DESC")
+}
+
+func (r *gormPaymentRepo) List(filter PaymentFilter) ([]models.Payment, error) {
+	var list []models.Payment
+	err := r.query(filter).Find(&list).Error
+	return list, err
+}
+
+func (r *gormPaymentRepo) LockList(filter PaymentFilter) ([]models.Payment, error) {
+	var list []models.Payment
+	err := r.query(filter).Clauses(forUpdate).Find(&list).Error
+	return list, err
+}
+
+type gormSessionRepo struct{ db *gorm.DB }
+
+func (r *gormSessionRepo) Create(session *models.Session) error {
+	return r.db.Omit("User").Create(session).Error
+}
+
+func (r *gormSessionRepo) FindByID(id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.First(&session, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepo) Lock(id string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Clauses(forUpdate).First(&session, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *gormSessionRepo) Update(id string, fields map[string]interface{}) error {
+	return r.db.Model(&models.Session{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *gormSessionRepo) Revoke(id, userID string, at time.Time) (bool, error) {
+	q := r.db.Model(&models.Session{}).Where("id = ? AND revoked_at IS NULL", id)
+	if userID != "" {
+		q = q.Where("user_id = ?", userID)
+	}
+	return updateIf(q, map[string]interface{}{"revoked_at": at})
+}
+
+func (r *gormSessionRepo) RevokeAll(userID string, at time.Time) error {
+	return r.db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", at).Error
+}
+
+func (r *gormSessionRepo) ListActive(userID string, now time.Time) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+type gormRecoveryCodeRepo struct{ db *gorm.DB }
+
+func (r *gormRecoveryCodeRepo) Replace(userID string, codes []models.RecoveryCode) error {
+	if err := r.DeleteAll(userID); err != nil {
+		return err
+	}
+	return r.db.Omit("User").Create(&codes).Error
+}
+
+func (r *gormRecoveryCodeRepo) DeleteAll(userID string) error {
+	return r.db.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+func (r *gormRecoveryCodeRepo) Use(userID, hash string, at time.Time) (bool, error) {
+	return updateIf(r.db.Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash),
+		map[string]interface{}{"used_at": at})
+}
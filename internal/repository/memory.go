@@ -0,0 +1,1414 @@
+package repository
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/google/uuid"
+	"gorm.io/gorm/schema"
+)
+
+// NewMemoryRepos returns in-memory repositories sharing one store, so
+// relations (an appointment's patient, a check's appointment) resolve the
+// same way they do in Postgres. Meant for tests and local experiments.
+//
+// Transactions are serialised, so Lock methods are plain reads and advisory
+// locks are always free; a failed transaction restores the whole store. The
+// Outbox is a *notify.MemoryQueue that only sees committed messages.
+func NewMemoryRepos() Repos {
+	s := &memoryStore{
+		users:         map[string]models.User{},
+		doctors:       map[string]models.DoctorProfile{},
+		admins:        map[string]models.AdminProfile{},
+		appointments:  map[string]models.Appointment{},
+		proposals:     map[string]models.RescheduleProposal{},
+		leaves:        map[string]models.DoctorLeave{},
+		reminders:     map[string]models.AppointmentReminder{},
+		checks:        map[string]models.MedicalCheck{},
+		prescriptions: map[string]models.Prescription{},
+		orders:        map[string]models.Order{},
+		medicines:     map[string]models.Medicine{},
+		payments:      map[string]models.Payment{},
+		transactions:  map[string]models.Transaction{},
+		reviews:       map[string]models.Review{},
+		sessions:      map[string]models.Session{},
+		recoveryCodes: map[string]models.RecoveryCode{},
+	}
+	return s.repos(notify.NewMemoryQueue(), false)
+}
+
+type memoryStore struct {
+	// txMu is held by the outermost running transaction
+	txMu sync.Mutex
+
+	mu            sync.RWMutex
+	users         map[string]models.User
+	doctors       map[string]models.DoctorProfile
+	admins        map[string]models.AdminProfile
+	appointments  map[string]models.Appointment
+	proposals     map[string]models.RescheduleProposal
+	leaves        map[string]models.DoctorLeave
+	reminders     map[string]models.AppointmentReminder
+	checks        map[string]models.MedicalCheck
+	prescriptions map[string]models.Prescription
+	orders        map[string]models.Order
+	medicines     map[string]models.Medicine
+	payments      map[string]models.Payment
+	transactions  map[string]models.Transaction
+	reviews       map[string]models.Review
+	sessions      map[string]models.Session
+	recoveryCodes map[string]models.RecoveryCode
+}
+
+// repos binds the repositories to s, queueing messages on outbox. inTx marks
+// the Repos handed to a transaction, whose own transactions nest.
+func (s *memoryStore) repos(outbox notify.Queue, inTx bool) Repos {
+	return Repos{
+		Tx:            &memoryTx{s: s, outbox: outbox, nested: inTx},
+		Users:         &memoryUserRepo{s},
+		Doctors:       &memoryDoctorRepo{s},
+		Admins:        &memoryAdminRepo{s},
+		Appointments:  &memoryAppointmentRepo{s},
+		Proposals:     &memoryProposalRepo{s},
+		Leaves:        &memoryLeaveRepo{s},
+		Reminders:     &memoryReminderRepo{s},
+		MedicalChecks: &memoryMedicalCheckRepo{s},
+		Prescriptions: &memoryPrescriptionRepo{s},
+		Orders:        &memoryOrderRepo{s},
+		Medicines:     &memoryMedicineRepo{s},
+		Payments:      &memoryPaymentRepo{s},
+		Ledger:        &memoryLedgerRepo{s},
+		Reviews:       &memoryReviewRepo{s},
+		Sessions:      &memorySessionRepo{s},
+		RecoveryCodes: &memoryRecoveryCodeRepo{s},
+		Outbox:        outbox,
+	}
+}
+
+// snapshot copies every table. Stored rows are values and never mutated in
+// place, so copying the maps is enough.
+func (s *memoryStore) snapshot() *memoryStore {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return &memoryStore{
+		users:         maps.Clone(s.users),
+		doctors:       maps.Clone(s.doctors),
+		admins:        maps.Clone(s.admins),
+		appointments:  maps.Clone(s.appointments),
+		proposals:     maps.Clone(s.proposals),
+		leaves:        maps.Clone(s.leaves),
+		reminders:     maps.Clone(s.reminders),
+		checks:        maps.Clone(s.checks),
+		prescriptions: maps.Clone(s.prescriptions),
+		orders:        maps.Clone(s.orders),
+		medicines:     maps.Clone(s.medicines),
+		payments:      maps.Clone(s.payments),
+		transactions:  maps.Clone(s.transactions),
+		reviews:       maps.Clone(s.reviews),
+		sessions:      maps.Clone(s.sessions),
+		recoveryCodes: maps.Clone(s.recoveryCodes),
+	}
+}
+
+func (s *memoryStore) restore(snap *memoryStore) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users, s.doctors, s.admins = snap.users, snap.doctors, snap.admins
+	s.appointments, s.proposals, s.leaves, s.reminders = snap.appointments, snap.proposals, snap.leaves, snap.reminders
+	s.checks, s.prescriptions, s.orders, s.medicines = snap.checks, snap.prescriptions, snap.orders, snap.medicines
+	s.payments, s.transactions, s.reviews = snap.payments, snap.transactions, snap.reviews
+	s.sessions, s.recoveryCodes = snap.sessions, snap.recoveryCodes
+}
+
+type memoryTx struct {
+	s *memoryStore
+	// outbox receives the messages of a committed transaction
+	outbox notify.Queue
+	nested bool
+}
+
+func (t *memoryTx) Transaction(fn func(r Repos) error) error {
+	if !t.nested {
+		t.s.txMu.Lock()
+		defer t.s.txMu.Unlock()
+	}
+	snap := t.s.snapshot()
+	queued := notify.NewMemoryQueue()
+	if err := fn(t.s.repos(queued, true)); err != nil {
+		t.s.restore(snap)
+		return err
+	}
+	return t.outbox.Enqueue(queued.Messages()...)
+}
+
+func (t *memoryTx) TryAdvisoryLock(key int64) (bool, error) {
+	return true, nil
+}
+
+// stamp fills the ID and timestamps the way Postgres defaults and GORM would.
+func stamp(id *string, createdAt, updatedAt *time.Time) {
+	now := time.Now()
+	if *id == "" {
+		*id = uuid.NewString()
+	}
+	if createdAt != nil && createdAt.IsZero() {
+		*createdAt = now
+	}
+	if updatedAt != nil {
+		*updatedAt = now
+	}
+}
+
+func (s *memoryStore) doctorWithUser(id string) (models.DoctorProfile, bool) {
+	d, ok := s.doctors[id]
+	if !ok {
+		return d, false
+	}
+	if u, ok := s.users[d.UserID]; ok {
+		d.User = &u
+	}
+	return d, true
+}
+
+func (s *memoryStore) hydrate(a models.Appointment) models.Appointment {
+	a.Patient = s.users[a.PatientID]
+	if d, ok := s.doctorWithUser(a.DoctorProfileID); ok {
+		a.DoctorProfile = d
+	}
+	return a
+}
+
+type memoryUserRepo struct{ s *memoryStore }
+
+func (r *memoryUserRepo) FindByID(id string) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &u, nil
+}
+
+func (r *memoryUserRepo) find(match func(models.User) bool) (*models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, u := range r.s.users {
+		if match(u) {
+			return &u, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryUserRepo) FindByEmail(email string) (*models.User, error) {
+	return r.find(func(u models.User) bool { return u.Email == email })
+}
+
+func (r *memoryUserRepo) FindByPhone(phone string) (*models.User, error) {
+	return r.find(func(u models.User) bool { return u.Phone == phone })
+}
+
+func (r *memoryUserRepo) ExistsByPhoneOrEmail(phone, email string) (bool, error) {
+	_, err := r.find(func(u models.User) bool {
+		return (u.Phone != "" && u.Phone == phone) || (u.Email != "" && u.Email == email)
+	})
+	return err == nil, nil
+}
+
+func (r *memoryUserRepo) ListByRole(role models.Role, limit, offset int) ([]models.User, int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var users []models.User
+	for _, u := range r.s.users {
+		if u.Role == role {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.Before(users[j].CreatedAt) })
+
+	total := int64(len(users))
+	if offset >= len(users) {
+		return []models.User{}, total, nil
+	}
+	end := offset + limit
+	if end > len(users) {
+		end = len(users)
+	}
+	return users[offset:end], total, nil
+}
+
+func (r *memoryUserRepo) Create(user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if _, exists := r.s.users[user.ID]; exists {
+		return fmt.Errorf("duplicate user id %s", user.ID)
+	}
+	r.s.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepo) Save(user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	r.s.users[user.ID] = *user
+	return nil
+}
+
+func (r *memoryUserRepo) Update(id string, fields map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		// GORM's Updates with a Where clause does not fail on zero rows either
+		return nil
+	}
+	if err := applyFields(&u, fields); err != nil {
+		return err
+	}
+	u.UpdatedAt = time.Now()
+	r.s.users[id] = u
+	return nil
+}
+
+func (r *memoryUserRepo) Lock(id string) (*models.User, error) {
+	return r.FindByID(id)
+}
+
+func (r *memoryUserRepo) IncrementFailedLogins(id string) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	u, ok := r.s.users[id]
+	if !ok {
+		return 0, nil
+	}
+	u.FailedLoginAttempts++
+	u.UpdatedAt = time.Now()
+	r.s.users[id] = u
+	return u.FailedLoginAttempts, nil
+}
+
+func (r *memoryUserRepo) IncrementNoShows(id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if u, ok := r.s.users[id]; ok {
+		u.NoShowCount++
+		r.s.users[id] = u
+	}
+	return nil
+}
+
+func (r *memoryUserRepo) ExtendBookingBlock(id string, until time.Time) error {
+	_, err := updateRow(r.s, usersTable, id, map[string]interface{}{"booking_blocked_until": until},
+		func(u models.User) bool { return u.BookingBlockedUntil == nil || u.BookingBlockedUntil.Before(until) })
+	return err
+}
+
+type memoryDoctorRepo struct{ s *memoryStore }
+
+func (r *memoryDoctorRepo) FindByID(id string) (*models.DoctorProfile, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	d, ok := r.s.doctorWithUser(id)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	d.Reviews = r.s.reviewsOf(d.UserID)
+	return &d, nil
+}
+
+func (r *memoryDoctorRepo) FindByUserID(userID string) (*models.DoctorProfile, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, d := range r.s.doctors {
+		if d.UserID == userID {
+			return &d, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryDoctorRepo) List(filter DoctorFilter) ([]models.DoctorProfile, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var doctors []models.DoctorProfile
+	for id := range r.s.doctors {
+		d, _ := r.s.doctorWithUser(id)
+		if d.IsPending {
+			continue
+		}
+		if filter.Specialization != "" &&
+			!strings.Contains(strings.ToLower(d.Specialization), strings.ToLower(filter.Specialization)) {
+			continue
+		}
+		if filter.Search != "" &&
+			(d.User == nil || !strings.Contains(strings.ToLower(d.User.Name), strings.ToLower(filter.Search))) {
+			continue
+		}
+		if d.Rating < filter.MinRating {
+			continue
+		}
+		d.Reviews = r.s.reviewsOf(d.UserID)
+		doctors = append(doctors, d)
+	}
+	sort.Slice(doctors, func(i, j int) bool { return doctors[i].CreatedAt.Before(doctors[j].CreatedAt) })
+	return doctors, nil
+}
+
+func (r *memoryDoctorRepo) Create(profile *models.DoctorProfile) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&profile.ID, &profile.CreatedAt, &profile.UpdatedAt)
+	if _, exists := r.s.doctors[profile.ID]; exists {
+		return fmt.Errorf("duplicate doctor profile id %s", profile.ID)
+	}
+	stored := *profile
+	stored.User, stored.Reviews = nil, nil
+	r.s.doctors[profile.ID] = stored
+	return nil
+}
+
+func (r *memoryDoctorRepo) Save(profile *models.DoctorProfile) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&profile.ID, &profile.CreatedAt, &profile.UpdatedAt)
+	stored := *profile
+	stored.User, stored.Reviews = nil, nil
+	r.s.doctors[profile.ID] = stored
+	return nil
+}
+
+func (r *memoryDoctorRepo) Lock(id string) (*models.DoctorProfile, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	d, ok := r.s.doctors[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &d, nil
+}
+
+func (r *memoryDoctorRepo) Approve(id, adminID string) (bool, error) {
+	return updateRow(r.s, doctorsTable, id, map[string]interface{}{"is_pending": false, "approved_by": adminID},
+		func(d models.DoctorProfile) bool { return d.IsPending })
+}
+
+func (r *memoryDoctorRepo) IncrementNoShows(id string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	if d, ok := r.s.doctors[id]; ok {
+		d.NoShowCount++
+		r.s.doctors[id] = d
+	}
+	return nil
+}
+
+type memoryAdminRepo struct{ s *memoryStore }
+
+func (r *memoryAdminRepo) Create(profile *models.AdminProfile) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&profile.ID, &profile.CreatedAt, &profile.UpdatedAt)
+	for _, a := range r.s.admins {
+		if a.UserID == profile.UserID {
+			return fmt.Errorf("user %s already has an admin profile", profile.UserID)
+		}
+	}
+	stored := *profile
+	stored.User = models.User{}
+	r.s.admins[profile.ID] = stored
+	return nil
+}
+
+func (r *memoryAdminRepo) Ensure(userID string) error {
+	r.s.mu.RLock()
+	for _, a := range r.s.admins {
+		if a.UserID == userID {
+			r.s.mu.RUnlock()
+			return nil
+		}
+	}
+	r.s.mu.RUnlock()
+	return r.Create(&models.AdminProfile{UserID: userID})
+}
+
+type memoryAppointmentRepo struct{ s *memoryStore }
+
+func (r *memoryAppointmentRepo) FindByID(id string) (*models.Appointment, error) {
+	return r.findWhere(func(a models.Appointment) bool { return a.ID == id })
+}
+
+func (r *memoryAppointmentRepo) FindForDoctor(id, doctorProfileID string) (*models.Appointment, error) {
+	return r.findWhere(func(a models.Appointment) bool {
+		return a.ID == id && a.DoctorProfileID == doctorProfileID
+	})
+}
+
+func (r *memoryAppointmentRepo) FindForPatient(id, patientID string) (*models.Appointment, error) {
+	return r.findWhere(func(a models.Appointment) bool {
+		return a.ID == id && a.PatientID == patientID
+	})
+}
+
+func (r *memoryAppointmentRepo) findWhere(match func(models.Appointment) bool) (*models.Appointment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, a := range r.s.appointments {
+		if match(a) {
+			a = r.s.hydrate(a)
+			return &a, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryAppointmentRepo) List(filter AppointmentFilter) ([]models.Appointment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	appts := []models.Appointment{}
+	for _, a := range r.s.appointments {
+		if filter.DoctorProfileID != "" && a.DoctorProfileID != filter.DoctorProfileID {
+			continue
+		}
+		if filter.PatientID != "" && a.PatientID != filter.PatientID {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !hasStatus(filter.Statuses, a.Status) {
+			continue
+		}
+		if filter.After != nil && !a.ScheduledAt.After(*filter.After) {
+			continue
+		}
+		if filter.From != nil && a.ScheduledAt.Before(*filter.From) {
+			continue
+		}
+		if filter.Before != nil && !a.ScheduledAt.Before(*filter.Before) {
+			continue
+		}
+		appts = append(appts, r.s.hydrate(a))
+	}
+	sort.Slice(appts, func(i, j int) bool {
+		if filter.NewestFirst {
+			return appts[i].ScheduledAt.After(appts[j].ScheduledAt)
+		}
+		return appts[i].ScheduledAt.Before(appts[j].ScheduledAt)
+	})
+	return appts, nil
+}
+
+func (r *memoryAppointmentRepo) PatientsOf(doctorProfileID string, status models.AppointmentStatus) ([]models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	seen := map[string]bool{}
+	var patients []models.User
+	for _, a := range r.s.appointments {
+		if a.DoctorProfileID != doctorProfileID || a.Status != status || seen[a.PatientID] {
+			continue
+		}
+		if u, ok := r.s.users[a.PatientID]; ok {
+			seen[a.PatientID] = true
+			patients = append(patients, u)
+		}
+	}
+	return patients, nil
+}
+
+func (r *memoryAppointmentRepo) CountByDoctor(status models.AppointmentStatus, since *time.Time) ([]DoctorCount, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	byDoctor := map[string]*DoctorCount{}
+	for _, a := range r.s.appointments {
+		if a.Status != status || (since != nil && a.UpdatedAt.Before(*since)) {
+			continue
+		}
+		c, ok := byDoctor[a.DoctorProfileID]
+		if !ok {
+			c = &DoctorCount{DoctorProfileID: a.DoctorProfileID}
+			if d, found := r.s.doctorWithUser(a.DoctorProfileID); found && d.User != nil {
+				c.DoctorName = d.User.Name
+			}
+			byDoctor[a.DoctorProfileID] = c
+		}
+		c.Count++
+	}
+
+	counts := make([]DoctorCount, 0, len(byDoctor))
+	for _, c := range byDoctor {
+		counts = append(counts, *c)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Count > counts[j].Count })
+	return counts, nil
+}
+
+func (r *memoryAppointmentRepo) Create(appt *models.Appointment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&appt.ID, &appt.CreatedAt, &appt.UpdatedAt)
+	if _, exists := r.s.appointments[appt.ID]; exists {
+		return fmt.Errorf("duplicate appointment id %s", appt.ID)
+	}
+	r.s.appointments[appt.ID] = stripAppointment(*appt)
+	return nil
+}
+
+func (r *memoryAppointmentRepo) Save(appt *models.Appointment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&appt.ID, &appt.CreatedAt, &appt.UpdatedAt)
+	r.s.appointments[appt.ID] = stripAppointment(*appt)
+	return nil
+}
+
+func (r *memoryAppointmentRepo) Update(id string, fields map[string]interface{}) error {
+	_, err := updateRow(r.s, appointmentsTable, id, fields, nil)
+	return err
+}
+
+func (r *memoryAppointmentRepo) UpdateIfStatus(id string, status models.AppointmentStatus, fields map[string]interface{}) (bool, error) {
+	return updateRow(r.s, appointmentsTable, id, fields,
+		func(a models.Appointment) bool { return a.Status == status })
+}
+
+func (r *memoryAppointmentRepo) Lock(id string) (*models.Appointment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	a, ok := r.s.appointments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &a, nil
+}
+
+func (r *memoryAppointmentRepo) LockStaleRequests(f StaleRequestFilter) ([]models.Appointment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	stale := []models.Appointment{}
+	for _, a := range r.s.appointments {
+		madeAt := a.CreatedAt
+		var madeBy time.Time
+		switch a.Status {
+		case models.PENDING:
+			madeBy = f.PendingMadeBy
+		case models.RESCHEDULE_REQUESTED:
+			madeBy = f.RescheduleMadeBy
+			for _, p := range r.s.proposals {
+				if p.AppointmentID == a.ID && p.CreatedAt.After(madeAt) {
+					madeAt = p.CreatedAt
+				}
+			}
+		default:
+			continue
+		}
+		if !a.ScheduledAt.After(f.Now) || !madeAt.After(madeBy) {
+			stale = append(stale, a)
+		}
+	}
+	sort.Slice(stale, func(i, j int) bool { return stale[i].ScheduledAt.Before(stale[j].ScheduledAt) })
+	if f.Limit > 0 && len(stale) > f.Limit {
+		stale = stale[:f.Limit]
+	}
+	return stale, nil
+}
+
+type memoryProposalRepo struct{ s *memoryStore }
+
+func (r *memoryProposalRepo) Create(p *models.RescheduleProposal) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if p.Status == "" {
+		p.Status = models.PROPOSAL_OPEN
+	}
+	stored := *p
+	stored.Appointment = models.Appointment{}
+	r.s.proposals[p.ID] = stored
+	return nil
+}
+
+func (r *memoryProposalRepo) FindOpen(appointmentID string) (*models.RescheduleProposal, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, p := range r.s.proposals {
+		if p.AppointmentID == appointmentID && p.Status == models.PROPOSAL_OPEN {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryProposalRepo) Close(id string, status models.ProposalStatus, at time.Time) error {
+	_, err := updateRow(r.s, proposalsTable, id, map[string]interface{}{"status": status, "responded_at": at}, nil)
+	return err
+}
+
+func (r *memoryProposalRepo) ExpireDue(now time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, p := range r.s.proposals {
+		if p.Status == models.PROPOSAL_OPEN && !p.ExpiresAt.After(now) {
+			p.Status, p.RespondedAt, p.UpdatedAt = models.PROPOSAL_EXPIRED, &now, time.Now()
+			r.s.proposals[id] = p
+		}
+	}
+	return nil
+}
+
+func (r *memoryProposalRepo) ListByAppointment(appointmentID string) ([]models.RescheduleProposal, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	proposals := []models.RescheduleProposal{}
+	for _, p := range r.s.proposals {
+		if p.AppointmentID == appointmentID {
+			proposals = append(proposals, p)
+		}
+	}
+	sort.Slice(proposals, func(i, j int) bool { return proposals[i].CreatedAt.After(proposals[j].CreatedAt) })
+	return proposals, nil
+}
+
+type memoryLeaveRepo struct{ s *memoryStore }
+
+func (r *memoryLeaveRepo) Create(leave *models.DoctorLeave) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&leave.ID, &leave.CreatedAt, &leave.UpdatedAt)
+	stored := *leave
+	stored.DoctorProfile = models.DoctorProfile{}
+	r.s.leaves[leave.ID] = stored
+	return nil
+}
+
+func (r *memoryLeaveRepo) Cancel(id, doctorProfileID string, at time.Time) (bool, error) {
+	return updateRow(r.s, leavesTable, id, map[string]interface{}{"cancelled_at": at},
+		func(l models.DoctorLeave) bool { return l.DoctorProfileID == doctorProfileID && l.CancelledAt == nil })
+}
+
+func (r *memoryLeaveRepo) Active(doctorProfileID string, from, to time.Time) ([]models.DoctorLeave, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	leaves := []models.DoctorLeave{}
+	for _, l := range r.s.leaves {
+		if l.DoctorProfileID == doctorProfileID && l.CancelledAt == nil &&
+			l.StartsAt.Before(to) && l.EndsAt.After(from) {
+			leaves = append(leaves, l)
+		}
+	}
+	sort.Slice(leaves, func(i, j int) bool { return leaves[i].StartsAt.Before(leaves[j].StartsAt) })
+	return leaves, nil
+}
+
+type memoryReminderRepo struct{ s *memoryStore }
+
+func (r *memoryReminderRepo) ForAppointments(ids []string) ([]models.AppointmentReminder, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	want := map[string]bool{}
+	for _, id := range ids {
+		want[id] = true
+	}
+	rows := []models.AppointmentReminder{}
+	for _, row := range r.s.reminders {
+		if want[row.AppointmentID] {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func (r *memoryReminderRepo) CreateNew(rows []models.AppointmentReminder) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	var inserted int64
+	for _, row := range rows {
+		// the key of the unique index
+		key := fmt.Sprintf("%s/%d/%s", row.AppointmentID, row.OffsetMinutes, row.ScheduledAt.UTC().Format(time.RFC3339Nano))
+		if _, exists := r.s.reminders[key]; exists {
+			continue
+		}
+		stamp(&row.ID, &row.CreatedAt, nil)
+		r.s.reminders[key] = row
+		inserted++
+	}
+	return inserted, nil
+}
+
+func stripAppointment(a models.Appointment) models.Appointment {
+	a.Patient = models.User{}
+	a.DoctorProfile = models.DoctorProfile{}
+	return a
+}
+
+func hasStatus(statuses []models.AppointmentStatus, s models.AppointmentStatus) bool {
+	for _, candidate := range statuses {
+		if candidate == s {
+			return true
+		}
+	}
+	return false
+}
+
+type memoryMedicalCheckRepo struct{ s *memoryStore }
+
+func (r *memoryMedicalCheckRepo) FindForDoctor(id, doctorUserID string) (*models.MedicalCheck, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	c, ok := r.s.checks[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	a, ok := r.s.appointments[c.AppointmentID]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if d, ok := r.s.doctors[a.DoctorProfileID]; !ok || d.UserID != doctorUserID {
+		return nil, ErrNotFound
+	}
+	return &c, nil
+}
+
+func (r *memoryMedicalCheckRepo) ListByPatient(patientID string) ([]models.MedicalCheck, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	checks := []models.MedicalCheck{}
+	for _, c := range r.s.checks {
+		if a, ok := r.s.appointments[c.AppointmentID]; ok && a.PatientID == patientID {
+			checks = append(checks, c)
+		}
+	}
+	sort.Slice(checks, func(i, j int) bool { return checks[i].CreatedAt.After(checks[j].CreatedAt) })
+	return checks, nil
+}
+
+func (r *memoryMedicalCheckRepo) Create(check *models.MedicalCheck) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&check.ID, &check.CreatedAt, &check.UpdatedAt)
+	for _, c := range r.s.checks {
+		if c.AppointmentID == check.AppointmentID {
+			return fmt.Errorf("appointment %s already has a medical check", check.AppointmentID)
+		}
+	}
+	stored := *check
+	stored.Appointment = models.Appointment{}
+	r.s.checks[check.ID] = stored
+	return nil
+}
+
+func (r *memoryMedicalCheckRepo) Save(check *models.MedicalCheck) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&check.ID, &check.CreatedAt, &check.UpdatedAt)
+	stored := *check
+	stored.Appointment = models.Appointment{}
+	r.s.checks[check.ID] = stored
+	return nil
+}
+
+type memoryOrderRepo struct{ s *memoryStore }
+
+func (r *memoryOrderRepo) FindByID(id string) (*models.Order, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	o, ok := r.s.orders[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &o, nil
+}
+
+func (r *memoryOrderRepo) FindForUser(id, userID string) (*models.Order, error) {
+	o, err := r.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if o.UserID != userID {
+		return nil, ErrNotFound
+	}
+	return o, nil
+}
+
+func (r *memoryOrderRepo) List(filter OrderFilter) ([]models.Order, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	orders := []models.Order{}
+	for _, o := range r.s.orders {
+		if filter.UserID != "" && o.UserID != filter.UserID {
+			continue
+		}
+		if filter.Status != "" && o.Status != filter.Status {
+			continue
+		}
+		if filter.PrescriptionID != "" && (o.PrescriptionID == nil || *o.PrescriptionID != filter.PrescriptionID) {
+			continue
+		}
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.Before(orders[j].CreatedAt) })
+	return orders, nil
+}
+
+func (r *memoryOrderRepo) ListByUser(userID string) ([]models.Order, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	orders := []models.Order{}
+	for _, o := range r.s.orders {
+		if o.UserID == userID {
+			orders = append(orders, o)
+		}
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].CreatedAt.After(orders[j].CreatedAt) })
+	return orders, nil
+}
+
+func (r *memoryOrderRepo) Create(order *models.Order) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	if _, exists := r.s.orders[order.ID]; exists {
+		return fmt.Errorf("duplicate order id %s", order.ID)
+	}
+	stored := *order
+	stored.User = models.User{}
+	r.s.orders[order.ID] = stored
+	return nil
+}
+
+func (r *memoryOrderRepo) Save(order *models.Order) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&order.ID, &order.CreatedAt, &order.UpdatedAt)
+	stored := *order
+	stored.User = models.User{}
+	r.s.orders[order.ID] = stored
+	return nil
+}
+
+func (r *memoryOrderRepo) Update(id string, fields map[string]interface{}) error {
+	_, err := updateRow(r.s, ordersTable, id, fields, nil)
+	return err
+}
+
+func (r *memoryOrderRepo) Lock(id string) (*models.Order, error) {
+	return r.FindByID(id)
+}
+
+type memoryMedicineRepo struct{ s *memoryStore }
+
+func (r *memoryMedicineRepo) FindByID(id string) (*models.Medicine, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	m, ok := r.s.medicines[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &m, nil
+}
+
+func (r *memoryMedicineRepo) FindBySKU(sku string) (*models.Medicine, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, m := range r.s.medicines {
+		if m.SKU == sku {
+			return &m, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryMedicineRepo) List(filter MedicineFilter) ([]models.Medicine, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	search := strings.ToLower(filter.Search)
+	meds := []models.Medicine{}
+	for _, m := range r.s.medicines {
+		if !filter.IncludeInactive && !m.Active {
+			continue
+		}
+		if search != "" && !strings.Contains(strings.ToLower(m.Name), search) &&
+			!strings.Contains(strings.ToLower(m.SKU), search) {
+			continue
+		}
+		if filter.Form != "" && m.Form != filter.Form {
+			continue
+		}
+		if filter.InStockOnly && m.StockQuantity <= 0 {
+			continue
+		}
+		meds = append(meds, m)
+	}
+	sort.Slice(meds, func(i, j int) bool { return meds[i].Name < meds[j].Name })
+	return meds, nil
+}
+
+func (r *memoryMedicineRepo) Create(med *models.Medicine) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&med.ID, &med.CreatedAt, &med.UpdatedAt)
+	for _, m := range r.s.medicines {
+		if m.SKU == med.SKU {
+			return fmt.Errorf("duplicate medicine sku %s", med.SKU)
+		}
+	}
+	r.s.medicines[med.ID] = *med
+	return nil
+}
+
+func (r *memoryMedicineRepo) Update(id string, fields map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m, ok := r.s.medicines[id]
+	if !ok {
+		return nil
+	}
+	if err := applyFields(&m, fields); err != nil {
+		return err
+	}
+	m.UpdatedAt = time.Now()
+	r.s.medicines[id] = m
+	return nil
+}
+
+func (r *memoryMedicineRepo) UpsertBySKU(meds []models.Medicine) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	bySKU := map[string]string{}
+	for id, m := range r.s.medicines {
+		bySKU[m.SKU] = id
+	}
+	for _, med := range meds {
+		if id, ok := bySKU[med.SKU]; ok {
+			existing := r.s.medicines[id]
+			med.ID, med.CreatedAt = id, existing.CreatedAt
+			med.UpdatedAt = time.Now()
+		} else {
+			stamp(&med.ID, &med.CreatedAt, &med.UpdatedAt)
+			bySKU[med.SKU] = med.ID
+		}
+		r.s.medicines[med.ID] = med
+	}
+	return nil
+}
+
+func (r *memoryMedicineRepo) LockMany(ids []string) ([]models.Medicine, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	meds := []models.Medicine{}
+	for _, id := range ids {
+		if m, ok := r.s.medicines[id]; ok {
+			meds = append(meds, m)
+		}
+	}
+	sort.Slice(meds, func(i, j int) bool { return meds[i].ID < meds[j].ID })
+	return meds, nil
+}
+
+func (r *memoryMedicineRepo) AddStock(id string, delta int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	m, ok := r.s.medicines[id]
+	if !ok || m.StockQuantity+delta < 0 {
+		return false, nil
+	}
+	m.StockQuantity += delta
+	m.UpdatedAt = time.Now()
+	r.s.medicines[id] = m
+	return true, nil
+}
+
+type memoryLedgerRepo struct{ s *memoryStore }
+
+func (r *memoryLedgerRepo) Create(txn *models.Transaction) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&txn.ID, &txn.CreatedAt, nil)
+	for i := range txn.Entries {
+		txn.Entries[i].TransactionID = txn.ID
+		stamp(&txn.Entries[i].ID, &txn.Entries[i].CreatedAt, nil)
+	}
+	stored := *txn
+	stored.Entries = append([]models.LedgerEntry(nil), txn.Entries...)
+	r.s.transactions[txn.ID] = stored
+	return nil
+}
+
+func (r *memoryLedgerRepo) ForDoctor(doctorProfileID string) ([]models.Transaction, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	txns := []models.Transaction{}
+	for _, t := range r.s.transactions {
+		if t.DoctorProfileID != nil && *t.DoctorProfileID == doctorProfileID {
+			t.Entries = append([]models.LedgerEntry(nil), t.Entries...)
+			txns = append(txns, t)
+		}
+	}
+	sort.Slice(txns, func(i, j int) bool { return txns[i].OccurredAt.Before(txns[j].OccurredAt) })
+	return txns, nil
+}
+
+func (r *memoryLedgerRepo) DoctorBalance(doctorProfileID string) (int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var sum int64
+	for _, t := range r.s.transactions {
+		for _, e := range t.Entries {
+			if e.Account == models.ACCOUNT_DOCTOR_PAYABLE && e.DoctorProfileID != nil && *e.DoctorProfileID == doctorProfileID {
+				sum += e.Amount
+			}
+		}
+	}
+	// credits are negative
+	return -sum, nil
+}
+
+func (r *memoryLedgerRepo) CommissionTaken(paymentID string) (int64, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	var sum int64
+	for _, t := range r.s.transactions {
+		if t.PaymentID == nil || *t.PaymentID != paymentID || t.Kind != models.TXN_COMMISSION {
+			continue
+		}
+		for _, e := range t.Entries {
+			if e.Account == models.ACCOUNT_PLATFORM_REVENUE {
+				sum += e.Amount
+			}
+		}
+	}
+	return -sum, nil
+}
+
+type memoryReviewRepo struct{ s *memoryStore }
+
+func (s *memoryStore) reviewsOf(doctorID string) []models.Review {
+	reviews := []models.Review{}
+	for _, rv := range s.reviews {
+		if rv.DoctorID == doctorID {
+			reviews = append(reviews, rv)
+		}
+	}
+	sort.Slice(reviews, func(i, j int) bool { return reviews[i].CreatedAt.Before(reviews[j].CreatedAt) })
+	return reviews
+}
+
+func (r *memoryReviewRepo) ListByDoctor(doctorID string) ([]models.Review, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	return r.s.reviewsOf(doctorID), nil
+}
+
+func (r *memoryReviewRepo) Create(review *models.Review) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&review.ID, &review.CreatedAt, nil)
+	r.s.reviews[review.ID] = *review
+	return nil
+}
+
+type memoryPrescriptionRepo struct{ s *memoryStore }
+
+func (r *memoryPrescriptionRepo) Create(rx *models.Prescription) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&rx.ID, &rx.CreatedAt, &rx.UpdatedAt)
+	stored := *rx
+	stored.Appointment = models.Appointment{}
+	r.s.prescriptions[rx.ID] = stored
+	return nil
+}
+
+func (r *memoryPrescriptionRepo) FindForPatient(id, patientID string) (*models.Prescription, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	rx, ok := r.s.prescriptions[id]
+	if !ok || rx.PatientID != patientID {
+		return nil, ErrNotFound
+	}
+	return &rx, nil
+}
+
+func (r *memoryPrescriptionRepo) LockForPatient(id, patientID string) (*models.Prescription, error) {
+	return r.FindForPatient(id, patientID)
+}
+
+func (r *memoryPrescriptionRepo) list(match func(models.Prescription) bool) []models.Prescription {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	list := []models.Prescription{}
+	for _, rx := range r.s.prescriptions {
+		if match(rx) {
+			list = append(list, rx)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list
+}
+
+func (r *memoryPrescriptionRepo) ListByPatient(patientID string) ([]models.Prescription, error) {
+	return r.list(func(rx models.Prescription) bool { return rx.PatientID == patientID }), nil
+}
+
+func (r *memoryPrescriptionRepo) ListByAppointment(appointmentID string) ([]models.Prescription, error) {
+	return r.list(func(rx models.Prescription) bool { return rx.AppointmentID == appointmentID }), nil
+}
+
+type memoryPaymentRepo struct{ s *memoryStore }
+
+func (r *memoryPaymentRepo) Create(p *models.Payment) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	for _, existing := range r.s.payments {
+		if existing.IntentID == p.IntentID {
+			return fmt.Errorf("duplicate payment intent %s", p.IntentID)
+		}
+	}
+	r.s.payments[p.ID] = *p
+	return nil
+}
+
+func (r *memoryPaymentRepo) Lock(id string) (*models.Payment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	p, ok := r.s.payments[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &p, nil
+}
+
+func (r *memoryPaymentRepo) LockByIntent(intentID string) (*models.Payment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	for _, p := range r.s.payments {
+		if p.IntentID == intentID {
+			return &p, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (r *memoryPaymentRepo) Update(id string, fields map[string]interface{}) error {
+	_, err := updateRow(r.s, paymentsTable, id, fields, nil)
+	return err
+}
+
+func (r *memoryPaymentRepo) UpdateIfStatus(id string, status models.PaymentStatus, fields map[string]interface{}) (bool, error) {
+	return updateRow(r.s, paymentsTable, id, fields, func(p models.Payment) bool { return p.Status == status })
+}
+
+func (r *memoryPaymentRepo) List(filter PaymentFilter) ([]models.Payment, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	list := []models.Payment{}
+	for _, p := range r.s.payments {
+		if filter.UserID != "" && p.UserID != filter.UserID {
+			continue
+		}
+		if filter.AppointmentID != "" && (p.AppointmentID == nil || *p.AppointmentID != filter.AppointmentID) {
+			continue
+		}
+		if filter.OrderID != "" && (p.OrderID == nil || *p.OrderID != filter.OrderID) {
+			continue
+		}
+		if len(filter.Statuses) > 0 && !hasPaymentStatus(filter.Statuses, p.Status) {
+			continue
+		}
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.After(list[j].CreatedAt) })
+	return list, nil
+}
+
+func (r *memoryPaymentRepo) LockList(filter PaymentFilter) ([]models.Payment, error) {
+	return r.List(filter)
+}
+
+func hasPaymentStatus(statuses []models.PaymentStatus, s models.PaymentStatus) bool {
+	for _, candidate := range statuses {
+		if candidate == s {
+			return true
+		}
+	}
+	return false
+}
+
+type memorySessionRepo struct{ s *memoryStore }
+
+func (r *memorySessionRepo) Create(session *models.Session) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	stamp(&session.ID, &session.CreatedAt, &session.UpdatedAt)
+	stored := *session
+	stored.User = models.User{}
+	r.s.sessions[session.ID] = stored
+	return nil
+}
+
+func (r *memorySessionRepo) FindByID(id string) (*models.Session, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	session, ok := r.s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &session, nil
+}
+
+func (r *memorySessionRepo) Lock(id string) (*models.Session, error) {
+	return r.FindByID(id)
+}
+
+func (r *memorySessionRepo) Update(id string, fields map[string]interface{}) error {
+	_, err := updateRow(r.s, sessionsTable, id, fields, nil)
+	return err
+}
+
+func (r *memorySessionRepo) Revoke(id, userID string, at time.Time) (bool, error) {
+	return updateRow(r.s, sessionsTable, id, map[string]interface{}{"revoked_at": at},
+		func(s models.Session) bool { return s.RevokedAt == nil && (userID == "" || s.UserID == userID) })
+}
+
+func (r *memorySessionRepo) RevokeAll(userID string, at time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, session := range r.s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt, session.UpdatedAt = &at, time.Now()
+			r.s.sessions[id] = session
+		}
+	}
+	return nil
+}
+
+func (r *memorySessionRepo) ListActive(userID string, now time.Time) ([]models.Session, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+	sessions := []models.Session{}
+	for _, session := range r.s.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt) })
+	return sessions, nil
+}
+
+type memoryRecoveryCodeRepo struct{ s *memoryStore }
+
+func (r *memoryRecoveryCodeRepo) Replace(userID string, codes []models.RecoveryCode) error {
+	if err := r.DeleteAll(userID); err != nil {
+		return err
+	}
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for i := range codes {
+		stamp(&codes[i].ID, &codes[i].CreatedAt, nil)
+		r.s.recoveryCodes[codes[i].ID] = codes[i]
+	}
+	return nil
+}
+
+func (r *memoryRecoveryCodeRepo) DeleteAll(userID string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, c := range r.s.recoveryCodes {
+		if c.UserID == userID {
+			delete(r.s.recoveryCodes, id)
+		}
+	}
+	return nil
+}
+
+func (r *memoryRecoveryCodeRepo) Use(userID, hash string, at time.Time) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+	for id, c := range r.s.recoveryCodes {
+		if c.UserID == userID && c.CodeHash == hash && c.UsedAt == nil {
+			c.UsedAt = &at
+			r.s.recoveryCodes[id] = c
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// table selectors for updateRow; the maps are swapped on rollback, so they
+// are looked up under the store lock.
+func usersTable(s *memoryStore) map[string]models.User                   { return s.users }
+func doctorsTable(s *memoryStore) map[string]models.DoctorProfile        { return s.doctors }
+func appointmentsTable(s *memoryStore) map[string]models.Appointment     { return s.appointments }
+func proposalsTable(s *memoryStore) map[string]models.RescheduleProposal { return s.proposals }
+func leavesTable(s *memoryStore) map[string]models.DoctorLeave           { return s.leaves }
+func ordersTable(s *memoryStore) map[string]models.Order                 { return s.orders }
+func paymentsTable(s *memoryStore) map[string]models.Payment             { return s.payments }
+func sessionsTable(s *memoryStore) map[string]models.Session             { return s.sessions }
+
+// updateRow applies fields to row id of a table when match, if set, accepts
+// it, and reports whether it did. Like GORM, a missing row is no error.
+func updateRow[T any](s *memoryStore, table func(*memoryStore) map[string]T, id string, fields map[string]interface{}, match func(T) bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rows := table(s)
+	row, ok := rows[id]
+	if !ok || (match != nil && !match(row)) {
+		return false, nil
+	}
+	if err := applyFields(&row, fields); err != nil {
+		return false, err
+	}
+	if updated := reflect.ValueOf(&row).Elem().FieldByName("UpdatedAt"); updated.IsValid() {
+		updated.Set(reflect.ValueOf(time.Now()))
+	}
+	rows[id] = row
+	return true, nil
+}
+
+var naming = schema.NamingStrategy{}
+
+// applyFields mimics GORM's Updates(map) on a struct: keys are column names,
+// nil clears pointer fields and zeroes value fields.
+func applyFields(dst interface{}, fields map[string]interface{}) error {
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+	for column, value := range fields {
+		idx := -1
+		for i := 0; i < t.NumField(); i++ {
+			if naming.ColumnName("", t.Field(i).Name) == column {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return fmt.Errorf("unknown column %q on %s", column, t.Name())
+		}
+
+		field := v.Field(idx)
+		if value == nil {
+			field.Set(reflect.Zero(field.Type()))
+			continue
+		}
+		val := reflect.ValueOf(value)
+		switch {
+		case val.Type().AssignableTo(field.Type()):
+			field.Set(val)
+		case field.Kind() == reflect.Ptr && val.Type().AssignableTo(field.Type().Elem()):
+			ptr := reflect.New(field.Type().Elem())
+			ptr.Elem().Set(val)
+			field.Set(ptr)
+		case val.Type().ConvertibleTo(field.Type()):
+			field.Set(val.Convert(field.Type()))
+		default:
+			return fmt.Errorf("cannot assign %T to column %q", value, column)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+// Package repository hides persistence behind small interfaces so services
+// can run against Postgres (GORM) in production and in-memory fakes in tests.
+package repository
+
+import (
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned by every implementation when a lookup misses. It
+// is GORM's sentinel so existing errors.Is checks keep working.
+var ErrNotFound = gorm.ErrRecordNotFound
+
+type UserRepo interface {
+	FindByID(id string) (*models.User, error)
+	FindByEmail(email string) (*models.User, error)
+	FindByPhone(phone string) (*models.User, error)
+	// ExistsByPhoneOrEmail ignores empty arguments.
+	ExistsByPhoneOrEmail(phone, email string) (bool, error)
+	// ListByRole returns one page of users with their appointments, orders
+	// and role profile, plus the total count for the role.
+	ListByRole(role models.Role, limit, offset int) ([]models.User, int64, error)
+	Create(user *models.User) error
+	Save(user *models.User) error
+	Update(id string, fields map[string]interface{}) error
+	// Lock loads the user FOR UPDATE; inside a Transaction only.
+	Lock(id string) (*models.User, error)
+	// IncrementFailedLogins adds one failed login atomically and returns the
+	// new count, so concurrent failures are all counted.
+	IncrementFailedLogins(id string) (int, error)
+	IncrementNoShows(id string) error
+	// ExtendBookingBlock blocks the user from booking until until, unless an
+	// existing block already lasts longer.
+	ExtendBookingBlock(id string, until time.Time) error
+}
+
+// DoctorFilter narrows the public doctor listing; zero values match all.
+type DoctorFilter struct {
+	Specialization string
+	Search         string
+	MinRating      float64
+}
+
+type DoctorRepo interface {
+	// FindByID loads an approved or pending profile with its user and reviews.
+	FindByID(id string) (*models.DoctorProfile, error)
+	FindByUserID(userID string) (*models.DoctorProfile, error)
+	// List returns approved doctors only.
+	List(filter DoctorFilter) ([]models.DoctorProfile, error)
+	Create(profile *models.DoctorProfile) error
+	Save(profile *models.DoctorProfile) error
+	// Lock loads the profile FOR UPDATE. Every flow that checks or takes a
+	// doctor's slots holds this lock, so they are serialised per doctor.
+	Lock(id string) (*models.DoctorProfile, error)
+	// Approve clears is_pending on behalf of adminID and reports false if
+	// the profile was not pending.
+	Approve(id, adminID string) (bool, error)
+	IncrementNoShows(id string) error
+}
+
+// AppointmentFilter selects appointments; zero values match all.
+type AppointmentFilter struct {
+	DoctorProfileID string
+	PatientID       string
+	Statuses        []models.AppointmentStatus
+	// After keeps appointments scheduled strictly after this time, From
+	// those scheduled at or after it and Before those strictly before it.
+	After       *time.Time
+	From        *time.Time
+	Before      *time.Time
+	NewestFirst bool
+}
+
+// StaleRequestFilter selects the requests to expire: PENDING appointments
+// created at or before PendingMadeBy, RESCHEDULE_REQUESTED ones whose latest
+// proposal (or the appointment itself, if there is none) was made at or
+// before RescheduleMadeBy, and either kind once its slot has begun by Now.
+type StaleRequestFilter struct {
+	Now              time.Time
+	PendingMadeBy    time.Time
+	RescheduleMadeBy time.Time
+	Limit            int
+}
+
+// DoctorCount is one row of a per-doctor appointment count.
+type DoctorCount struct {
+	DoctorProfileID string `json:"doctorProfileId"`
+	DoctorName      string `json:"doctorName"`
+	Count           int64  `json:"count"`
+}
+
+type AppointmentRepo interface {
+	FindByID(id string) (*models.Appointment, error)
+	// FindForDoctor and FindForPatient only match appointments owned by the
+	// given doctor profile or patient.
+	FindForDoctor(id, doctorProfileID string) (*models.Appointment, error)
+	FindForPatient(id, patientID string) (*models.Appointment, error)
+	// List preloads the patient and the doctor profile with its user.
+	List(filter AppointmentFilter) ([]models.Appointment, error)
+	// PatientsOf returns the distinct patients with an appointment in status.
+	PatientsOf(doctorProfileID string, status models.AppointmentStatus) ([]models.User, error)
+	// CountByDoctor counts appointments in status per doctor, most first.
+	// since, when set, keeps only rows last updated at or after it.
+	CountByDoctor(status models.AppointmentStatus, since *time.Time) ([]DoctorCount, error)
+	Create(appt *models.Appointment) error
+	Save(appt *models.Appointment) error
+	Update(id string, fields map[string]interface{}) error
+	// UpdateIfStatus applies fields only while the appointment is still in
+	// status and reports whether it did, so two concurrent transitions
+	// cannot both move it.
+	UpdateIfStatus(id string, status models.AppointmentStatus, fields map[string]interface{}) (bool, error)
+	// Lock loads the bare appointment FOR UPDATE.
+	Lock(id string) (*models.Appointment, error)
+	// LockStaleRequests locks up to filter.Limit stale requests, oldest slot
+	// first, skipping rows another transaction holds.
+	LockStaleRequests(filter StaleRequestFilter) ([]models.Appointment, error)
+}
+
+type MedicalCheckRepo interface {
+	// FindForDoctor matches a check only if it belongs to an appointment of
+	// the doctor with user ID doctorUserID.
+	FindForDoctor(id, doctorUserID string) (*models.MedicalCheck, error)
+	ListByPatient(patientID string) ([]models.MedicalCheck, error)
+	Create(check *models.MedicalCheck) error
+	Save(check *models.MedicalCheck) error
+}
+
+// OrderFilter selects orders; zero values match all.
+type OrderFilter struct {
+	UserID         string
+	Status         models.OrderStatus
+	PrescriptionID string
+}
+
+type OrderRepo interface {
+	FindByID(id string) (*models.Order, error)
+	// FindForUser only matches an order placed by userID.
+	FindForUser(id, userID string) (*models.Order, error)
+	ListByUser(userID string) ([]models.Order, error)
+	// List returns matching orders, oldest first, so staff work the queue in
+	// order.
+	List(filter OrderFilter) ([]models.Order, error)
+	Create(order *models.Order) error
+	Save(order *models.Order) error
+	Update(id string, fields map[string]interface{}) error
+	Lock(id string) (*models.Order, error)
+}
+
+// MedicineFilter narrows the catalog listing; zero values match all active
+// medicines.
+type MedicineFilter struct {
+	// Search matches name or SKU, case-insensitively.
+	Search          string
+	Form            models.MedicineForm
+	InStockOnly     bool
+	IncludeInactive bool
+}
+
+type MedicineRepo interface {
+	FindByID(id string) (*models.Medicine, error)
+	FindBySKU(sku string) (*models.Medicine, error)
+	// List returns matching medicines by name.
+	List(filter MedicineFilter) ([]models.Medicine, error)
+	Create(med *models.Medicine) error
+	// Update never touches stock_quantity unless it is in fields; stock is
+	// changed through the atomic reserve/release/adjust paths instead.
+	Update(id string, fields map[string]interface{}) error
+	// UpsertBySKU inserts meds or overwrites the existing row with the same
+	// SKU, stock included, all in one statement.
+	UpsertBySKU(meds []models.Medicine) error
+	// LockMany loads the medicines FOR UPDATE in ID order, so two checkouts
+	// cannot deadlock. Unknown IDs are skipped.
+	LockMany(ids []string) ([]models.Medicine, error)
+	// AddStock adds delta to the stock in one statement and reports false,
+	// changing nothing, if that would take it below zero.
+	AddStock(id string, delta int) (bool, error)
+}
+
+type LedgerRepo interface {
+	// Create stores a transaction with its entries.
+	Create(txn *models.Transaction) error
+	// ForDoctor returns the doctor's transactions with their entries,
+	// oldest first.
+	ForDoctor(doctorProfileID string) ([]models.Transaction, error)
+	// DoctorBalance is what the platform owes the doctor, in minor units.
+	DoctorBalance(doctorProfileID string) (int64, error)
+	// CommissionTaken is the commission booked for a payment, in minor units.
+	CommissionTaken(paymentID string) (int64, error)
+}
+
+type ReviewRepo interface {
+	// ListByDoctor takes the doctor's user ID.
+	ListByDoctor(doctorID string) ([]models.Review, error)
+	Create(review *models.Review) error
+}
+
+type AdminRepo interface {
+	Create(profile *models.AdminProfile) error
+	// Ensure creates an empty profile for userID unless one exists.
+	Ensure(userID string) error
+}
+
+type SessionRepo interface {
+	Create(session *models.Session) error
+	FindByID(id string) (*models.Session, error)
+	Lock(id string) (*models.Session, error)
+	Update(id string, fields map[string]interface{}) error
+	// Revoke revokes the session if it is still active and, unless userID
+	// is empty, owned by userID, and reports whether it did.
+	Revoke(id, userID string, at time.Time) (bool, error)
+	// RevokeAll signs the user out everywhere.
+	RevokeAll(userID string, at time.Time) error
+	// ListActive returns sessions neither revoked nor expired at now, most
+	// recently used first.
+	ListActive(userID string, now time.Time) ([]models.Session, error)
+}
+
+type RecoveryCodeRepo interface {
+	// Replace deletes the user's codes and stores codes instead.
+	Replace(userID string, codes []models.RecoveryCode) error
+	DeleteAll(userID string) error
+	// Use marks the user's unused code with hash as used at at and reports
+	// whether there was one.
+	Use(userID, hash string, at time.Time) (bool, error)
+}
+
+// PaymentFilter selects payments; zero values match all.
+type PaymentFilter struct {
+	UserID        string
+	AppointmentID string
+	OrderID       string
+	Statuses      []models.PaymentStatus
+}
+
+type PaymentRepo interface {
+	Create(p *models.Payment) error
+	Lock(id string) (*models.Payment, error)
+	LockByIntent(intentID string) (*models.Payment, error)
+	Update(id string, fields map[string]interface{}) error
+	// UpdateIfStatus applies fields only while the payment is still in
+	// status and reports whether it did.
+	UpdateIfStatus(id string, status models.PaymentStatus, fields map[string]interface{}) (bool, error)
+	// List returns matching payments, newest first.
+	List(filter PaymentFilter) ([]models.Payment, error)
+	// LockList is List FOR UPDATE.
+	LockList(filter PaymentFilter) ([]models.Payment, error)
+}
+
+type PrescriptionRepo interface {
+	Create(rx *models.Prescription) error
+	// FindForPatient and LockForPatient only match prescriptions of patientID.
+	FindForPatient(id, patientID string) (*models.Prescription, error)
+	LockForPatient(id, patientID string) (*models.Prescription, error)
+	// ListByPatient and ListByAppointment return newest first.
+	ListByPatient(patientID string) ([]models.Prescription, error)
+	ListByAppointment(appointmentID string) ([]models.Prescription, error)
+}
+
+type ProposalRepo interface {
+	Create(p *models.RescheduleProposal) error
+	// FindOpen returns the appointment's OPEN proposal.
+	FindOpen(appointmentID string) (*models.RescheduleProposal, error)
+	// Close records the answer to a proposal.
+	Close(id string, status models.ProposalStatus, at time.Time) error
+	// ExpireDue moves OPEN proposals past their expiry to EXPIRED.
+	ExpireDue(now time.Time) error
+	// ListByAppointment returns the proposal history, newest first.
+	ListByAppointment(appointmentID string) ([]models.RescheduleProposal, error)
+}
+
+type LeaveRepo interface {
+	Create(leave *models.DoctorLeave) error
+	// Cancel cancels one of the doctor's active leaves and reports whether
+	// there was one.
+	Cancel(id, doctorProfileID string, at time.Time) (bool, error)
+	// Active returns the doctor's uncancelled leaves overlapping from..to,
+	// earliest first.
+	Active(doctorProfileID string, from, to time.Time) ([]models.DoctorLeave, error)
+}
+
+type ReminderRepo interface {
+	// ForAppointments returns every reminder row of the appointments.
+	ForAppointments(ids []string) ([]models.AppointmentReminder, error)
+	// CreateNew inserts rows, skipping any another writer already recorded,
+	// and returns how many were inserted.
+	CreateNew(rows []models.AppointmentReminder) (int64, error)
+}
+
+// Transactor runs fn in one transaction. The Repos handed to fn are bound to
+// it: their writes, outbox messages included, commit or roll back together,
+// and rows taken with a Lock method stay locked until fn returns. Starting a
+// Transaction from those Repos nests it.
+type Transactor interface {
+	Transaction(fn func(r Repos) error) error
+	// TryAdvisoryLock takes the lock key until the transaction ends and
+	// reports false if another transaction holds it. It is only meaningful
+	// on the Repos handed to fn.
+	TryAdvisoryLock(key int64) (bool, error)
+}
+
+// Repos bundles one implementation of every repository.
+type Repos struct {
+	Tx Transactor
+
+	Users         UserRepo
+	Doctors       DoctorRepo
+	Admins        AdminRepo
+	Appointments  AppointmentRepo
+	Proposals     ProposalRepo
+	Leaves        LeaveRepo
+	Reminders     ReminderRepo
+	MedicalChecks MedicalCheckRepo
+	Prescriptions PrescriptionRepo
+	Orders        OrderRepo
+	Medicines     MedicineRepo
+	Payments      PaymentRepo
+	Ledger        LedgerRepo
+	Reviews       ReviewRepo
+	Sessions      SessionRepo
+	RecoveryCodes RecoveryCodeRepo
+
+	// Outbox queues notifications for the notify worker.
+	Outbox notify.Queue
+}
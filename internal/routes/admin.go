@@ -2,13 +2,40 @@ package routes
 
 import (
 	"github.com/GitNinja36/wello-backend/internal/controllers"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
-func AdminRoutes(r chi.Router) {
-	//create new admin
-	r.Post("/register", controllers.CreateAdminAccount)
+func AdminRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(middleware.JWTAuthMiddleware(h.Keys, h.Sessions))
+		r.Use(middleware.RequireRole(models.ADMIN))
 
-	//approved Doctor
-	r.Post("/approved/doctor/{id}", controllers.ApproveDoctor)
+		// everything here can grant access, so require a fresh second factor
+		r.Use(middleware.RequireStepUp(h.TwoFactor))
+
+		//create new admin
+		r.Post("/register", h.CreateAdminAccount)
+
+		//approved Doctor
+		r.Post("/approved/doctor/{id}", h.ApproveDoctor)
+
+		//change user role or approval
+		r.Put("/users/{id}/access", h.UpdateUserAccess)
+
+		//enforce or reset two-factor for a user
+		r.Put("/users/{id}/2fa", h.SetUserTwoFactorRequired)
+		r.Delete("/users/{id}/2fa", h.ResetUserTwoFactor)
+
+		//lift a no-show booking restriction
+		r.Delete("/users/{id}/booking-restriction", h.LiftBookingRestriction)
+
+		//doctor ledger and payouts
+		r.Get("/doctors/{id}/ledger", h.GetDoctorLedger)
+		r.Post("/doctors/{id}/payouts", h.CreateDoctorPayout)
+
+		//expired appointment requests per doctor
+		r.Get("/appointments/expired", h.GetExpiredRequestCounts)
+	}
 }
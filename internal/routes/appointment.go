@@ -3,10 +3,25 @@ package routes
 import (
 	"github.com/GitNinja36/wello-backend/internal/controllers"
 	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
-func AppointmentRoutes(r chi.Router) {
-	// Book Appointment
-	r.With(middleware.JWTAuthMiddleware).Post("/book", controllers.BookAppointment)
+func AppointmentRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(middleware.JWTAuthMiddleware(h.Keys, h.Sessions))
+
+		// Book Appointment
+		r.With(middleware.RequireRole(models.PATIENT), middleware.RequireProfile).Post("/book", h.BookAppointment)
+
+		// reschedule proposals, from either side
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(models.PATIENT, models.DOCTOR))
+
+			r.Get("/{id}/proposals", h.GetRescheduleProposals)
+			r.Post("/{id}/proposals", h.ProposeReschedule)
+			r.Post("/{id}/proposals/{proposalId}/accept", h.AcceptRescheduleProposal)
+			r.Post("/{id}/proposals/{proposalId}/reject", h.RejectRescheduleProposal)
+		})
+	}
 }
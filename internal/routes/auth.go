@@ -2,13 +2,53 @@ package routes
 
 import (
 	"github.com/GitNinja36/wello-backend/internal/controllers"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
-func AuthRoutes(r chi.Router) {
-	r.Post("/send/phone/otp", controllers.SendOTPPhone)
-	r.Post("/verify/phone/otp", controllers.VerifyOTPPhone)
+func AuthRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Post("/send/phone/otp", h.SendOTPPhone)
+		r.Post("/verify/phone/otp", h.VerifyOTPPhone)
 
-	r.Post("/send/email/otp", controllers.SendOTPEmail)
-	r.Post("/verify/email/otp", controllers.VerifyOTPEmail)
+		r.Post("/send/email/otp", h.SendOTPEmail)
+		r.Post("/verify/email/otp", h.VerifyOTPEmail)
+
+		// password login, plus OTP step for admins
+		r.Post("/login", h.Login)
+		r.Post("/login/otp", h.VerifyLoginOTP)
+		r.Post("/login/totp", h.VerifyLoginTOTP)
+
+		// forgot / reset password via OTP
+		r.Post("/password/forgot", h.ForgotPassword)
+		r.Post("/password/reset", h.ResetPassword)
+
+		// rotate refresh token
+		r.Post("/refresh", h.RefreshToken)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.JWTAuthMiddleware(h.Keys, h.Sessions))
+
+			// revoke current session
+			r.Post("/logout", h.Logout)
+
+			// list and revoke sessions per device
+			r.Get("/sessions", h.GetSessions)
+			r.Delete("/sessions/{id}", h.RevokeSession)
+
+			// TOTP two-factor for doctors and admins
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireRole(models.DOCTOR, models.ADMIN))
+
+				r.Post("/2fa/totp/enroll", h.EnrollTOTP)
+				r.Post("/2fa/totp/confirm", h.ConfirmTOTP)
+				r.Post("/2fa/totp/disable", h.DisableTOTP)
+				r.Post("/2fa/recovery-codes", h.RegenerateRecoveryCodes)
+
+				// re-verify before sensitive actions
+				r.Post("/2fa/step-up", h.StepUp)
+			})
+		})
+	}
 }
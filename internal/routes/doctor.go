@@ -3,64 +3,111 @@ package routes
 import (
 	"github.com/GitNinja36/wello-backend/internal/controllers"
 	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
-func DoctorRoutes(r chi.Router) {
-	//update profile
-	r.With(middleware.JWTAuthMiddleware).Put("/profile", controllers.UpdateDoctorProfile)
+func DoctorRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		// Get free slots of a doctor (public)
+		r.Get("/{id}/availability", h.GetDoctorAvailability)
 
-	//update doctor slot
-	r.With(middleware.JWTAuthMiddleware).Post("/slots", controllers.UpdateDoctorAvailability)
+		r.Group(authenticatedDoctorRoutes(h))
+	}
+}
+
+func authenticatedDoctorRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(middleware.JWTAuthMiddleware(h.Keys, h.Sessions))
+
+		// doctor-only routes, allowed while approval is still pending
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(models.DOCTOR))
+
+			//update profile
+			r.Put("/profile", h.UpdateDoctorProfile)
+
+			//update doctor slot
+			r.Post("/slots", h.UpdateDoctorAvailability)
+
+			//update doctor fee
+			r.Put("/fee", h.UpdateDoctorFee)
+		})
+
+		// doctor-only routes that need an approved account
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(models.DOCTOR))
+			r.Use(middleware.RequireApproved)
+
+			//get All upcoming appointments
+			r.Get("/dashboard/appointments", h.GetDoctorAppointments)
+
+			//accept or reject appointment
+			r.Put("/dashboard/appointment/{id}", h.RespondToAppointment)
+
+			//get Doctor Earnings
+			r.Get("/earnings", h.GetDoctorEarnings)
+
+			//get doctor reviews
+			r.Get("/reviews", h.GetDoctorReviews)
+
+			// to created Dummy appointment --> for test route
+			r.Post("/debug/seed-appointment", h.SeedDummyAppointment)
+
+			//to Reschedule Appointment
+			r.Put("/appointment/reschedule/{id}", h.RescheduleAppointment)
 
-	//update doctor fee
-	r.With(middleware.JWTAuthMiddleware).Put("/fee", controllers.UpdateDoctorFee)
+			// view reschedule requests
+			r.Get("/reschedule-requests", h.GetDoctorRescheduleRequests)
 
-	//get All upcoming appointments
-	r.With(middleware.JWTAuthMiddleware).Get("/dashboard/appointments", controllers.GetDoctorAppointments)
+			// View all upcoming appointments for doctor
+			r.Get("/upcoming-appointments", h.GetUpcomingAppointmentsForDoctor)
 
-	//accept or reject appointment
-	r.With(middleware.JWTAuthMiddleware).Put("/dashboard/appointment/{id}", controllers.RespondToAppointment)
+			// mark appointment as completed
+			r.Put("/appointments/{id}/complete", h.CompleteAppointment)
 
-	//get Doctor Earnings
-	r.With(middleware.JWTAuthMiddleware).Get("/earnings", controllers.GetDoctorEarnings)
+			// cancel appointment with a reason
+			r.Put("/appointments/{id}/cancel", h.CancelAppointmentByDoctor)
 
-	//get doctor reviews
-	r.With(middleware.JWTAuthMiddleware).Get("/reviews", controllers.GetDoctorReviews)
+			// mark patient as no-show
+			r.Put("/appointments/{id}/no-show", h.MarkPatientNoShow)
 
-	// to created Dummy appointment --> for test route
-	r.With(middleware.JWTAuthMiddleware).Post("/debug/seed-appointment", controllers.SeedDummyAppointment)
+			//Add Appointment Summary
+			r.Put("/appointments/{id}/summary", h.AddAppointmentSummary)
 
-	//to Reschedule Appointment
-	r.With(middleware.JWTAuthMiddleware).Put("/appointment/reschedule/{id}", controllers.RescheduleAppointment)
+			//Get All Unique Patients of a Doctor
+			r.Get("/patients", h.GetAllPatientsForDoctor)
 
-	// view reschedule requests
-	r.With(middleware.JWTAuthMiddleware).Get("/reschedule-requests", controllers.GetDoctorRescheduleRequests)
+			// Download/Print Summary as PDF
+			r.Get("/appointments/{id}/summary-pdf", h.GenerateSummaryPDF)
 
-	// View all upcoming appointments for doctor
-	r.With(middleware.JWTAuthMiddleware).Get("/upcoming-appointments", controllers.GetUpcomingAppointmentsForDoctor)
+			// issue or list prescriptions for an appointment
+			r.Post("/appointments/{id}/prescriptions", h.IssuePrescription)
+			r.Get("/appointments/{id}/prescriptions", h.GetAppointmentPrescriptions)
 
-	// mark appointment as completed
-	r.With(middleware.JWTAuthMiddleware).Put("/appointments/{id}/complete", controllers.CompleteAppointment)
+			// Email Summary PDF to the patient
+			r.Post("/appointments/{id}/summary-pdf/email", h.EmailSummaryPDF)
 
-	//Add Appointment Summary
-	r.With(middleware.JWTAuthMiddleware).Put("/appointments/{id}/summary", controllers.AddAppointmentSummary)
+			// Add Test from Doctor Side
+			r.Post("/tests/add", h.CreateMedicalCheck)
 
-	//Get All Unique Patients of a Doctor
-	r.With(middleware.JWTAuthMiddleware).Get("/patients", controllers.GetAllPatientsForDoctor)
+			//Uploading Test Reports
+			r.Put("/tests/{id}/upload-report", h.UploadTestReport)
 
-	// Download/Print Summary as PDF
-	r.With(middleware.JWTAuthMiddleware).Get("/appointments/{id}/summary-pdf", controllers.GenerateSummaryPDF)
+			// create leave / blackout window
+			r.Post("/leaves", h.CreateDoctorLeave)
 
-	// Add Test from Doctor Side
-	r.With(middleware.JWTAuthMiddleware).Post("/tests/add", controllers.CreateMedicalCheck)
+			// list upcoming leaves
+			r.Get("/leaves", h.GetDoctorLeaves)
 
-	//Uploading Test Reports
-	r.With(middleware.JWTAuthMiddleware).Put("/tests/{id}/upload-report", controllers.UploadTestReport)
+			// cancel leave
+			r.Put("/leaves/{id}/cancel", h.CancelDoctorLeave)
+		})
 
-	// Get All Doctors
-	r.With(middleware.JWTAuthMiddleware).Get("/", controllers.GetAllDoctors)
+		// Get All Doctors
+		r.Get("/", h.GetAllDoctors)
 
-	// Get Single Doctor by ID
-	r.With(middleware.JWTAuthMiddleware).Get("/{id}", controllers.GetDoctorByID)
+		// Get Single Doctor by ID
+		r.Get("/{id}", h.GetDoctorByID)
+	}
 }
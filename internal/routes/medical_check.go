@@ -1,7 +1,12 @@
 package routes
 
-import "github.com/go-chi/chi/v5"
+import (
+	"github.com/GitNinja36/wello-backend/internal/controllers"
+	"github.com/go-chi/chi/v5"
+)
 
-func MedicalCheckRoutes(r chi.Router) {
-	// medical check routes
+func MedicalCheckRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		// medical check routes
+	}
 }
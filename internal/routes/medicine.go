@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"github.com/GitNinja36/wello-backend/internal/controllers"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/go-chi/chi/v5"
+)
+
+func MedicineRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(middleware.JWTAuthMiddleware(h.Keys, h.Sessions))
+
+		// browse the catalog
+		r.Get("/", h.GetMedicines)
+		r.Get("/{id}", h.GetMedicine)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(models.ADMIN))
+
+			// add a medicine
+			r.Post("/", h.CreateMedicine)
+
+			// bulk import from CSV
+			r.Post("/import", h.ImportMedicines)
+
+			// edit or discontinue
+			r.Put("/{id}", h.UpdateMedicine)
+			r.Delete("/{id}", h.DeleteMedicine)
+
+			// restock or write off
+			r.Patch("/{id}/stock", h.AdjustMedicineStock)
+		})
+	}
+}
@@ -1,7 +1,43 @@
 package routes
 
-import "github.com/go-chi/chi/v5"
+import (
+	"github.com/GitNinja36/wello-backend/internal/controllers"
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/go-chi/chi/v5"
+)
 
-func OrderRoutes(r chi.Router) {
-	// order routes
+func OrderRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(middleware.JWTAuthMiddleware(h.Keys, h.Sessions))
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(models.PATIENT))
+			r.Use(middleware.RequireProfile)
+
+			// place an order from the cart
+			r.Post("/", h.PlaceOrder)
+
+			// list my orders
+			r.Get("/", h.GetMyOrders)
+
+			// view one order
+			r.Get("/{id}", h.GetMyOrder)
+
+			// cancel while still pending
+			r.Put("/{id}/cancel", h.CancelOrder)
+		})
+
+		// fulfilment, for admins and pharmacy staff
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(domain.OrderStaff...))
+
+			// orders waiting to be worked, ?status=PENDING
+			r.Get("/manage", h.GetOrderQueue)
+
+			// move an order along
+			r.Put("/manage/{id}/status", h.UpdateOrderStatus)
+		})
+	}
 }
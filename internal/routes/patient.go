@@ -3,35 +3,55 @@ package routes
 import (
 	"github.com/GitNinja36/wello-backend/internal/controllers"
 	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
-func PatientRoutes(r chi.Router) {
+func PatientRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		r.Use(middleware.JWTAuthMiddleware(h.Keys, h.Sessions))
 
-	// Patient response to reschedule request
-	r.With(middleware.JWTAuthMiddleware).Put("/appointment/respond-reschedule/{id}", controllers.PatientRespondReschedule)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.RequireRole(models.PATIENT))
+			r.Use(middleware.RequireProfile)
 
-	// View upcoming appointments for patient
-	r.With(middleware.JWTAuthMiddleware).Get("/appointments/upcoming", controllers.GetUpcomingAppointmentsForPatient)
+			// Patient response to reschedule request
+			r.Put("/appointment/respond-reschedule/{id}", h.PatientRespondReschedule)
 
-	//Get Patient History for a Doctor
-	r.With(middleware.JWTAuthMiddleware).Get("/{patientId}/history", controllers.GetPatientHistoryForDoctor)
+			// View upcoming appointments for patient
+			r.Get("/appointments/upcoming", h.GetUpcomingAppointmentsForPatient)
 
-	//View Past Appointment History
-	r.With(middleware.JWTAuthMiddleware).Get("/appointments/history", controllers.GetPatientAppointmentHistory)
+			//View Past Appointment History
+			r.Get("/appointments/history", h.GetPatientAppointmentHistory)
 
-	// Cancel upcoming appointment
-	r.With(middleware.JWTAuthMiddleware).Put("/appointments/{id}/cancel", controllers.CancelAppointmentByPatient)
+			// Cancel upcoming appointment
+			r.Put("/appointments/{id}/cancel", h.CancelAppointmentByPatient)
 
-	//to give review
-	r.With(middleware.JWTAuthMiddleware).Post("/appointments/{id}/review", controllers.SubmitReviewForAppointment)
+			// Report that the doctor did not attend
+			r.Put("/appointments/{id}/no-show", h.ReportDoctorNoShow)
 
-	//Get All Appointments of a Patient
-	r.With(middleware.JWTAuthMiddleware).Get("/appointments/all", controllers.GetAllAppointmentsForPatient)
+			//to give review
+			r.Post("/appointments/{id}/review", h.SubmitReviewForAppointment)
 
-	//Get Patient Profile
-	r.With(middleware.JWTAuthMiddleware).Get("/profile", controllers.GetPatientProfile)
+			//Get All Appointments of a Patient
+			r.Get("/appointments/all", h.GetAllAppointmentsForPatient)
 
-	//Get Patient Test History
-	r.With(middleware.JWTAuthMiddleware).Get("/tests/history", controllers.GetPatientTestHistory)
+			//Get Patient Profile
+			r.Get("/profile", h.GetPatientProfile)
+
+			// prescriptions issued to the patient
+			r.Get("/prescriptions", h.GetMyPrescriptions)
+			r.Get("/prescriptions/{id}", h.GetMyPrescription)
+
+			// order everything on a prescription from the pharmacy
+			r.Post("/prescriptions/{id}/order", h.OrderPrescription)
+
+			//Get Patient Test History
+			r.Get("/tests/history", h.GetPatientTestHistory)
+		})
+
+		//Get Patient History for a Doctor
+		r.With(middleware.RequireRole(models.DOCTOR), middleware.RequireApproved, middleware.RequireStepUp(h.TwoFactor)).
+			Get("/{patientId}/history", h.GetPatientHistoryForDoctor)
+	}
 }
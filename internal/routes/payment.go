@@ -0,0 +1,40 @@
+package routes
+
+import (
+	"github.com/GitNinja36/wello-backend/internal/controllers"
+	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+	"github.com/go-chi/chi/v5"
+)
+
+func PaymentRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		// gateway callback, authenticated by its signature
+		r.Post("/webhook", h.PaymentWebhook)
+
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.JWTAuthMiddleware(h.Keys, h.Sessions))
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireRole(models.PATIENT))
+
+				// start paying an appointment fee or an order
+				r.Post("/appointments/{id}", h.CreateAppointmentPayment)
+				r.Post("/orders/{id}", h.CreateOrderPayment)
+
+				// my payments
+				r.Get("/", h.GetMyPayments)
+
+				// finish a checkout on the local mock gateway
+				if _, ok := h.Gateway.(*payments.MockGateway); ok {
+					r.Post("/mock/{intentId}/pay", h.MockCheckout)
+				}
+			})
+
+			// refund a payment
+			r.With(middleware.RequireRole(models.ADMIN), middleware.RequireStepUp(h.TwoFactor)).
+				Post("/{id}/refund", h.RefundPayment)
+		})
+	}
+}
@@ -3,11 +3,12 @@ package routes
 import (
 	"net/http"
 
+	"github.com/GitNinja36/wello-backend/internal/controllers"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-func SetupRoutes() *chi.Mux {
+func SetupRoutes(h *controllers.Handler) *chi.Mux {
 	r := chi.NewRouter()
 
 	// middleware
@@ -21,15 +22,20 @@ func SetupRoutes() *chi.Mux {
 		w.Write([]byte("server setup"))
 	})
 
+	// public JWT verification keys
+	r.Get("/.well-known/jwks.json", h.GetJWKS)
+
 	// All routes
-	r.Route("/auth", AuthRoutes)
-	r.Route("/admin", AdminRoutes)
-	r.Route("/user", UserRoutes)
-	r.Route("/doctor", DoctorRoutes)
-	r.Route("/patient", PatientRoutes)
-	r.Route("/appointment", AppointmentRoutes)
-	r.Route("/medical-check", MedicalCheckRoutes)
-	r.Route("/order", OrderRoutes)
+	r.Route("/auth", AuthRoutes(h))
+	r.Route("/admin", AdminRoutes(h))
+	r.Route("/user", UserRoutes(h))
+	r.Route("/doctor", DoctorRoutes(h))
+	r.Route("/patient", PatientRoutes(h))
+	r.Route("/appointment", AppointmentRoutes(h))
+	r.Route("/medical-check", MedicalCheckRoutes(h))
+	r.Route("/order", OrderRoutes(h))
+	r.Route("/medicines", MedicineRoutes(h))
+	r.Route("/payments", PaymentRoutes(h))
 
 	return r
 }
@@ -3,28 +3,31 @@ package routes
 import (
 	"github.com/GitNinja36/wello-backend/internal/controllers"
 	"github.com/GitNinja36/wello-backend/internal/middleware"
+	"github.com/GitNinja36/wello-backend/internal/models"
 	"github.com/go-chi/chi/v5"
 )
 
-func UserRoutes(r chi.Router) {
-	//register Patient
-	r.Post("/register/patient", controllers.RegisterPatient)
+func UserRoutes(h *controllers.Handler) func(chi.Router) {
+	return func(r chi.Router) {
+		//finish signup with the onboarding token from OTP verification
+		r.With(middleware.OnboardingAuthMiddleware(h.Keys)).Post("/onboard/patient", h.CompletePatientOnboarding)
 
-	//register Doctor
-	r.Post("/register/doctor", controllers.RegisterDoctor)
+		//doctor signup, also behind OTP verification; approval is still required
+		r.With(middleware.OnboardingAuthMiddleware(h.Keys)).Post("/onboard/doctor", h.CompleteDoctorOnboarding)
 
-	//
-	r.With(middleware.JWTAuthMiddleware).Post("/onboard/patient", controllers.CompletePatientOnboarding)
+		//Update Patient Profile
+		r.With(middleware.JWTAuthMiddleware(h.Keys, h.Sessions), middleware.RequireRole(models.PATIENT)).Put("/edit/patient/profile", h.UpdatePatientProfile)
 
-	//Update Patient Profile
-	r.With(middleware.JWTAuthMiddleware).Put("/edit/patient/profile", controllers.UpdatePatientProfile)
+		//Get Current User
+		r.With(middleware.JWTAuthMiddleware(h.Keys, h.Sessions)).Get("/me", h.GetCurrentUser)
 
-	//Get Current User
-	r.With(middleware.JWTAuthMiddleware).Get("/me", controllers.GetCurrentUser)
+		//update photo
+		r.With(middleware.JWTAuthMiddleware(h.Keys, h.Sessions)).Put("/me/photo", h.UpdateProfilePhoto)
 
-	//update photo
-	r.With(middleware.JWTAuthMiddleware).Put("/me/photo", controllers.UpdateProfilePhoto)
+		//set or change password
+		r.With(middleware.JWTAuthMiddleware(h.Keys, h.Sessions)).Put("/me/password", h.UpdatePassword)
 
-	//Get all user
-	r.Get("/all", controllers.GetUsersByRole)
+		//Get all user
+		r.With(middleware.JWTAuthMiddleware(h.Keys, h.Sessions), middleware.RequireRole(models.ADMIN)).Get("/all", h.GetUsersByRole)
+	}
 }
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+var (
+	ErrAdminExists        = errors.New("an admin account already exists")
+	ErrMissingCredentials = errors.New("email and password are required")
+	ErrAlreadyApproved    = errors.New("doctor is already approved")
+	ErrNoDoctorProfile    = errors.New("user has no doctor profile")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrBootstrapRunning   = errors.New("another admin bootstrap is in progress")
+)
+
+// bootstrapAdminLockKey is the Postgres advisory lock held while the first
+// admin is created.
+const bootstrapAdminLockKey int64 = 0x57656c6c6f41
+
+// AdminService holds the account administration flows. Each one changes who
+// a user is or what they may do, so it revokes the user's sessions in the
+// same transaction.
+type AdminService struct {
+	Repos repository.Repos
+}
+
+func NewAdminService(repos repository.Repos) *AdminService {
+	return &AdminService{Repos: repos}
+}
+
+type AdminInput struct {
+	Name       string
+	Email      string
+	Phone      string
+	Password   string
+	Position   string
+	Department string
+}
+
+// CreateAdmin creates an ADMIN user and its profile in a single transaction.
+func (s *AdminService) CreateAdmin(in AdminInput) (*models.User, error) {
+	hashedPassword, err := adminPassword(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var admin *models.User
+	err = s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		admin, err = createAdmin(r, in, hashedPassword)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// BootstrapAdmin creates the very first admin, for cmd/createadmin. It
+// refuses once any ADMIN user exists. The check and the insert run in one
+// transaction under an advisory lock, so two runs at once cannot both
+// create one.
+func (s *AdminService) BootstrapAdmin(in AdminInput) (*models.User, error) {
+	hashedPassword, err := adminPassword(in)
+	if err != nil {
+		return nil, err
+	}
+
+	var admin *models.User
+	err = s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		locked, err := r.Tx.TryAdvisoryLock(bootstrapAdminLockKey)
+		if err != nil {
+			return err
+		}
+		if !locked {
+			return ErrBootstrapRunning
+		}
+
+		_, count, err := r.Users.ListByRole(models.ADMIN, 1, 0)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrAdminExists
+		}
+		admin, err = createAdmin(r, in, hashedPassword)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
+
+// adminPassword validates in and returns the hash of its password.
+func adminPassword(in AdminInput) (string, error) {
+	if strings.TrimSpace(in.Email) == "" || strings.TrimSpace(in.Password) == "" {
+		return "", ErrMissingCredentials
+	}
+	if err := utils.ValidatePassword(in.Password); err != nil {
+		return "", err
+	}
+	return utils.HashPassword(in.Password)
+}
+
+func createAdmin(r repository.Repos, in AdminInput, hashedPassword string) (*models.User, error) {
+	adminUser := models.User{
+		Name:       in.Name,
+		Email:      in.Email,
+		Phone:      in.Phone,
+		Password:   hashedPassword,
+		Role:       models.ADMIN,
+		Verified:   true,
+		IsApproved: true,
+	}
+	if err := r.Users.Create(&adminUser); err != nil {
+		return nil, err
+	}
+	err := r.Admins.Create(&models.AdminProfile{
+		UserID:     adminUser.ID,
+		Position:   in.Position,
+		Department: in.Department,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &adminUser, nil
+}
+
+// ApproveDoctor approves a pending doctor profile on behalf of adminID. The
+// update is guarded by is_pending, so a second approval fails with
+// ErrAlreadyApproved.
+func (s *AdminService) ApproveDoctor(profile *models.DoctorProfile, adminID string) error {
+	return s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		approved, err := r.Doctors.Approve(profile.ID, adminID)
+		if err != nil {
+			return err
+		}
+		if !approved {
+			return ErrAlreadyApproved
+		}
+		if err := r.Users.Update(profile.UserID, map[string]interface{}{"is_approved": true}); err != nil {
+			return err
+		}
+		// existing tokens still say isApproved=false
+		return revokeUserSessions(r.Sessions, profile.UserID)
+	})
+}
+
+// AccessInput changes a user's role, approval or both; nil fields are kept.
+type AccessInput struct {
+	Role       *models.Role
+	IsApproved *bool
+}
+
+// UpdateUserAccess applies in to the user. Promoting to ADMIN creates the
+// admin profile, and only users with a doctor profile may become DOCTOR.
+func (s *AdminService) UpdateUserAccess(userID string, in AccessInput) error {
+	updates := map[string]interface{}{}
+	if in.Role != nil {
+		switch *in.Role {
+		case models.PATIENT, models.DOCTOR, models.ADMIN, models.PHARMACIST:
+			updates["role"] = *in.Role
+		default:
+			return ErrInvalidRole
+		}
+	}
+	if in.IsApproved != nil {
+		updates["is_approved"] = *in.IsApproved
+	}
+
+	return s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		user, err := r.Users.Lock(userID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if in.Role != nil && *in.Role == models.DOCTOR {
+			if _, err := r.Doctors.FindByUserID(user.ID); errors.Is(err, repository.ErrNotFound) {
+				return ErrNoDoctorProfile
+			} else if err != nil {
+				return err
+			}
+		}
+
+		if len(updates) > 0 {
+			if err := r.Users.Update(user.ID, updates); err != nil {
+				return err
+			}
+		}
+		if in.Role != nil && *in.Role == models.ADMIN {
+			if err := r.Admins.Ensure(user.ID); err != nil {
+				return err
+			}
+		}
+		return revokeUserSessions(r.Sessions, user.ID)
+	})
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+)
+
+func TestBootstrapAdminOnlyOnce(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	s := NewAdminService(repos)
+
+	if _, err := s.BootstrapAdmin(AdminInput{Email: "root@wello.com", Password: "Adm1n!Passw0rd"}); err != nil {
+		t.Fatalf("first bootstrap: %v", err)
+	}
+	_, err := s.BootstrapAdmin(AdminInput{Email: "other@wello.com", Password: "Adm1n!Passw0rd"})
+	if !errors.Is(err, ErrAdminExists) {
+		t.Fatalf("second bootstrap: got %v, want ErrAdminExists", err)
+	}
+}
+
+func TestBootstrapAdminConcurrentRunsCreateOne(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	s := NewAdminService(repos)
+
+	const runs = 4
+	var wg sync.WaitGroup
+	errs := make([]error, runs)
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, errs[i] = s.BootstrapAdmin(AdminInput{Email: fmt.Sprintf("admin%d@wello.com", i), Password: "Adm1n!Passw0rd"})
+		}(i)
+	}
+	wg.Wait()
+
+	created := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case !errors.Is(err, ErrAdminExists) && !errors.Is(err, ErrBootstrapRunning):
+			t.Fatalf("bootstrap: %v", err)
+		}
+	}
+	if _, count, err := repos.Users.ListByRole(models.ADMIN, 10, 0); err != nil || count != 1 || created != 1 {
+		t.Fatalf("%d admins stored, %d runs succeeded (err %v); want exactly one", count, created, err)
+	}
+}
+
+func TestBootstrapAdminNeedsCredentials(t *testing.T) {
+	s := NewAdminService(repository.NewMemoryRepos())
+	if _, err := s.BootstrapAdmin(AdminInput{Email: "root@wello.com"}); !errors.Is(err, ErrMissingCredentials) {
+		t.Fatalf("got %v, want ErrMissingCredentials", err)
+	}
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+// AppointmentService runs the appointment flows that take row locks:
+// booking, status transitions, reschedule proposals and doctor leave.
+type AppointmentService struct {
+	Repos repository.Repos
+	// Payments refunds the fee of appointments that end without a
+	// consultation.
+	Payments *PaymentService
+	// ProposalTTL is how long a reschedule proposal stays open at most.
+	ProposalTTL time.Duration
+	// NoShow is the booking restriction for patients who repeatedly miss
+	// appointments.
+	NoShow domain.NoShowPolicy
+}
+
+func NewAppointmentService(repos repository.Repos, payments *PaymentService, proposalTTL time.Duration, noShow domain.NoShowPolicy) *AppointmentService {
+	return &AppointmentService{Repos: repos, Payments: payments, ProposalTTL: proposalTTL, NoShow: noShow}
+}
+
+// Transition applies event to appt through the domain transition table,
+// persists the new status together with any extra column changes and queues
+// the notification declared for the transition in the same transaction.
+// Ending the appointment without its consultation refunds a paid fee and
+// voids open payment intents in that transaction too; the refund is sent
+// to the gateway once it commits.
+//
+// The update is guarded by the current status, so two concurrent requests
+// cannot both move the same appointment.
+func (s *AppointmentService) Transition(appt *models.Appointment, event domain.AppointmentEvent, role models.Role, changes map[string]interface{}) error {
+	var refunds []models.Payment
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		t, settle, err := s.applyTransition(r, appt, event, role, changes)
+		if err != nil {
+			return err
+		}
+		refunds = settle
+		return enqueueTransition(r.Outbox, appt, t, role, nil)
+	})
+	if err != nil {
+		return err
+	}
+	s.Payments.settleCancelled(refunds)
+	return nil
+}
+
+// applyTransition is Transition without the notification, for callers that
+// already run inside a transaction. The returned payments are REFUNDING and
+// must be handed to Payments.settleCancelled after the commit.
+func (s *AppointmentService) applyTransition(r repository.Repos, appt *models.Appointment, event domain.AppointmentEvent, role models.Role, changes map[string]interface{}) (domain.Transition, []models.Payment, error) {
+	from := appt.Status
+	t, err := domain.NextStatus(from, event, role)
+	if err != nil {
+		return t, nil, err
+	}
+
+	reason, _ := changes[domain.ReasonKey].(string)
+	if err := t.CheckPreconditions(reason, appt.ScheduledAt, utils.CurrentTime()); err != nil {
+		return t, nil, err
+	}
+
+	var live []models.Payment
+	if domain.RefundsFee(t.To) {
+		if live, err = lockAppointmentPayments(r, appt.ID); err != nil {
+			return t, nil, err
+		}
+	}
+
+	updates := map[string]interface{}{"status": t.To}
+	for k, v := range changes {
+		updates[k] = v
+	}
+
+	moved, err := r.Appointments.UpdateIfStatus(appt.ID, appt.Status, updates)
+	if err != nil {
+		return t, nil, err
+	}
+	if !moved {
+		return t, nil, domain.ErrInvalidTransition
+	}
+
+	// leaving RESCHEDULE_REQUESTED other than by an answer closes the proposal
+	if from == models.RESCHEDULE_REQUESTED && t.To != models.RESCHEDULE_REQUESTED {
+		open, err := r.Proposals.FindOpen(appt.ID)
+		if err == nil {
+			err = r.Proposals.Close(open.ID, domain.ClosedProposalStatus(t.To), utils.CurrentTime())
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return t, nil, err
+		}
+	}
+
+	if err := recordNoShow(r, s.NoShow, appt, t.To); err != nil {
+		return t, nil, err
+	}
+
+	refunds, err := s.Payments.cancelAppointmentPayments(r, live)
+	if err != nil {
+		return t, nil, err
+	}
+
+	fresh, err := r.Appointments.FindByID(appt.ID)
+	if err != nil {
+		return t, nil, err
+	}
+	*appt = *fresh
+	return t, refunds, nil
+}
+
+// enqueueTransition hands the notification for t, fired by role, to q.
+// extra is merged into the template data. appt must have Patient and
+// DoctorProfile.User loaded.
+func enqueueTransition(q notify.Queue, appt *models.Appointment, t domain.Transition, role models.Role, extra map[string]interface{}) error {
+	if t.Notify == domain.NotifyNone || t.Template == "" {
+		return nil
+	}
+
+	party := t.Notify
+	if party == domain.NotifyOther {
+		switch role {
+		case models.PATIENT:
+			party = domain.NotifyDoctor
+		case models.DOCTOR:
+			party = domain.NotifyPatient
+		default:
+			party = domain.NotifyBoth
+		}
+	}
+
+	data := notify.AppointmentData(appt)
+	for k, v := range extra {
+		data[k] = v
+	}
+
+	var to []notify.Recipient
+	switch party {
+	case domain.NotifyPatient:
+		to = append(to, notify.RecipientOf(&appt.Patient))
+	case domain.NotifyDoctor:
+		to = append(to, notify.RecipientOf(appt.DoctorProfile.User))
+	case domain.NotifyBoth:
+		to = append(to, notify.RecipientOf(&appt.Patient), notify.RecipientOf(appt.DoctorProfile.User))
+	}
+
+	for _, r := range to {
+		if err := enqueue(q, t.Template, data, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueue renders template for to and hands the messages to q.
+func enqueue(q notify.Queue, template string, data map[string]interface{}, to notify.Recipient) error {
+	msgs, err := notify.Compose(template, data, to)
+	if err != nil {
+		return err
+	}
+	return q.Enqueue(msgs...)
+}
+
+type BookingInput struct {
+	PatientID       string
+	DoctorProfileID string
+	ScheduledAt     time.Time
+	Mode            models.AppointmentMode
+	Location        string
+}
+
+// Book creates a PENDING appointment after checking the patient is not
+// blocked for no-shows, the doctor is approved, the time matches the weekly template and no other active
+// appointment overlaps it. The doctor profile row is locked for the duration
+// of the check, so concurrent bookings for the same doctor are serialised.
+// The doctor is notified of the new request through the outbox.
+func (s *AppointmentService) Book(in BookingInput) (*models.Appointment, error) {
+	if in.ScheduledAt.Before(utils.CurrentTime()) {
+		return nil, domain.ErrSlotInPast
+	}
+
+	var appt *models.Appointment
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		if err := checkBookingAllowed(r.Users, in.PatientID); err != nil {
+			return err
+		}
+		profile, err := r.Doctors.Lock(in.DoctorProfileID)
+		if err != nil {
+			return err
+		}
+		slot, err := checkSlot(r, profile, in.ScheduledAt, "")
+		if err != nil {
+			return err
+		}
+
+		created := models.Appointment{
+			PatientID:       in.PatientID,
+			DoctorProfileID: profile.ID,
+			ScheduledAt:     in.ScheduledAt,
+			DurationMinutes: int(slot.Duration / time.Minute),
+			Mode:            in.Mode,
+			Location:        &in.Location,
+			Status:          models.PENDING,
+		}
+		if err := r.Appointments.Create(&created); err != nil {
+			return err
+		}
+
+		if appt, err = r.Appointments.FindByID(created.ID); err != nil {
+			return err
+		}
+		return enqueue(r.Outbox, notify.TemplateAppointmentBooked,
+			notify.AppointmentData(appt), notify.RecipientOf(appt.DoctorProfile.User))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return appt, nil
+}
+
+// checkSlot verifies that at is bookable with the locked profile: the doctor
+// is approved, the time matches the weekly template, there is no leave and no
+// other active appointment's [start, end+buffer) intersects the slot's. It
+// returns the matched slot. excludeID skips the appointment being moved.
+func checkSlot(r repository.Repos, profile *models.DoctorProfile, at time.Time, excludeID string) (domain.SlotWindow, error) {
+	if profile.IsPending {
+		return domain.SlotWindow{}, domain.ErrDoctorNotApproved
+	}
+
+	cfg := domain.ScheduleConfig(profile)
+	slot, ok := domain.MatchSlot(profile.Availability, at, cfg)
+	if !ok {
+		return slot, domain.ErrOutsideAvailability
+	}
+
+	leaves, err := r.Leaves.Active(profile.ID, slot.Start, slot.End())
+	if err != nil {
+		return slot, err
+	}
+	if len(leaves) > 0 {
+		return slot, domain.ErrDoctorOnLeave
+	}
+
+	// anything that can reach the slot starts less than the longest slot
+	// plus buffer before it
+	after := slot.Start.Add(-domain.MaxSlotDuration - cfg.Buffer)
+	before := slot.End().Add(cfg.Buffer)
+	nearby, err := r.Appointments.List(repository.AppointmentFilter{
+		DoctorProfileID: profile.ID,
+		Statuses:        domain.BlockingStatuses,
+		After:           &after,
+		Before:          &before,
+	})
+	if err != nil {
+		return slot, err
+	}
+	for i := range nearby {
+		if nearby[i].ID != excludeID && slot.Clashes(domain.BookedWindow(&nearby[i]), cfg.Buffer) {
+			return slot, domain.ErrSlotTaken
+		}
+	}
+	return slot, nil
+}
+
+type FreeSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// FreeSlots expands the doctor's weekly template over the calendar days
+// fromDate..toDate (inclusive, read in the doctor's timezone) and drops slots
+// that are in the past or already held by an active appointment.
+func (s *AppointmentService) FreeSlots(doctorProfileID string, fromDate, toDate time.Time) (*models.DoctorProfile, []FreeSlot, error) {
+	profile, err := s.Repos.Doctors.FindByID(doctorProfileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if profile.IsPending {
+		return nil, nil, repository.ErrNotFound
+	}
+
+	cfg := domain.ScheduleConfig(profile)
+	from := time.Date(fromDate.Year(), fromDate.Month(), fromDate.Day(), 0, 0, 0, 0, cfg.Location)
+	to := time.Date(toDate.Year(), toDate.Month(), toDate.Day(), 0, 0, 0, 0, cfg.Location).AddDate(0, 0, 1)
+
+	// a slot or booking reaches at most the longest slot plus buffer past
+	// its start
+	reach := domain.MaxSlotDuration + cfg.Buffer
+	after, before := from.Add(-reach), to.Add(reach)
+	appts, err := s.Repos.Appointments.List(repository.AppointmentFilter{
+		DoctorProfileID: profile.ID,
+		Statuses:        domain.BlockingStatuses,
+		After:           &after,
+		Before:          &before,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	booked := make([]domain.SlotWindow, len(appts))
+	for i := range appts {
+		booked[i] = domain.BookedWindow(&appts[i])
+	}
+
+	leaves, err := s.ActiveLeaves(profile.ID, from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := utils.CurrentTime()
+	free := []FreeSlot{}
+	for _, slot := range domain.ExpandSlots(profile.Availability, from, to, cfg) {
+		if slot.Start.Before(now) || isBooked(slot, booked, cfg) || onLeave(slot, leaves) {
+			continue
+		}
+		free = append(free, FreeSlot{Start: slot.Start, End: slot.End()})
+	}
+	return profile, free, nil
+}
+
+func isBooked(slot domain.SlotWindow, booked []domain.SlotWindow, cfg domain.SlotConfig) bool {
+	for _, b := range booked {
+		if slot.Clashes(b, cfg.Buffer) {
+			return true
+		}
+	}
+	return false
+}
+
+func onLeave(slot domain.SlotWindow, leaves []models.DoctorLeave) bool {
+	for i := range leaves {
+		if domain.LeaveCovers(&leaves[i], slot) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,429 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+)
+
+// everyDay is open 08:00-20:00 UTC in 30-minute slots, seven days a week.
+func everyDay() models.Availability {
+	var a models.Availability
+	for _, d := range []models.Weekday{models.MONDAY, models.TUESDAY, models.WEDNESDAY,
+		models.THURSDAY, models.FRIDAY, models.SATURDAY, models.SUNDAY} {
+		a.Weekly = append(a.Weekly, models.WeeklyRule{
+			Day:    d,
+			Ranges: []models.TimeRange{{Start: "08:00", End: "20:00"}},
+		})
+	}
+	return a
+}
+
+// seedBooking stores an approved doctor open every day and a patient, and
+// returns the service with both.
+func seedBooking(t *testing.T) (*AppointmentService, repository.Repos, *models.DoctorProfile, *models.User) {
+	t.Helper()
+	repos := repository.NewMemoryRepos()
+
+	doctor := models.User{Name: "Dr Rao", Email: "rao@example.com", Role: models.DOCTOR, IsApproved: true}
+	patient := models.User{Name: "Asha", Email: "asha@example.com", Role: models.PATIENT, IsApproved: true}
+	for _, u := range []*models.User{&doctor, &patient} {
+		if err := repos.Users.Create(u); err != nil {
+			t.Fatalf("create user: %v", err)
+		}
+	}
+	profile := models.DoctorProfile{
+		UserID:           doctor.ID,
+		Availability:     everyDay(),
+		Timezone:         "UTC",
+		SlotDuration:     30,
+		ConsultationFees: 500,
+	}
+	if err := repos.Doctors.Create(&profile); err != nil {
+		t.Fatalf("create profile: %v", err)
+	}
+
+	ledger := NewLedgerService(repos, "INR", 10)
+	paymentService := NewPaymentService(repos, payments.NewMockGateway("test-webhook-secret"), ledger)
+	s := NewAppointmentService(repos, paymentService, 48*time.Hour, domain.NoShowPolicy{Limit: 3, Window: 90 * 24 * time.Hour, Restriction: 30 * 24 * time.Hour})
+	return s, repos, &profile, &patient
+}
+
+// tomorrowAt is hour:00 UTC tomorrow.
+func tomorrowAt(hour int) time.Time {
+	d := time.Now().UTC().AddDate(0, 0, 1)
+	return time.Date(d.Year(), d.Month(), d.Day(), hour, 0, 0, 0, time.UTC)
+}
+
+func TestBookTakesSlotOnce(t *testing.T) {
+	s, repos, profile, patient := seedBooking(t)
+	in := BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tomorrowAt(10), Mode: models.APPT_MODE_ONLINE}
+
+	appt, err := s.Book(in)
+	if err != nil {
+		t.Fatalf("book: %v", err)
+	}
+	if appt.Status != models.PENDING || appt.DoctorProfile.User == nil {
+		t.Fatalf("booked %+v", appt)
+	}
+	if got := len(repos.Outbox.(*notify.MemoryQueue).Messages()); got == 0 {
+		t.Fatal("doctor not notified of the booking")
+	}
+
+	if _, err := s.Book(in); !errors.Is(err, domain.ErrSlotTaken) {
+		t.Fatalf("same slot: got %v, want ErrSlotTaken", err)
+	}
+	in.ScheduledAt = in.ScheduledAt.Add(30 * time.Minute)
+	if _, err := s.Book(in); err != nil {
+		t.Fatalf("next slot: %v", err)
+	}
+}
+
+func TestBookRejectsUnbookableTimes(t *testing.T) {
+	s, repos, profile, patient := seedBooking(t)
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want error
+	}{
+		{"in the past", time.Now().Add(-time.Hour), domain.ErrSlotInPast},
+		{"outside hours", tomorrowAt(21), domain.ErrOutsideAvailability},
+		{"off the grid", tomorrowAt(10).Add(10 * time.Minute), domain.ErrOutsideAvailability},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tt.at})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	appts, err := repos.Appointments.List(repository.AppointmentFilter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(appts) != 0 {
+		t.Fatalf("%d appointments stored by failed bookings", len(appts))
+	}
+}
+
+func TestTransitionIsGuardedByStatus(t *testing.T) {
+	s, repos, profile, patient := seedBooking(t)
+	appt, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tomorrowAt(11)})
+	if err != nil {
+		t.Fatalf("book: %v", err)
+	}
+	stale := *appt
+
+	if err := s.Transition(appt, domain.EventAccept, models.DOCTOR, nil); err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if appt.Status != models.ACCEPTED {
+		t.Fatalf("status %s after accept", appt.Status)
+	}
+
+	// a second request still holding the PENDING copy must not move it again
+	queued := len(repos.Outbox.(*notify.MemoryQueue).Messages())
+	err = s.Transition(&stale, domain.EventReject, models.DOCTOR, map[string]interface{}{domain.ReasonKey: "busy"})
+	if !errors.Is(err, domain.ErrInvalidTransition) {
+		t.Fatalf("stale reject: got %v, want ErrInvalidTransition", err)
+	}
+	stored, err := repos.Appointments.FindByID(appt.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if stored.Status != models.ACCEPTED {
+		t.Fatalf("stored status %s, want ACCEPTED", stored.Status)
+	}
+	if got := len(repos.Outbox.(*notify.MemoryQueue).Messages()); got != queued {
+		t.Fatalf("failed transition queued %d messages", got-queued)
+	}
+}
+
+func TestCreateLeaveCancelsCoveredAppointments(t *testing.T) {
+	s, repos, profile, patient := seedBooking(t)
+	inside, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tomorrowAt(10)})
+	if err != nil {
+		t.Fatalf("book inside: %v", err)
+	}
+	outside, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tomorrowAt(15)})
+	if err != nil {
+		t.Fatalf("book outside: %v", err)
+	}
+
+	_, cancelled, err := s.CreateLeave(profile.ID, tomorrowAt(9), tomorrowAt(12), "conference")
+	if err != nil {
+		t.Fatalf("leave: %v", err)
+	}
+	if len(cancelled) != 1 || cancelled[0].ID != inside.ID {
+		t.Fatalf("cancelled %+v, want only the 10:00 appointment", cancelled)
+	}
+
+	for id, want := range map[string]models.AppointmentStatus{
+		inside.ID:  models.CANCELLED_BY_DOCTOR,
+		outside.ID: models.PENDING,
+	} {
+		a, err := repos.Appointments.FindByID(id)
+		if err != nil {
+			t.Fatalf("find: %v", err)
+		}
+		if a.Status != want {
+			t.Fatalf("appointment at %s: status %s, want %s", a.ScheduledAt.Format("15:04"), a.Status, want)
+		}
+	}
+
+	if _, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tomorrowAt(11)}); !errors.Is(err, domain.ErrDoctorOnLeave) {
+		t.Fatalf("book during leave: got %v, want ErrDoctorOnLeave", err)
+	}
+}
+
+// payFee pays the appointment fee through the mock gateway and its webhook.
+func payFee(t *testing.T, s *AppointmentService, appt *models.Appointment) *models.Payment {
+	t.Helper()
+	p, err := s.Payments.StartAppointmentPayment(appt)
+	if err != nil {
+		t.Fatalf("start payment: %v", err)
+	}
+	gw := s.Payments.Gateway.(*payments.MockGateway)
+	payload, signature, err := gw.Pay(p.IntentID, true)
+	if err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	ev, err := gw.VerifyWebhook(payload, signature)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if err := s.Payments.HandleEvent(ev); err != nil {
+		t.Fatalf("handle event: %v", err)
+	}
+	return p
+}
+
+func paymentStatus(t *testing.T, repos repository.Repos, id string) models.PaymentStatus {
+	t.Helper()
+	list, err := repos.Payments.List(repository.PaymentFilter{})
+	if err != nil {
+		t.Fatalf("list payments: %v", err)
+	}
+	for _, p := range list {
+		if p.ID == id {
+			return p.Status
+		}
+	}
+	t.Fatalf("payment %s not stored", id)
+	return ""
+}
+
+func TestEndingPaidAppointmentRefundsFee(t *testing.T) {
+	reason := map[string]interface{}{domain.ReasonKey: "cannot make it"}
+	tests := []struct {
+		name   string
+		accept bool
+		// started moves the slot into the past before the event
+		started bool
+		event   domain.AppointmentEvent
+		role    models.Role
+		want    models.PaymentStatus
+	}{
+		{"rejected", false, false, domain.EventReject, models.DOCTOR, models.PAYMENT_REFUNDED},
+		{"cancelled by patient", false, false, domain.EventCancelByPatient, models.PATIENT, models.PAYMENT_REFUNDED},
+		{"cancelled by doctor", true, false, domain.EventCancelByDoctor, models.DOCTOR, models.PAYMENT_REFUNDED},
+		{"expired", false, false, domain.EventExpire, domain.SystemRole, models.PAYMENT_REFUNDED},
+		{"doctor no-show", true, true, domain.EventNoShowDoctor, models.PATIENT, models.PAYMENT_REFUNDED},
+		{"patient no-show keeps the fee", true, true, domain.EventNoShowPatient, models.DOCTOR, models.PAYMENT_SUCCEEDED},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repos, profile, patient := seedBooking(t)
+			appt, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tomorrowAt(10)})
+			if err != nil {
+				t.Fatalf("book: %v", err)
+			}
+			if tt.accept {
+				if err := s.Transition(appt, domain.EventAccept, models.DOCTOR, nil); err != nil {
+					t.Fatalf("accept: %v", err)
+				}
+			}
+			p := payFee(t, s, appt)
+			if tt.started {
+				if err := repos.Appointments.Update(appt.ID, map[string]interface{}{"scheduled_at": time.Now().Add(-time.Hour)}); err != nil {
+					t.Fatalf("move slot: %v", err)
+				}
+			}
+			if appt, err = repos.Appointments.FindByID(appt.ID); err != nil {
+				t.Fatalf("find: %v", err)
+			}
+
+			if err := s.Transition(appt, tt.event, tt.role, reason); err != nil {
+				t.Fatalf("transition: %v", err)
+			}
+			if got := paymentStatus(t, repos, p.ID); got != tt.want {
+				t.Fatalf("payment %s, want %s", got, tt.want)
+			}
+			refunded := tt.want == models.PAYMENT_REFUNDED
+			if appt.FeePaid == refunded {
+				t.Fatalf("fee paid %v after a refund of %v", appt.FeePaid, refunded)
+			}
+			balance, err := s.Payments.Ledger.DoctorBalance(profile.ID)
+			if err != nil {
+				t.Fatalf("balance: %v", err)
+			}
+			if refunded && balance != 0 {
+				t.Fatalf("doctor balance %d after the refund, want 0", balance)
+			}
+			if !refunded && balance <= 0 {
+				t.Fatalf("doctor balance %d, want the fee less commission", balance)
+			}
+		})
+	}
+}
+
+func TestEndingAppointmentVoidsOpenIntent(t *testing.T) {
+	s, repos, profile, patient := seedBooking(t)
+	appt, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tomorrowAt(10)})
+	if err != nil {
+		t.Fatalf("book: %v", err)
+	}
+	p, err := s.Payments.StartAppointmentPayment(appt)
+	if err != nil {
+		t.Fatalf("start payment: %v", err)
+	}
+
+	if err := s.Transition(appt, domain.EventCancelByPatient, models.PATIENT, map[string]interface{}{domain.ReasonKey: "changed plans"}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if got := paymentStatus(t, repos, p.ID); got != models.PAYMENT_CANCELLED {
+		t.Fatalf("payment %s, want CANCELLED", got)
+	}
+	if _, _, err := s.Payments.Gateway.(*payments.MockGateway).Pay(p.IntentID, true); !errors.Is(err, payments.ErrNotCapturable) {
+		t.Fatalf("paying a voided intent: got %v, want ErrNotCapturable", err)
+	}
+}
+
+func TestLeaveRefundsPaidAppointments(t *testing.T) {
+	s, repos, profile, patient := seedBooking(t)
+	appt, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tomorrowAt(10)})
+	if err != nil {
+		t.Fatalf("book: %v", err)
+	}
+	p := payFee(t, s, appt)
+
+	if _, _, err := s.CreateLeave(profile.ID, tomorrowAt(9), tomorrowAt(12), "conference"); err != nil {
+		t.Fatalf("leave: %v", err)
+	}
+	if got := paymentStatus(t, repos, p.ID); got != models.PAYMENT_REFUNDED {
+		t.Fatalf("payment %s, want REFUNDED", got)
+	}
+}
+
+// A booking keeps the length it was made with, so shortening the doctor's
+// slots later does not free the second half of a long appointment.
+func TestBookingKeepsItsLength(t *testing.T) {
+	s, repos, profile, patient := seedBooking(t)
+	profile.SlotDuration = 120
+	if err := repos.Doctors.Save(profile); err != nil {
+		t.Fatalf("save profile: %v", err)
+	}
+	appt, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tomorrowAt(10)})
+	if err != nil {
+		t.Fatalf("book: %v", err)
+	}
+	if appt.DurationMinutes != 120 {
+		t.Fatalf("stored duration %d, want 120", appt.DurationMinutes)
+	}
+
+	profile.SlotDuration = 30
+	profile.BufferMinutes = 30
+	if err := repos.Doctors.Save(profile); err != nil {
+		t.Fatalf("save profile: %v", err)
+	}
+	tests := []struct {
+		name string
+		at   time.Time
+		want error
+	}{
+		{"inside the booking", tomorrowAt(11), domain.ErrSlotTaken},
+		{"in the buffer after it", tomorrowAt(12), domain.ErrSlotTaken},
+		{"whose buffer ends where it starts", tomorrowAt(9), nil},
+		{"clear of it", tomorrowAt(13), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Book(BookingInput{PatientID: patient.ID, DoctorProfileID: profile.ID, ScheduledAt: tt.at})
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	_, free, err := s.FreeSlots(profile.ID, tomorrowAt(0), tomorrowAt(0))
+	if err != nil {
+		t.Fatalf("free slots: %v", err)
+	}
+	for _, f := range free {
+		if f.Start.After(tomorrowAt(9)) && f.Start.Before(tomorrowAt(12).Add(30*time.Minute)) {
+			t.Fatalf("slot %s offered inside the booking or its buffer", f.Start.Format("15:04"))
+		}
+	}
+}
+
+func TestCreateLeaveCancelsOverlappingAppointments(t *testing.T) {
+	tests := []struct {
+		name      string
+		start     time.Time
+		minutes   int
+		cancelled bool
+	}{
+		{"ends where the leave starts", tomorrowAt(9).Add(30 * time.Minute), 30, false},
+		{"runs into the leave", tomorrowAt(9), 90, true},
+		{"starts just before the leave ends", tomorrowAt(11).Add(30 * time.Minute), 30, true},
+		{"starts where the leave ends", tomorrowAt(12), 30, false},
+		{"already over", time.Now().Add(-2 * time.Hour), 30, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, repos, profile, patient := seedBooking(t)
+			appt := models.Appointment{
+				PatientID:       patient.ID,
+				DoctorProfileID: profile.ID,
+				ScheduledAt:     tt.start,
+				DurationMinutes: tt.minutes,
+				Status:          models.ACCEPTED,
+			}
+			if err := repos.Appointments.Create(&appt); err != nil {
+				t.Fatalf("create: %v", err)
+			}
+
+			// a leave that began yesterday only takes effect from now
+			startsAt := tomorrowAt(10)
+			if tt.start.Before(time.Now()) {
+				startsAt = time.Now().Add(-24 * time.Hour)
+			}
+			leave, cancelled, err := s.CreateLeave(profile.ID, startsAt, tomorrowAt(12), "")
+			if err != nil {
+				t.Fatalf("leave: %v", err)
+			}
+			if leave.StartsAt.Before(time.Now().Add(-time.Minute)) {
+				t.Fatalf("leave starts %v, want no earlier than now", leave.StartsAt)
+			}
+			if got := len(cancelled) == 1; got != tt.cancelled {
+				t.Fatalf("cancelled %d appointments, want cancelled=%v", len(cancelled), tt.cancelled)
+			}
+			stored, err := repos.Appointments.FindByID(appt.ID)
+			if err != nil {
+				t.Fatalf("find: %v", err)
+			}
+			if want := models.ACCEPTED; !tt.cancelled && stored.Status != want {
+				t.Fatalf("status %s, want %s", stored.Status, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+const (
+	maxFailedLogins = 5
+	lockoutDuration = 15 * time.Minute
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrAccountLocked      = errors.New("account locked after repeated failed logins, try again later")
+)
+
+// AuthService checks passwords and keeps the failed-login lockout.
+type AuthService struct {
+	Repos repository.Repos
+	Now   func() time.Time
+}
+
+func NewAuthService(repos repository.Repos) *AuthService {
+	return &AuthService{Repos: repos, Now: utils.CurrentTime}
+}
+
+// dummyHash is checked when there is no real hash to compare against, so
+// unknown accounts cost the same bcrypt work as a wrong password.
+var dummyHash = sync.OnceValue(func() string {
+	hash, _ := utils.HashPassword("timing-equaliser-not-a-password")
+	return hash
+})
+
+// checkPassword is utils.CheckPassword that always runs bcrypt once.
+func checkPassword(hash, password string) bool {
+	if hash == "" {
+		utils.CheckPassword(dummyHash(), password)
+		return false
+	}
+	return utils.CheckPassword(hash, password)
+}
+
+// Login authenticates by email or phone plus password. Unknown identifiers
+// fail with ErrInvalidCredentials after the same work as a wrong password,
+// so response time does not reveal which accounts exist.
+func (s *AuthService) Login(email, phone, password string) (*models.User, error) {
+	user, err := s.FindByIdentifier(email, phone)
+	if errors.Is(err, repository.ErrNotFound) {
+		checkPassword("", password)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Authenticate(user, password); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// FindByIdentifier looks a user up by email or phone, whichever is set.
+func (s *AuthService) FindByIdentifier(email, phone string) (*models.User, error) {
+	switch {
+	case email != "":
+		return s.Repos.Users.FindByEmail(email)
+	case phone != "":
+		return s.Repos.Users.FindByPhone(phone)
+	}
+	return nil, repository.ErrNotFound
+}
+
+// Authenticate checks password for user, counting failures and locking the
+// account for lockoutDuration after maxFailedLogins in a row. A locked
+// account costs the same bcrypt work as a wrong password, so callers can
+// answer both alike without the timing telling them apart.
+func (s *AuthService) Authenticate(user *models.User, password string) error {
+	now := s.Now()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		checkPassword("", password)
+		return ErrAccountLocked
+	}
+
+	if !checkPassword(user.Password, password) {
+		// incremented atomically so concurrent failures are all counted
+		attempts, err := s.Repos.Users.IncrementFailedLogins(user.ID)
+		if err != nil {
+			return err
+		}
+		user.FailedLoginAttempts = attempts
+		if attempts < maxFailedLogins {
+			return ErrInvalidCredentials
+		}
+		lockedUntil := now.Add(lockoutDuration)
+		if err := s.Repos.Users.Update(user.ID, map[string]interface{}{
+			"failed_login_attempts": 0,
+			"locked_until":          lockedUntil,
+		}); err != nil {
+			return err
+		}
+		user.FailedLoginAttempts, user.LockedUntil = 0, &lockedUntil
+		return ErrAccountLocked
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := s.Repos.Users.Update(user.ID, map[string]interface{}{
+			"failed_login_attempts": 0,
+			"locked_until":          nil,
+		}); err != nil {
+			return err
+		}
+		user.FailedLoginAttempts, user.LockedUntil = 0, nil
+	}
+	return nil
+}
+
+// SetPassword validates and stores a new password, clears any lockout and
+// signs the user out of every other session.
+func (s *AuthService) SetPassword(userID, password string) error {
+	if err := utils.ValidatePassword(password); err != nil {
+		return err
+	}
+	hash, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		if err := r.Users.Update(userID, map[string]interface{}{
+			"password":              hash,
+			"failed_login_attempts": 0,
+			"locked_until":          nil,
+		}); err != nil {
+			return err
+		}
+		return revokeUserSessions(r.Sessions, userID)
+	})
+}
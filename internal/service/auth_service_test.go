@@ -0,0 +1,122 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+// newAuthService stores a patient with password "correct horse" and returns
+// the service on a clock the test moves with *now.
+func newAuthService(t *testing.T, now *time.Time) (*AuthService, *models.User) {
+	t.Helper()
+	repos := repository.NewMemoryRepos()
+	hash, err := utils.HashPassword("correct horse")
+	if err != nil {
+		t.Fatalf("hash: %v", err)
+	}
+	user := models.User{Name: "Asha", Email: "asha@example.com", Password: hash, Role: models.PATIENT}
+	if err := repos.Users.Create(&user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	s := NewAuthService(repos)
+	s.Now = func() time.Time { return *now }
+	return s, &user
+}
+
+func storedUser(t *testing.T, s *AuthService, id string) *models.User {
+	t.Helper()
+	u, err := s.Repos.Users.FindByID(id)
+	if err != nil {
+		t.Fatalf("find user: %v", err)
+	}
+	return u
+}
+
+func TestAuthenticateLocksAfterRepeatedFailures(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s, user := newAuthService(t, &now)
+
+	for i := 1; i < maxFailedLogins; i++ {
+		if err := s.Authenticate(user, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failure %d: got %v, want ErrInvalidCredentials", i, err)
+		}
+		if got := storedUser(t, s, user.ID).FailedLoginAttempts; got != i {
+			t.Fatalf("after %d failures the counter is %d", i, got)
+		}
+	}
+
+	if err := s.Authenticate(user, "wrong"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("failure %d: got %v, want ErrAccountLocked", maxFailedLogins, err)
+	}
+	stored := storedUser(t, s, user.ID)
+	if stored.FailedLoginAttempts != 0 {
+		t.Fatalf("counter %d after lockout, want 0", stored.FailedLoginAttempts)
+	}
+	if stored.LockedUntil == nil || !stored.LockedUntil.Equal(now.Add(lockoutDuration)) {
+		t.Fatalf("locked until %v, want %v", stored.LockedUntil, now.Add(lockoutDuration))
+	}
+
+	// the right password does not get through a lockout
+	now = now.Add(lockoutDuration - time.Second)
+	if err := s.Authenticate(stored, "correct horse"); !errors.Is(err, ErrAccountLocked) {
+		t.Fatalf("while locked: got %v, want ErrAccountLocked", err)
+	}
+
+	now = now.Add(time.Second)
+	if err := s.Authenticate(stored, "correct horse"); err != nil {
+		t.Fatalf("after lockout: %v", err)
+	}
+	if u := storedUser(t, s, user.ID); u.LockedUntil != nil || u.FailedLoginAttempts != 0 {
+		t.Fatalf("lockout not cleared: locked until %v, %d failures", u.LockedUntil, u.FailedLoginAttempts)
+	}
+}
+
+func TestAuthenticateSuccessResetsCounter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s, user := newAuthService(t, &now)
+
+	for i := 0; i < maxFailedLogins-1; i++ {
+		if err := s.Authenticate(user, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("failure %d: got %v", i+1, err)
+		}
+	}
+	if err := s.Authenticate(user, "correct horse"); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if got := storedUser(t, s, user.ID).FailedLoginAttempts; got != 0 {
+		t.Fatalf("counter %d after a success, want 0", got)
+	}
+
+	// the count starts again, so one more failure does not lock
+	if err := s.Authenticate(user, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("failure after reset: got %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestSetPasswordClearsLockout(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	s, user := newAuthService(t, &now)
+	for i := 0; i < maxFailedLogins; i++ {
+		s.Authenticate(user, "wrong")
+	}
+
+	if err := s.SetPassword(user.ID, "Battery-Staple-42"); err != nil {
+		t.Fatalf("set password: %v", err)
+	}
+	if _, err := s.Login(user.Email, "", "Battery-Staple-42"); err != nil {
+		t.Fatalf("login after reset: %v", err)
+	}
+}
+
+func TestLoginUnknownAccount(t *testing.T) {
+	now := time.Now()
+	s, _ := newAuthService(t, &now)
+	if _, err := s.Login("nobody@example.com", "", "correct horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("got %v, want ErrInvalidCredentials", err)
+	}
+}
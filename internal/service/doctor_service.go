@@ -1 +1,338 @@
 package service
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+var (
+	ErrDoctorProfileNotFound = errors.New("doctor profile not found")
+	ErrMedicalCheckNotFound  = errors.New("test not found or unauthorized")
+	ErrSummaryNotCompleted   = errors.New("summary can only be added to completed appointments")
+	ErrNoSummary             = errors.New("no summary found for this appointment")
+)
+
+// DoctorService holds the doctor dashboard logic: profile, appointments,
+// patients and medical checks of the signed-in doctor, plus public listings.
+type DoctorService struct {
+	Users         repository.UserRepo
+	Doctors       repository.DoctorRepo
+	Appointments  repository.AppointmentRepo
+	MedicalChecks repository.MedicalCheckRepo
+	Reviews       repository.ReviewRepo
+	// Outbox receives patient notifications, e.g. test reports.
+	Outbox notify.Queue
+}
+
+func NewDoctorService(repos repository.Repos, outbox notify.Queue) *DoctorService {
+	return &DoctorService{
+		Users:         repos.Users,
+		Doctors:       repos.Doctors,
+		Appointments:  repos.Appointments,
+		MedicalChecks: repos.MedicalChecks,
+		Reviews:       repos.Reviews,
+		Outbox:        outbox,
+	}
+}
+
+// Profile returns the doctor profile of the user with userID.
+func (s *DoctorService) Profile(userID string) (*models.DoctorProfile, error) {
+	profile, err := s.Doctors.FindByUserID(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrDoctorProfileNotFound
+	}
+	return profile, err
+}
+
+// DoctorProfileInput replaces the editable profile fields. Nil pointers
+// clear the corresponding value.
+type DoctorProfileInput struct {
+	Name           string
+	Age            *int
+	Gender         *string
+	Address        *string
+	Specialization string
+	LicenseNumber  string
+	ClinicName     string
+	Experience     string
+	Bio            string
+	Certifications string
+	PhotoURL       *string
+}
+
+func (s *DoctorService) UpdateProfile(userID string, in DoctorProfileInput) error {
+	userUpdates := map[string]interface{}{
+		"name":    in.Name,
+		"age":     nil,
+		"gender":  nil,
+		"address": nil,
+	}
+	if in.Age != nil {
+		userUpdates["age"] = *in.Age
+	}
+	if in.Gender != nil {
+		userUpdates["gender"] = *in.Gender
+	}
+	if in.Address != nil {
+		userUpdates["address"] = *in.Address
+	}
+	if err := s.Users.Update(userID, userUpdates); err != nil {
+		return err
+	}
+
+	profile, err := s.Profile(userID)
+	if err != nil {
+		return err
+	}
+	profile.Specialization = in.Specialization
+	profile.LicenseNumber = in.LicenseNumber
+	profile.ClinicName = in.ClinicName
+	profile.Experience = in.Experience
+	profile.Bio = in.Bio
+	profile.Certifications = in.Certifications
+	profile.PhotoURL = in.PhotoURL
+	return s.Doctors.Save(profile)
+}
+
+// AvailabilityInput replaces the weekly template; nil settings are kept.
+type AvailabilityInput struct {
+	Availability  models.Availability
+	Timezone      *string
+	SlotDuration  *int
+	BufferMinutes *int
+}
+
+// UpdateAvailability validates the template and settings, returning
+// domain.FieldErrors when any field is invalid.
+func (s *DoctorService) UpdateAvailability(userID string, in AvailabilityInput) error {
+	fieldErrors := domain.ValidateAvailability(in.Availability)
+	if fieldErrors == nil {
+		fieldErrors = domain.FieldErrors{}
+	}
+	if in.Timezone != nil {
+		if _, err := time.LoadLocation(*in.Timezone); err != nil || *in.Timezone == "" {
+			fieldErrors["timezone"] = "must be an IANA timezone name"
+		}
+	}
+	if in.SlotDuration != nil && (*in.SlotDuration < 5 || *in.SlotDuration > 240) {
+		fieldErrors["slotDuration"] = "must be between 5 and 240 minutes"
+	}
+	if in.BufferMinutes != nil && (*in.BufferMinutes < 0 || *in.BufferMinutes > 120) {
+		fieldErrors["bufferMinutes"] = "must be between 0 and 120 minutes"
+	}
+	if len(fieldErrors) > 0 {
+		return fieldErrors
+	}
+
+	profile, err := s.Profile(userID)
+	if err != nil {
+		return err
+	}
+	profile.Availability = in.Availability
+	if in.Timezone != nil {
+		profile.Timezone = *in.Timezone
+	}
+	if in.SlotDuration != nil {
+		profile.SlotDuration = *in.SlotDuration
+	}
+	if in.BufferMinutes != nil {
+		profile.BufferMinutes = *in.BufferMinutes
+	}
+	return s.Doctors.Save(profile)
+}
+
+func (s *DoctorService) UpdateFee(userID string, fee float64) error {
+	profile, err := s.Profile(userID)
+	if err != nil {
+		return err
+	}
+	profile.ConsultationFees = fee
+	return s.Doctors.Save(profile)
+}
+
+// Appointment returns one appointment of the doctor with userID.
+func (s *DoctorService) Appointment(userID, appointmentID string) (*models.Appointment, error) {
+	profile, err := s.Profile(userID)
+	if err != nil {
+		return nil, err
+	}
+	appt, err := s.Appointments.FindForDoctor(appointmentID, profile.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAppointmentNotFound
+	}
+	return appt, err
+}
+
+func (s *DoctorService) listAppointments(userID string, filter repository.AppointmentFilter) ([]models.Appointment, error) {
+	profile, err := s.Profile(userID)
+	if err != nil {
+		return nil, err
+	}
+	filter.DoctorProfileID = profile.ID
+	return s.Appointments.List(filter)
+}
+
+func (s *DoctorService) AllAppointments(userID string) ([]models.Appointment, error) {
+	return s.listAppointments(userID, repository.AppointmentFilter{})
+}
+
+func (s *DoctorService) RescheduleRequests(userID string) ([]models.Appointment, error) {
+	return s.listAppointments(userID, repository.AppointmentFilter{
+		Statuses: []models.AppointmentStatus{models.RESCHEDULE_REQUESTED},
+	})
+}
+
+// UpcomingAppointments lists confirmed appointments still ahead.
+func (s *DoctorService) UpcomingAppointments(userID string) ([]models.Appointment, error) {
+	now := utils.CurrentTime()
+	return s.listAppointments(userID, repository.AppointmentFilter{
+		Statuses: []models.AppointmentStatus{models.ACCEPTED, models.RESCHEDULED_CONFIRMED, models.RESCHEDULE_REJECTED},
+		After:    &now,
+	})
+}
+
+// PatientHistory lists the completed appointments between this doctor and
+// one patient, newest first.
+func (s *DoctorService) PatientHistory(userID, patientID string) ([]models.Appointment, error) {
+	return s.listAppointments(userID, repository.AppointmentFilter{
+		PatientID:   patientID,
+		Statuses:    []models.AppointmentStatus{models.COMPLETED},
+		NewestFirst: true,
+	})
+}
+
+// Patients returns everyone the doctor has completed an appointment with.
+func (s *DoctorService) Patients(userID string) ([]models.User, error) {
+	profile, err := s.Profile(userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Appointments.PatientsOf(profile.ID, models.COMPLETED)
+}
+
+func (s *DoctorService) ReviewsOf(userID string) ([]models.Review, error) {
+	return s.Reviews.ListByDoctor(userID)
+}
+
+func (s *DoctorService) AddSummary(userID, appointmentID, summary string) error {
+	appt, err := s.Appointment(userID, appointmentID)
+	if err != nil {
+		return err
+	}
+	if appt.Status != models.COMPLETED {
+		return ErrSummaryNotCompleted
+	}
+	appt.Summary = &summary
+	return s.Appointments.Save(appt)
+}
+
+// SummaryAppointment returns an appointment that has a summary to print.
+func (s *DoctorService) SummaryAppointment(userID, appointmentID string) (*models.Appointment, error) {
+	appt, err := s.Appointment(userID, appointmentID)
+	if err != nil {
+		return nil, err
+	}
+	if appt.Summary == nil || *appt.Summary == "" {
+		return nil, ErrNoSummary
+	}
+	return appt, nil
+}
+
+type MedicalCheckInput struct {
+	AppointmentID string
+	Type          models.TestType
+	Location      string
+}
+
+// CreateMedicalCheck orders a test for one of the doctor's appointments.
+func (s *DoctorService) CreateMedicalCheck(userID string, in MedicalCheckInput) (*models.MedicalCheck, error) {
+	if _, err := s.Appointment(userID, in.AppointmentID); err != nil {
+		return nil, err
+	}
+	check := models.MedicalCheck{
+		AppointmentID: in.AppointmentID,
+		Type:          in.Type,
+		Location:      in.Location,
+	}
+	if err := s.MedicalChecks.Create(&check); err != nil {
+		return nil, err
+	}
+	return &check, nil
+}
+
+// UploadReport attaches the report URL, marks the test reported and queues a
+// notification to the patient. A failed enqueue is logged, not returned, since
+// the report itself is already saved.
+func (s *DoctorService) UploadReport(userID, testID, reportURL string) error {
+	check, err := s.MedicalChecks.FindForDoctor(testID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return ErrMedicalCheckNotFound
+	}
+	if err != nil {
+		return err
+	}
+	check.ReportUrl = &reportURL
+	check.ReportUploaded = true
+	check.Status = models.REPORTED
+	if err := s.MedicalChecks.Save(check); err != nil {
+		return err
+	}
+
+	if err := s.notifyReport(check); err != nil {
+		log.Printf("Test report notification for %s failed: %v", check.ID, err)
+	}
+	return nil
+}
+
+func (s *DoctorService) notifyReport(check *models.MedicalCheck) error {
+	if s.Outbox == nil {
+		return nil
+	}
+	appt, err := s.Appointments.FindByID(check.AppointmentID)
+	if err != nil {
+		return err
+	}
+	patient, err := s.Users.FindByID(appt.PatientID)
+	if err != nil {
+		return err
+	}
+	return enqueue(s.Outbox, notify.TemplateTestReported, map[string]interface{}{
+		"PatientName": patient.Name,
+		"TestType":    string(check.Type),
+		"ReportURL":   *check.ReportUrl,
+	}, notify.RecipientOf(patient))
+}
+
+// List returns approved doctors matching filter.
+func (s *DoctorService) List(filter repository.DoctorFilter) ([]models.DoctorProfile, error) {
+	return s.Doctors.List(filter)
+}
+
+func (s *DoctorService) Get(id string) (*models.DoctorProfile, error) {
+	return s.Doctors.FindByID(id)
+}
+
+// SeedAppointment creates a pending appointment two days out for testing.
+func (s *DoctorService) SeedAppointment(userID, patientID string) (*models.Appointment, error) {
+	profile, err := s.Profile(userID)
+	if err != nil {
+		return nil, err
+	}
+	appt := models.Appointment{
+		DoctorProfileID: profile.ID,
+		PatientID:       patientID,
+		Status:          models.PENDING,
+		ScheduledAt:     utils.CurrentTime().Add(48 * time.Hour),
+	}
+	if err := s.Appointments.Create(&appt); err != nil {
+		return nil, err
+	}
+	return &appt, nil
+}
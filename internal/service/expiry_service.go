@@ -0,0 +1,98 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+// expiryLockKey is the Postgres advisory lock held while expiring requests.
+const expiryLockKey int64 = 0x57656c6c6f45
+
+// RequestExpirer moves unanswered PENDING and RESCHEDULE_REQUESTED
+// appointments to EXPIRED and notifies both parties. It also lapses
+// reschedule proposals past their own expiry.
+type RequestExpirer struct {
+	Repos repository.Repos
+	// Appointments applies the EXPIRE transition.
+	Appointments *AppointmentService
+	Policy       domain.ExpiryPolicy
+	BatchSize    int
+	Now          func() time.Time
+}
+
+func NewRequestExpirer(appointments *AppointmentService, policy domain.ExpiryPolicy) *RequestExpirer {
+	return &RequestExpirer{
+		Repos:        appointments.Repos,
+		Appointments: appointments,
+		Policy:       policy,
+		BatchSize:    100,
+		Now:          utils.CurrentTime,
+	}
+}
+
+// Run calls RunOnce every interval, forever.
+func (e *RequestExpirer) Run(every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := e.RunOnce(); err != nil {
+			log.Printf("Appointment expiry failed: %v", err)
+		}
+	}
+}
+
+// RunOnce expires one batch of stale requests and returns how many were
+// expired. Only one replica runs at a time; the others skip the tick.
+func (e *RequestExpirer) RunOnce() (int, error) {
+	now := e.Now()
+	expired := 0
+	var refunds []models.Payment
+	err := e.Repos.Tx.Transaction(func(r repository.Repos) error {
+		locked, err := r.Tx.TryAdvisoryLock(expiryLockKey)
+		if err != nil || !locked {
+			return err
+		}
+
+		if err := r.Proposals.ExpireDue(now); err != nil {
+			return err
+		}
+
+		// selects what Policy.Expired holds for. For RESCHEDULE_REQUESTED the
+		// request was made by the latest proposal, which may have lapsed
+		// just above.
+		pendingMadeBy, _ := e.Policy.MadeBy(models.PENDING, now)
+		rescheduleMadeBy, _ := e.Policy.MadeBy(models.RESCHEDULE_REQUESTED, now)
+		stale, err := r.Appointments.LockStaleRequests(repository.StaleRequestFilter{
+			Now:              now,
+			PendingMadeBy:    pendingMadeBy,
+			RescheduleMadeBy: rescheduleMadeBy,
+			Limit:            e.BatchSize,
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := range stale {
+			t, settle, err := e.Appointments.applyTransition(r, &stale[i], domain.EventExpire, domain.SystemRole, nil)
+			if err != nil {
+				return err
+			}
+			refunds = append(refunds, settle...)
+			if err := enqueueTransition(r.Outbox, &stale[i], t, domain.SystemRole, nil); err != nil {
+				return err
+			}
+			expired++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	e.Appointments.Payments.settleCancelled(refunds)
+	return expired, nil
+}
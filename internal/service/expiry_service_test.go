@@ -0,0 +1,94 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+)
+
+// RunOnce expires exactly the requests Policy.Expired holds for, deadline
+// edges included.
+func TestRunOnceExpiresWhatThePolicySays(t *testing.T) {
+	s, repos, profile, patient := seedBooking(t)
+	policy := domain.ExpiryPolicy{PendingTTL: 48 * time.Hour, RescheduleTTL: 24 * time.Hour}
+	now := time.Now().UTC().Truncate(time.Second)
+	later := now.Add(72 * time.Hour)
+
+	type request struct {
+		status      models.AppointmentStatus
+		scheduledAt time.Time
+		createdAt   time.Time
+		// proposedAt adds a proposal made then, for RESCHEDULE_REQUESTED
+		proposedAt *time.Time
+	}
+	at := func(d time.Duration) *time.Time { t := now.Add(d); return &t }
+	requests := map[string]request{
+		"pending, fresh":                          {models.PENDING, later, now.Add(-time.Hour), nil},
+		"pending, at deadline":                    {models.PENDING, later, now.Add(-48 * time.Hour), nil},
+		"pending, a second short":                 {models.PENDING, later, now.Add(-48*time.Hour + time.Second), nil},
+		"pending, slot begun":                     {models.PENDING, now.Add(-time.Minute), now.Add(-time.Hour), nil},
+		"reschedule, old booking, fresh proposal": {models.RESCHEDULE_REQUESTED, later, now.Add(-100 * time.Hour), at(-time.Hour)},
+		"reschedule, proposal at deadline":        {models.RESCHEDULE_REQUESTED, later, now.Add(-100 * time.Hour), at(-24 * time.Hour)},
+		"reschedule, no proposal":                 {models.RESCHEDULE_REQUESTED, later, now.Add(-25 * time.Hour), nil},
+		"accepted long ago":                       {models.ACCEPTED, later, now.Add(-100 * time.Hour), nil},
+	}
+
+	ids := map[string]string{}
+	for name, req := range requests {
+		appt := models.Appointment{
+			PatientID:       patient.ID,
+			DoctorProfileID: profile.ID,
+			ScheduledAt:     req.scheduledAt,
+			Status:          req.status,
+			CreatedAt:       req.createdAt,
+		}
+		if err := repos.Appointments.Create(&appt); err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if req.proposedAt != nil {
+			proposal := models.RescheduleProposal{
+				AppointmentID: appt.ID,
+				ProposedBy:    models.DOCTOR,
+				ProposedTime:  later.Add(time.Hour),
+				OriginalTime:  later,
+				ExpiresAt:     later,
+				CreatedAt:     *req.proposedAt,
+			}
+			if err := repos.Proposals.Create(&proposal); err != nil {
+				t.Fatalf("propose %s: %v", name, err)
+			}
+		}
+		ids[name] = appt.ID
+	}
+
+	e := NewRequestExpirer(s, policy)
+	e.Now = func() time.Time { return now }
+	expired, err := e.RunOnce()
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+
+	want := 0
+	for name, req := range requests {
+		madeAt := req.createdAt
+		if req.proposedAt != nil {
+			madeAt = *req.proposedAt
+		}
+		shouldExpire := policy.Expired(req.status, req.scheduledAt, madeAt, now)
+		if shouldExpire {
+			want++
+		}
+		a, err := repos.Appointments.FindByID(ids[name])
+		if err != nil {
+			t.Fatalf("find %s: %v", name, err)
+		}
+		if got := a.Status == models.EXPIRED; got != shouldExpire {
+			t.Errorf("%s: expired %v, policy says %v", name, got, shouldExpire)
+		}
+	}
+	if expired != want {
+		t.Errorf("RunOnce reported %d, want %d", expired, want)
+	}
+}
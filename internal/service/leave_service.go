@@ -0,0 +1,102 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+// CreateLeave records a blackout window for the doctor and cancels every
+// active appointment that overlaps it for any part of its booked length.
+// A window that has already begun starts now, so appointments that are
+// already over are left alone. Patient notifications are queued in the same transaction.
+func (s *AppointmentService) CreateLeave(doctorProfileID string, startsAt, endsAt time.Time, reason string) (*models.DoctorLeave, []models.Appointment, error) {
+	now := utils.CurrentTime()
+	if !endsAt.After(startsAt) || !endsAt.After(now) {
+		return nil, nil, domain.ErrInvalidLeave
+	}
+	if startsAt.Before(now) {
+		startsAt = now
+	}
+
+	leave := models.DoctorLeave{
+		DoctorProfileID: doctorProfileID,
+		StartsAt:        startsAt,
+		EndsAt:          endsAt,
+		Reason:          reason,
+	}
+
+	cancelReason := map[string]interface{}{domain.ReasonKey: "Doctor on leave"}
+	if strings.TrimSpace(reason) != "" {
+		cancelReason[domain.ReasonKey] = "Doctor on leave: " + strings.TrimSpace(reason)
+	}
+
+	var affected []models.Appointment
+	var refunds []models.Payment
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		// same lock as Book, so no booking can slip in meanwhile
+		if _, err := r.Doctors.Lock(doctorProfileID); err != nil {
+			return err
+		}
+
+		if err := r.Leaves.Create(&leave); err != nil {
+			return err
+		}
+
+		// an appointment reaching into the leave starts at most the longest
+		// slot before it
+		after := startsAt.Add(-domain.MaxSlotDuration)
+		appointments, err := r.Appointments.List(repository.AppointmentFilter{
+			DoctorProfileID: doctorProfileID,
+			Statuses:        domain.BlockingStatuses,
+			After:           &after,
+			Before:          &endsAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		for i := range appointments {
+			if !domain.LeaveCovers(&leave, domain.BookedWindow(&appointments[i])) {
+				continue
+			}
+			t, settle, err := s.applyTransition(r, &appointments[i], domain.EventCancelByDoctor, models.DOCTOR, cancelReason)
+			if err != nil {
+				return err
+			}
+			refunds = append(refunds, settle...)
+			if err := enqueueTransition(r.Outbox, &appointments[i], t, models.DOCTOR, nil); err != nil {
+				return err
+			}
+			affected = append(affected, appointments[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	s.Payments.settleCancelled(refunds)
+	return &leave, affected, nil
+}
+
+// CancelLeave re-opens a blackout window. Appointments that were cancelled
+// because of it stay cancelled.
+func (s *AppointmentService) CancelLeave(doctorProfileID, leaveID string) error {
+	cancelled, err := s.Repos.Leaves.Cancel(leaveID, doctorProfileID, utils.CurrentTime())
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// ActiveLeaves lists the doctor's leaves that have not ended or been cancelled.
+func (s *AppointmentService) ActiveLeaves(doctorProfileID string, from, to time.Time) ([]models.DoctorLeave, error) {
+	return s.Repos.Leaves.Active(doctorProfileID, from, to)
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+// LedgerService posts payments, refunds and payouts to the double-entry
+// ledger.
+type LedgerService struct {
+	Repos repository.Repos
+	// Currency is what payments are charged and payouts are made in.
+	Currency string
+	// CommissionPercent is the platform's cut of every appointment fee.
+	CommissionPercent float64
+}
+
+func NewLedgerService(repos repository.Repos, currency string, commissionPercent float64) *LedgerService {
+	return &LedgerService{Repos: repos, Currency: currency, CommissionPercent: commissionPercent}
+}
+
+// postTransactions checks that every transaction balances and stores them
+// with their entries.
+func postTransactions(ledger repository.LedgerRepo, txns ...models.Transaction) error {
+	for i := range txns {
+		if err := domain.CheckBalanced(&txns[i]); err != nil {
+			return err
+		}
+		if err := ledger.Create(&txns[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordPaymentReceived posts a succeeded payment, and for appointment fees
+// the platform commission, to the ledger.
+func (s *LedgerService) recordPaymentReceived(r repository.Repos, p *models.Payment) error {
+	amount := payments.ToMinor(p.Amount)
+	now := utils.CurrentTime()
+
+	if p.OrderID != nil {
+		return postTransactions(r.Ledger, domain.OrderPaymentReceived(p, amount, now))
+	}
+
+	doctorProfileID, err := appointmentDoctor(r.Appointments, *p.AppointmentID)
+	if err != nil {
+		return err
+	}
+	commission := domain.Commission(amount, s.CommissionPercent)
+	return postTransactions(r.Ledger, domain.AppointmentFeeReceived(p, doctorProfileID, amount, commission, now)...)
+}
+
+// recordRefund posts the reversal of a refunded payment. The commission
+// returned is the one actually taken, not today's rate.
+func (s *LedgerService) recordRefund(r repository.Repos, p *models.Payment) error {
+	amount := payments.ToMinor(p.Amount)
+	now := utils.CurrentTime()
+
+	if p.OrderID != nil {
+		return postTransactions(r.Ledger, domain.OrderPaymentRefunded(p, amount, now))
+	}
+
+	doctorProfileID, err := appointmentDoctor(r.Appointments, *p.AppointmentID)
+	if err != nil {
+		return err
+	}
+	taken, err := r.Ledger.CommissionTaken(p.ID)
+	if err != nil {
+		return err
+	}
+	return postTransactions(r.Ledger, domain.AppointmentFeeRefunded(p, doctorProfileID, amount, taken, now))
+}
+
+func appointmentDoctor(appointments repository.AppointmentRepo, appointmentID string) (string, error) {
+	appt, err := appointments.FindByID(appointmentID)
+	if err != nil {
+		return "", err
+	}
+	return appt.DoctorProfileID, nil
+}
+
+// DoctorBalance is what the platform currently owes the doctor, in minor
+// units.
+func (s *LedgerService) DoctorBalance(doctorProfileID string) (int64, error) {
+	return s.Repos.Ledger.DoctorBalance(doctorProfileID)
+}
+
+// RecordPayout posts amount paid out to the doctor, e.g. by bank transfer
+// with the given reference. The doctor profile is locked, so two payouts
+// cannot both spend the same balance.
+func (s *LedgerService) RecordPayout(doctorProfileID string, amount float64, reference string) (*models.Transaction, error) {
+	var txn models.Transaction
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		if _, err := r.Doctors.Lock(doctorProfileID); err != nil {
+			return err
+		}
+		balance, err := r.Ledger.DoctorBalance(doctorProfileID)
+		if err != nil {
+			return err
+		}
+		payout, err := domain.DoctorPayout(doctorProfileID, payments.ToMinor(amount), balance,
+			s.Currency, reference, utils.CurrentTime())
+		if err != nil {
+			return err
+		}
+		posted := []models.Transaction{payout}
+		if err := postTransactions(r.Ledger, posted...); err != nil {
+			return err
+		}
+		txn = posted[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &txn, nil
+}
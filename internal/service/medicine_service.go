@@ -0,0 +1,212 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+)
+
+var (
+	ErrMedicineNotFound = errors.New("medicine not found")
+	ErrDuplicateSKU     = errors.New("a medicine with this SKU already exists")
+)
+
+// CatalogService manages the medicine catalog. Stock levels only change
+// through the atomic helpers below, never through a read-modify-write.
+type CatalogService struct {
+	Medicines repository.MedicineRepo
+}
+
+func NewCatalogService(repos repository.Repos) *CatalogService {
+	return &CatalogService{Medicines: repos.Medicines}
+}
+
+// Medicine returns one catalog entry; discontinued ones only for staff.
+func (s *CatalogService) Medicine(id string, includeInactive bool) (*models.Medicine, error) {
+	med, err := s.Medicines.FindByID(id)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !med.Active && !includeInactive) {
+		return nil, ErrMedicineNotFound
+	}
+	return med, err
+}
+
+func (s *CatalogService) List(filter repository.MedicineFilter) ([]models.Medicine, error) {
+	return s.Medicines.List(filter)
+}
+
+// Create validates med and adds it to the catalog as active.
+func (s *CatalogService) Create(med *models.Medicine) error {
+	if err := domain.ValidateMedicine(med); err != nil {
+		return err
+	}
+	if _, err := s.Medicines.FindBySKU(med.SKU); err == nil {
+		return ErrDuplicateSKU
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return err
+	}
+	med.ID = ""
+	med.Active = true
+	return s.Medicines.Create(med)
+}
+
+// MedicinePatch holds the catalog fields an admin may edit; nil leaves a
+// field unchanged. Stock is adjusted separately.
+type MedicinePatch struct {
+	SKU                  *string  `json:"sku"`
+	Name                 *string  `json:"name"`
+	Strength             *string  `json:"strength"`
+	Form                 *string  `json:"form"`
+	Price                *float64 `json:"price"`
+	RequiresPrescription *bool    `json:"requiresPrescription"`
+	Active               *bool    `json:"active"`
+}
+
+// Update applies patch to the medicine and returns the result.
+func (s *CatalogService) Update(id string, patch MedicinePatch) (*models.Medicine, error) {
+	med, err := s.Medicine(id, true)
+	if err != nil {
+		return nil, err
+	}
+	oldSKU := med.SKU
+
+	if patch.SKU != nil {
+		med.SKU = *patch.SKU
+	}
+	if patch.Name != nil {
+		med.Name = *patch.Name
+	}
+	if patch.Strength != nil {
+		med.Strength = *patch.Strength
+	}
+	if patch.Form != nil {
+		med.Form = models.MedicineForm(*patch.Form)
+	}
+	if patch.Price != nil {
+		med.Price = *patch.Price
+	}
+	if patch.RequiresPrescription != nil {
+		med.RequiresPrescription = *patch.RequiresPrescription
+	}
+	if patch.Active != nil {
+		med.Active = *patch.Active
+	}
+	if err := domain.ValidateMedicine(med); err != nil {
+		return nil, err
+	}
+	if med.SKU != oldSKU {
+		if _, err := s.Medicines.FindBySKU(med.SKU); err == nil {
+			return nil, ErrDuplicateSKU
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, err
+		}
+	}
+
+	if err := s.Medicines.Update(id, map[string]interface{}{
+		"sku":                   med.SKU,
+		"name":                  med.Name,
+		"strength":              med.Strength,
+		"form":                  med.Form,
+		"price":                 med.Price,
+		"requires_prescription": med.RequiresPrescription,
+		"active":                med.Active,
+	}); err != nil {
+		return nil, err
+	}
+	return s.Medicine(id, true)
+}
+
+// Discontinue hides the medicine from the catalog and from new orders.
+func (s *CatalogService) Discontinue(id string) error {
+	if _, err := s.Medicine(id, true); err != nil {
+		return err
+	}
+	return s.Medicines.Update(id, map[string]interface{}{"active": false})
+}
+
+// Import upserts parsed CSV rows by SKU. Stock is set to the imported
+// quantity, so an import is meant to follow a stock take.
+func (s *CatalogService) Import(meds []models.Medicine) error {
+	return s.Medicines.UpsertBySKU(meds)
+}
+
+// AdjustStock atomically adds delta (negative to write off) to the stock of
+// a medicine. Stock never goes below zero.
+func (s *CatalogService) AdjustStock(medicineID string, delta int) (*models.Medicine, error) {
+	if _, err := s.Medicines.FindByID(medicineID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, ErrMedicineNotFound
+		}
+		return nil, err
+	}
+
+	adjusted, err := s.Medicines.AddStock(medicineID, delta)
+	if err != nil {
+		return nil, err
+	}
+	if !adjusted {
+		return nil, domain.ErrOutOfStock
+	}
+	return s.Medicines.FindByID(medicineID)
+}
+
+// reserveStock locks the medicines in cart, which must come from
+// domain.NormalizeCart, copies their SKU, name and price onto the lines and
+// takes the quantities out of stock. Rows are locked in ID order so two
+// checkouts cannot deadlock. Prescription-only lines must be covered by rx.
+func reserveStock(medicines repository.MedicineRepo, cart models.OrderItems, rx *models.Prescription) error {
+	ids := make([]string, len(cart))
+	for i, item := range cart {
+		ids[i] = item.MedicineID
+	}
+
+	meds, err := medicines.LockMany(ids)
+	if err != nil {
+		return err
+	}
+	byID := make(map[string]models.Medicine, len(meds))
+	for _, m := range meds {
+		byID[m.ID] = m
+	}
+
+	for i := range cart {
+		item := &cart[i]
+		med, ok := byID[item.MedicineID]
+		if !ok || !med.Active {
+			return fmt.Errorf("%w: %s", domain.ErrMedicineUnavailable, item.MedicineID)
+		}
+		if med.RequiresPrescription {
+			if rx == nil {
+				return fmt.Errorf("%w: %s", domain.ErrPrescriptionRequired, med.Name)
+			}
+			if domain.CoveredQuantity(rx, med.ID) < item.Quantity {
+				return fmt.Errorf("%w: %s", domain.ErrPrescriptionNotCovered, med.Name)
+			}
+		}
+		if med.StockQuantity < item.Quantity {
+			return fmt.Errorf("%w: %d of %s left", domain.ErrOutOfStock, med.StockQuantity, med.Name)
+		}
+		item.SKU, item.Name, item.UnitPrice = med.SKU, med.Name, med.Price
+
+		if _, err := medicines.AddStock(med.ID, -item.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseStock puts the quantities of a cancelled order back. Lines for
+// medicines deleted since are skipped.
+func releaseStock(medicines repository.MedicineRepo, items models.OrderItems) error {
+	for _, item := range items {
+		if item.MedicineID == "" {
+			continue
+		}
+		if _, err := medicines.AddStock(item.MedicineID, item.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
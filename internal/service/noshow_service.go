@@ -0,0 +1,66 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+// recordNoShow bumps the no-show counter of whoever missed appt and, for
+// patients, applies the booking restriction of policy once they reach the
+// limit.
+func recordNoShow(r repository.Repos, policy domain.NoShowPolicy, appt *models.Appointment, status models.AppointmentStatus) error {
+	switch status {
+	case models.NO_SHOW_DOCTOR:
+		return r.Doctors.IncrementNoShows(appt.DoctorProfileID)
+
+	case models.NO_SHOW_PATIENT:
+		if err := r.Users.IncrementNoShows(appt.PatientID); err != nil {
+			return err
+		}
+
+		now := utils.CurrentTime()
+		since := now.Add(-policy.Window)
+		recent, err := r.Appointments.List(repository.AppointmentFilter{
+			PatientID: appt.PatientID,
+			Statuses:  []models.AppointmentStatus{models.NO_SHOW_PATIENT},
+			From:      &since,
+		})
+		if err != nil {
+			return err
+		}
+		until, ok := policy.RestrictUntil(len(recent), now)
+		if !ok {
+			return nil
+		}
+		// never shorten a restriction that is already longer
+		return r.Users.ExtendBookingBlock(appt.PatientID, until)
+	}
+	return nil
+}
+
+// checkBookingAllowed fails with domain.ErrBookingRestricted while the
+// patient is blocked from booking.
+func checkBookingAllowed(users repository.UserRepo, patientID string) error {
+	patient, err := users.FindByID(patientID)
+	if err != nil {
+		return err
+	}
+	if patient.BookingBlockedUntil != nil && patient.BookingBlockedUntil.After(utils.CurrentTime()) {
+		return fmt.Errorf("%w until %s", domain.ErrBookingRestricted, patient.BookingBlockedUntil.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// LiftBookingRestriction lets a blocked patient book again. The no-show
+// counter is kept.
+func (s *AppointmentService) LiftBookingRestriction(userID string) error {
+	if _, err := s.Repos.Users.FindByID(userID); err != nil {
+		return err
+	}
+	return s.Repos.Users.Update(userID, map[string]interface{}{"booking_blocked_until": nil})
+}
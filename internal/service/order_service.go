@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+)
+
+var ErrOrderNotFound = errors.New("order not found")
+
+// OrderService holds the pharmacy order queries, and the checkout and
+// status flows that reserve stock under row locks.
+type OrderService struct {
+	Repos repository.Repos
+	// Payments refunds or voids the payments of cancelled orders.
+	Payments *PaymentService
+}
+
+func NewOrderService(repos repository.Repos, payments *PaymentService) *OrderService {
+	return &OrderService{Repos: repos, Payments: payments}
+}
+
+// Order returns one of the user's own orders.
+func (s *OrderService) Order(userID, orderID string) (*models.Order, error) {
+	order, err := s.Repos.Orders.FindForUser(orderID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	return order, err
+}
+
+// Any returns an order regardless of who placed it, for staff.
+func (s *OrderService) Any(orderID string) (*models.Order, error) {
+	order, err := s.Repos.Orders.FindByID(orderID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrOrderNotFound
+	}
+	return order, err
+}
+
+// ForUser lists the user's orders, newest first.
+func (s *OrderService) ForUser(userID string) ([]models.Order, error) {
+	return s.Repos.Orders.ListByUser(userID)
+}
+
+// Queue lists orders for staff, oldest first; an empty status matches all.
+func (s *OrderService) Queue(status models.OrderStatus) ([]models.Order, error) {
+	return s.Repos.Orders.List(repository.OrderFilter{Status: status})
+}
+
+type OrderInput struct {
+	UserID        string
+	Items         models.OrderItems
+	PaymentMethod string
+	// PrescriptionID is required when the cart has prescription-only
+	// medicines.
+	PrescriptionID string
+}
+
+// Place creates a PENDING order from the cart. Prices come from the
+// catalog and the amount is computed from them; the client never supplies
+// either. Stock for every line is reserved in the same transaction, so
+// concurrent checkouts cannot oversell, and prescription-only lines must be
+// covered by a valid prescription of the user.
+func (s *OrderService) Place(in OrderInput) (*models.Order, error) {
+	method, err := domain.ParsePaymentMethod(in.PaymentMethod)
+	if err != nil {
+		return nil, err
+	}
+	cart, err := domain.NormalizeCart(in.Items)
+	if err != nil {
+		return nil, err
+	}
+
+	order := models.Order{
+		UserID:        in.UserID,
+		Status:        models.ORDER_PENDING,
+		PaymentMethod: method,
+	}
+	err = s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		var rx *models.Prescription
+		if in.PrescriptionID != "" {
+			if rx, err = lockPrescription(r, in.UserID, in.PrescriptionID); err != nil {
+				return err
+			}
+			order.PrescriptionID = &rx.ID
+		}
+		if err := reserveStock(r.Medicines, cart, rx); err != nil {
+			return err
+		}
+		order.Items = cart
+		order.Amount = domain.PriceItems(cart)
+		return r.Orders.Create(&order)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// Transition moves order to status on behalf of role. Like appointment
+// transitions the update is guarded by the current status, so two
+// concurrent requests cannot both move the same order. The rules are
+// re-checked on the locked row, so an ONLINE order whose payment was
+// refunded meanwhile is not processed. Cancelling returns
+// the reserved stock in the same transaction, refunds an online payment and
+// voids a checkout still in progress.
+func (s *OrderService) Transition(order *models.Order, to models.OrderStatus, role models.Role) error {
+	if err := domain.NextOrderStatus(order, to, role); err != nil {
+		return err
+	}
+
+	var settle []models.Payment
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		if to == models.ORDER_CANCELLED {
+			// payments before the order, the lock order of a gateway callback
+			var err error
+			if settle, err = s.Payments.cancelOrderPayments(r, order.ID); err != nil {
+				return err
+			}
+		}
+
+		locked, err := r.Orders.Lock(order.ID)
+		if err != nil {
+			return err
+		}
+		if locked.Status != order.Status {
+			return domain.ErrInvalidOrderTransition
+		}
+		if err := domain.NextOrderStatus(locked, to, role); err != nil {
+			return err
+		}
+		if err := r.Orders.Update(order.ID, map[string]interface{}{"status": to}); err != nil {
+			return err
+		}
+		if to == models.ORDER_CANCELLED {
+			if err := releaseStock(r.Medicines, order.Items); err != nil {
+				return err
+			}
+		}
+		current, err := r.Orders.FindByID(order.ID)
+		if err != nil {
+			return err
+		}
+		*order = *current
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.Payments.settleCancelled(settle)
+	return nil
+}
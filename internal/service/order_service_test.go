@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+)
+
+func newOrderService(repos repository.Repos) *OrderService {
+	ledger := NewLedgerService(repos, "INR", 10)
+	return NewOrderService(repos, NewPaymentService(repos, payments.NewMockGateway("test-webhook-secret"), ledger))
+}
+
+func seedMedicine(t *testing.T, repos repository.Repos, sku string, price float64, stock int) *models.Medicine {
+	t.Helper()
+	med := models.Medicine{SKU: sku, Name: sku, Price: price, StockQuantity: stock, Active: true}
+	if err := repos.Medicines.Create(&med); err != nil {
+		t.Fatalf("create medicine: %v", err)
+	}
+	return &med
+}
+
+func stockOf(t *testing.T, repos repository.Repos, id string) int {
+	t.Helper()
+	med, err := repos.Medicines.FindByID(id)
+	if err != nil {
+		t.Fatalf("find medicine: %v", err)
+	}
+	return med.StockQuantity
+}
+
+func TestPlaceReservesStockAndCancelReturnsIt(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	s := newOrderService(repos)
+	med := seedMedicine(t, repos, "PARA-500", 20, 5)
+
+	order, err := s.Place(OrderInput{
+		UserID:        "patient-1",
+		Items:         models.OrderItems{{MedicineID: med.ID, Quantity: 3, UnitPrice: 1}},
+		PaymentMethod: "cod",
+	})
+	if err != nil {
+		t.Fatalf("place: %v", err)
+	}
+	if order.Amount != 60 || order.Items[0].UnitPrice != 20 {
+		t.Fatalf("order priced at %v with unit price %v, want the catalog price", order.Amount, order.Items[0].UnitPrice)
+	}
+	if got := stockOf(t, repos, med.ID); got != 2 {
+		t.Fatalf("stock %d after placing, want 2", got)
+	}
+
+	if err := s.Transition(order, models.ORDER_CANCELLED, models.PATIENT); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if order.Status != models.ORDER_CANCELLED {
+		t.Fatalf("status %s after cancel", order.Status)
+	}
+	if got := stockOf(t, repos, med.ID); got != 5 {
+		t.Fatalf("stock %d after cancel, want 5", got)
+	}
+
+	// the stale copy cannot cancel twice and return the stock again
+	stale := *order
+	stale.Status = models.ORDER_PENDING
+	if err := s.Transition(&stale, models.ORDER_CANCELLED, models.PATIENT); !errors.Is(err, domain.ErrInvalidOrderTransition) {
+		t.Fatalf("second cancel: got %v, want ErrInvalidOrderTransition", err)
+	}
+	if got := stockOf(t, repos, med.ID); got != 5 {
+		t.Fatalf("stock %d after second cancel, want 5", got)
+	}
+}
+
+func TestPlaceIsAllOrNothing(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	s := newOrderService(repos)
+	plenty := seedMedicine(t, repos, "A-PLENTY", 10, 10)
+	scarce := seedMedicine(t, repos, "B-SCARCE", 10, 1)
+
+	_, err := s.Place(OrderInput{
+		UserID: "patient-1",
+		Items: models.OrderItems{
+			{MedicineID: plenty.ID, Quantity: 4},
+			{MedicineID: scarce.ID, Quantity: 2},
+		},
+	})
+	if !errors.Is(err, domain.ErrOutOfStock) {
+		t.Fatalf("got %v, want ErrOutOfStock", err)
+	}
+	if got := stockOf(t, repos, plenty.ID); got != 10 {
+		t.Fatalf("stock of the available line %d after a failed checkout, want 10", got)
+	}
+	orders, err := repos.Orders.ListByUser("patient-1")
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(orders) != 0 {
+		t.Fatalf("%d orders stored by a failed checkout", len(orders))
+	}
+}
+
+// payOrder pays the order through the mock gateway and its webhook and
+// returns the stored order.
+func payOrder(t *testing.T, s *OrderService, order *models.Order) (*models.Order, *models.Payment) {
+	t.Helper()
+	p, err := s.Payments.StartOrderPayment(order)
+	if err != nil {
+		t.Fatalf("start payment: %v", err)
+	}
+	gw := s.Payments.Gateway.(*payments.MockGateway)
+	payload, signature, err := gw.Pay(p.IntentID, true)
+	if err != nil {
+		t.Fatalf("pay: %v", err)
+	}
+	ev, err := gw.VerifyWebhook(payload, signature)
+	if err != nil {
+		t.Fatalf("verify: %v", err)
+	}
+	if err := s.Payments.HandleEvent(ev); err != nil {
+		t.Fatalf("handle event: %v", err)
+	}
+	paid, err := s.Repos.Orders.FindByID(order.ID)
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	return paid, p
+}
+
+func TestOnlineOrderIsProcessedOnlyOncePaid(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	s := newOrderService(repos)
+	med := seedMedicine(t, repos, "PARA-500", 20, 5)
+
+	order, err := s.Place(OrderInput{
+		UserID:        "patient-1",
+		Items:         models.OrderItems{{MedicineID: med.ID, Quantity: 1}},
+		PaymentMethod: "online",
+	})
+	if err != nil {
+		t.Fatalf("place: %v", err)
+	}
+	if err := s.Transition(order, models.PROCESSING, models.PHARMACIST); !errors.Is(err, domain.ErrOrderNotPaid) {
+		t.Fatalf("unpaid: got %v, want ErrOrderNotPaid", err)
+	}
+
+	// a copy read while paid must not slip past a refund made since
+	paid, p := payOrder(t, s, order)
+	if _, err := s.Payments.Refund(p.ID); err != nil {
+		t.Fatalf("refund: %v", err)
+	}
+	if err := s.Transition(paid, models.PROCESSING, models.PHARMACIST); !errors.Is(err, domain.ErrOrderNotPaid) {
+		t.Fatalf("refunded: got %v, want ErrOrderNotPaid", err)
+	}
+
+	paid, _ = payOrder(t, s, order)
+	if err := s.Transition(paid, models.PROCESSING, models.PHARMACIST); err != nil {
+		t.Fatalf("paid: %v", err)
+	}
+	if paid.Status != models.PROCESSING {
+		t.Fatalf("status %s, want PROCESSING", paid.Status)
+	}
+}
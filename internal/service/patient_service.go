@@ -0,0 +1,103 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+var (
+	ErrAppointmentNotFound = errors.New("appointment not found")
+	ErrPatientNotFound     = errors.New("patient not found")
+	ErrInvalidRating       = errors.New("rating must be between 1 and 5")
+	ErrReviewNotCompleted  = errors.New("only completed appointments can be reviewed")
+)
+
+// PatientService holds the patient-facing appointment and record queries.
+type PatientService struct {
+	Users         repository.UserRepo
+	Appointments  repository.AppointmentRepo
+	MedicalChecks repository.MedicalCheckRepo
+}
+
+func NewPatientService(repos repository.Repos) *PatientService {
+	return &PatientService{
+		Users:         repos.Users,
+		Appointments:  repos.Appointments,
+		MedicalChecks: repos.MedicalChecks,
+	}
+}
+
+// Appointment returns one of the patient's own appointments.
+func (s *PatientService) Appointment(patientID, appointmentID string) (*models.Appointment, error) {
+	appt, err := s.Appointments.FindForPatient(appointmentID, patientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrAppointmentNotFound
+	}
+	return appt, err
+}
+
+// Upcoming lists future appointments that are still going ahead.
+func (s *PatientService) Upcoming(patientID string) ([]models.Appointment, error) {
+	now := utils.CurrentTime()
+	return s.Appointments.List(repository.AppointmentFilter{
+		PatientID: patientID,
+		Statuses: []models.AppointmentStatus{
+			models.PENDING,
+			models.ACCEPTED,
+			models.RESCHEDULE_REQUESTED,
+			models.RESCHEDULED_CONFIRMED,
+			models.RESCHEDULE_REJECTED,
+		},
+		After: &now,
+	})
+}
+
+// History lists completed appointments, newest first.
+func (s *PatientService) History(patientID string) ([]models.Appointment, error) {
+	return s.Appointments.List(repository.AppointmentFilter{
+		PatientID:   patientID,
+		Statuses:    []models.AppointmentStatus{models.COMPLETED},
+		NewestFirst: true,
+	})
+}
+
+func (s *PatientService) All(patientID string) ([]models.Appointment, error) {
+	return s.Appointments.List(repository.AppointmentFilter{
+		PatientID:   patientID,
+		NewestFirst: true,
+	})
+}
+
+func (s *PatientService) Profile(patientID string) (*models.User, error) {
+	user, err := s.Users.FindByID(patientID)
+	if err != nil || user.Role != models.PATIENT {
+		return nil, ErrPatientNotFound
+	}
+	return user, nil
+}
+
+func (s *PatientService) Tests(patientID string) ([]models.MedicalCheck, error) {
+	return s.MedicalChecks.ListByPatient(patientID)
+}
+
+// SubmitReview rates a completed appointment.
+func (s *PatientService) SubmitReview(patientID, appointmentID string, rating int, review string) error {
+	if rating < 1 || rating > 5 {
+		return ErrInvalidRating
+	}
+
+	appt, err := s.Appointment(patientID, appointmentID)
+	if err != nil {
+		return err
+	}
+	if appt.Status != models.COMPLETED {
+		return ErrReviewNotCompleted
+	}
+
+	appt.Rating = &rating
+	appt.Review = &review
+	return s.Appointments.Save(appt)
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+var (
+	ErrPaymentNotFound = errors.New("payment not found")
+	ErrRefundNotSent   = errors.New("refund recorded but the gateway did not confirm it, retry the refund")
+)
+
+// PaymentService charges appointment fees and orders through the gateway and
+// posts the outcome to the ledger.
+type PaymentService struct {
+	Repos   repository.Repos
+	Gateway payments.Gateway
+	Ledger  *LedgerService
+}
+
+func NewPaymentService(repos repository.Repos, gw payments.Gateway, ledger *LedgerService) *PaymentService {
+	return &PaymentService{Repos: repos, Gateway: gw, Ledger: ledger}
+}
+
+// Currency is charged for every payment; it is the ledger's currency.
+func (s *PaymentService) Currency() string {
+	return s.Ledger.Currency
+}
+
+// StartAppointmentPayment opens a gateway intent for the appointment fee,
+// or returns the one still pending. appt must have DoctorProfile loaded.
+// FeePaid is only set once the gateway confirms the payment.
+func (s *PaymentService) StartAppointmentPayment(appt *models.Appointment) (*models.Payment, error) {
+	var payment *models.Payment
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		// re-read under lock so two clicks cannot open two intents
+		locked, err := r.Appointments.Lock(appt.ID)
+		if err != nil {
+			return err
+		}
+		locked.DoctorProfile = appt.DoctorProfile
+		if err := domain.CheckAppointmentPayable(locked); err != nil {
+			return err
+		}
+
+		payment, err = s.startPayment(r.Payments, models.Payment{
+			Purpose:       models.PURPOSE_APPOINTMENT_FEE,
+			UserID:        locked.PatientID,
+			AppointmentID: &locked.ID,
+			Amount:        locked.DoctorProfile.ConsultationFees,
+		})
+		return err
+	})
+	return payment, err
+}
+
+// StartOrderPayment opens a gateway intent for an ONLINE order, or returns
+// the one still pending.
+func (s *PaymentService) StartOrderPayment(order *models.Order) (*models.Payment, error) {
+	var payment *models.Payment
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		locked, err := r.Orders.Lock(order.ID)
+		if err != nil {
+			return err
+		}
+		if err := domain.CheckOrderPayable(locked); err != nil {
+			return err
+		}
+
+		payment, err = s.startPayment(r.Payments, models.Payment{
+			Purpose: models.PURPOSE_ORDER,
+			UserID:  locked.UserID,
+			OrderID: &locked.ID,
+			Amount:  locked.Amount,
+		})
+		return err
+	})
+	return payment, err
+}
+
+// startPayment reuses a pending payment for the same appointment or order
+// and amount, or creates an intent for p and records it.
+func (s *PaymentService) startPayment(repo repository.PaymentRepo, p models.Payment) (*models.Payment, error) {
+	filter := repository.PaymentFilter{Statuses: []models.PaymentStatus{models.PAYMENT_PENDING}}
+	var reference string
+	if p.AppointmentID != nil {
+		filter.AppointmentID = *p.AppointmentID
+		reference = "appointment:" + *p.AppointmentID
+	} else {
+		filter.OrderID = *p.OrderID
+		reference = "order:" + *p.OrderID
+	}
+
+	pending, err := repo.List(filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range pending {
+		if pending[i].Amount == p.Amount && pending[i].Provider == s.Gateway.Name() {
+			return &pending[i], nil
+		}
+	}
+
+	intent, err := s.Gateway.CreateIntent(payments.IntentRequest{
+		Amount:    payments.ToMinor(p.Amount),
+		Currency:  s.Currency(),
+		Reference: reference,
+	})
+	if err != nil {
+		return nil, err
+	}
+	p.Provider = s.Gateway.Name()
+	p.IntentID = intent.ID
+	p.ClientSecret = intent.ClientSecret
+	p.Currency = intent.Currency
+	p.Status = models.PAYMENT_PENDING
+	if err := repo.Create(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// HandleEvent applies a verified gateway event and posts succeeded payments
+// to the ledger. Replays are ignored, so providers may deliver an event more
+// than once. A success for an appointment or order that is no longer
+// payable, because it was cancelled or paid through another intent, is not
+// booked: the payment is marked REFUNDING and the money sent back once the
+// transaction commits. If the gateway refuses, the payment stays REFUNDING
+// for Refund to retry.
+func (s *PaymentService) HandleEvent(ev payments.Event) error {
+	var p models.Payment
+	var action domain.PaymentAction
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		locked, err := r.Payments.LockByIntent(ev.IntentID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrPaymentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		p = *locked
+
+		var payable error
+		if p.Status == models.PAYMENT_PENDING && ev.Type == payments.EventPaymentSucceeded {
+			payable = recheckPayable(r, &p)
+			if payable != nil && !errors.Is(payable, domain.ErrAlreadyPaid) && !errors.Is(payable, domain.ErrNotPayable) {
+				return payable
+			}
+		}
+		if action, err = domain.PaymentEventAction(&p, ev, payable); err != nil {
+			return err
+		}
+
+		now := utils.CurrentTime()
+		switch action {
+		case domain.PaymentSucceed:
+			p.Status, p.PaidAt = models.PAYMENT_SUCCEEDED, &now
+			if err := r.Payments.Update(p.ID, map[string]interface{}{
+				"status":  p.Status,
+				"paid_at": now,
+			}); err != nil {
+				return err
+			}
+			if err := markPaid(r, &p, true); err != nil {
+				return err
+			}
+			return s.Ledger.recordPaymentReceived(r, &p)
+
+		case domain.PaymentRefund:
+			// never booked, so there is nothing to reverse in the ledger
+			p.Status, p.PaidAt = models.PAYMENT_REFUNDING, &now
+			return r.Payments.Update(p.ID, map[string]interface{}{
+				"status":  p.Status,
+				"paid_at": now,
+			})
+
+		case domain.PaymentFail:
+			p.Status = models.PAYMENT_FAILED
+			return r.Payments.Update(p.ID, map[string]interface{}{"status": p.Status})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if action == domain.PaymentRefund {
+		if err := s.sendRefund(&p); err != nil {
+			log.Printf("payment %s for an unpayable %s left REFUNDING: %v", p.ID, p.Purpose, err)
+		}
+	}
+	return nil
+}
+
+// recheckPayable locks the appointment or order p pays for and reports why
+// it can no longer be paid, if so.
+func recheckPayable(r repository.Repos, p *models.Payment) error {
+	switch {
+	case p.AppointmentID != nil:
+		appt, err := r.Appointments.Lock(*p.AppointmentID)
+		if err != nil {
+			return err
+		}
+		profile, err := r.Doctors.FindByID(appt.DoctorProfileID)
+		if err != nil {
+			return err
+		}
+		appt.DoctorProfile = *profile
+		return domain.CheckAppointmentPayable(appt)
+	case p.OrderID != nil:
+		order, err := r.Orders.Lock(*p.OrderID)
+		if err != nil {
+			return err
+		}
+		return domain.CheckOrderPayable(order)
+	}
+	return domain.ErrNotPayable
+}
+
+// Refund refunds a succeeded payment in full. The refund is recorded first:
+// the payment moves to REFUNDING, the paid flag on what it paid for is
+// cleared and the reversal is posted to the ledger, all in one transaction.
+// Only then is the gateway called, outside the transaction, so a gateway
+// error or crash leaves a REFUNDING payment to retry instead of money
+// returned without a record. Refunding a REFUNDING payment retries the
+// gateway call.
+func (s *PaymentService) Refund(paymentID string) (*models.Payment, error) {
+	var p models.Payment
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		locked, err := r.Payments.Lock(paymentID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return ErrPaymentNotFound
+		}
+		if err != nil {
+			return err
+		}
+		p = *locked
+		switch p.Status {
+		case models.PAYMENT_SUCCEEDED:
+			return s.beginRefund(r, &p)
+		case models.PAYMENT_REFUNDING:
+			return nil
+		}
+		return domain.ErrNotRefundable
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.sendRefund(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// beginRefund moves the locked, succeeded payment p to REFUNDING, clears the
+// paid flag on what it paid for and posts the reversal to the ledger.
+func (s *PaymentService) beginRefund(r repository.Repos, p *models.Payment) error {
+	p.Status = models.PAYMENT_REFUNDING
+	if err := r.Payments.Update(p.ID, map[string]interface{}{"status": p.Status}); err != nil {
+		return err
+	}
+	if err := markPaid(r, p, false); err != nil {
+		return err
+	}
+	return s.Ledger.recordRefund(r, p)
+}
+
+// sendRefund asks the gateway to return the money of the REFUNDING payment
+// p and then marks it REFUNDED. The payment ID is the idempotency key, so a
+// retry never refunds twice.
+func (s *PaymentService) sendRefund(p *models.Payment) error {
+	if _, err := s.Gateway.Refund(p.IntentID, payments.ToMinor(p.Amount), "refund:"+p.ID); err != nil {
+		return fmt.Errorf("%w: %v", ErrRefundNotSent, err)
+	}
+	now := utils.CurrentTime()
+	updated, err := s.Repos.Payments.UpdateIfStatus(p.ID, models.PAYMENT_REFUNDING, map[string]interface{}{
+		"status":      models.PAYMENT_REFUNDED,
+		"refunded_at": now,
+	})
+	if updated {
+		p.Status, p.RefundedAt = models.PAYMENT_REFUNDED, &now
+	}
+	return err
+}
+
+// cancelOrderPayments locks the live payments of an order being cancelled.
+// A succeeded one moves to REFUNDING with its ledger reversal. They are
+// returned, with the pending ones, for settleCancelled once the cancel has
+// committed.
+func (s *PaymentService) cancelOrderPayments(r repository.Repos, orderID string) ([]models.Payment, error) {
+	list, err := r.Payments.LockList(repository.PaymentFilter{
+		OrderID:  orderID,
+		Statuses: []models.PaymentStatus{models.PAYMENT_PENDING, models.PAYMENT_SUCCEEDED},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range list {
+		if list[i].Status == models.PAYMENT_SUCCEEDED {
+			if err := s.beginRefund(r, &list[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return list, nil
+}
+
+// lockAppointmentPayments locks the live payments of an appointment about
+// to end without its consultation. Call it before the appointment row is
+// updated, the lock order of a gateway callback.
+func lockAppointmentPayments(r repository.Repos, appointmentID string) ([]models.Payment, error) {
+	return r.Payments.LockList(repository.PaymentFilter{
+		AppointmentID: appointmentID,
+		Statuses:      []models.PaymentStatus{models.PAYMENT_PENDING, models.PAYMENT_SUCCEEDED},
+	})
+}
+
+// cancelAppointmentPayments moves the succeeded payments in list to
+// REFUNDING with their ledger reversal and voids the pending intents, in the
+// transaction that ended the appointment. An intent the gateway will not
+// void stays PENDING: it was paid meanwhile, and its success event is
+// refunded because the appointment is no longer payable. The REFUNDING
+// payments are returned for settleCancelled once the transaction commits.
+func (s *PaymentService) cancelAppointmentPayments(r repository.Repos, list []models.Payment) ([]models.Payment, error) {
+	var refunds []models.Payment
+	for i := range list {
+		p := &list[i]
+		switch p.Status {
+		case models.PAYMENT_SUCCEEDED:
+			if err := s.beginRefund(r, p); err != nil {
+				return nil, err
+			}
+			refunds = append(refunds, *p)
+		case models.PAYMENT_PENDING:
+			if err := s.void(r.Payments, p); err != nil {
+				log.Printf("pending payment %s not voided: %v", p.ID, err)
+			}
+		}
+	}
+	return refunds, nil
+}
+
+// settleCancelled sends the refunds and voids the pending intents collected
+// by cancelOrderPayments. Failures are only logged: a refund stays REFUNDING
+// for Refund to retry, and an intent paid before it could be voided is
+// refunded when its success event arrives.
+func (s *PaymentService) settleCancelled(list []models.Payment) {
+	for i := range list {
+		p := &list[i]
+		switch p.Status {
+		case models.PAYMENT_REFUNDING:
+			if err := s.sendRefund(p); err != nil {
+				log.Printf("refund of payment %s left REFUNDING: %v", p.ID, err)
+			}
+		case models.PAYMENT_PENDING:
+			if err := s.void(s.Repos.Payments, p); err != nil {
+				log.Printf("pending payment %s not voided: %v", p.ID, err)
+			}
+		}
+	}
+}
+
+// void cancels the pending intent of p at the gateway and marks p CANCELLED
+// through repo.
+func (s *PaymentService) void(repo repository.PaymentRepo, p *models.Payment) error {
+	if err := s.Gateway.Cancel(p.IntentID); err != nil {
+		return err
+	}
+	updated, err := repo.UpdateIfStatus(p.ID, models.PAYMENT_PENDING,
+		map[string]interface{}{"status": models.PAYMENT_CANCELLED})
+	if updated {
+		p.Status = models.PAYMENT_CANCELLED
+	}
+	return err
+}
+
+// markPaid sets or clears the paid marker of the appointment or order p is
+// for.
+func markPaid(r repository.Repos, p *models.Payment, paid bool) error {
+	switch {
+	case p.AppointmentID != nil:
+		return r.Appointments.Update(*p.AppointmentID, map[string]interface{}{"fee_paid": paid})
+	case p.OrderID != nil:
+		var paymentID interface{}
+		if paid {
+			paymentID = p.IntentID
+		}
+		return r.Orders.Update(*p.OrderID, map[string]interface{}{"payment_id": paymentID})
+	}
+	return nil
+}
+
+// ForUser lists the user's payments, newest first.
+func (s *PaymentService) ForUser(userID string) ([]models.Payment, error) {
+	return s.Repos.Payments.List(repository.PaymentFilter{UserID: userID})
+}
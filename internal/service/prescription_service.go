@@ -0,0 +1,157 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+var ErrPrescriptionNotFound = errors.New("prescription not found")
+
+// PrescriptionService issues prescriptions and turns them into orders.
+type PrescriptionService struct {
+	Repos  repository.Repos
+	Orders *OrderService
+}
+
+func NewPrescriptionService(repos repository.Repos, orders *OrderService) *PrescriptionService {
+	return &PrescriptionService{Repos: repos, Orders: orders}
+}
+
+type PrescriptionInput struct {
+	Items models.PrescriptionItems
+	Notes string
+	// ValidFor defaults to domain.DefaultPrescriptionValidity when zero.
+	ValidFor time.Duration
+}
+
+// Issue records a prescription for appt, which the caller has
+// already matched to the treating doctor, and queues a notification to the
+// patient in the same transaction. Catalog-linked lines must reference
+// medicines that are still sold.
+func (s *PrescriptionService) Issue(appt *models.Appointment, in PrescriptionInput) (*models.Prescription, error) {
+	if !domain.CanPrescribe(appt.Status) {
+		return nil, domain.ErrNotPrescribable
+	}
+	if in.ValidFor == 0 {
+		in.ValidFor = domain.DefaultPrescriptionValidity
+	}
+	if in.ValidFor < 0 || in.ValidFor > domain.MaxPrescriptionValidity {
+		return nil, fmt.Errorf("%w: validity must be between 1 and 365 days", domain.ErrInvalidPrescription)
+	}
+	if err := domain.ValidatePrescriptionItems(in.Items); err != nil {
+		return nil, err
+	}
+
+	rx := models.Prescription{
+		AppointmentID:   appt.ID,
+		DoctorProfileID: appt.DoctorProfileID,
+		PatientID:       appt.PatientID,
+		Items:           in.Items,
+		Notes:           in.Notes,
+		ValidUntil:      utils.CurrentTime().Add(in.ValidFor),
+	}
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		for _, id := range prescribedMedicines(in.Items) {
+			med, err := r.Medicines.FindByID(id)
+			if errors.Is(err, repository.ErrNotFound) || (err == nil && !med.Active) {
+				return domain.ErrMedicineUnavailable
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := r.Prescriptions.Create(&rx); err != nil {
+			return err
+		}
+
+		data := notify.AppointmentData(appt)
+		data["ValidUntil"] = notify.FormatTime(appt, rx.ValidUntil)
+		return enqueue(r.Outbox, notify.TemplatePrescriptionIssued, data, notify.RecipientOf(&appt.Patient))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rx, nil
+}
+
+// prescribedMedicines returns the distinct catalog IDs in items.
+func prescribedMedicines(items models.PrescriptionItems) []string {
+	seen := map[string]bool{}
+	var ids []string
+	for _, item := range items {
+		if item.MedicineID != "" && !seen[item.MedicineID] {
+			seen[item.MedicineID] = true
+			ids = append(ids, item.MedicineID)
+		}
+	}
+	return ids
+}
+
+// ForPatient lists the patient's prescriptions, newest first.
+func (s *PrescriptionService) ForPatient(patientID string) ([]models.Prescription, error) {
+	return s.Repos.Prescriptions.ListByPatient(patientID)
+}
+
+// Prescription returns one of the patient's own prescriptions.
+func (s *PrescriptionService) Prescription(patientID, id string) (*models.Prescription, error) {
+	rx, err := s.Repos.Prescriptions.FindForPatient(id, patientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPrescriptionNotFound
+	}
+	return rx, err
+}
+
+// ForAppointment lists the prescriptions issued for an appointment.
+func (s *PrescriptionService) ForAppointment(appointmentID string) ([]models.Prescription, error) {
+	return s.Repos.Prescriptions.ListByAppointment(appointmentID)
+}
+
+// Order places an order for every catalog-linked line of the patient's
+// prescription, backed by that prescription.
+func (s *PrescriptionService) Order(patientID, prescriptionID, paymentMethod string) (*models.Order, error) {
+	rx, err := s.Prescription(patientID, prescriptionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.Orders.Place(OrderInput{
+		UserID:         patientID,
+		Items:          domain.PrescriptionCart(rx.Items),
+		PaymentMethod:  paymentMethod,
+		PrescriptionID: rx.ID,
+	})
+}
+
+// lockPrescription loads the patient's prescription FOR UPDATE and checks it
+// can still back an order: it has not expired and no other live order uses
+// it. Holding the lock serialises checkouts against the same prescription.
+func lockPrescription(r repository.Repos, patientID, id string) (*models.Prescription, error) {
+	rx, err := r.Prescriptions.LockForPatient(id, patientID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrPrescriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !rx.ValidUntil.After(utils.CurrentTime()) {
+		return nil, domain.ErrPrescriptionNotValid
+	}
+
+	orders, err := r.Orders.List(repository.OrderFilter{PrescriptionID: rx.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, o := range orders {
+		if o.Status != models.ORDER_CANCELLED {
+			return nil, domain.ErrPrescriptionNotValid
+		}
+	}
+	return rx, nil
+}
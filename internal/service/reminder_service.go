@@ -0,0 +1,174 @@
+package service
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+// reminderLockKey is the Postgres advisory lock that lets only one replica
+// scan for reminders at a time.
+const reminderLockKey int64 = 0x57656c6c6f52
+
+// ReminderScheduler queues reminders for upcoming appointments at fixed
+// offsets before their start.
+type ReminderScheduler struct {
+	Repos   repository.Repos
+	Offsets []time.Duration
+	Now     func() time.Time
+}
+
+func NewReminderScheduler(repos repository.Repos, offsets []time.Duration) *ReminderScheduler {
+	return &ReminderScheduler{Repos: repos, Offsets: offsets, Now: utils.CurrentTime}
+}
+
+// Run calls RunOnce every interval, forever. It returns at once when no
+// offsets are configured.
+func (s *ReminderScheduler) Run(every time.Duration) {
+	if len(s.Offsets) == 0 {
+		return
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for range ticker.C {
+		if _, err := s.RunOnce(); err != nil {
+			log.Printf("Appointment reminders failed: %v", err)
+		}
+	}
+}
+
+// RunOnce queues every reminder that is due and returns how many appointments
+// were reminded. The scan runs in one transaction holding an advisory lock;
+// when another replica holds it, RunOnce does nothing. Reminder rows and their
+// notifications commit together, and the unique index on the rows is the
+// final guard against duplicates.
+func (s *ReminderScheduler) RunOnce() (int, error) {
+	if len(s.Offsets) == 0 {
+		return 0, nil
+	}
+	now := s.Now()
+	horizon := s.Offsets[0]
+	for _, o := range s.Offsets {
+		if o > horizon {
+			horizon = o
+		}
+	}
+
+	reminded := 0
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		locked, err := r.Tx.TryAdvisoryLock(reminderLockKey)
+		if err != nil || !locked {
+			return err
+		}
+
+		// Before is exclusive, so step past the end of the horizon
+		until := now.Add(horizon + time.Nanosecond)
+		appointments, err := r.Appointments.List(repository.AppointmentFilter{
+			Statuses: domain.ReminderStatuses,
+			After:    &now,
+			Before:   &until,
+		})
+		if err != nil {
+			return err
+		}
+		if len(appointments) == 0 {
+			return nil
+		}
+
+		sent, err := sentReminders(r.Reminders, appointments)
+		if err != nil {
+			return err
+		}
+
+		for i := range appointments {
+			a := &appointments[i]
+			key := reminderKey(a.ID, a.ScheduledAt)
+			send, mark, ok := domain.DueReminder(s.Offsets, a.ScheduledAt.Sub(now), sent[key])
+			if len(mark) == 0 {
+				continue
+			}
+
+			rows := make([]models.AppointmentReminder, 0, len(mark))
+			for _, o := range mark {
+				rows = append(rows, models.AppointmentReminder{
+					AppointmentID: a.ID,
+					OffsetMinutes: int(o / time.Minute),
+					ScheduledAt:   a.ScheduledAt,
+					Sent:          ok && o == send,
+				})
+			}
+			inserted, err := r.Reminders.CreateNew(rows)
+			if err != nil {
+				return err
+			}
+			// another writer recorded these first
+			if !ok || inserted < int64(len(rows)) {
+				continue
+			}
+
+			if err := enqueueReminder(r.Outbox, a, send); err != nil {
+				return err
+			}
+			reminded++
+		}
+		return nil
+	})
+	return reminded, err
+}
+
+// sentReminders loads the handled offsets per appointment and start time.
+func sentReminders(reminders repository.ReminderRepo, appointments []models.Appointment) (map[string]map[time.Duration]bool, error) {
+	ids := make([]string, len(appointments))
+	for i, a := range appointments {
+		ids[i] = a.ID
+	}
+	rows, err := reminders.ForAppointments(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	sent := map[string]map[time.Duration]bool{}
+	for _, r := range rows {
+		key := reminderKey(r.AppointmentID, r.ScheduledAt)
+		if sent[key] == nil {
+			sent[key] = map[time.Duration]bool{}
+		}
+		sent[key][time.Duration(r.OffsetMinutes)*time.Minute] = true
+	}
+	return sent, nil
+}
+
+func reminderKey(appointmentID string, scheduledAt time.Time) string {
+	return appointmentID + "@" + scheduledAt.UTC().Format(time.RFC3339)
+}
+
+// enqueueReminder queues the reminder to both the patient and the doctor.
+func enqueueReminder(q notify.Queue, a *models.Appointment, offset time.Duration) error {
+	data := notify.AppointmentData(a)
+	data["Lead"] = leadTime(offset)
+
+	if err := enqueue(q, notify.TemplateReminderPatient, data, notify.RecipientOf(&a.Patient)); err != nil {
+		return err
+	}
+	return enqueue(q, notify.TemplateReminderDoctor, data, notify.RecipientOf(a.DoctorProfile.User))
+}
+
+// leadTime phrases an offset for a message, e.g. "in 24 hours".
+func leadTime(d time.Duration) string {
+	switch {
+	case d == time.Hour:
+		return "in 1 hour"
+	case d%time.Hour == 0:
+		return fmt.Sprintf("in %d hours", d/time.Hour)
+	case d == time.Minute:
+		return "in 1 minute"
+	default:
+		return fmt.Sprintf("in %d minutes", d/time.Minute)
+	}
+}
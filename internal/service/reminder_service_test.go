@@ -0,0 +1,66 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+)
+
+func TestRunOnceRemindsOnlyConfirmedAppointments(t *testing.T) {
+	_, repos, profile, patient := seedBooking(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	reminded := map[models.AppointmentStatus]bool{
+		models.ACCEPTED:              true,
+		models.RESCHEDULED_CONFIRMED: true,
+		models.RESCHEDULE_REJECTED:   true,
+		models.PENDING:               false,
+		models.RESCHEDULE_REQUESTED:  false,
+		models.CANCELLED_BY_PATIENT:  false,
+		models.EXPIRED:               false,
+	}
+	ids := map[models.AppointmentStatus]string{}
+	for status := range reminded {
+		appt := models.Appointment{
+			PatientID:       patient.ID,
+			DoctorProfileID: profile.ID,
+			ScheduledAt:     now.Add(30 * time.Minute),
+			Status:          status,
+		}
+		if err := repos.Appointments.Create(&appt); err != nil {
+			t.Fatalf("create %s: %v", status, err)
+		}
+		ids[status] = appt.ID
+	}
+
+	s := NewReminderScheduler(repos, []time.Duration{24 * time.Hour, time.Hour})
+	s.Now = func() time.Time { return now }
+	queued := len(repos.Outbox.(*notify.MemoryQueue).Messages())
+	n, err := s.RunOnce()
+	if err != nil {
+		t.Fatalf("run: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("reminded %d appointments, want 3", n)
+	}
+	if len(repos.Outbox.(*notify.MemoryQueue).Messages()) == queued {
+		t.Fatal("no reminder queued")
+	}
+
+	// a second run finds every window already recorded
+	if n, err := s.RunOnce(); err != nil || n != 0 {
+		t.Fatalf("second run: reminded %d, err %v", n, err)
+	}
+
+	for status, want := range reminded {
+		rows, err := repos.Reminders.ForAppointments([]string{ids[status]})
+		if err != nil {
+			t.Fatalf("list %s: %v", status, err)
+		}
+		if got := len(rows) > 0; got != want {
+			t.Errorf("%s: reminded %v, want %v", status, got, want)
+		}
+	}
+}
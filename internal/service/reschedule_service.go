@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/notify"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+type ProposalInput struct {
+	Role   models.Role
+	UserID string
+	Time   time.Time
+	Reason string
+}
+
+// ProposeReschedule opens a proposal to move appt, superseding any open one
+// (a counter-proposal). The appointment keeps its time and moves to
+// RESCHEDULE_REQUESTED; the other party is notified. The proposed time must
+// be free in the doctor's schedule now, and is checked again on acceptance.
+func (s *AppointmentService) ProposeReschedule(appt *models.Appointment, in ProposalInput) (*models.RescheduleProposal, error) {
+	now := utils.CurrentTime()
+	if !in.Time.After(now) {
+		return nil, domain.ErrSlotInPast
+	}
+
+	var proposal models.RescheduleProposal
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		profile, current, err := lockAppointment(r, appt)
+		if err != nil {
+			return err
+		}
+		if _, err := domain.NextStatus(current.Status, domain.EventRequestReschedule, in.Role); err != nil {
+			return err
+		}
+		if in.Time.Equal(current.ScheduledAt) {
+			return domain.ErrSameTime
+		}
+		if _, err := checkSlot(r, profile, in.Time, current.ID); err != nil {
+			return err
+		}
+
+		var parentID *string
+		open, err := openProposal(r.Proposals, current.ID)
+		if err != nil && !errors.Is(err, domain.ErrNoOpenProposal) {
+			return err
+		}
+		if open != nil {
+			if err := closeProposal(r.Proposals, open, models.PROPOSAL_SUPERSEDED, now); err != nil {
+				return err
+			}
+			parentID = &open.ID
+		}
+
+		proposal = models.RescheduleProposal{
+			AppointmentID: current.ID,
+			ParentID:      parentID,
+			ProposedBy:    in.Role,
+			ProposerID:    in.UserID,
+			ProposedTime:  in.Time,
+			OriginalTime:  current.ScheduledAt,
+			Reason:        strings.TrimSpace(in.Reason),
+			Status:        models.PROPOSAL_OPEN,
+			ExpiresAt:     domain.ProposalExpiry(now, in.Time, current.ScheduledAt, s.ProposalTTL),
+		}
+		if err := r.Proposals.Create(&proposal); err != nil {
+			return err
+		}
+
+		// rescheduling never ends the appointment, so nothing is refunded
+		t, _, err := s.applyTransition(r, current, domain.EventRequestReschedule, in.Role, nil)
+		if err != nil {
+			return err
+		}
+		*appt = *current
+		return enqueueTransition(r.Outbox, current, t, in.Role, proposalData(current, &proposal))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &proposal, nil
+}
+
+// RespondToProposal accepts or rejects the open proposal of appt on behalf of
+// the party that did not make it. proposalID, when set, must name the open
+// proposal, so an answer to a proposal that was countered meanwhile fails.
+// Accepting re-checks the slot and only then moves the appointment.
+func (s *AppointmentService) RespondToProposal(appt *models.Appointment, proposalID string, role models.Role, accept bool) (*models.RescheduleProposal, error) {
+	now := utils.CurrentTime()
+
+	var proposal *models.RescheduleProposal
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		profile, current, err := lockAppointment(r, appt)
+		if err != nil {
+			return err
+		}
+
+		open, err := openProposal(r.Proposals, current.ID)
+		if err != nil {
+			return err
+		}
+		if proposalID != "" && open.ID != proposalID {
+			return domain.ErrNoOpenProposal
+		}
+		if open.ProposedBy == role {
+			return domain.ErrOwnProposal
+		}
+		if !open.ExpiresAt.After(now) {
+			return domain.ErrProposalExpired
+		}
+
+		event := domain.EventRejectReschedule
+		status := models.PROPOSAL_REJECTED
+		var changes map[string]interface{}
+		if accept {
+			if !open.ProposedTime.After(now) {
+				return domain.ErrSlotInPast
+			}
+			slot, err := checkSlot(r, profile, open.ProposedTime, current.ID)
+			if err != nil {
+				return err
+			}
+			event = domain.EventAcceptReschedule
+			status = models.PROPOSAL_ACCEPTED
+			changes = map[string]interface{}{
+				"scheduled_at":     open.ProposedTime,
+				"duration_minutes": int(slot.Duration / time.Minute),
+			}
+		}
+
+		if err := closeProposal(r.Proposals, open, status, now); err != nil {
+			return err
+		}
+		t, _, err := s.applyTransition(r, current, event, role, changes)
+		if err != nil {
+			return err
+		}
+		proposal = open
+		*appt = *current
+		return enqueueTransition(r.Outbox, current, t, role, proposalData(current, open))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// ListProposals returns the proposal history of an appointment, newest first.
+func (s *AppointmentService) ListProposals(appointmentID string) ([]models.RescheduleProposal, error) {
+	return s.Repos.Proposals.ListByAppointment(appointmentID)
+}
+
+// lockAppointment locks the doctor profile and then the appointment, the
+// same order booking and leave use, and returns fresh copies of both.
+func lockAppointment(r repository.Repos, appt *models.Appointment) (*models.DoctorProfile, *models.Appointment, error) {
+	profile, err := r.Doctors.Lock(appt.DoctorProfileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	current, err := r.Appointments.Lock(appt.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return profile, current, nil
+}
+
+func openProposal(proposals repository.ProposalRepo, appointmentID string) (*models.RescheduleProposal, error) {
+	p, err := proposals.FindOpen(appointmentID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, domain.ErrNoOpenProposal
+	}
+	return p, err
+}
+
+func closeProposal(proposals repository.ProposalRepo, p *models.RescheduleProposal, status models.ProposalStatus, now time.Time) error {
+	p.Status = status
+	p.RespondedAt = &now
+	return proposals.Close(p.ID, status, now)
+}
+
+// proposalData is the template data describing p.
+func proposalData(appt *models.Appointment, p *models.RescheduleProposal) map[string]interface{} {
+	return map[string]interface{}{
+		"ProposedWhen": notify.FormatTime(appt, p.ProposedTime),
+		"ProposerRole": strings.ToLower(string(p.ProposedBy)),
+		"Reason":       p.Reason,
+	}
+}
@@ -0,0 +1,155 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+var ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+
+// SessionService manages device sessions and the refresh tokens that keep
+// them alive. Access tokens are signed with Keys.
+type SessionService struct {
+	Repos repository.Repos
+	Keys  *utils.KeyManager
+}
+
+func NewSessionService(repos repository.Repos, keys *utils.KeyManager) *SessionService {
+	return &SessionService{Repos: repos, Keys: keys}
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+	ExpiresIn    int    `json:"expiresIn"`
+	SessionID    string `json:"-"`
+}
+
+// Start records a new device session for user and returns its first
+// access/refresh token pair.
+func (s *SessionService) Start(user *models.User, device, ip string) (*TokenPair, error) {
+	secret, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := utils.CurrentTime()
+	session := models.Session{
+		UserID:           user.ID,
+		RefreshTokenHash: utils.HashToken(secret),
+		Device:           device,
+		IPAddress:        ip,
+		ExpiresAt:        now.Add(RefreshTokenTTL),
+		LastUsedAt:       now,
+	}
+	if err := s.Repos.Sessions.Create(&session); err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, &session, secret)
+}
+
+// Refresh rotates the refresh token and issues a new access token with
+// claims re-read from the database. Presenting an already-rotated token is
+// treated as theft and revokes the whole session.
+func (s *SessionService) Refresh(refreshToken, ip string) (*TokenPair, error) {
+	sessionID, secret, ok := strings.Cut(refreshToken, ".")
+	if !ok || sessionID == "" || secret == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	var pair *TokenPair
+	var reused bool
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		session, err := r.Sessions.Lock(sessionID)
+		if err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		now := utils.CurrentTime()
+		if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+		if session.RefreshTokenHash != utils.HashToken(secret) {
+			reused = true
+			return ErrInvalidRefreshToken
+		}
+
+		user, err := r.Users.FindByID(session.UserID)
+		if err != nil {
+			return err
+		}
+		next, err := utils.RandomToken(32)
+		if err != nil {
+			return err
+		}
+		if err := r.Sessions.Update(session.ID, map[string]interface{}{
+			"refresh_token_hash": utils.HashToken(next),
+			"last_used_at":       now,
+			"ip_address":         ip,
+		}); err != nil {
+			return err
+		}
+
+		pair, err = s.issueTokens(user, session, next)
+		return err
+	})
+	if reused {
+		s.Revoke("", sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+// Revoke signs out one session. An empty userID skips the ownership check.
+func (s *SessionService) Revoke(userID, sessionID string) error {
+	revoked, err := s.Repos.Sessions.Revoke(sessionID, userID, utils.CurrentTime())
+	if err != nil {
+		return err
+	}
+	if !revoked {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// revokeUserSessions signs the user out everywhere, e.g. after their role
+// or approval changed and existing tokens carry stale claims.
+func revokeUserSessions(sessions repository.SessionRepo, userID string) error {
+	return sessions.RevokeAll(userID, utils.CurrentTime())
+}
+
+// List returns the user's active sessions, newest first.
+func (s *SessionService) List(userID string) ([]models.Session, error) {
+	return s.Repos.Sessions.ListActive(userID, utils.CurrentTime())
+}
+
+// Active reports whether an access token's session is still valid.
+func (s *SessionService) Active(sessionID string) bool {
+	session, err := s.Repos.Sessions.FindByID(sessionID)
+	if err != nil {
+		return false
+	}
+	return session.RevokedAt == nil && session.ExpiresAt.After(utils.CurrentTime())
+}
+
+func (s *SessionService) issueTokens(user *models.User, session *models.Session, secret string) (*TokenPair, error) {
+	access, err := s.Keys.GenerateJWT(user.ID, string(user.Role), user.IsApproved, false, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: session.ID + "." + secret,
+		ExpiresIn:    int(utils.AccessTokenTTL / time.Second),
+		SessionID:    session.ID,
+	}, nil
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+func newSessionService(t *testing.T) (*SessionService, *models.User) {
+	t.Helper()
+	repos := repository.NewMemoryRepos()
+	key, err := utils.NewHMACKey("test", "0123456789abcdef0123456789abcdef")
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	user := models.User{Name: "Asha", Email: "asha@example.com", Role: models.PATIENT, IsApproved: true}
+	if err := repos.Users.Create(&user); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return NewSessionService(repos, utils.NewKeyManager(key)), &user
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	s, user := newSessionService(t)
+	first, err := s.Start(user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	claims, err := s.Keys.ParseJWT(first.AccessToken)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if claims.UserID != user.ID || claims.SessionID != first.SessionID {
+		t.Fatalf("claims %+v", claims)
+	}
+
+	second, err := s.Refresh(first.RefreshToken, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.SessionID != first.SessionID {
+		t.Fatalf("refresh token not rotated within the session")
+	}
+	if !s.Active(first.SessionID) {
+		t.Fatal("session inactive after a normal refresh")
+	}
+}
+
+// Presenting a rotated refresh token means it leaked; the whole session goes.
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	s, user := newSessionService(t)
+	first, err := s.Start(user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+	second, err := s.Refresh(first.RefreshToken, "127.0.0.1")
+	if err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	if _, err := s.Refresh(first.RefreshToken, "10.0.0.9"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("reuse: got %v, want ErrInvalidRefreshToken", err)
+	}
+	if s.Active(first.SessionID) {
+		t.Fatal("session still active after refresh token reuse")
+	}
+	if _, err := s.Refresh(second.RefreshToken, "127.0.0.1"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("current token after reuse: got %v, want ErrInvalidRefreshToken", err)
+	}
+}
+
+func TestRevokeChecksOwner(t *testing.T) {
+	s, user := newSessionService(t)
+	pair, err := s.Start(user, "test", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("start: %v", err)
+	}
+
+	if err := s.Revoke("someone-else", pair.SessionID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("other user: got %v, want ErrNotFound", err)
+	}
+	if err := s.Revoke(user.ID, pair.SessionID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if s.Active(pair.SessionID) {
+		t.Fatal("session active after revoke")
+	}
+	if err := s.Revoke(user.ID, pair.SessionID); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("second revoke: got %v, want ErrNotFound", err)
+	}
+}
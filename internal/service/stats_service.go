@@ -1 +1,134 @@
 package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/domain"
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/payments"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+)
+
+var ErrInvalidPeriod = errors.New("period must be one of all, daily, weekly, monthly, yearly")
+
+// StatsService computes dashboard figures from repository data.
+type StatsService struct {
+	Doctors      repository.DoctorRepo
+	Appointments repository.AppointmentRepo
+	Ledger       repository.LedgerRepo
+}
+
+func NewStatsService(repos repository.Repos) *StatsService {
+	return &StatsService{Doctors: repos.Doctors, Appointments: repos.Appointments, Ledger: repos.Ledger}
+}
+
+type EarningsBucket struct {
+	Label string  `json:"label"`
+	Total float64 `json:"total"`
+	Count int64   `json:"count"`
+}
+
+type EarningsReport struct {
+	TotalEarnings     float64          `json:"totalEarnings"`
+	TotalAppointments int64            `json:"totalAppointments"`
+	PendingPayout     float64          `json:"pendingPayout"`
+	GroupedData       []EarningsBucket `json:"groupedData"`
+	Period            string           `json:"period"`
+}
+
+// DoctorEarnings totals the doctor's share of paid appointment fees from the
+// ledger: fees received less platform commission and refunds, at the
+// amounts actually charged. Paid appointments count once and refunded ones
+// are taken back out. Unless period is "all" the figures are grouped by
+// day, ISO week, month or year of the ledger date in the doctor's timezone.
+// PendingPayout is what has not been paid out yet.
+func (s *StatsService) DoctorEarnings(userID, period string) (*EarningsReport, error) {
+	if period == "" {
+		period = "all"
+	}
+	label, ok := earningsLabels[period]
+	if !ok {
+		return nil, ErrInvalidPeriod
+	}
+
+	profile, err := s.Doctors.FindByUserID(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, ErrDoctorProfileNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	txns, err := s.Ledger.ForDoctor(profile.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &EarningsReport{Period: period}
+	loc := domain.ScheduleConfig(profile).Location
+	buckets := map[string]*EarningsBucket{}
+	// totals are summed in minor units and converted once at the end
+	totals := map[string]int64{}
+	var earned, owed int64
+	for _, t := range txns {
+		share := domain.DoctorOwed(t.Entries)
+		owed += share
+		if t.Kind == models.TXN_PAYOUT {
+			continue
+		}
+
+		var count int64
+		switch t.Kind {
+		case models.TXN_PAYMENT:
+			count = 1
+		case models.TXN_REFUND:
+			count = -1
+		}
+		earned += share
+		report.TotalAppointments += count
+
+		if label == nil {
+			continue
+		}
+		key := label(t.OccurredAt.In(loc))
+		b, ok := buckets[key]
+		if !ok {
+			b = &EarningsBucket{Label: key}
+			buckets[key] = b
+		}
+		totals[key] += share
+		b.Count += count
+	}
+	report.TotalEarnings = payments.FromMinor(earned)
+	report.PendingPayout = payments.FromMinor(owed)
+
+	for key, b := range buckets {
+		b.Total = payments.FromMinor(totals[key])
+		report.GroupedData = append(report.GroupedData, *b)
+	}
+	sort.Slice(report.GroupedData, func(i, j int) bool {
+		return report.GroupedData[i].Label < report.GroupedData[j].Label
+	})
+	return report, nil
+}
+
+// earningsLabels maps a period to its bucket label; "all" has no buckets.
+var earningsLabels = map[string]func(time.Time) string{
+	"all":     nil,
+	"daily":   func(t time.Time) string { return t.Format("2006-01-02") },
+	"monthly": func(t time.Time) string { return t.Format("2006-01") },
+	"yearly":  func(t time.Time) string { return t.Format("2006") },
+	"weekly": func(t time.Time) string {
+		y, w := t.ISOWeek()
+		return fmt.Sprintf("%04d-%02d", y, w)
+	},
+}
+
+// ExpiredRequestsByDoctor counts expired appointment requests per doctor,
+// optionally only those expired since the given time.
+func (s *StatsService) ExpiredRequestsByDoctor(since *time.Time) ([]repository.DoctorCount, error) {
+	return s.Appointments.CountByDoctor(models.EXPIRED, since)
+}
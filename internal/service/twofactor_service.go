@@ -0,0 +1,351 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/GitNinja36/wello-backend/internal/totp"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+const (
+	TOTPIssuer        = "Wello"
+	recoveryCodeCount = 10
+	// StepUpTTL is how long a fresh second-factor check unlocks sensitive endpoints.
+	StepUpTTL = 10 * time.Minute
+	// maxSecondFactorFailures wrong TOTP or recovery codes in a row lock the
+	// second factor for secondFactorLockout, which keeps the 6-digit code
+	// space out of reach of online guessing.
+	maxSecondFactorFailures = 5
+	secondFactorLockout     = 15 * time.Minute
+)
+
+var (
+	ErrTOTPAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotEnrolled     = errors.New("two-factor authentication is not enabled")
+	ErrTOTPNotStarted      = errors.New("start TOTP enrollment first")
+	ErrInvalidSecondFactor = errors.New("invalid authentication code")
+	ErrTwoFactorEnforced   = errors.New("two-factor authentication is required for this account")
+	ErrEnrollmentRequired  = errors.New("two-factor enrollment required before using this endpoint")
+	ErrStepUpRequired      = errors.New("recent two-factor verification required")
+	ErrTwoFactorRole       = errors.New("two-factor authentication is only available to doctors and admins")
+	ErrSecondFactorLocked  = errors.New("too many invalid authentication codes, try again later")
+)
+
+// TwoFactorService handles TOTP enrollment, second-factor checks and step-up.
+// Now is the clock TOTP codes and lockouts are checked against.
+type TwoFactorService struct {
+	Repos repository.Repos
+	Now   func() time.Time
+}
+
+func NewTwoFactorService(repos repository.Repos) *TwoFactorService {
+	return &TwoFactorService{Repos: repos, Now: utils.CurrentTime}
+}
+
+// TwoFactorRole reports whether role may (and can be made to) use TOTP.
+func TwoFactorRole(role models.Role) bool {
+	return role == models.DOCTOR || role == models.ADMIN
+}
+
+// BeginTOTPEnrollment stores a fresh, not yet active secret for user and
+// returns it with the otpauth:// URI to render as a QR code.
+func (s *TwoFactorService) BeginTOTPEnrollment(user *models.User) (secret, uri string, err error) {
+	if !TwoFactorRole(user.Role) {
+		return "", "", ErrTwoFactorRole
+	}
+	if user.TOTPEnabled {
+		return "", "", ErrTOTPAlreadyEnabled
+	}
+
+	secret, err = totp.NewSecret()
+	if err != nil {
+		return "", "", err
+	}
+	sealed, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.Repos.Users.Update(user.ID, map[string]interface{}{
+		"totp_secret":       sealed,
+		"totp_last_counter": 0,
+	}); err != nil {
+		return "", "", err
+	}
+	user.TOTPSecret, user.TOTPLastCounter = sealed, 0
+
+	account := user.Email
+	if account == "" {
+		account = user.Phone
+	}
+	return secret, totp.Default.ProvisioningURI(TOTPIssuer, account, secret), nil
+}
+
+// ConfirmTOTPEnrollment activates the pending secret once the user proves
+// their app produces valid codes, and returns a fresh set of recovery codes.
+func (s *TwoFactorService) ConfirmTOTPEnrollment(userID, code string) ([]string, error) {
+	var codes []string
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		user, err := r.Users.Lock(userID)
+		if err != nil {
+			return err
+		}
+		if user.TOTPEnabled {
+			return ErrTOTPAlreadyEnabled
+		}
+		if user.TOTPSecret == "" {
+			return ErrTOTPNotStarted
+		}
+
+		counter, err := checkTOTP(user, code, s.Now())
+		if err != nil {
+			return err
+		}
+		if err := r.Users.Update(user.ID, map[string]interface{}{
+			"totp_enabled":      true,
+			"totp_last_counter": counter,
+		}); err != nil {
+			return err
+		}
+
+		codes, err = replaceRecoveryCodes(r.RecoveryCodes, user.ID)
+		return err
+	})
+	return codes, err
+}
+
+// VerifySecondFactor accepts either a TOTP code or an unused recovery code.
+// A TOTP step is accepted only once, so an observed code cannot be replayed.
+// Wrong codes are counted per user; after maxSecondFactorFailures in a row
+// every check fails with ErrSecondFactorLocked until the lockout passes.
+func (s *TwoFactorService) VerifySecondFactor(userID, code, recoveryCode string) error {
+	var result error
+	err := s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		user, err := r.Users.Lock(userID)
+		if err != nil {
+			return err
+		}
+		if !user.TOTPEnabled {
+			return ErrTOTPNotEnrolled
+		}
+		now := s.Now()
+		if user.SecondFactorLockedUntil != nil && now.Before(*user.SecondFactorLockedUntil) {
+			result = ErrSecondFactorLocked
+			return nil
+		}
+
+		err = s.checkSecondFactor(r, user, code, recoveryCode, now)
+		if errors.Is(err, ErrInvalidSecondFactor) {
+			// commit the failure count; the error is reported after commit
+			fields, locked := secondFactorFailure(user.SecondFactorFailures, now)
+			result = ErrInvalidSecondFactor
+			if locked {
+				result = ErrSecondFactorLocked
+			}
+			return r.Users.Update(user.ID, fields)
+		}
+		if err != nil {
+			return err
+		}
+		if user.SecondFactorFailures == 0 {
+			return nil
+		}
+		return r.Users.Update(user.ID, map[string]interface{}{"second_factor_failures": 0})
+	})
+	if err != nil {
+		return err
+	}
+	return result
+}
+
+// checkSecondFactor verifies code or recoveryCode for the locked user,
+// consuming the recovery code or TOTP step on success.
+func (s *TwoFactorService) checkSecondFactor(r repository.Repos, user *models.User, code, recoveryCode string, now time.Time) error {
+	if recoveryCode != "" {
+		return useRecoveryCode(r.RecoveryCodes, user.ID, recoveryCode, now)
+	}
+
+	counter, err := checkTOTP(user, code, now)
+	if err != nil {
+		return err
+	}
+	if counter <= user.TOTPLastCounter {
+		return ErrInvalidSecondFactor
+	}
+	return r.Users.Update(user.ID, map[string]interface{}{"totp_last_counter": counter})
+}
+
+// secondFactorFailure returns the user columns to write after one more wrong
+// code on top of failures, and whether that locks the second factor.
+func secondFactorFailure(failures int, now time.Time) (map[string]interface{}, bool) {
+	failures++
+	if failures < maxSecondFactorFailures {
+		return map[string]interface{}{"second_factor_failures": failures}, false
+	}
+	return map[string]interface{}{
+		"second_factor_failures":     0,
+		"second_factor_locked_until": now.Add(secondFactorLockout),
+	}, true
+}
+
+// DisableTOTP turns two-factor off after a final verification. Accounts an
+// admin has enforced 2FA on cannot opt out.
+func (s *TwoFactorService) DisableTOTP(userID, code, recoveryCode string) error {
+	user, err := s.Repos.Users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.TwoFactorRequired {
+		return ErrTwoFactorEnforced
+	}
+	if err := s.VerifySecondFactor(userID, code, recoveryCode); err != nil {
+		return err
+	}
+	return s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		return clearTwoFactor(r, userID)
+	})
+}
+
+// RegenerateRecoveryCodes invalidates the old codes and returns new ones.
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID, code string) ([]string, error) {
+	if err := s.VerifySecondFactor(userID, code, ""); err != nil {
+		return nil, err
+	}
+	return replaceRecoveryCodes(s.Repos.RecoveryCodes, userID)
+}
+
+// MarkStepUp records that the session just passed a second-factor check.
+func (s *TwoFactorService) MarkStepUp(sessionID string) error {
+	return s.Repos.Sessions.Update(sessionID, map[string]interface{}{"step_up_at": s.Now()})
+}
+
+// CheckStepUp guards sensitive endpoints. Users without 2FA pass unless an
+// admin enforced it; enrolled users need a step-up within StepUpTTL.
+func (s *TwoFactorService) CheckStepUp(userID, sessionID string) error {
+	user, err := s.Repos.Users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		if user.TwoFactorRequired {
+			return ErrEnrollmentRequired
+		}
+		return nil
+	}
+
+	session, err := s.Repos.Sessions.FindByID(sessionID)
+	if err != nil {
+		return ErrStepUpRequired
+	}
+	if session.StepUpAt == nil || s.Now().Sub(*session.StepUpAt) > StepUpTTL {
+		return ErrStepUpRequired
+	}
+	return nil
+}
+
+// SetTwoFactorRequired lets an admin enforce (or relax) 2FA for a doctor or
+// admin. Existing sessions are revoked so the requirement applies at once.
+func (s *TwoFactorService) SetTwoFactorRequired(userID string, required bool) error {
+	return s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		user, err := r.Users.Lock(userID)
+		if err != nil {
+			return err
+		}
+		if required && !TwoFactorRole(user.Role) {
+			return ErrTwoFactorRole
+		}
+		if err := r.Users.Update(user.ID, map[string]interface{}{"two_factor_required": required}); err != nil {
+			return err
+		}
+		return revokeUserSessions(r.Sessions, user.ID)
+	})
+}
+
+// ResetTwoFactor removes a user's TOTP secret and recovery codes, e.g. after
+// a lost device. The enforcement flag is kept so they must enroll again.
+func (s *TwoFactorService) ResetTwoFactor(userID string) error {
+	return s.Repos.Tx.Transaction(func(r repository.Repos) error {
+		if _, err := r.Users.Lock(userID); err != nil {
+			return err
+		}
+		if err := clearTwoFactor(r, userID); err != nil {
+			return err
+		}
+		return revokeUserSessions(r.Sessions, userID)
+	})
+}
+
+// checkTOTP verifies code against the user's sealed secret at now and returns
+// the matched time step.
+func checkTOTP(user *models.User, code string, now time.Time) (int64, error) {
+	plain, err := utils.DecryptSecret(user.TOTPSecret)
+	if err != nil {
+		return 0, err
+	}
+	secret, err := totp.DecodeSecret(plain)
+	if err != nil {
+		return 0, err
+	}
+	counter, ok := totp.Default.Verify(secret, code, now)
+	if !ok {
+		return 0, ErrInvalidSecondFactor
+	}
+	return counter, nil
+}
+
+func clearTwoFactor(r repository.Repos, userID string) error {
+	if err := r.Users.Update(userID, map[string]interface{}{
+		"totp_secret":                "",
+		"totp_enabled":               false,
+		"totp_last_counter":          0,
+		"second_factor_failures":     0,
+		"second_factor_locked_until": nil,
+	}); err != nil {
+		return err
+	}
+	return r.RecoveryCodes.DeleteAll(userID)
+}
+
+func replaceRecoveryCodes(recoveryCodes repository.RecoveryCodeRepo, userID string) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	rows := make([]models.RecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+		rows = append(rows, models.RecoveryCode{UserID: userID, CodeHash: utils.HashToken(code)})
+	}
+	if err := recoveryCodes.Replace(userID, rows); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func useRecoveryCode(recoveryCodes repository.RecoveryCodeRepo, userID, code string, now time.Time) error {
+	normalized := strings.ToLower(strings.TrimSpace(code))
+	used, err := recoveryCodes.Use(userID, utils.HashToken(normalized), now)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidSecondFactor
+	}
+	return nil
+}
+
+// newRecoveryCode returns a code like "k3f9x-2mqpa" (50 bits of entropy).
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	s := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))[:10]
+	return s[:5] + "-" + s[5:], nil
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/totp"
+	"github.com/GitNinja36/wello-backend/internal/utils"
+)
+
+func TestCheckTOTPUsesGivenClock(t *testing.T) {
+	t.Setenv("DATA_ENCRYPTION_KEY", "test-data-encryption-key")
+	// base32 of the RFC 6238 seed "12345678901234567890"
+	sealed, err := utils.EncryptSecret("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{TOTPSecret: sealed}
+
+	at := time.Unix(1111111111, 0)
+	counter, err := checkTOTP(user, "050471", at)
+	if err != nil {
+		t.Fatalf("RFC code rejected: %v", err)
+	}
+	if counter != totp.Default.Counter(at) {
+		t.Fatalf("counter %d, want %d", counter, totp.Default.Counter(at))
+	}
+
+	// the same code an hour later is outside the skew window
+	if _, err := checkTOTP(user, "050471", at.Add(time.Hour)); !errors.Is(err, ErrInvalidSecondFactor) {
+		t.Fatalf("stale code: got %v, want ErrInvalidSecondFactor", err)
+	}
+}
+
+func TestSecondFactorFailure(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for failures := 0; failures < maxSecondFactorFailures-1; failures++ {
+		fields, locked := secondFactorFailure(failures, now)
+		if locked {
+			t.Fatalf("locked after %d failures", failures+1)
+		}
+		if fields["second_factor_failures"] != failures+1 {
+			t.Fatalf("failures %v, want %d", fields["second_factor_failures"], failures+1)
+		}
+	}
+
+	fields, locked := secondFactorFailure(maxSecondFactorFailures-1, now)
+	if !locked {
+		t.Fatal("not locked at the limit")
+	}
+	if fields["second_factor_failures"] != 0 {
+		t.Fatalf("counter not reset on lockout: %v", fields["second_factor_failures"])
+	}
+	if until := fields["second_factor_locked_until"]; until != now.Add(secondFactorLockout) {
+		t.Fatalf("locked until %v, want %v", until, now.Add(secondFactorLockout))
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+	"github.com/google/uuid"
+)
+
+var (
+	ErrAccountExists = errors.New("an account with this phone or email already exists")
+	ErrNameRequired  = errors.New("name is required")
+	ErrInvalidRole   = errors.New("invalid role")
+)
+
+// UserService holds account registration and profile logic.
+type UserService struct {
+	Users   repository.UserRepo
+	Doctors repository.DoctorRepo
+}
+
+func NewUserService(repos repository.Repos) *UserService {
+	return &UserService{Users: repos.Users, Doctors: repos.Doctors}
+}
+
+type PatientInput struct {
+	Name    string
+	Email   string
+	Phone   string
+	Age     int
+	Gender  string
+	Address string
+}
+
+type DoctorRegistration struct {
+	Name             string
+	Email            string
+	Phone            string
+	Specialization   string
+	LicenseNumber    string
+	ConsultationFees float64
+}
+
+// OnboardDoctor creates the DOCTOR account for an identifier that already
+// passed OTP verification, with a pending profile awaiting approval.
+func (s *UserService) OnboardDoctor(in DoctorRegistration) (*models.User, error) {
+	if err := s.checkNewAccount(in.Name, in.Phone, in.Email); err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		ID:                uuid.NewString(),
+		Name:              in.Name,
+		Email:             in.Email,
+		Phone:             in.Phone,
+		Role:              models.DOCTOR,
+		RequestedAsDoctor: true,
+		IsApproved:        false,
+		Verified:          true,
+	}
+	if err := s.Users.Create(&user); err != nil {
+		return nil, err
+	}
+
+	profile := models.DoctorProfile{
+		ID:               uuid.NewString(),
+		UserID:           user.ID,
+		Specialization:   in.Specialization,
+		LicenseNumber:    in.LicenseNumber,
+		ConsultationFees: in.ConsultationFees,
+		IsPending:        true,
+	}
+	if err := s.Doctors.Create(&profile); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// OnboardPatient creates the PATIENT account for an identifier that already
+// passed OTP verification.
+func (s *UserService) OnboardPatient(in PatientInput) (*models.User, error) {
+	if err := s.checkNewAccount(in.Name, in.Phone, in.Email); err != nil {
+		return nil, err
+	}
+
+	user := models.User{
+		ID:         uuid.NewString(),
+		Name:       in.Name,
+		Email:      in.Email,
+		Phone:      in.Phone,
+		Age:        in.Age,
+		Gender:     in.Gender,
+		Address:    in.Address,
+		Role:       models.PATIENT,
+		Verified:   true,
+		IsApproved: true,
+	}
+	if err := s.Users.Create(&user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// checkNewAccount rejects a nameless signup or one whose phone or email is
+// already taken.
+func (s *UserService) checkNewAccount(name, phone, email string) error {
+	if strings.TrimSpace(name) == "" {
+		return ErrNameRequired
+	}
+	exists, err := s.Users.ExistsByPhoneOrEmail(phone, email)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return ErrAccountExists
+	}
+	return nil
+}
+
+func (s *UserService) Get(userID string) (*models.User, error) {
+	return s.Users.FindByID(userID)
+}
+
+// ListByRole pages through patients or doctors; page is 1-based.
+func (s *UserService) ListByRole(role models.Role, page, limit int) ([]models.User, int64, error) {
+	if role != models.PATIENT && role != models.DOCTOR {
+		return nil, 0, ErrInvalidRole
+	}
+	return s.Users.ListByRole(role, limit, (page-1)*limit)
+}
+
+// PatientProfileInput holds the editable profile fields. Phone and email are
+// login identifiers and only ever come from OTP verification.
+type PatientProfileInput struct {
+	Name    string
+	Age     int
+	Gender  string
+	Bio     string
+	Address string
+}
+
+func (s *UserService) UpdatePatientProfile(userID string, in PatientProfileInput) error {
+	user, err := s.Users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	user.Name = in.Name
+	user.Age = in.Age
+	user.Gender = in.Gender
+	user.Bio = in.Bio
+	user.Address = in.Address
+	return s.Users.Save(user)
+}
+
+func (s *UserService) UpdatePhoto(userID, photoURL string) error {
+	return s.Users.Update(userID, map[string]interface{}{"photo_url": photoURL})
+}
@@ -0,0 +1,92 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/GitNinja36/wello-backend/internal/models"
+	"github.com/GitNinja36/wello-backend/internal/repository"
+)
+
+func TestOnboardPatient(t *testing.T) {
+	s := NewUserService(repository.NewMemoryRepos())
+
+	user, err := s.OnboardPatient(PatientInput{Name: "Asha", Phone: "+919800000001", Age: 31})
+	if err != nil {
+		t.Fatalf("onboard: %v", err)
+	}
+	if user.Role != models.PATIENT || !user.Verified || !user.IsApproved {
+		t.Fatalf("got role %s verified %v approved %v", user.Role, user.Verified, user.IsApproved)
+	}
+
+	stored, err := s.Get(user.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if stored.Phone != "+919800000001" || stored.Age != 31 {
+		t.Fatalf("stored %+v", stored)
+	}
+}
+
+func TestOnboardRejectsTakenIdentifier(t *testing.T) {
+	s := NewUserService(repository.NewMemoryRepos())
+	if _, err := s.OnboardPatient(PatientInput{Name: "Asha", Phone: "+919800000001"}); err != nil {
+		t.Fatalf("onboard: %v", err)
+	}
+
+	if _, err := s.OnboardPatient(PatientInput{Name: "Other", Phone: "+919800000001"}); !errors.Is(err, ErrAccountExists) {
+		t.Fatalf("same phone as patient: got %v, want ErrAccountExists", err)
+	}
+	if _, err := s.OnboardDoctor(DoctorRegistration{Name: "Dr Other", Phone: "+919800000001"}); !errors.Is(err, ErrAccountExists) {
+		t.Fatalf("same phone as doctor: got %v, want ErrAccountExists", err)
+	}
+	// a blank email on both accounts is not a clash
+	if _, err := s.OnboardPatient(PatientInput{Name: "Ravi", Phone: "+919800000002"}); err != nil {
+		t.Fatalf("different phone: %v", err)
+	}
+}
+
+func TestOnboardRequiresName(t *testing.T) {
+	s := NewUserService(repository.NewMemoryRepos())
+	if _, err := s.OnboardPatient(PatientInput{Name: "  ", Email: "a@example.com"}); !errors.Is(err, ErrNameRequired) {
+		t.Fatalf("patient: got %v, want ErrNameRequired", err)
+	}
+	if _, err := s.OnboardDoctor(DoctorRegistration{Email: "d@example.com"}); !errors.Is(err, ErrNameRequired) {
+		t.Fatalf("doctor: got %v, want ErrNameRequired", err)
+	}
+}
+
+func TestOnboardDoctorAwaitsApproval(t *testing.T) {
+	repos := repository.NewMemoryRepos()
+	s := NewUserService(repos)
+
+	user, err := s.OnboardDoctor(DoctorRegistration{
+		Name:             "Dr Mehta",
+		Email:            "mehta@example.com",
+		Specialization:   "Dermatology",
+		ConsultationFees: 500,
+	})
+	if err != nil {
+		t.Fatalf("onboard: %v", err)
+	}
+	if user.Role != models.DOCTOR || user.IsApproved || !user.RequestedAsDoctor {
+		t.Fatalf("got role %s approved %v requested %v", user.Role, user.IsApproved, user.RequestedAsDoctor)
+	}
+
+	profile, err := NewDoctorService(repos, nil).Profile(user.ID)
+	if err != nil {
+		t.Fatalf("profile: %v", err)
+	}
+	if !profile.IsPending || profile.ConsultationFees != 500 {
+		t.Fatalf("profile %+v", profile)
+	}
+
+	// pending doctors are not listed publicly
+	listed, err := repos.Doctors.List(repository.DoctorFilter{})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if len(listed) != 0 {
+		t.Fatalf("listed %d pending doctors", len(listed))
+	}
+}
@@ -0,0 +1,102 @@
+// Package totp implements RFC 6238 time-based one-time passwords (HMAC-SHA1,
+// the variant every authenticator app supports). All functions take the
+// current time as an argument so callers control the clock.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Config controls code length, step size and the accepted clock drift.
+type Config struct {
+	Digits int
+	Period time.Duration
+	// Skew is how many steps before and after the current one are accepted.
+	Skew int
+}
+
+var Default = Config{Digits: 6, Period: 30 * time.Second, Skew: 1}
+
+// NewSecret returns a random 160-bit secret, base32 encoded.
+func NewSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(b), nil
+}
+
+// DecodeSecret accepts a base32 secret, ignoring case, spaces and padding.
+func DecodeSecret(secret string) ([]byte, error) {
+	s := strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return encoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+// Counter returns the time step t falls in.
+func (c Config) Counter(t time.Time) int64 {
+	return t.Unix() / int64(c.Period/time.Second)
+}
+
+// CodeAt returns the code for a given time step (RFC 4226 HOTP).
+func (c Config) CodeAt(secret []byte, counter int64) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+	mac := hmac.New(sha1.New, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < c.Digits; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", c.Digits, value%mod)
+}
+
+// Code returns the code valid at t.
+func (c Config) Code(secret []byte, t time.Time) string {
+	return c.CodeAt(secret, c.Counter(t))
+}
+
+// Verify checks code against the steps around t. On success it returns the
+// matched step so callers can reject reuse of the same or an older step.
+func (c Config) Verify(secret []byte, code string, t time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != c.Digits {
+		return 0, false
+	}
+	now := c.Counter(t)
+	for i := -c.Skew; i <= c.Skew; i++ {
+		counter := now + int64(i)
+		if subtle.ConstantTimeCompare([]byte(c.CodeAt(secret, counter)), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+	return 0, false
+}
+
+// ProvisioningURI builds the otpauth:// URI authenticator apps read from a QR code.
+func (c Config) ProvisioningURI(issuer, account, secret string) string {
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(c.Digits))
+	q.Set("period", fmt.Sprint(int(c.Period/time.Second)))
+
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
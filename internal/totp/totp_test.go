@@ -0,0 +1,92 @@
+package totp
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of the RFC 6238 Appendix B test vectors.
+var rfcSecret = []byte("12345678901234567890")
+
+func TestRFC6238Vectors(t *testing.T) {
+	eight := Config{Digits: 8, Period: 30 * time.Second}
+	tests := []struct {
+		unix    int64
+		counter int64
+		code    string
+	}{
+		{59, 0x1, "94287082"},
+		{1111111109, 0x23523EC, "07081804"},
+		{1111111111, 0x23523ED, "14050471"},
+		{1234567890, 0x273EF07, "89005924"},
+		{2000000000, 0x3F940AA, "69279037"},
+		{20000000000, 0x27BC86AA, "65353130"},
+	}
+	for _, tc := range tests {
+		at := time.Unix(tc.unix, 0).UTC()
+		if got := eight.Counter(at); got != tc.counter {
+			t.Errorf("T=%d: counter %#x, want %#x", tc.unix, got, tc.counter)
+		}
+		if got := eight.Code(rfcSecret, at); got != tc.code {
+			t.Errorf("T=%d: code %s, want %s", tc.unix, got, tc.code)
+		}
+		// six digits are the same value truncated, as authenticator apps show
+		if got, want := Default.Code(rfcSecret, at), tc.code[2:]; got != want {
+			t.Errorf("T=%d: 6-digit code %s, want %s", tc.unix, got, want)
+		}
+	}
+}
+
+func TestVerifySkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Default.Period
+
+	tests := []struct {
+		name   string
+		codeAt time.Time
+		ok     bool
+	}{
+		{"current step", now, true},
+		{"previous step", now.Add(-step), true},
+		{"next step", now.Add(step), true},
+		{"two steps old", now.Add(-2 * step), false},
+		{"two steps ahead", now.Add(2 * step), false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			code := Default.Code(rfcSecret, tc.codeAt)
+			counter, ok := Default.Verify(rfcSecret, code, now)
+			if ok != tc.ok {
+				t.Fatalf("Verify = %v, want %v", ok, tc.ok)
+			}
+			if ok && counter != Default.Counter(tc.codeAt) {
+				t.Fatalf("matched step %d, want %d", counter, Default.Counter(tc.codeAt))
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsMalformed(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "abcdef"} {
+		if _, ok := Default.Verify(rfcSecret, code, now); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+	if _, ok := Default.Verify(rfcSecret, " 287082 ", now); !ok {
+		t.Error("surrounding spaces should be ignored")
+	}
+}
+
+func TestDecodeSecret(t *testing.T) {
+	encoded := encoding.EncodeToString(rfcSecret)
+	spaced := strings.ToLower(encoded[:8] + " " + encoded[8:] + "====")
+	got, err := DecodeSecret(spaced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(rfcSecret) {
+		t.Fatalf("decoded %q, want %q", got, rfcSecret)
+	}
+}
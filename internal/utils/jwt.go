@@ -2,47 +2,103 @@ package utils
 
 import (
 	"fmt"
-	"log"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var jwtSecret = []byte(os.Getenv("JWT_SECRET"))
+const (
+	ScopeOnboarding = "onboarding"
+	ScopeLoginOTP   = "login-otp"
+	ScopeLoginTOTP  = "login-totp"
+
+	IdentifierPhone = "phone"
+	IdentifierEmail = "email"
+)
 
 type Claims struct {
-	UserID       string `json:"userId"`
-	Role         string `json:"role"`
-	IsApproved   bool   `json:"isApproved"`
-	NeedsProfile bool   `json:"needsProfile"`
+	UserID         string `json:"userId"`
+	Role           string `json:"role"`
+	IsApproved     bool   `json:"isApproved"`
+	NeedsProfile   bool   `json:"needsProfile"`
+	Scope          string `json:"scope,omitempty"`
+	Identifier     string `json:"identifier,omitempty"`
+	IdentifierType string `json:"identifierType,omitempty"`
+	SessionID      string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func GenerateJWT(userID, role string, isApproved, needsProfile bool) string {
+// AccessTokenTTL is deliberately short; clients renew through /auth/refresh.
+const AccessTokenTTL = 15 * time.Minute
+
+// GenerateJWT issues an access token bound to sessionID, so revoking the
+// session revokes the token.
+func (m *KeyManager) GenerateJWT(userID, role string, isApproved, needsProfile bool, sessionID string) (string, error) {
 	claims := Claims{
 		UserID:       userID,
 		Role:         role,
 		IsApproved:   isApproved,
 		NeedsProfile: needsProfile,
+		SessionID:    sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(72 * time.Hour)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	if m == nil {
+		return "", ErrKeysNotLoaded
+	}
+	tokenString, err := m.Sign(claims)
 	if err != nil {
-		log.Println("Error signing JWT:", err)
-		return ""
+		return "", fmt.Errorf("signing JWT: %w", err)
 	}
-	return tokenString
+	return tokenString, nil
 }
 
-func ParseJWT(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return jwtSecret, nil
-	})
+// GenerateOnboardingJWT issues a 15-minute token that only proves the holder
+// verified an OTP for identifier. It can be used solely to finish signup.
+func (m *KeyManager) GenerateOnboardingJWT(identifier, identifierType string) (string, error) {
+	claims := Claims{
+		NeedsProfile:   true,
+		Scope:          ScopeOnboarding,
+		Identifier:     identifier,
+		IdentifierType: identifierType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
+		},
+	}
+
+	if m == nil {
+		return "", ErrKeysNotLoaded
+	}
+	return m.Sign(claims)
+}
+
+// GenerateChallengeJWT issues a 5-minute token proving userID passed the
+// first login factor. It is only accepted by the matching second-step endpoint.
+func (m *KeyManager) GenerateChallengeJWT(userID, scope string) (string, error) {
+	claims := Claims{
+		UserID: userID,
+		Scope:  scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(5 * time.Minute)),
+		},
+	}
+
+	if m == nil {
+		return "", ErrKeysNotLoaded
+	}
+	return m.Sign(claims)
+}
+
+func (m *KeyManager) ParseJWT(tokenString string) (*Claims, error) {
+	if m == nil {
+		return nil, ErrKeysNotLoaded
+	}
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, m.Keyfunc,
+		jwt.WithValidMethods([]string{"HS256", "RS256", "EdDSA"}),
+		jwt.WithExpirationRequired())
 	if err != nil {
 		return nil, err
 	}
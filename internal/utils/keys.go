@@ -0,0 +1,296 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	minSecretLength = 32
+	minRSABits      = 2048
+)
+
+var (
+	ErrKeysNotLoaded = errors.New("JWT keys not loaded")
+	ErrUnknownKeyID  = errors.New("unknown JWT key id")
+	ErrWeakSecret    = fmt.Errorf("JWT_SECRET must be at least %d bytes", minSecretLength)
+)
+
+// SigningKey is one JWT key. HMAC keys carry only Secret; asymmetric keys
+// carry a Public key and, for the active key, a Private one.
+type SigningKey struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Secret  []byte
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+func (k *SigningKey) signingKey() interface{} {
+	if k.Secret != nil {
+		return k.Secret
+	}
+	return k.Private
+}
+
+func (k *SigningKey) verifyKey() interface{} {
+	if k.Secret != nil {
+		return k.Secret
+	}
+	return k.Public
+}
+
+// KeyManager signs with one active key and verifies with any loaded key, so
+// keys can be rotated without invalidating tokens signed by the previous one.
+type KeyManager struct {
+	mu     sync.RWMutex
+	active *SigningKey
+	keys   map[string]*SigningKey
+}
+
+func NewKeyManager(active *SigningKey, previous ...*SigningKey) *KeyManager {
+	m := &KeyManager{active: active, keys: map[string]*SigningKey{active.ID: active}}
+	for _, k := range previous {
+		m.keys[k.ID] = k
+	}
+	return m
+}
+
+func (m *KeyManager) Sign(claims jwt.Claims) (string, error) {
+	m.mu.RLock()
+	key := m.active
+	m.mu.RUnlock()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signingKey())
+}
+
+// Keyfunc resolves the verification key from the token's kid header and
+// refuses tokens whose alg does not match that key.
+func (m *KeyManager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	m.mu.RLock()
+	key, ok := m.keys[kid]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrUnknownKeyID
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s", token.Method.Alg())
+	}
+	return key.verifyKey(), nil
+}
+
+// Rotate makes next the signing key while keeping the old one for verification.
+func (m *KeyManager) Rotate(next *SigningKey) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.active = next
+	m.keys[next.ID] = next
+}
+
+// JWK is a public key in RFC 7517 form.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JWKS lists the public verification keys. HMAC secrets are never published.
+func (m *KeyManager) JWKS() []JWK {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	out := []JWK{}
+	for _, k := range m.keys {
+		switch pub := k.Public.(type) {
+		case *rsa.PublicKey:
+			out = append(out, JWK{
+				Kty: "RSA", Kid: k.ID, Alg: k.Method.Alg(), Use: "sig",
+				N: base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E: base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			out = append(out, JWK{
+				Kty: "OKP", Kid: k.ID, Alg: k.Method.Alg(), Use: "sig",
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	return out
+}
+
+// LoadKeysFromEnv loads the JWT keys from the environment. It must run after
+// the .env file has been loaded and fails on missing or weak key material.
+//
+//	JWT_ALG              HS256 (default), RS256 or EdDSA
+//	JWT_KID              id of the active key (default "primary")
+//	JWT_SECRET           HMAC secret for HS256, at least 32 bytes
+//	JWT_PREVIOUS_SECRETS "kid:secret,..." still accepted for verification
+//	JWT_PRIVATE_KEY_FILE PEM (PKCS#8 or PKCS#1) private key for RS256/EdDSA,
+//	                     of the type JWT_ALG names
+//	JWT_PUBLIC_KEY_FILES "kid:path,..." previous public keys for verification,
+//	                     RSA or Ed25519, so signing can move between the two
+func LoadKeysFromEnv() (*KeyManager, error) {
+	kid := os.Getenv("JWT_KID")
+	if kid == "" {
+		kid = "primary"
+	}
+
+	alg := strings.ToUpper(os.Getenv("JWT_ALG"))
+	switch alg {
+	case "", "HS256":
+		// key files mean asymmetric signing was intended; do not silently
+		// fall back to the shared secret
+		if os.Getenv("JWT_PRIVATE_KEY_FILE") != "" || os.Getenv("JWT_PUBLIC_KEY_FILES") != "" {
+			return nil, errors.New("JWT key files are set but JWT_ALG is HS256")
+		}
+		active, err := NewHMACKey(kid, os.Getenv("JWT_SECRET"))
+		if err != nil {
+			return nil, err
+		}
+		var previous []*SigningKey
+		for _, pair := range splitPairs(os.Getenv("JWT_PREVIOUS_SECRETS")) {
+			k, err := NewHMACKey(pair[0], pair[1])
+			if err != nil {
+				return nil, fmt.Errorf("JWT_PREVIOUS_SECRETS %s: %w", pair[0], err)
+			}
+			previous = append(previous, k)
+		}
+		return NewKeyManager(active, previous...), nil
+
+	case "RS256", "EDDSA":
+		active, err := privateKeyFromFile(kid, os.Getenv("JWT_PRIVATE_KEY_FILE"))
+		if err != nil {
+			return nil, err
+		}
+		if !strings.EqualFold(active.Method.Alg(), alg) {
+			return nil, fmt.Errorf("JWT_ALG is %s but JWT_PRIVATE_KEY_FILE is a key for %s", alg, active.Method.Alg())
+		}
+		var previous []*SigningKey
+		for _, pair := range splitPairs(os.Getenv("JWT_PUBLIC_KEY_FILES")) {
+			k, err := publicKeyFromFile(pair[0], pair[1])
+			if err != nil {
+				return nil, fmt.Errorf("JWT_PUBLIC_KEY_FILES %s: %w", pair[0], err)
+			}
+			previous = append(previous, k)
+		}
+		return NewKeyManager(active, previous...), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALG %q", alg)
+	}
+}
+
+// NewHMACKey builds an HS256 key, refusing secrets shorter than 32 bytes.
+func NewHMACKey(kid, secret string) (*SigningKey, error) {
+	if len(secret) < minSecretLength {
+		return nil, ErrWeakSecret
+	}
+	return &SigningKey{ID: kid, Method: jwt.SigningMethodHS256, Secret: []byte(secret)}, nil
+}
+
+func privateKeyFromFile(kid, path string) (*SigningKey, error) {
+	if path == "" {
+		return nil, errors.New("JWT_PRIVATE_KEY_FILE is required for asymmetric signing")
+	}
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var parsed interface{}
+	if parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
+		if parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PrivateKey:
+		if err := checkRSASize(&key.PublicKey); err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, Private: key, Public: &key.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, Private: key, Public: key.Public()}, nil
+	default:
+		return nil, fmt.Errorf("unsupported private key type %T", parsed)
+	}
+}
+
+func publicKeyFromFile(kid, path string) (*SigningKey, error) {
+	block, err := readPEM(path)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+
+	switch key := parsed.(type) {
+	case *rsa.PublicKey:
+		if err := checkRSASize(key); err != nil {
+			return nil, err
+		}
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodRS256, Public: key}, nil
+	case ed25519.PublicKey:
+		return &SigningKey{ID: kid, Method: jwt.SigningMethodEdDSA, Public: key}, nil
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", parsed)
+	}
+}
+
+// checkRSASize refuses RSA keys shorter than minRSABits, for signing and
+// verification alike: a weak previous key is as forgeable as a weak active one.
+func checkRSASize(key *rsa.PublicKey) error {
+	if key.N.BitLen() < minRSABits {
+		return fmt.Errorf("RSA keys must be at least %d bits", minRSABits)
+	}
+	return nil
+}
+
+func readPEM(path string) (*pem.Block, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("%s is not PEM encoded", path)
+	}
+	return block, nil
+}
+
+// splitPairs parses "a:b,c:d" into [[a b] [c d]], skipping malformed parts.
+func splitPairs(s string) [][2]string {
+	var out [][2]string
+	for _, part := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if ok && k != "" && v != "" {
+			out = append(out, [2]string{k, v})
+		}
+	}
+	return out
+}
@@ -0,0 +1,220 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+// writePEM stores der as a PEM block of type typ in a temp file.
+func writePEM(t *testing.T, typ string, der []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), strings.ReplaceAll(strings.ToLower(typ), " ", "-")+".pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func privateKeyFile(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, "PRIVATE KEY", der)
+}
+
+func publicKeyFile(t *testing.T, key interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, "PUBLIC KEY", der)
+}
+
+func rsaKey(t *testing.T, bits int) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, bits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func edKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestLoadKeysFromEnv(t *testing.T) {
+	strong := rsaKey(t, 2048)
+	weak := rsaKey(t, 1024)
+	ed := edKey(t)
+	strongFile := privateKeyFile(t, strong)
+	weakFile := privateKeyFile(t, weak)
+	edFile := privateKeyFile(t, ed)
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantAlg string
+		wantErr string
+	}{
+		{"HS256 by default", map[string]string{"JWT_SECRET": testSecret}, "HS256", ""},
+		{"short secret", map[string]string{"JWT_SECRET": "short"}, "", "at least 32 bytes"},
+		{"short previous secret", map[string]string{"JWT_SECRET": testSecret, "JWT_PREVIOUS_SECRETS": "old:short"}, "", "JWT_PREVIOUS_SECRETS old"},
+		{"key file with HS256", map[string]string{"JWT_SECRET": testSecret, "JWT_PRIVATE_KEY_FILE": strongFile}, "", "JWT_ALG is HS256"},
+
+		{"RS256", map[string]string{"JWT_ALG": "RS256", "JWT_PRIVATE_KEY_FILE": strongFile}, "RS256", ""},
+		{"EdDSA", map[string]string{"JWT_ALG": "EdDSA", "JWT_PRIVATE_KEY_FILE": edFile}, "EdDSA", ""},
+		{"RS256 with an Ed25519 key", map[string]string{"JWT_ALG": "RS256", "JWT_PRIVATE_KEY_FILE": edFile}, "", "is a key for EdDSA"},
+		{"EdDSA with an RSA key", map[string]string{"JWT_ALG": "EdDSA", "JWT_PRIVATE_KEY_FILE": strongFile}, "", "is a key for RS256"},
+		{"weak RSA signing key", map[string]string{"JWT_ALG": "RS256", "JWT_PRIVATE_KEY_FILE": weakFile}, "", "at least 2048 bits"},
+		{"weak previous RSA key", map[string]string{"JWT_ALG": "RS256", "JWT_PRIVATE_KEY_FILE": strongFile,
+			"JWT_PUBLIC_KEY_FILES": "old:" + publicKeyFile(t, &weak.PublicKey)}, "", "at least 2048 bits"},
+		{"previous Ed25519 key while on RSA", map[string]string{"JWT_ALG": "RS256", "JWT_PRIVATE_KEY_FILE": strongFile,
+			"JWT_PUBLIC_KEY_FILES": "old:" + publicKeyFile(t, ed.Public())}, "RS256", ""},
+		{"missing key file", map[string]string{"JWT_ALG": "RS256"}, "", "JWT_PRIVATE_KEY_FILE is required"},
+		{"unknown algorithm", map[string]string{"JWT_ALG": "none"}, "", "unsupported JWT_ALG"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, k := range []string{"JWT_ALG", "JWT_KID", "JWT_SECRET", "JWT_PREVIOUS_SECRETS", "JWT_PRIVATE_KEY_FILE", "JWT_PUBLIC_KEY_FILES"} {
+				t.Setenv(k, tc.env[k])
+			}
+			m, err := LoadKeysFromEnv()
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("load: %v", err)
+			}
+			if got := m.active.Method.Alg(); got != tc.wantAlg {
+				t.Fatalf("signing with %s, want %s", got, tc.wantAlg)
+			}
+		})
+	}
+}
+
+func TestKeyfunc(t *testing.T) {
+	rsaPriv := rsaKey(t, 2048)
+	hmacKey, err := NewHMACKey("hmac", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSigning := &SigningKey{ID: "rsa", Method: jwt.SigningMethodRS256, Private: rsaPriv, Public: &rsaPriv.PublicKey}
+	m := NewKeyManager(rsaSigning, hmacKey)
+
+	sign := func(method jwt.SigningMethod, kid string, key interface{}) string {
+		token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "u1"})
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		s, err := token.SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&rsaPriv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"active key", sign(jwt.SigningMethodRS256, "rsa", rsaPriv), nil},
+		{"previous key", sign(jwt.SigningMethodHS256, "hmac", []byte(testSecret)), nil},
+		{"unknown kid", sign(jwt.SigningMethodHS256, "gone", []byte(testSecret)), ErrUnknownKeyID},
+		{"no kid", sign(jwt.SigningMethodRS256, "", rsaPriv), ErrUnknownKeyID},
+		// the classic confusion: HMAC keyed with the published RSA key
+		{"HS256 under an RSA kid", sign(jwt.SigningMethodHS256, "rsa", publicDER), jwt.ErrTokenUnverifiable},
+		{"RS256 under an HMAC kid", sign(jwt.SigningMethodRS256, "hmac", rsaPriv), jwt.ErrTokenUnverifiable},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := jwt.Parse(tc.token, m.Keyfunc)
+			if tc.want == nil {
+				if err != nil {
+					t.Fatalf("rejected: %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tc.want) {
+				t.Fatalf("got %v, want %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	rsaPriv := rsaKey(t, 2048)
+	ed := edKey(t)
+	hmacKey, err := NewHMACKey("hmac", testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := NewKeyManager(
+		&SigningKey{ID: "rsa", Method: jwt.SigningMethodRS256, Private: rsaPriv, Public: &rsaPriv.PublicKey},
+		&SigningKey{ID: "ed", Method: jwt.SigningMethodEdDSA, Public: ed.Public()},
+		hmacKey,
+	)
+
+	byKid := map[string]JWK{}
+	for _, k := range m.JWKS() {
+		byKid[k.Kid] = k
+	}
+	if len(byKid) != 2 {
+		t.Fatalf("published %d keys, want the RSA and Ed25519 ones only", len(byKid))
+	}
+	if _, ok := byKid["hmac"]; ok {
+		t.Fatal("HMAC secret published")
+	}
+
+	r := byKid["rsa"]
+	if r.Kty != "RSA" || r.Alg != "RS256" || r.Use != "sig" {
+		t.Fatalf("RSA key %+v", r)
+	}
+	n, err := base64.RawURLEncoding.DecodeString(r.N)
+	if err != nil || new(big.Int).SetBytes(n).Cmp(rsaPriv.N) != 0 {
+		t.Fatalf("modulus does not round-trip: %v", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(r.E)
+	if err != nil || new(big.Int).SetBytes(e).Int64() != int64(rsaPriv.E) {
+		t.Fatalf("exponent does not round-trip: %v", err)
+	}
+
+	o := byKid["ed"]
+	if o.Kty != "OKP" || o.Crv != "Ed25519" || o.Alg != "EdDSA" {
+		t.Fatalf("Ed25519 key %+v", o)
+	}
+	x, err := base64.RawURLEncoding.DecodeString(o.X)
+	if err != nil || !ed25519.PublicKey(x).Equal(ed.Public()) {
+		t.Fatalf("public key does not round-trip: %v", err)
+	}
+}